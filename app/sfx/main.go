@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"satellite/shell"
+)
+
+// satellite-sfx is the minimal extraction stub of self-extracting executables,
+// satellite shell -t sfx append the payload and trailer after it
+func main() {
+	dest := flag.String("o", ".", "output path: extract the payload into this path")
+	passphrase := flag.String("p", "", "passphrase: used by encrypted payload, asked when it is empty")
+	flag.Parse()
+	err := shell.ExtractSfxExecutable(*dest, *passphrase)
+	if err != nil {
+		fmt.Println("Extract failure:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Extract success.")
+}
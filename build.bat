@@ -50,6 +50,8 @@ goto build_go_app
     echo [Build Debug]
     echo go build -o bin/satellite.exe main.go
     go build -o bin/satellite.exe main.go
+    echo go build -ldflags="-w -s" -o bin/satellite-sfx.exe ./app/sfx
+    go build -ldflags="-w -s" -o bin/satellite-sfx.exe ./app/sfx
     echo.
     echo.
 
@@ -67,6 +69,8 @@ goto build_go_app
     echo [Build Release]
     echo go build -ldflags="-w -s" -o bin/satellite.exe main.go
     go build -ldflags="-w -s" -o bin/satellite.exe main.go
+    echo go build -ldflags="-w -s" -o bin/satellite-sfx.exe ./app/sfx
+    go build -ldflags="-w -s" -o bin/satellite-sfx.exe ./app/sfx
     echo.
     echo.
 
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	. "satellite/global"
+	"satellite/nets"
+)
+
+// MD5Encode function
+// md5 hex digest of src
+func (c *TClient) MD5Encode(ctx context.Context, src string) (string, error) {
+	var r nets.TNetsRpcPackMD5EncodeResp
+	err := c.Call(ctx, "GoApi.MD5Encode", nets.TNetsRpcPackMD5EncodeReq{Src: src}, &r)
+	return r.Dest, err
+}
+
+// SHAEncode function
+// hex digest of src by "sha1", "sha256" or "sha512"
+func (c *TClient) SHAEncode(ctx context.Context, src string, tp string) (string, error) {
+	var r nets.TNetsRpcHashResp
+	err := c.Call(ctx, "GoApi.SHAEncode", nets.TNetsRpcHashReq{Src: src, Type: tp}, &r)
+	return r.Dest, err
+}
+
+// HMACEncode function
+// hex HMAC of src with key by "sha1", "sha256" or "sha512"
+func (c *TClient) HMACEncode(ctx context.Context, src string, key string, tp string) (string, error) {
+	var r nets.TNetsRpcHashResp
+	err := c.Call(ctx, "GoApi.HMACEncode", nets.TNetsRpcHashReq{Src: src, Type: tp, Key: key}, &r)
+	return r.Dest, err
+}
+
+// Pack function
+// pack remote source files into remote dest package
+func (c *TClient) Pack(ctx context.Context, request nets.TNetsRpcPackReq) (r nets.TNetsRpcPackResp, err error) {
+	err = c.Call(ctx, "GoApi.Pack", request, &r)
+	return r, err
+}
+
+// Unpack function
+// unpack remote package into remote dest directory
+func (c *TClient) Unpack(ctx context.Context, request nets.TNetsRpcUnpackReq) (r nets.TNetsRpcUnpackResp, err error) {
+	err = c.Call(ctx, "GoApi.Unpack", request, &r)
+	return r, err
+}
+
+// ExtractInfo function
+// algorithm and files of remote package
+func (c *TClient) ExtractInfo(ctx context.Context, src string) (r nets.TNetsRpcExtractInfoResp, err error) {
+	err = c.Call(ctx, "GoApi.ExtractInfo", nets.TNetsRpcExtractInfoReq{Src: src}, &r)
+	return r, err
+}
+
+// Compress function
+// compress remote source files into remote dest archive
+func (c *TClient) Compress(ctx context.Context, request nets.TNetsRpcCompReq) (r nets.TNetsRpcCompResp, err error) {
+	err = c.Call(ctx, "GoApi.Compress", request, &r)
+	return r, err
+}
+
+// Decompress function
+// decompress remote archive into remote dest directory
+func (c *TClient) Decompress(ctx context.Context, request nets.TNetsRpcDecompReq) (r nets.TNetsRpcDecompResp, err error) {
+	err = c.Call(ctx, "GoApi.Decompress", request, &r)
+	return r, err
+}
+
+// IniGet function
+// value of section and name in remote ini file
+func (c *TClient) IniGet(ctx context.Context, request nets.TNetsRpcIniReq) (string, error) {
+	var r nets.TNetsRpcIniResp
+	err := c.Call(ctx, "GoApi.IniGet", request, &r)
+	return r.Value, err
+}
+
+// IniSet function
+// set value of section and name in remote ini file
+func (c *TClient) IniSet(ctx context.Context, request nets.TNetsRpcIniReq) error {
+	return c.Call(ctx, "GoApi.IniSet", request, &nets.TNetsRpcIniResp{})
+}
+
+// QRCode function
+// QR code PNG of content
+func (c *TClient) QRCode(ctx context.Context, content string, size int) ([]byte, error) {
+	var r nets.TNetsRpcQRCodeResp
+	err := c.Call(ctx, "GoApi.QRCode", nets.TNetsRpcQRCodeReq{Content: content, Size: size}, &r)
+	return r.Data, err
+}
+
+// Download function
+// copy remote file to local file in chunks
+func (c *TClient) Download(ctx context.Context, remote string, local string) (n int64, err error) {
+	file, err := os.Create(local)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return n, err
+	}
+	defer file.Close()
+	for {
+		var r nets.TNetsRpcDownloadResp
+		err = c.Call(ctx, "GoApi.Download", nets.TNetsRpcDownloadReq{Path: remote, Offset: n, Size: RpcChunkSize}, &r)
+		if err != nil {
+			return n, err
+		}
+		_, err = file.Write(r.Data)
+		if err != nil {
+			log.Println("Error write file:", err)
+			return n, err
+		}
+		n += int64(len(r.Data))
+		if r.EOF || len(r.Data) == 0 {
+			return n, nil
+		}
+	}
+}
+
+// Upload function
+// copy local file to remote file in chunks
+func (c *TClient) Upload(ctx context.Context, local string, remote string) (n int64, err error) {
+	file, err := os.Open(local)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return n, err
+	}
+	defer file.Close()
+	buf := make([]byte, RpcChunkSize)
+	for {
+		m, err := io.ReadFull(file, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			log.Println("Error read file:", err)
+			return n, err
+		}
+		// empty file still create remote file
+		if m == 0 && n > 0 {
+			return n, nil
+		}
+		var r nets.TNetsRpcUploadResp
+		err = c.Call(ctx, "GoApi.Upload", nets.TNetsRpcUploadReq{Path: remote, Offset: n, Data: buf[:m]}, &r)
+		if err != nil {
+			return n, err
+		}
+		n = r.Size
+		if m < len(buf) {
+			return n, nil
+		}
+	}
+}
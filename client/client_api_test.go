@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"satellite/nets"
+	"testing"
+)
+
+func TestClientTransfer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	err = nets.SetHttpRoots([]nets.TNetsRoot{{Name: "box", Path: dir}})
+	if err != nil {
+		t.Fatal("Error set roots:", err)
+	}
+	defer nets.SetHttpRoots(nil)
+	s := startRpcServer(t, "12017", "tcp")
+	defer s.Shutdown(context.Background())
+	c := NewClient("127.0.0.1:12017", TClientConfig{})
+	defer c.Close()
+	ctx := context.Background()
+	// upload, pack, unpack remotely then download
+	remote := "box:remote.txt"
+	n, err := c.Upload(ctx, "../test/data/pack/file_1.txt", remote)
+	if err != nil {
+		t.Fatal("Error upload:", err)
+	}
+	origin, _ := ioutil.ReadFile("../test/data/pack/file_1.txt")
+	if n != int64(len(origin)) {
+		t.Errorf("Upload %v bytes of %v", n, len(origin))
+	}
+	_, err = c.Pack(ctx, nets.TNetsRpcPackReq{Src: []string{remote}, Dest: "box:file.pak", Type: "DES"})
+	if err != nil {
+		t.Fatal("Error pack:", err)
+	}
+	info, err := c.ExtractInfo(ctx, "box:file.pak")
+	if err != nil || len(info.Files) != 1 || info.Files[0].Name != "remote.txt" {
+		t.Errorf("Extract info %+v: %v", info, err)
+	}
+	_ = os.MkdirAll(filepath.Join(dir, "out"), os.ModePerm)
+	_, err = c.Unpack(ctx, nets.TNetsRpcUnpackReq{Src: "box:file.pak", Dest: "box:out/"})
+	if err != nil {
+		t.Fatal("Error unpack:", err)
+	}
+	local := filepath.Join(dir, "local.txt")
+	_, err = c.Download(ctx, "box:out/remote.txt", local)
+	if err != nil {
+		t.Fatal("Error download:", err)
+	}
+	data, _ := ioutil.ReadFile(local)
+	if !bytes.Equal(origin, data) {
+		t.Errorf("Download %q not equal %q", data, origin)
+	}
+}
+
+func BenchmarkClientTransfer(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	err = nets.SetHttpRoots([]nets.TNetsRoot{{Name: "box", Path: dir}})
+	if err != nil {
+		b.Fatal("Error set roots:", err)
+	}
+	defer nets.SetHttpRoots(nil)
+	s := startRpcServer(b, "12018", "tcp")
+	defer s.Shutdown(context.Background())
+	c := NewClient("127.0.0.1:12018", TClientConfig{})
+	defer c.Close()
+	for i := 0; i < b.N; i++ {
+		_, err = c.Upload(context.Background(), "../test/data/pack/file_1.txt", "box:remote.txt")
+		if err != nil {
+			b.Fatal("Error upload:", err)
+		}
+		_, err = c.Download(context.Background(), "box:remote.txt", filepath.Join(dir, "local.txt"))
+		if err != nil {
+			b.Fatal("Error download:", err)
+		}
+	}
+}
+
+func TestClientHelpers(t *testing.T) {
+	s := startRpcServer(t, "12019", "tcp")
+	defer s.Shutdown(context.Background())
+	c := NewClient("127.0.0.1:12019", TClientConfig{})
+	defer c.Close()
+	ctx := context.Background()
+	for _, v := range []string{"sha1", "sha256", "sha512"} {
+		d, err := c.SHAEncode(ctx, "Satellite", v)
+		if err != nil || d == "" {
+			t.Errorf("%v digest %v: %v", v, d, err)
+		}
+		d, err = c.HMACEncode(ctx, "Satellite", "key", v)
+		if err != nil || d == "" {
+			t.Errorf("%v hmac %v: %v", v, d, err)
+		}
+	}
+	png, err := c.QRCode(ctx, "Satellite", 128)
+	if err != nil || !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Errorf("QR code not PNG: %v", err)
+	}
+}
+
+func BenchmarkClientHelpers(b *testing.B) {
+	s := startRpcServer(b, "12020", "tcp")
+	defer s.Shutdown(context.Background())
+	c := NewClient("127.0.0.1:12020", TClientConfig{})
+	defer c.Close()
+	for i := 0; i < b.N; i++ {
+		_, err := c.SHAEncode(context.Background(), "Satellite", "sha256")
+		if err != nil {
+			b.Fatal("Error sha encode:", err)
+		}
+	}
+}
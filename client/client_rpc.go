@@ -0,0 +1,316 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"reflect"
+	. "satellite/global"
+	"satellite/nets"
+	"sync"
+	"time"
+)
+
+// TClientConfig configure rpc client, zero values use defaults in global
+// Protocol is "tcp" (json codec) or "http" (gob codec), same as rpc server
+// TLS is used to dial when not nil, negative Retries for no retry
+// Token is sent in handshake when server requires it
+type TClientConfig struct {
+	Protocol    string
+	Token       string
+	Timeout     time.Duration
+	DialTimeout time.Duration
+	Retries     int
+	RetryWait   time.Duration
+	PoolSize    int
+	TLS         *tls.Config
+}
+
+// TClient is rpc client of a satellite GoApi server with connection pool,
+// calls are retried on connection errors, errors returned by server and timeouts are not retried,
+// methods changing files are only retried when the request was not sent
+type TClient struct {
+	Addr   string
+	config TClientConfig
+	mutex  sync.Mutex
+	idle   []*rpc.Client
+	closed bool
+}
+
+var (
+	ErrClientClosed  = errors.New("rpc client closed")
+	ErrClientMethod  = errors.New("rpc method not found")
+	ErrClientTimeout = errors.New("rpc call timeout")
+)
+
+// clientIdempotent methods can be sent again after a connection error,
+// the others may already run on server and are not repeated
+var clientIdempotent = map[string]bool{
+	"GoApi.MD5Encode":   true,
+	"GoApi.MD5Equal":    true,
+	"GoApi.SHAEncode":   true,
+	"GoApi.SHAEqual":    true,
+	"GoApi.HMACEncode":  true,
+	"GoApi.ExtractInfo": true,
+	"GoApi.IniGet":      true,
+	"GoApi.Download":    true,
+}
+
+// NewClient function
+// create client of server addr such as "127.0.0.1:13514", no connection is made until first call
+func NewClient(addr string, config TClientConfig) *TClient {
+	if config.Protocol == "" {
+		config.Protocol = "tcp"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = RpcClientTimeout * time.Millisecond
+	}
+	if config.DialTimeout == 0 {
+		config.DialTimeout = RpcClientDialTimeout * time.Millisecond
+	}
+	if config.Retries == 0 {
+		config.Retries = RpcClientRetries
+	} else if config.Retries < 0 {
+		config.Retries = 0
+	}
+	if config.RetryWait == 0 {
+		config.RetryWait = RpcClientRetryWait * time.Millisecond
+	}
+	if config.PoolSize == 0 {
+		config.PoolSize = RpcClientPoolSize
+	}
+	return &TClient{Addr: addr, config: config}
+}
+
+// Call function
+// call method such as "GoApi.MD5Encode" within timeout, retry with backoff on connection errors,
+// methods not idempotent are retried only when connection failed before the request was sent
+func (c *TClient) Call(ctx context.Context, method string, request interface{}, response interface{}) (err error) {
+	var sent bool
+	wait := c.config.RetryWait
+	for i := 0; i <= c.config.Retries; i++ {
+		if i > 0 {
+			log.Printf("Retry rpc call %v after error: %v\n", method, err)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			wait *= 2
+		}
+		err = c.call(ctx, method, request, response, &sent)
+		if !retryable(err) || (sent && !clientIdempotent[method]) {
+			return err
+		}
+	}
+	return err
+}
+
+// call function
+// one attempt on a pooled connection, broken connections are not returned to pool,
+// sent is set once the request is handed to connection
+func (c *TClient) call(ctx context.Context, method string, request interface{}, response interface{}, sent *bool) error {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return err
+	}
+	*sent = true
+	timer := time.NewTimer(c.config.Timeout)
+	defer timer.Stop()
+	call := conn.Go(method, request, response, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		var e rpc.ServerError
+		if call.Error == nil || errors.As(call.Error, &e) {
+			c.put(conn)
+		} else {
+			_ = conn.Close()
+		}
+		return call.Error
+	case <-timer.C:
+		_ = conn.Close()
+		return fmt.Errorf("%w: %v after %v", ErrClientTimeout, method, c.config.Timeout)
+	case <-ctx.Done():
+		_ = conn.Close()
+		return ctx.Err()
+	}
+}
+
+// retryable function
+// connection errors are retried, server errors, unauthorized, timeouts and cancel are not
+func retryable(err error) bool {
+	var e rpc.ServerError
+	switch {
+	case err == nil, errors.As(err, &e), errors.Is(err, ErrClientClosed), errors.Is(err, nets.ErrRpcUnauthorized):
+		return false
+	case errors.Is(err, ErrClientTimeout), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return false
+	}
+	return true
+}
+
+// get function
+// idle connection from pool, or dial a new one
+func (c *TClient) get(ctx context.Context) (*rpc.Client, error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, ErrClientClosed
+	}
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mutex.Unlock()
+		return conn, nil
+	}
+	c.mutex.Unlock()
+	return c.dial(ctx)
+}
+
+// put function
+// return connection to pool, close it when pool full or client closed
+func (c *TClient) put(conn *rpc.Client) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed || len(c.idle) >= c.config.PoolSize {
+		_ = conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// dial function
+// connect server with json codec over tcp, or gob codec after http CONNECT
+func (c *TClient) dial(ctx context.Context) (*rpc.Client, error) {
+	d := &net.Dialer{Timeout: c.config.DialTimeout}
+	var conn net.Conn
+	var err error
+	if c.config.TLS != nil {
+		conn, err = (&tls.Dialer{NetDialer: d, Config: c.config.TLS}).DialContext(ctx, "tcp", c.Addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", c.Addr)
+	}
+	if err != nil {
+		log.Println("Error dial rpc server:", err)
+		return nil, err
+	}
+	switch c.config.Protocol {
+	case "tcp":
+		if c.config.Token != "" {
+			err = c.handshake(conn)
+			if err != nil {
+				_ = conn.Close()
+				log.Println("Error rpc handshake:", err)
+				return nil, err
+			}
+		}
+		return jsonrpc.NewClient(conn), nil
+	case "http":
+		_ = conn.SetDeadline(time.Now().Add(c.config.DialTimeout))
+		header := ""
+		if c.config.Token != "" {
+			header = "Authorization: Bearer " + c.config.Token + "\n"
+		}
+		_, err = io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n"+header+"\n")
+		if err == nil {
+			var resp *http.Response
+			resp, err = http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+			if err == nil && resp.StatusCode == http.StatusUnauthorized {
+				err = nets.ErrRpcUnauthorized
+			} else if err == nil && resp.Status != "200 Connected to Go RPC" {
+				err = errors.New("unexpected HTTP response: " + resp.Status)
+			}
+		}
+		if err != nil {
+			_ = conn.Close()
+			log.Println("Error connect rpc http server:", err)
+			return nil, err
+		}
+		_ = conn.SetDeadline(time.Time{})
+		return rpc.NewClient(conn), nil
+	}
+	_ = conn.Close()
+	return nil, nets.ErrRpcProtocol
+}
+
+// handshake function
+// send "AUTH <token>" line and wait for "OK" line of server
+func (c *TClient) handshake(conn net.Conn) error {
+	_ = conn.SetDeadline(time.Now().Add(c.config.DialTimeout))
+	defer conn.SetDeadline(time.Time{})
+	_, err := io.WriteString(conn, "AUTH "+c.config.Token+"\n")
+	if err != nil {
+		return err
+	}
+	// read byte by byte, data after the line belongs to codec
+	line := make([]byte, 0, 32)
+	b := make([]byte, 1)
+	for len(line) < 512 {
+		_, err = conn.Read(b)
+		if err != nil {
+			return err
+		}
+		if b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
+	}
+	if string(line) != "OK" {
+		return fmt.Errorf("%w: %s", nets.ErrRpcUnauthorized, line)
+	}
+	return nil
+}
+
+// Close function
+// close idle connections, calls after Close return ErrClientClosed
+func (c *TClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+	for _, v := range c.idle {
+		_ = v.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+// CallJSON function
+// call GoApi method with json request, return json response,
+// request and response types are looked up from GoApi method signature
+func (c *TClient) CallJSON(ctx context.Context, method string, request []byte) ([]byte, error) {
+	name := method
+	if len(name) > 6 && name[:6] == "GoApi." {
+		name = name[6:]
+	}
+	m, ok := reflect.TypeOf(new(nets.GoApi)).MethodByName(name)
+	if !ok || m.Type.NumIn() != 3 {
+		return nil, fmt.Errorf("%w: %v", ErrClientMethod, method)
+	}
+	req := reflect.New(m.Type.In(1))
+	if len(request) > 0 {
+		err := json.Unmarshal(request, req.Interface())
+		if err != nil {
+			log.Println("Error unmarshal request:", err)
+			return nil, err
+		}
+	}
+	resp := reflect.New(m.Type.In(2).Elem())
+	err := c.Call(ctx, "GoApi."+name, req.Elem().Interface(), resp.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(resp.Interface(), "", "\t")
+}
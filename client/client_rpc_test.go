@@ -0,0 +1,287 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"satellite/nets"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startRpcServer function
+// start GoApi rpc server and wait until it accepts calls
+func startRpcServer(t testing.TB, port string, protocol string) *nets.TNetsRpcServer {
+	s, err := nets.NewRpcServer("127.0.0.1", port, protocol)
+	if err != nil {
+		t.Fatal("Error create rpc server:", err)
+	}
+	go s.ListenAndServe()
+	c := NewClient("127.0.0.1:"+port, TClientConfig{Protocol: protocol, Retries: 10, RetryWait: 10 * time.Millisecond})
+	defer c.Close()
+	_, err = c.MD5Encode(context.Background(), "Satellite")
+	if err != nil {
+		t.Fatal("Error call rpc server:", err)
+	}
+	return s
+}
+
+func TestClientCall(t *testing.T) {
+	for k, v := range map[string]string{"tcp": "12010", "http": "12011"} {
+		s := startRpcServer(t, v, k)
+		c := NewClient("127.0.0.1:"+v, TClientConfig{Protocol: k, PoolSize: 2})
+		for i := 0; i < 5; i++ {
+			r, err := c.MD5Encode(context.Background(), "Satellite")
+			if err != nil {
+				t.Fatalf("Error %v rpc call: %v", k, err)
+			}
+			if r != "c2b5e73361a4bf9d26a73413d0abee5e" {
+				t.Errorf("%v md5 is %v", k, r)
+			}
+		}
+		if len(c.idle) != 1 {
+			t.Errorf("%v idle connections %v", k, len(c.idle))
+		}
+		// server error is returned without retry and keep connection
+		_, err := c.MD5Encode(context.Background(), "")
+		var e rpc.ServerError
+		if !errors.As(err, &e) {
+			t.Errorf("%v server error is %v", k, err)
+		}
+		if len(c.idle) != 1 {
+			t.Errorf("%v connection dropped after server error", k)
+		}
+		_ = c.Close()
+		_, err = c.MD5Encode(context.Background(), "Satellite")
+		if err != ErrClientClosed {
+			t.Errorf("%v call after close error is %v", k, err)
+		}
+		_ = s.Shutdown(context.Background())
+	}
+}
+
+func BenchmarkClientCall(b *testing.B) {
+	s := startRpcServer(b, "12012", "tcp")
+	defer s.Shutdown(context.Background())
+	c := NewClient("127.0.0.1:12012", TClientConfig{})
+	defer c.Close()
+	for i := 0; i < b.N; i++ {
+		_, err := c.MD5Encode(context.Background(), "Satellite")
+		if err != nil {
+			b.Fatal("Error rpc call:", err)
+		}
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	c := NewClient("127.0.0.1:12013", TClientConfig{Retries: 5, RetryWait: 50 * time.Millisecond})
+	defer c.Close()
+	// server start after first attempt refused
+	s, err := nets.NewRpcServer("127.0.0.1", "12013", "tcp")
+	if err != nil {
+		t.Fatal("Error create rpc server:", err)
+	}
+	defer s.Shutdown(context.Background())
+	time.AfterFunc(60*time.Millisecond, func() { _ = s.ListenAndServe() })
+	_, err = c.MD5Encode(context.Background(), "Satellite")
+	if err != nil {
+		t.Errorf("Error rpc call with retries: %v", err)
+	}
+	// no retry left
+	c = NewClient("127.0.0.1:12014", TClientConfig{Retries: -1})
+	defer c.Close()
+	_, err = c.MD5Encode(context.Background(), "Satellite")
+	if err == nil {
+		t.Error("Call server not listening without error")
+	}
+	// context canceled stop retries
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c = NewClient("127.0.0.1:12014", TClientConfig{Retries: 100, RetryWait: time.Second})
+	start := time.Now()
+	_, err = c.MD5Encode(ctx, "Satellite")
+	if err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Retries not stopped by context: %v after %v", err, time.Since(start))
+	}
+}
+
+func BenchmarkClientRetry(b *testing.B) {
+	c := NewClient("127.0.0.1:12014", TClientConfig{Retries: -1})
+	defer c.Close()
+	for i := 0; i < b.N; i++ {
+		_, _ = c.MD5Encode(context.Background(), "Satellite")
+	}
+}
+
+// startFakeServer function
+// accept connections and read requests, close them when hang is false,
+// return listener and counter of accepted connections
+func startFakeServer(t testing.TB, port string, hang bool) (net.Listener, *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatal("Error listen:", err)
+	}
+	var n int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&n, 1)
+			go func() {
+				defer conn.Close()
+				b := make([]byte, 1024)
+				_, _ = conn.Read(b)
+				if hang {
+					_, _ = io.Copy(ioutil.Discard, conn)
+				}
+			}()
+		}
+	}()
+	return l, &n
+}
+
+func TestClientNoRetry(t *testing.T) {
+	// timeout is not retried
+	l, n := startFakeServer(t, "12026", true)
+	c := NewClient("127.0.0.1:12026", TClientConfig{Timeout: 100 * time.Millisecond, Retries: 3, RetryWait: 10 * time.Millisecond})
+	_, err := c.MD5Encode(context.Background(), "Satellite")
+	if !errors.Is(err, ErrClientTimeout) || atomic.LoadInt32(n) != 1 {
+		t.Errorf("Timeout error %v after %v connections", err, atomic.LoadInt32(n))
+	}
+	c.Close()
+	l.Close()
+	// connection closed after request sent
+	l, n = startFakeServer(t, "12027", false)
+	defer l.Close()
+	c = NewClient("127.0.0.1:12027", TClientConfig{Retries: 3, RetryWait: 10 * time.Millisecond})
+	defer c.Close()
+	_, err = c.Pack(context.Background(), nets.TNetsRpcPackReq{Src: []string{"box:file.txt"}, Dest: "box:file.pak", Type: "AES"})
+	if err == nil || atomic.LoadInt32(n) != 1 {
+		t.Errorf("Pack error %v after %v connections", err, atomic.LoadInt32(n))
+	}
+	_, err = c.MD5Encode(context.Background(), "Satellite")
+	if err == nil || atomic.LoadInt32(n) != 5 {
+		t.Errorf("MD5 error %v after %v connections", err, atomic.LoadInt32(n))
+	}
+}
+
+func BenchmarkClientNoRetry(b *testing.B) {
+	l, _ := startFakeServer(b, "12028", false)
+	defer l.Close()
+	c := NewClient("127.0.0.1:12028", TClientConfig{Retries: 3, RetryWait: time.Millisecond})
+	defer c.Close()
+	for i := 0; i < b.N; i++ {
+		_, _ = c.Pack(context.Background(), nets.TNetsRpcPackReq{Src: []string{"box:file.txt"}, Dest: "box:file.pak", Type: "AES"})
+	}
+}
+
+func TestClientCallJSON(t *testing.T) {
+	s := startRpcServer(t, "12015", "http")
+	defer s.Shutdown(context.Background())
+	c := NewClient("127.0.0.1:12015", TClientConfig{Protocol: "http"})
+	defer c.Close()
+	r, err := c.CallJSON(context.Background(), "GoApi.SHAEncode", []byte(`{"src":"Satellite","type":"sha1"}`))
+	if err != nil {
+		t.Fatal("Error rpc call json:", err)
+	}
+	var h nets.TNetsRpcHashResp
+	err = json.Unmarshal(r, &h)
+	if err != nil {
+		t.Fatal("Error unmarshal response:", err)
+	}
+	var e nets.TNetsRpcHashEqualResp
+	err = c.Call(context.Background(), "GoApi.SHAEqual", nets.TNetsRpcHashEqualReq{Src: "Satellite", Dest: h.Dest, Type: "sha1"}, &e)
+	if err != nil || !e.Equal {
+		t.Errorf("Call json response %s not equal digest: %v", r, err)
+	}
+	_, err = c.CallJSON(context.Background(), "GoApi.Missing", nil)
+	if !errors.Is(err, ErrClientMethod) {
+		t.Errorf("Call missing method error is %v", err)
+	}
+}
+
+func BenchmarkClientCallJSON(b *testing.B) {
+	s := startRpcServer(b, "12016", "tcp")
+	defer s.Shutdown(context.Background())
+	c := NewClient("127.0.0.1:12016", TClientConfig{})
+	defer c.Close()
+	for i := 0; i < b.N; i++ {
+		_, err := c.CallJSON(context.Background(), "GoApi.MD5Encode", []byte(`{"src":"Satellite"}`))
+		if err != nil {
+			b.Fatal("Error rpc call json:", err)
+		}
+	}
+}
+
+func TestClientTLSToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for k, v := range map[string]string{"tcp": "12024", "http": "12025"} {
+		c := nets.TNetsRpcConfig{
+			TLS:   &nets.TNetsHttpsConfig{SelfSigned: true, Cert: filepath.Join(dir, k+".pem"), Key: filepath.Join(dir, k+".key")},
+			Token: "secret",
+		}
+		s, err := nets.NewRpcServerWithConfig("127.0.0.1", v, k, c)
+		if err != nil {
+			t.Fatal("Error create rpc server:", err)
+		}
+		go s.ListenAndServe()
+		config := TClientConfig{Protocol: k, Token: "secret", TLS: &tls.Config{InsecureSkipVerify: true}, Retries: 10, RetryWait: 10 * time.Millisecond}
+		cl := NewClient("127.0.0.1:"+v, config)
+		_, err = cl.MD5Encode(context.Background(), "Satellite")
+		if err != nil {
+			t.Errorf("Error %v rpc call over tls: %v", k, err)
+		}
+		cl.Close()
+		// wrong token is not retried
+		config.Token = "wrong"
+		config.RetryWait = time.Second
+		cl = NewClient("127.0.0.1:"+v, config)
+		start := time.Now()
+		_, err = cl.MD5Encode(context.Background(), "Satellite")
+		if !errors.Is(err, nets.ErrRpcUnauthorized) || time.Since(start) > 500*time.Millisecond {
+			t.Errorf("%v wrong token error is %v after %v", k, err, time.Since(start))
+		}
+		cl.Close()
+		_ = s.Shutdown(context.Background())
+	}
+}
+
+func BenchmarkClientTLSToken(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	c := nets.TNetsRpcConfig{
+		TLS:   &nets.TNetsHttpsConfig{SelfSigned: true, Cert: filepath.Join(dir, "tcp.pem"), Key: filepath.Join(dir, "tcp.key")},
+		Token: "secret",
+	}
+	s, err := nets.NewRpcServerWithConfig("127.0.0.1", "12029", "tcp", c)
+	if err != nil {
+		b.Fatal("Error create rpc server:", err)
+	}
+	go s.ListenAndServe()
+	defer s.Shutdown(context.Background())
+	cl := NewClient("127.0.0.1:12029", TClientConfig{Token: "secret", TLS: &tls.Config{InsecureSkipVerify: true}, Retries: 10, RetryWait: 10 * time.Millisecond})
+	defer cl.Close()
+	for i := 0; i < b.N; i++ {
+		_, err = cl.MD5Encode(context.Background(), "Satellite")
+		if err != nil {
+			b.Fatal("Error rpc call over tls:", err)
+		}
+	}
+}
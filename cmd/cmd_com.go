@@ -35,7 +35,7 @@ Brief: Satellite is a tool for both multiple command and web service develop by
 Version: v1.00a
 Author: alopex
 
-Usage: satellite [help] [pack/unpack] [comp/decomp] [tcp/udp] [http/https/ftp/rpc] [qrcode] [shell] [parses]
+Usage: satellite [help] [pack/unpack] [comp/decomp] [tcp/udp] [http/https/ftp/rpc] [qrcode] [shell] [parses] [discover]
 
 Options:
 	help	- help information about satellite application.
@@ -47,11 +47,12 @@ Options:
 	udp     - udp simple server/client.
 	http 	- http restful server.
 	https 	- https restful server.
-	ftp     - ftp server/client.
+	ftp     - ftp server with chroot root and FTPS.
 	rpc     - rpc server for GoApi support tcp and http.
 	qrcode  - generate qrcode save as images.
 	shell   - shell executable file.
 	parses	- multiple file parser.
+	discover - list satellite nodes announced on LAN.
 `)
 	if err != nil {
 		log.Println("Error print information:", err)
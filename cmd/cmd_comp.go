@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -13,11 +14,17 @@ var compCmd = flag.NewFlagSet(CmdCompress, flag.ExitOnError)
 var compSrc []string
 var compDest string
 var compType string
+var compSnapshot string
+var compPassword string
+var compReproducible bool
 
 func init() {
-	compCmd.Var(NewStrSlice([]string{}, &compSrc), "i", "input files: file list to compress, such as \"file_1.txt,file_2.mov,file_3.png...\"")
-	compCmd.StringVar(&compDest, "o", "", "output files: one file end with 'tar.gz' or 'zip', such as \"file.tar.gz\" or \"file.zip\"")
-	compCmd.StringVar(&compType, "t", "zip", "compress type: one type of enum [tar.gz,zip]")
+	compCmd.Var(NewStrSlice([]string{}, &compSrc), "i", "input files: file list to compress, such as \"file_1.txt,file_2.mov,file_3.png...\" ('-' read stdin, gzip or zlib only)")
+	compCmd.StringVar(&compDest, "o", "", "output files: one file end with 'tar.gz' or 'zip', such as \"file.tar.gz\" or \"file.zip\" ('-' write stdout)")
+	compCmd.StringVar(&compType, "t", "zip", "compress type: one type of enum [tar,tar.gz,zip,gzip,zlib] (gzip and zlib compress one data stream)")
+	compCmd.StringVar(&compSnapshot, "incremental", "", "snapshot file: only compress files changed since the last run recorded in snapshot, such as \"snapshot.json\" (tar or tar.gz only)")
+	compCmd.StringVar(&compPassword, "p", "", "password: encrypt zip entries with WinZip AES-256, such as \"123456\" (zip only)")
+	compCmd.BoolVar(&compReproducible, "reproducible", false, "reproducible mode: sorted entries, timestamps from SOURCE_DATE_EPOCH (default 1980-01-01), normalized ownership and permissions, fixed gzip header (tar, tar.gz, zip, gzip or zlib)")
 }
 
 func ParseCmdComp() {
@@ -33,12 +40,23 @@ func ParseCmdComp() {
 		os.Exit(1)
 	}
 	// handle command parameters
-	err = handleCmdComp(compSrc, compDest, compType)
+	stream := isStream(compDest)
+	if compReproducible && compType != "gzip" && compType != "zlib" {
+		err = handleCmdCompReproducible(compSrc, compDest, compType, compSnapshot, compPassword)
+	} else if stream || (len(compSrc) == 1 && isStream(compSrc[0])) || compType == "gzip" || compType == "zlib" {
+		err = handleCmdCompStream(compSrc, compDest, compType, compSnapshot, compPassword, compReproducible)
+	} else if compSnapshot != "" {
+		err = handleCmdCompIncremental(compSrc, compDest, compType, compSnapshot)
+	} else if compPassword != "" {
+		err = handleCmdCompEncrypt(compSrc, compDest, compType, compPassword)
+	} else {
+		err = handleCmdComp(compSrc, compDest, compType)
+	}
 	if err != nil {
-		fmt.Println("Compress failure:", err)
+		fmt.Fprintln(messageOutput(stream), "Compress failure:", err)
 		os.Exit(1)
 	}
-	fmt.Println("Compress success.")
+	fmt.Fprintln(messageOutput(stream), "Compress success.")
 }
 
 func handleCmdComp(src []string, dest string, algorithm string) (err error) {
@@ -51,3 +69,96 @@ func handleCmdComp(src []string, dest string, algorithm string) (err error) {
 	log.Println("Compress success.")
 	return err
 }
+
+func handleCmdCompIncremental(src []string, dest string, algorithm string, snapshot string) (err error) {
+	// execute incremental compress function
+	err = comp.CompressIncremental(src, dest, algorithm, snapshot)
+	if err != nil {
+		log.Println("Compress incremental failure:", err)
+		return err
+	}
+	log.Println("Compress incremental success.")
+	return err
+}
+
+func handleCmdCompEncrypt(src []string, dest string, algorithm string, password string) (err error) {
+	// execute encrypt compress function
+	err = comp.CompressEncrypt(src, dest, algorithm, password)
+	if err != nil {
+		log.Println("Compress encrypt failure:", err)
+		return err
+	}
+	log.Println("Compress encrypt success.")
+	return err
+}
+
+func handleCmdCompStream(src []string, dest string, algorithm string, snapshot string, password string, reproducible bool) (err error) {
+	if snapshot != "" || password != "" {
+		err = errors.New("stream compress not support incremental or password")
+		return err
+	}
+	// open the output file or stdout
+	out, err := createStreamOutput(dest)
+	if err != nil {
+		log.Println("Error create output:", err)
+		return err
+	}
+	defer out.Close()
+	switch algorithm {
+	case "gzip", "zlib":
+		// compress one data stream, no archive entry
+		if len(src) != 1 {
+			err = errors.New("data stream compress need only one input")
+			return err
+		}
+		in, e := openStreamInput(src[0])
+		if e != nil {
+			log.Println("Error open input:", e)
+			return e
+		}
+		defer in.Close()
+		if reproducible {
+			err = comp.CompressDataStreamReproducible(in, out, algorithm)
+		} else {
+			err = comp.CompressDataStream(in, out, algorithm)
+		}
+	default:
+		if len(src) == 1 && isStream(src[0]) {
+			err = errors.New("stdin input only support 'gzip' or 'zlib'")
+			return err
+		}
+		err = comp.CompressStream(src, out, algorithm)
+	}
+	if err != nil {
+		log.Println("Compress stream failure:", err)
+		return err
+	}
+	log.Println("Compress stream success.")
+	return err
+}
+
+func handleCmdCompReproducible(src []string, dest string, algorithm string, snapshot string, password string) (err error) {
+	if snapshot != "" || password != "" {
+		err = errors.New("reproducible compress not support incremental or password")
+		return err
+	}
+	if len(src) == 1 && isStream(src[0]) {
+		err = errors.New("stdin input only support 'gzip' or 'zlib'")
+		return err
+	}
+	// open the output file or stdout
+	out, err := createStreamOutput(dest)
+	if err != nil {
+		log.Println("Error create output:", err)
+		return err
+	}
+	defer out.Close()
+	// execute reproducible compress function
+	err = comp.CompressReproducibleStream(src, out, algorithm)
+	if err != nil {
+		log.Println("Compress reproducible failure:", err)
+		return err
+	}
+	log.Println("Compress reproducible success.")
+	return err
+}
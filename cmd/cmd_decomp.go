@@ -1,23 +1,29 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"satellite/decomp"
 	. "satellite/global"
+	"strings"
 )
 
 var deCompCmd = flag.NewFlagSet(CmdDecompress, flag.ExitOnError)
 var deCompSrc string
 var deCompDest string
 var deCompType string
+var deCompRestore bool
+var deCompPassword string
 
 func init() {
-	deCompCmd.StringVar(&deCompSrc, "i", "", "input files: compress file, such as \"file.tar.gz\" or \"file.zip\"")
-	deCompCmd.StringVar(&deCompDest, "o", "", "output files: one or more origin files. (should be path not file)")
-	deCompCmd.StringVar(&deCompType, "t", "zip", "decompress type: one type of enum [tar.gz,zip]")
+	deCompCmd.StringVar(&deCompSrc, "i", "", "input files: compress file, such as \"file.tar.gz\" or \"file.zip\" ('-' read stdin)")
+	deCompCmd.StringVar(&deCompDest, "o", "", "output files: one or more origin files. (should be path not file, '-' write stdout, gzip, zlib or bzip2 only)")
+	deCompCmd.StringVar(&deCompType, "t", "zip", "decompress type: one type of enum [tar,tar.gz,tar.bz2,zip,gzip,zlib,bzip2] (gzip, zlib and bzip2 decompress one data stream)")
+	deCompCmd.BoolVar(&deCompRestore, "restore", false, "restore mode: apply a chain of full and incremental archives in order, input files such as \"full.tar.gz,incr_1.tar.gz,incr_2.tar.gz\"")
+	deCompCmd.StringVar(&deCompPassword, "p", "", "password: decrypt WinZip AES or ZipCrypto encrypted zip entries, such as \"123456\" (zip only)")
 }
 
 func ParseCmdDeComp() {
@@ -33,12 +39,21 @@ func ParseCmdDeComp() {
 		os.Exit(1)
 	}
 	// handle command parameters
-	err = handleCmdDeComp(deCompSrc, deCompDest, deCompType)
+	stream := isStream(deCompDest)
+	if stream || isStream(deCompSrc) || deCompType == "gzip" || deCompType == "zlib" || deCompType == "bzip2" || deCompType == "tar.bz2" {
+		err = handleCmdDeCompStream(deCompSrc, deCompDest, deCompType, deCompRestore, deCompPassword)
+	} else if deCompRestore {
+		err = handleCmdDeCompRestore(strings.Split(strings.ReplaceAll(deCompSrc, " ", ""), ","), deCompDest, deCompType)
+	} else if deCompPassword != "" {
+		err = handleCmdDeCompDecrypt(deCompSrc, deCompDest, deCompType, deCompPassword)
+	} else {
+		err = handleCmdDeComp(deCompSrc, deCompDest, deCompType)
+	}
 	if err != nil {
-		fmt.Println("Decompress failure:", err)
+		fmt.Fprintln(messageOutput(stream), "Decompress failure:", err)
 		os.Exit(1)
 	}
-	fmt.Println("Decompress success.")
+	fmt.Fprintln(messageOutput(stream), "Decompress success.")
 }
 
 func handleCmdDeComp(src string, dest string, algorithm string) (err error) {
@@ -51,3 +66,62 @@ func handleCmdDeComp(src string, dest string, algorithm string) (err error) {
 	log.Println("Decompress success.")
 	return err
 }
+
+func handleCmdDeCompRestore(src []string, dest string, algorithm string) (err error) {
+	// execute restore function
+	err = decomp.DeCompressRestore(src, dest, algorithm)
+	if err != nil {
+		log.Println("Decompress restore failure:", err)
+		return err
+	}
+	log.Println("Decompress restore success.")
+	return err
+}
+
+func handleCmdDeCompDecrypt(src string, dest string, algorithm string, password string) (err error) {
+	// execute decrypt decompress function
+	err = decomp.DeCompressDecrypt(src, dest, algorithm, password)
+	if err != nil {
+		log.Println("Decompress decrypt failure:", err)
+		return err
+	}
+	log.Println("Decompress decrypt success.")
+	return err
+}
+
+func handleCmdDeCompStream(src string, dest string, algorithm string, restore bool, password string) (err error) {
+	if restore || password != "" {
+		err = errors.New("stream decompress not support restore or password")
+		return err
+	}
+	// open the input file or stdin
+	in, err := openStreamInput(src)
+	if err != nil {
+		log.Println("Error open input:", err)
+		return err
+	}
+	defer in.Close()
+	switch algorithm {
+	case "gzip", "zlib", "bzip2":
+		// decompress one data stream, no archive entry
+		out, e := createStreamOutput(dest)
+		if e != nil {
+			log.Println("Error create output:", e)
+			return e
+		}
+		defer out.Close()
+		err = decomp.DeCompressDataStream(in, out, algorithm)
+	default:
+		if isStream(dest) {
+			err = errors.New("stdout output only support 'gzip', 'zlib' or 'bzip2'")
+			return err
+		}
+		err = decomp.DeCompressStream(in, dest, algorithm)
+	}
+	if err != nil {
+		log.Println("Decompress stream failure:", err)
+		return err
+	}
+	log.Println("Decompress stream success.")
+	return err
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	. "satellite/global"
+	"satellite/nets"
+	"sort"
+	"strings"
+	"time"
+)
+
+var discoverCmd = flag.NewFlagSet(CmdDiscover, flag.ExitOnError)
+var discoverAddr string
+var discoverWait int
+var discoverJson bool
+
+func init() {
+	discoverCmd.StringVar(&discoverAddr, "addr", DiscoverAddr, "addr: multicast group or broadcast address with port of satellite discovery")
+	discoverCmd.IntVar(&discoverWait, "wait", DiscoverWait, "wait: time(Millisecond) to collect announcements of nodes")
+	discoverCmd.BoolVar(&discoverJson, "json", false, "json: print peers as json")
+}
+
+func ParseCmdDiscover() {
+	// parse command discover, all flags optional
+	err := discoverCmd.Parse(os.Args[2:])
+	if err != nil {
+		log.Println("Error Parse Discover Command.")
+		os.Exit(1)
+	}
+	// handle command parameters
+	handleCmdDiscover(discoverAddr, discoverWait, discoverJson)
+}
+
+func handleCmdDiscover(addr string, wait int, j bool) {
+	peers, err := nets.DiscoverPeers(addr, time.Duration(wait)*time.Millisecond)
+	if err != nil {
+		fmt.Println("Error discover peers:", err)
+		os.Exit(1)
+	}
+	if j {
+		b, err := json.MarshalIndent(peers, "", "  ")
+		if err != nil {
+			log.Println("Error marshal peers:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+		return
+	}
+	if len(peers) == 0 {
+		fmt.Println("No satellite peers found.")
+		return
+	}
+	fmt.Printf("%-24s %-16s %-10s %s\n", "NAME", "ADDRESS", "VERSION", "SERVICES")
+	for _, v := range peers {
+		services := make([]string, 0, len(v.Services))
+		for k, p := range v.Services {
+			services = append(services, k+":"+p)
+		}
+		sort.Strings(services)
+		fmt.Printf("%-24s %-16s %-10s %s\n", v.Name, v.Addr, v.Version, strings.Join(services, " "))
+	}
+}
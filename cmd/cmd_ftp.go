@@ -2,19 +2,43 @@ package cmd
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	. "satellite/global"
 	"satellite/nets"
+	"strconv"
+	"strings"
 )
 
 var ftpCmd = flag.NewFlagSet(CmdFtp, flag.ExitOnError)
+var ftpAnnounce = addDiscoverFlags(ftpCmd)
 var ftpIp string
 var ftpPort string
+var ftpRoot string
+var ftpUsers string
+var ftpAnonymous bool
+var ftpCert string
+var ftpKey string
+var ftpSelfSigned bool
+var ftpRequireTLS bool
+var ftpPasvPorts string
+var ftpPublicIp string
+var ftpMaxConns int
 
 func init() {
-	ftpCmd.StringVar(&ftpIp, "ip", "127.0.0.1", "ip address: ipv4 address witch http server listen, such as \"127.0.0.1\"")
-	ftpCmd.StringVar(&ftpPort, "port", "16514", "port: port number witch http server listen, such as \"16514\"")
+	ftpCmd.StringVar(&ftpIp, "ip", "127.0.0.1", "ip address: ipv4 address witch ftp server listen, such as \"127.0.0.1\"")
+	ftpCmd.StringVar(&ftpPort, "port", "16514", "port: port number witch ftp server listen, such as \"21\"")
+	ftpCmd.StringVar(&ftpRoot, "root", ".", "root: directory witch ftp clients confined to")
+	ftpCmd.StringVar(&ftpUsers, "users", "", "users: users with read and write access, such as \"alice:secret,bob:secret2\"")
+	ftpCmd.BoolVar(&ftpAnonymous, "anonymous", false, "anonymous: allow read only login of user \"anonymous\" or \"ftp\"")
+	ftpCmd.StringVar(&ftpCert, "cert", "", "cert: PEM certificate file of explicit FTPS (AUTH TLS), reloaded on SIGHUP or file change")
+	ftpCmd.StringVar(&ftpKey, "key", "", "key: PEM private key file of explicit FTPS (AUTH TLS)")
+	ftpCmd.BoolVar(&ftpSelfSigned, "self-signed", false, "self signed: generate self-signed certificate into cert.pem and key.pem or -cert and -key")
+	ftpCmd.BoolVar(&ftpRequireTLS, "require-tls", false, "require tls: refuse login and data connections without TLS, need -cert and -key or -self-signed")
+	ftpCmd.StringVar(&ftpPasvPorts, "pasv-ports", "", "pasv ports: port range of passive data connections, such as \"30000-30100\", default is any port")
+	ftpCmd.StringVar(&ftpPublicIp, "public-ip", "", "public ip: ipv4 address in PASV reply when server behind NAT, default is -ip")
+	ftpCmd.IntVar(&ftpMaxConns, "max-conns", FtpMaxConns, "max conns: max concurrent control connections, 0 is unlimited")
 }
 
 func ParseCmdFtp() {
@@ -23,16 +47,61 @@ func ParseCmdFtp() {
 		ftpCmd.Usage()
 		os.Exit(1)
 	}
-	// parse command http
+	// parse command ftp
 	err := ftpCmd.Parse(os.Args[2:])
 	if err != nil {
 		log.Println("Error Parse Ftp Command.")
 		os.Exit(1)
 	}
+	// build server config from flags
+	c, err := ftpConfig()
+	if err != nil {
+		fmt.Println("Error ftp parameters:", err)
+		ftpCmd.Usage()
+		os.Exit(1)
+	}
+	// announce service when enabled
+	ftpAnnounce.start(CmdFtp, ftpPort)
 	// handle command parameters
-	handleCmdFtp(ftpIp, ftpPort)
+	handleCmdFtp(ftpIp, ftpPort, c)
+}
+
+// ftpConfig function
+// users, root, TLS and passive ports of ftp flags
+func ftpConfig() (nets.TNetsFtpConfig, error) {
+	c := nets.TNetsFtpConfig{Root: ftpRoot, Users: make(map[string]string), Anonymous: ftpAnonymous,
+		RequireTLS: ftpRequireTLS, PublicIP: ftpPublicIp, MaxConns: ftpMaxConns}
+	if ftpUsers != "" {
+		for _, v := range strings.Split(ftpUsers, ",") {
+			fields := strings.SplitN(v, ":", 2)
+			if len(fields) != 2 || fields[0] == "" {
+				return c, fmt.Errorf("user %q should be name:password", v)
+			}
+			c.Users[fields[0]] = fields[1]
+		}
+	}
+	if len(c.Users) == 0 && !c.Anonymous {
+		return c, nets.ErrFtpNoUsers
+	}
+	if ftpCert != "" || ftpKey != "" || ftpSelfSigned {
+		c.TLS = &nets.TNetsHttpsConfig{Cert: ftpCert, Key: ftpKey, SelfSigned: ftpSelfSigned}
+	}
+	if ftpPasvPorts != "" {
+		fields := strings.SplitN(ftpPasvPorts, "-", 2)
+		var err error
+		if len(fields) == 2 {
+			c.PasvMinPort, err = strconv.Atoi(fields[0])
+			if err == nil {
+				c.PasvMaxPort, err = strconv.Atoi(fields[1])
+			}
+		}
+		if len(fields) != 2 || err != nil || c.PasvMinPort <= 0 || c.PasvMaxPort > 65535 || c.PasvMaxPort < c.PasvMinPort {
+			return c, fmt.Errorf("passive ports %q should be min-max", ftpPasvPorts)
+		}
+	}
+	return c, nil
 }
 
-func handleCmdFtp(ip string, port string) {
-	nets.StartFtpServer(ip, port)
+func handleCmdFtp(ip string, port string, c nets.TNetsFtpConfig) {
+	exitOnServerError(nets.StartFtpServerWithConfig(ip, port, c))
 }
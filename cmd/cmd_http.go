@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	. "satellite/global"
@@ -9,12 +10,19 @@ import (
 )
 
 var httpCmd = flag.NewFlagSet(CmdHttp, flag.ExitOnError)
+var httpDiag = addDiagFlags(httpCmd)
+var httpLimit = addLimitFlags(httpCmd)
+var httpAnnounce = addDiscoverFlags(httpCmd)
 var httpIp string
 var httpPort string
+var httpAuth string
+var httpRoots string
 
 func init() {
 	httpCmd.StringVar(&httpIp, "ip", "127.0.0.1", "ip address: ipv4 address witch http server listen, such as \"127.0.0.1\"")
 	httpCmd.StringVar(&httpPort, "port", "14514", "port: port number witch http server listen, such as \"14514\"")
+	httpCmd.StringVar(&httpAuth, "auth", "", "auth: json config of api tokens, HMAC secrets, client certificate subjects, scopes and allowed directories")
+	httpCmd.StringVar(&httpRoots, "roots", "", "roots: named storage roots witch request paths confined to, such as \"inbox=/srv/inbox,outbox=/srv/outbox\", request paths such as \"inbox:dir/file.txt\"")
 }
 
 func ParseCmdHttp() {
@@ -29,10 +37,36 @@ func ParseCmdHttp() {
 		log.Println("Error Parse Http Command.")
 		os.Exit(1)
 	}
+	// start diagnostics listener when enabled
+	httpDiag.start()
+	// limit request rate, heavy jobs and body size
+	httpLimit.apply()
+	// announce service and expose peers when enabled
+	httpAnnounce.start(CmdHttp, httpPort)
 	// handle command parameters
-	handleCmdHttp(httpIp, httpPort)
+	handleCmdHttp(httpIp, httpPort, httpAuth, httpRoots)
 }
 
-func handleCmdHttp(ip string, port string) {
-	nets.StartHttpServer(ip, port)
+func handleCmdHttp(ip string, port string, auth string, roots string) {
+	// confine request paths to storage roots
+	if roots != "" {
+		r, err := nets.ParseHttpRoots(roots)
+		if err == nil {
+			err = nets.SetHttpRoots(r)
+		}
+		if err != nil {
+			fmt.Println("Error set storage roots:", err)
+			os.Exit(1)
+		}
+	}
+	// enable auth middleware
+	if auth != "" {
+		a, err := nets.LoadHttpAuth(auth)
+		if err != nil {
+			fmt.Println("Error load auth config:", err)
+			os.Exit(1)
+		}
+		nets.SetHttpAuth(a)
+	}
+	exitOnServerError(nets.StartHttpServer(ip, port))
 }
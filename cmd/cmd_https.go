@@ -2,19 +2,46 @@ package cmd
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	. "satellite/global"
 	"satellite/nets"
+	"strings"
 )
 
 var httpsCmd = flag.NewFlagSet(CmdHttps, flag.ExitOnError)
+var httpsDiag = addDiagFlags(httpsCmd)
+var httpsLimit = addLimitFlags(httpsCmd)
+var httpsAnnounce = addDiscoverFlags(httpsCmd)
 var httpsIp string
 var httpsPort string
+var httpsAuth string
+var httpsRoots string
+var httpsConfig string
+var httpsCert string
+var httpsKey string
+var httpsSelfSigned bool
+var httpsHosts string
+var httpsMinTLS string
+var httpsCiphers string
+var httpsClientCA string
+var httpsClientRequire bool
 
 func init() {
 	httpsCmd.StringVar(&httpsIp, "ip", "127.0.0.1", "ip address: ipv4 address witch http server listen, such as \"127.0.0.1\"")
 	httpsCmd.StringVar(&httpsPort, "port", "15514", "port: port number witch http server listen, such as \"15514\"")
+	httpsCmd.StringVar(&httpsAuth, "auth", "", "auth: json config of api tokens, HMAC secrets, client certificate subjects, scopes and allowed directories")
+	httpsCmd.StringVar(&httpsRoots, "roots", "", "roots: named storage roots witch request paths confined to, such as \"inbox=/srv/inbox,outbox=/srv/outbox\", request paths such as \"inbox:dir/file.txt\"")
+	httpsCmd.StringVar(&httpsConfig, "tls-config", "", "tls config: json config of certificate and TLS policy, flags override it")
+	httpsCmd.StringVar(&httpsCert, "cert", "", "cert: PEM certificate file, reloaded on SIGHUP or file change")
+	httpsCmd.StringVar(&httpsKey, "key", "", "key: PEM private key file, reloaded on SIGHUP or file change")
+	httpsCmd.BoolVar(&httpsSelfSigned, "self-signed", false, "self signed: generate self-signed certificate into cert.pem and key.pem or -cert and -key")
+	httpsCmd.StringVar(&httpsHosts, "hosts", "", "hosts: ip addresses and DNS names of self-signed certificate, such as \"127.0.0.1,localhost\", default is -ip")
+	httpsCmd.StringVar(&httpsMinTLS, "min-tls", "", "min tls: min TLS version, \"1.0\", \"1.1\", \"1.2\" or \"1.3\", default is \"1.2\"")
+	httpsCmd.StringVar(&httpsCiphers, "ciphers", "", "ciphers: TLS 1.2 cipher suites, such as \"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256\"")
+	httpsCmd.StringVar(&httpsClientCA, "client-ca", "", "client ca: PEM CA bundle used to verify mTLS client certificates")
+	httpsCmd.BoolVar(&httpsClientRequire, "client-require", false, "client require: reject clients without verified certificate, need -client-ca")
 }
 
 func ParseCmdHttps() {
@@ -29,10 +56,74 @@ func ParseCmdHttps() {
 		log.Println("Error Parse Https Command.")
 		os.Exit(1)
 	}
+	// merge tls config file and flags
+	var c nets.TNetsHttpsConfig
+	if httpsConfig != "" {
+		c, err = nets.LoadHttpsConfig(httpsConfig)
+		if err != nil {
+			fmt.Println("Error load tls config:", err)
+			os.Exit(1)
+		}
+	}
+	if httpsCert != "" {
+		c.Cert = httpsCert
+	}
+	if httpsKey != "" {
+		c.Key = httpsKey
+	}
+	if httpsSelfSigned {
+		c.SelfSigned = true
+	}
+	if httpsHosts != "" {
+		c.Hosts = strings.Split(httpsHosts, ",")
+	}
+	if httpsMinTLS != "" {
+		c.MinVersion = httpsMinTLS
+	}
+	if httpsCiphers != "" {
+		c.CipherSuites = strings.Split(httpsCiphers, ",")
+	}
+	if httpsClientCA != "" {
+		c.ClientCA = httpsClientCA
+	}
+	if httpsClientRequire {
+		c.RequireClientCert = true
+	}
+	if !c.SelfSigned && (c.Cert == "" || c.Key == "") {
+		fmt.Println("Certificate and key file required, or use -self-signed.")
+		httpsCmd.Usage()
+		os.Exit(1)
+	}
+	// start diagnostics listener when enabled
+	httpsDiag.start()
+	// limit request rate, heavy jobs and body size
+	httpsLimit.apply()
+	// announce service and expose peers when enabled
+	httpsAnnounce.start(CmdHttps, httpsPort)
 	// handle command parameters
-	handleCmdHttps(httpsIp, httpsPort)
+	handleCmdHttps(httpsIp, httpsPort, httpsAuth, httpsRoots, c)
 }
 
-func handleCmdHttps(ip string, port string) {
-	nets.StartHttpsServer(ip, port)
+func handleCmdHttps(ip string, port string, auth string, roots string, c nets.TNetsHttpsConfig) {
+	// confine request paths to storage roots
+	if roots != "" {
+		r, err := nets.ParseHttpRoots(roots)
+		if err == nil {
+			err = nets.SetHttpRoots(r)
+		}
+		if err != nil {
+			fmt.Println("Error set storage roots:", err)
+			os.Exit(1)
+		}
+	}
+	// enable auth middleware
+	if auth != "" {
+		a, err := nets.LoadHttpAuth(auth)
+		if err != nil {
+			fmt.Println("Error load auth config:", err)
+			os.Exit(1)
+		}
+		nets.SetHttpAuth(a)
+	}
+	exitOnServerError(nets.StartHttpsServerWithConfig(ip, port, c))
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -20,11 +21,15 @@ var packCmd = flag.NewFlagSet(CmdPacket, flag.ExitOnError)
 var packSrc []string
 var packDest string
 var packType string
+var packReproducible bool
+var packTestingSeed string
 
 func init() {
-	packCmd.Var(NewStrSlice([]string{}, &packSrc), "i", "input files: file list support with different type, such as \"file_1.txt,file_2.mov,file_3.png...\"")
-	packCmd.StringVar(&packDest, "o", "", "output files: one file which user can customize it type, such as \"file.dat\" or \"file.pak\"")
+	packCmd.Var(NewStrSlice([]string{}, &packSrc), "i", "input files: file list support with different type, such as \"file_1.txt,file_2.mov,file_3.png...\" ('-' read stdin, spooled into temp dir for its size)")
+	packCmd.StringVar(&packDest, "o", "", "output files: one file which user can customize it type, such as \"file.dat\" or \"file.pak\" ('-' write stdout)")
 	packCmd.StringVar(&packType, "t", "AES", "pack type: one type of enum [AES,DES,3DES,RSA,BASE64]")
+	packCmd.BoolVar(&packReproducible, "reproducible", false, "reproducible mode: files are sorted by name in package.")
+	packCmd.StringVar(&packTestingSeed, "testing-seed", "", "testing only: derive AES/DES/3DES keys from this seed to get byte-identical packages. (NOT secure)")
 }
 
 func ParseCmdPack() {
@@ -40,18 +45,35 @@ func ParseCmdPack() {
 		os.Exit(1)
 	}
 	// handle command parameters
+	out := messageOutput(isStream(packDest))
 	err = handleCmdPack(packSrc, packDest, packType)
 	if err != nil {
-		fmt.Print("\n")
-		fmt.Println("Pack failure:", err)
+		fmt.Fprint(out, "\n")
+		fmt.Fprintln(out, "Pack failure:", err)
 		os.Exit(1)
 	}
-	fmt.Print("\n")
-	fmt.Println("Pack success.")
+	fmt.Fprint(out, "\n")
+	fmt.Fprintln(out, "Pack success.")
 }
 
 func handleCmdPack(src []string, dest string, algorithm string) (err error) {
 	ch := make(chan bool)
+	out := messageOutput(isStream(dest))
+	// stdin input should be saved into file before pack, entry header
+	// records the file size before its data
+	if len(src) == 1 && isStream(src[0]) {
+		dir, err := ioutil.TempDir("", AppName)
+		if err != nil {
+			fmt.Fprintln(out, "Error create temp dir:", err)
+			return err
+		}
+		defer os.RemoveAll(dir)
+		src[0], err = spoolStdin(dir)
+		if err != nil {
+			fmt.Fprintln(out, "Error read stdin:", err)
+			return err
+		}
+	}
 	// check parameters
 	is := checkParameters(src, dest, algorithm)
 	if !is {
@@ -60,7 +82,28 @@ func handleCmdPack(src []string, dest string, algorithm string) (err error) {
 	}
 	src, err = refactorSource(src)
 	if err != nil {
-		fmt.Println("Error refactor source files:", err)
+		fmt.Fprintln(out, "Error refactor source files:", err)
+		return err
+	}
+	// reproducible mode sort files by name, keys are random unless testing seed given
+	if packTestingSeed != "" {
+		if algorithm == "RSA" || algorithm == "rsa" {
+			err = errors.New("rsa pack does not support testing seed")
+			return err
+		}
+		pack.SetTestingSeed([]byte(packTestingSeed))
+	}
+	if packReproducible {
+		src = pack.SortSource(src)
+	}
+	// stdout output stream the package without process bar
+	if isStream(dest) {
+		err = pack.PackStream(src, os.Stdout, algorithm)
+		if err != nil {
+			log.Println("Pack stream failure:", err)
+			return err
+		}
+		log.Println("Pack stream success.")
 		return err
 	}
 	// calculate work
@@ -124,16 +167,17 @@ func handleCmdPack(src []string, dest string, algorithm string) (err error) {
 
 func checkParameters(src []string, dest string, algorithm string) (is bool) {
 	is = true
+	out := messageOutput(isStream(dest))
 	// check src
 	if len(src) == 0 {
 		is = false
-		fmt.Println("Source file list can't be empty.")
+		fmt.Fprintln(out, "Source file list can't be empty.")
 		return is
 	}
 	for i := 0; i < len(src); i++ {
 		is, _ = PathExist(src[i])
 		if !is {
-			fmt.Printf("Source file %v path not exist.\n", i+1)
+			fmt.Fprintf(out, "Source file %v path not exist.\n", i+1)
 			return is
 		}
 	}
@@ -146,7 +190,7 @@ func checkParameters(src []string, dest string, algorithm string) (is bool) {
 	case "BASE64", "base64":
 	default:
 		is = false
-		fmt.Printf("Algorithm %v not support.\n", algorithm)
+		fmt.Fprintf(out, "Algorithm %v not support.\n", algorithm)
 	}
 	return is
 }
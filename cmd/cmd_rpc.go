@@ -1,23 +1,78 @@
 package cmd
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"satellite/client"
 	. "satellite/global"
 	"satellite/nets"
+	"time"
 )
 
 var rpcCmd = flag.NewFlagSet(CmdRpc, flag.ExitOnError)
+var rpcDiag = addDiagFlags(rpcCmd)
+var rpcAnnounce = addDiscoverFlags(rpcCmd)
 var rpcIp string
 var rpcPort string
 var rpcProtocol string
+var rpcRoots string
+var rpcToken string
+var rpcCert string
+var rpcKey string
+var rpcSelfSigned bool
+var rpcClientCA string
+var rpcClientRequire bool
+var rpcMaxConns int
+var rpcIdleTimeout int
+
+var rpcCallCmd = flag.NewFlagSet(CmdRpc+" "+CmdRpcCall, flag.ExitOnError)
+var rpcCallAddr string
+var rpcCallMethod string
+var rpcCallJson string
+var rpcCallProtocol string
+var rpcCallTimeout int
+var rpcCallRetries int
+var rpcCallTLS bool
+var rpcCallCA string
+var rpcCallInsecure bool
+var rpcCallToken string
+var rpcCallCert string
+var rpcCallKey string
 
 func init() {
 	rpcCmd.StringVar(&rpcIp, "ip", "127.0.0.1", "ip address: ipv4 address witch rpc server listen, such as \"127.0.0.1\"")
 	rpcCmd.StringVar(&rpcPort, "port", "13514", "port: port number witch rpc server listen, such as \"13514\"")
 	rpcCmd.StringVar(&rpcProtocol, "protocol", "tcp", "protocol: rpc realize protocol, you can choose one from ['tcp','http']")
+	rpcCmd.StringVar(&rpcRoots, "roots", "", "roots: named storage roots witch request paths confined to, such as \"inbox=/srv/inbox,outbox=/srv/outbox\", request paths such as \"inbox:dir/file.txt\", methods taking paths are refused without roots")
+	rpcCmd.StringVar(&rpcToken, "token", "", "token: token required from clients, \"AUTH <token>\" line over tcp or Bearer header over http")
+	rpcCmd.StringVar(&rpcCert, "cert", "", "cert: PEM certificate file, serve over TLS when set")
+	rpcCmd.StringVar(&rpcKey, "key", "", "key: PEM private key file")
+	rpcCmd.BoolVar(&rpcSelfSigned, "self-signed", false, "self signed: generate self-signed certificate into cert.pem and key.pem or -cert and -key")
+	rpcCmd.StringVar(&rpcClientCA, "client-ca", "", "client ca: PEM CA bundle used to verify client certificates, need TLS")
+	rpcCmd.BoolVar(&rpcClientRequire, "client-require", false, "client require: reject clients without verified certificate, need -client-ca")
+	rpcCmd.IntVar(&rpcMaxConns, "max-conns", RpcMaxConns, "max conns: max concurrent connections, 0 means unlimited")
+	rpcCmd.IntVar(&rpcIdleTimeout, "idle-timeout", RpcIdleTimeout, "idle timeout: close connections without call in progress after(Millisecond), 0 means never")
+}
+
+func init() {
+	rpcCallCmd.StringVar(&rpcCallAddr, "addr", "127.0.0.1:13514", "addr: rpc server address, such as \"127.0.0.1:13514\"")
+	rpcCallCmd.StringVar(&rpcCallMethod, "method", "", "method: rpc method, such as \"GoApi.MD5Encode\"")
+	rpcCallCmd.StringVar(&rpcCallJson, "json", "{}", "json: request of method in json, such as '{\"src\":\"Satellite\"}'")
+	rpcCallCmd.StringVar(&rpcCallProtocol, "protocol", "tcp", "protocol: rpc realize protocol of server, you can choose one from ['tcp','http']")
+	rpcCallCmd.IntVar(&rpcCallTimeout, "timeout", RpcClientTimeout, "timeout: call timeout(Millisecond)")
+	rpcCallCmd.IntVar(&rpcCallRetries, "retries", RpcClientRetries, "retries: retries on connection errors, methods changing files are not retried once sent, 0 means no retry")
+	rpcCallCmd.BoolVar(&rpcCallTLS, "tls", false, "tls: connect server with TLS")
+	rpcCallCmd.StringVar(&rpcCallCA, "ca", "", "ca: PEM CA bundle used to verify server certificate, implies -tls")
+	rpcCallCmd.BoolVar(&rpcCallInsecure, "insecure", false, "insecure: skip server certificate verification, implies -tls")
+	rpcCallCmd.StringVar(&rpcCallToken, "token", "", "token: token required by rpc server")
+	rpcCallCmd.StringVar(&rpcCallCert, "cert", "", "cert: PEM client certificate file, implies -tls")
+	rpcCallCmd.StringVar(&rpcCallKey, "key", "", "key: PEM client private key file")
 }
 
 func ParseCmdRpc() {
@@ -26,23 +81,111 @@ func ParseCmdRpc() {
 		rpcCmd.Usage()
 		os.Exit(1)
 	}
+	// call remote rpc method
+	if os.Args[2] == CmdRpcCall {
+		ParseCmdRpcCall()
+		return
+	}
 	// parse command rpc
 	err := rpcCmd.Parse(os.Args[2:])
 	if err != nil {
 		log.Println("Error Parse Rpc Command.")
 		os.Exit(1)
 	}
+	// security and limits
+	c := nets.TNetsRpcConfig{Token: rpcToken, MaxConns: rpcMaxConns, IdleTimeout: rpcIdleTimeout}
+	if rpcCert != "" || rpcKey != "" || rpcSelfSigned {
+		if !rpcSelfSigned && (rpcCert == "" || rpcKey == "") {
+			fmt.Println("Certificate and key file required, or use -self-signed.")
+			rpcCmd.Usage()
+			os.Exit(1)
+		}
+		c.TLS = &nets.TNetsHttpsConfig{Cert: rpcCert, Key: rpcKey, SelfSigned: rpcSelfSigned, ClientCA: rpcClientCA, RequireClientCert: rpcClientRequire}
+	} else if rpcClientCA != "" || rpcClientRequire {
+		fmt.Println("Client certificates need TLS, use -cert and -key or -self-signed.")
+		os.Exit(1)
+	}
+	// start diagnostics listener when enabled
+	rpcDiag.start()
+	// announce service when enabled
+	rpcAnnounce.start(CmdRpc, rpcPort)
 	// handle command parameters
-	handleCmdRpc(rpcIp, rpcPort, rpcProtocol)
+	handleCmdRpc(rpcIp, rpcPort, rpcProtocol, rpcRoots, c)
 }
 
-func handleCmdRpc(ip string, port string, protocol string) {
+func handleCmdRpc(ip string, port string, protocol string, roots string, c nets.TNetsRpcConfig) {
+	// confine request paths to storage roots
+	if roots != "" {
+		r, err := nets.ParseHttpRoots(roots)
+		if err == nil {
+			err = nets.SetHttpRoots(r)
+		}
+		if err != nil {
+			fmt.Println("Error set storage roots:", err)
+			os.Exit(1)
+		}
+	} else {
+		fmt.Println("No storage roots configured, rpc methods taking paths are refused.")
+	}
 	switch protocol {
-	case "tcp":
-		nets.StartRpcTcpServer(ip, port)
-	case "http":
-		nets.StartRpcHttpServer(ip, port)
+	case "tcp", "http":
+		exitOnServerError(nets.StartRpcServerWithConfig(ip, port, protocol, c))
 	default:
 		fmt.Println("Invalid Rpc protocol. You can choose one from ['tcp','http']")
 	}
 }
+
+func ParseCmdRpcCall() {
+	// parse command rpc call
+	err := rpcCallCmd.Parse(os.Args[3:])
+	if err != nil {
+		log.Println("Error Parse Rpc Call Command.")
+		os.Exit(1)
+	}
+	if rpcCallMethod == "" {
+		rpcCallCmd.Usage()
+		os.Exit(1)
+	}
+	// handle command parameters
+	handleCmdRpcCall(rpcCallAddr, rpcCallMethod, rpcCallJson)
+}
+
+func handleCmdRpcCall(addr string, method string, request string) {
+	config := client.TClientConfig{Protocol: rpcCallProtocol, Token: rpcCallToken, Timeout: time.Duration(rpcCallTimeout) * time.Millisecond, Retries: rpcCallRetries}
+	if rpcCallRetries == 0 {
+		config.Retries = -1
+	}
+	// tls config
+	if rpcCallTLS || rpcCallCA != "" || rpcCallInsecure || rpcCallCert != "" {
+		config.TLS = &tls.Config{InsecureSkipVerify: rpcCallInsecure}
+		if rpcCallCert != "" {
+			cert, err := tls.LoadX509KeyPair(rpcCallCert, rpcCallKey)
+			if err != nil {
+				fmt.Println("Error load client certificate:", err)
+				os.Exit(1)
+			}
+			config.TLS.Certificates = []tls.Certificate{cert}
+		}
+		if rpcCallCA != "" {
+			pem, err := ioutil.ReadFile(rpcCallCA)
+			if err != nil {
+				fmt.Println("Error read ca file:", err)
+				os.Exit(1)
+			}
+			config.TLS.RootCAs = x509.NewCertPool()
+			if !config.TLS.RootCAs.AppendCertsFromPEM(pem) {
+				fmt.Println("Error parse ca file:", rpcCallCA)
+				os.Exit(1)
+			}
+		}
+	}
+	c := client.NewClient(addr, config)
+	defer c.Close()
+	r, err := c.CallJSON(context.Background(), method, []byte(request))
+	if err != nil {
+		fmt.Println("Error rpc call:", err)
+		c.Close()
+		os.Exit(1)
+	}
+	fmt.Println(string(r))
+}
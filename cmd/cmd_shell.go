@@ -14,11 +14,15 @@ var shellCmd = flag.NewFlagSet(CmdShell, flag.ExitOnError)
 var shellSrc string
 var shellDest string
 var shellType string
+var shellStub string
+var shellPassphrase string
 
 func init() {
-	shellCmd.StringVar(&shellSrc, "i", "", "input file: the executable file which will be shelled")
+	shellCmd.StringVar(&shellSrc, "i", "", "input file: the executable file which will be shelled (sfx: package or archive, such as \"file.pak\" or \"file.tar.gz\")")
 	shellCmd.StringVar(&shellDest, "o", "", "output file: the output path after shell")
-	shellCmd.StringVar(&shellType, "t", "upx", "shell type: choose one shell type from enum [upx]")
+	shellCmd.StringVar(&shellType, "t", "upx", "shell type: choose one shell type from enum [upx,sfx]")
+	shellCmd.StringVar(&shellStub, "stub", "", "sfx stub: extraction stub executable built from app/sfx, default is satellite-sfx next to satellite")
+	shellCmd.StringVar(&shellPassphrase, "p", "", "sfx passphrase: encrypt payload with AES-256-GCM in chunks, asked when extracting")
 }
 
 func ParseCmdShell() {
@@ -54,12 +58,17 @@ func handleCmdShell(src string, dest string, algorithm string) (err error) {
 		err = errors.New("dest file can not be empty")
 		return err
 	}
-	if algorithm != "upx" {
-		err = errors.New("shell type should be one of enum [upx]")
+	if algorithm != "upx" && algorithm != "sfx" {
+		err = errors.New("shell type should be one of enum [upx,sfx]")
 		return err
 	}
 	// shell executable file
-	r, err := shell.Shell(src, dest, algorithm)
+	var r string
+	if algorithm == "sfx" {
+		r, err = shell.ShellSfx(shellStub, src, dest, shellPassphrase)
+	} else {
+		r, err = shell.Shell(src, dest, algorithm)
+	}
 	if err != nil {
 		log.Println("Error shell executable file:", err)
 		fmt.Println(r)
@@ -7,17 +7,49 @@ import (
 	"os"
 	. "satellite/global"
 	"satellite/nets"
+	"strings"
 )
 
 var tcpCmd = flag.NewFlagSet(CmdTcp, flag.ExitOnError)
 var tcpIp string
 var tcpPort string
 var tcpMode string
+var tcpTranscript string
+
+var tcpSendCmd = flag.NewFlagSet(CmdTcp+" "+CmdTcpSend, flag.ExitOnError)
+var tcpSendIp string
+var tcpSendPort string
+var tcpSendSrc string
+var tcpSendKey string
+
+var tcpRecvCmd = flag.NewFlagSet(CmdTcp+" "+CmdTcpRecv, flag.ExitOnError)
+var tcpRecvIp string
+var tcpRecvPort string
+var tcpRecvDest string
+var tcpRecvKey string
+
+var tcpForwardCmd = flag.NewFlagSet(CmdTcp+" "+CmdForward, flag.ExitOnError)
+var tcpForward = addForwardFlags(tcpForwardCmd, true)
 
 func init() {
 	tcpCmd.StringVar(&tcpIp, "ip", "127.0.0.1", "ip address: ipv4 address witch tcp server listen, such as \"127.0.0.1\"")
 	tcpCmd.StringVar(&tcpPort, "port", "11514", "port: port number witch tcp server listen, such as \"11514\"")
-	tcpCmd.StringVar(&tcpMode, "mode", "server", "mode: tcp mode choose, 's' or 'server' indicate tcp server, 'c' or 'client' indicate tcp client")
+	tcpCmd.StringVar(&tcpMode, "mode", "server", "mode: tcp mode choose, 's' or 'server' indicate tcp server, 'c' or 'client' indicate tcp client, 'h' or 'hub' indicate multi-client chat hub")
+	tcpCmd.StringVar(&tcpTranscript, "transcript", "", "transcript: file of hub joins, leaves and broadcast messages, such as \"log/hub.log\"")
+}
+
+func init() {
+	tcpSendCmd.StringVar(&tcpSendIp, "ip", "127.0.0.1", "ip address: ipv4 address of receiver, such as \"127.0.0.1\"")
+	tcpSendCmd.StringVar(&tcpSendPort, "port", "11514", "port: port number of receiver, such as \"11514\"")
+	tcpSendCmd.StringVar(&tcpSendSrc, "src", "", "source: files or directories to send, such as \"a.pak,dir\"")
+	tcpSendCmd.StringVar(&tcpSendKey, "key", "", "key: passphrase of AES-GCM encryption, same as receiver, empty means no encryption")
+}
+
+func init() {
+	tcpRecvCmd.StringVar(&tcpRecvIp, "ip", "127.0.0.1", "ip address: ipv4 address witch receiver listen, such as \"0.0.0.0\"")
+	tcpRecvCmd.StringVar(&tcpRecvPort, "port", "11514", "port: port number witch receiver listen, such as \"11514\"")
+	tcpRecvCmd.StringVar(&tcpRecvDest, "dest", ".", "destination: target directory of received files")
+	tcpRecvCmd.StringVar(&tcpRecvKey, "key", "", "key: passphrase of AES-GCM encryption, same as sender, empty means no encryption")
 }
 
 func ParseCmdTcp() {
@@ -26,6 +58,19 @@ func ParseCmdTcp() {
 		tcpCmd.Usage()
 		os.Exit(1)
 	}
+	// file transfer mode
+	switch os.Args[2] {
+	case CmdTcpSend:
+		ParseCmdTcpSend()
+		return
+	case CmdTcpRecv:
+		ParseCmdTcpRecv()
+		return
+	case CmdForward:
+		tcpForward.parse(os.Args[3:])
+		exitOnServerError(nets.StartTcpForward(tcpForward.listen, tcpForward.to, tcpForward.config()))
+		return
+	}
 	// parse command tcp
 	err := tcpCmd.Parse(os.Args[2:])
 	if err != nil {
@@ -39,10 +84,47 @@ func ParseCmdTcp() {
 func handleCmdTcp(ip string, port string, mode string) {
 	switch mode {
 	case "s", "server":
-		nets.StartTcpServer(ip, port)
+		exitOnServerError(nets.StartTcpServer(ip, port))
 	case "c", "client":
 		nets.StartTcpClient(ip, port)
+	case "h", "hub":
+		exitOnServerError(nets.StartTcpHub(ip, port, tcpTranscript))
 	default:
-		fmt.Println("Invalid Tcp Mode. You can input 'c' stand for 'client' or 's' for 'server'.")
+		fmt.Println("Invalid Tcp Mode. You can input 'c' stand for 'client', 's' for 'server' or 'h' for 'hub'.")
+	}
+}
+
+func ParseCmdTcpSend() {
+	// parse command tcp send
+	err := tcpSendCmd.Parse(os.Args[3:])
+	if err != nil {
+		log.Println("Error Parse Tcp Send Command.")
+		os.Exit(1)
 	}
+	if tcpSendSrc == "" {
+		tcpSendCmd.Usage()
+		os.Exit(1)
+	}
+	// handle command parameters
+	handleCmdTcpSend(tcpSendIp, tcpSendPort, strings.Split(tcpSendSrc, ","), tcpSendKey)
+}
+
+func handleCmdTcpSend(ip string, port string, src []string, key string) {
+	files, err := nets.SendTcpFiles(ip, port, src, key)
+	if err != nil {
+		fmt.Println("Error send files:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Sent", len(files), "files.")
+}
+
+func ParseCmdTcpRecv() {
+	// parse command tcp recv
+	err := tcpRecvCmd.Parse(os.Args[3:])
+	if err != nil {
+		log.Println("Error Parse Tcp Recv Command.")
+		os.Exit(1)
+	}
+	// handle command parameters
+	exitOnServerError(nets.StartTcpRecvServer(tcpRecvIp, tcpRecvPort, tcpRecvDest, tcpRecvKey))
 }
@@ -14,10 +14,13 @@ var udpIp string
 var udpPort string
 var udpMode string
 
+var udpForwardCmd = flag.NewFlagSet(CmdUdp+" "+CmdForward, flag.ExitOnError)
+var udpForward = addForwardFlags(udpForwardCmd, false)
+
 func init() {
 	udpCmd.StringVar(&udpIp, "ip", "127.0.0.1", "ip address: ipv4 address witch udp server listen, such as \"127.0.0.1\"")
 	udpCmd.StringVar(&udpPort, "port", "12514", "port: port number witch udp server listen, such as \"12514\"")
-	udpCmd.StringVar(&udpMode, "mode", "server", "mode: udp mode choose, 's' or 'server' indicate udp server, 'c' or 'client' indicate udp client")
+	udpCmd.StringVar(&udpMode, "mode", "server", "mode: udp mode choose, 's' or 'server' indicate udp server, 'c' or 'client' indicate udp client, 'rs' or 'reliable-server' and 'rc' or 'reliable-client' indicate reliable udp with acks and retransmission")
 }
 
 func ParseCmdUdp() {
@@ -26,6 +29,12 @@ func ParseCmdUdp() {
 		udpCmd.Usage()
 		os.Exit(1)
 	}
+	// forwarding mode
+	if os.Args[2] == CmdForward {
+		udpForward.parse(os.Args[3:])
+		exitOnServerError(nets.StartUdpForward(udpForward.listen, udpForward.to, udpForward.config()))
+		return
+	}
 	// parse command udp
 	err := udpCmd.Parse(os.Args[2:])
 	if err != nil {
@@ -39,10 +48,16 @@ func ParseCmdUdp() {
 func handleCmdUdp(ip string, port string, mode string) {
 	switch mode {
 	case "s", "server":
-		nets.StartUdpServer(ip, port)
+		exitOnServerError(nets.StartUdpServer(ip, port))
 	case "c", "client":
 		nets.StartUdpClient(ip, port)
+	case "rs", "reliable-server":
+		exitOnServerError(nets.StartRudpServer(ip, port))
+	case "rc", "reliable-client":
+		if nets.StartRudpClient(ip, port) != nil {
+			os.Exit(1)
+		}
 	default:
-		fmt.Println("Invalid Udp Mode. You can input 'c' stand for 'client' or 's' for 'server'.")
+		fmt.Println("Invalid Udp Mode. You can input 'c' stand for 'client', 's' for 'server', 'rc' for 'reliable-client' or 'rs' for 'reliable-server'.")
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	. "satellite/global"
@@ -25,8 +26,8 @@ var unpackVerbose bool
 var unpackConfine bool
 
 func init() {
-	unpackCmd.StringVar(&unpackSrc, "i", "", "input files: packet file, such as \"file.dat\" or \"file.pak\"")
-	unpackCmd.StringVar(&unpackDest, "o", "", "output files: one or more origin files. (should be path not file)")
+	unpackCmd.StringVar(&unpackSrc, "i", "", "input files: packet file, such as \"file.dat\" or \"file.pak\" ('-' read stdin, spooled into temp dir only with -v or -t and file output)")
+	unpackCmd.StringVar(&unpackDest, "o", "", "output files: one or more origin files. (should be path not file, '-' write target file to stdout)")
 	unpackCmd.StringVar(&unpackTarget, "t", "", "target file name: unpack choose one file. (should be file name)")
 	unpackCmd.BoolVar(&unpackVerbose, "v", false, "verbose information list.")
 	unpackCmd.BoolVar(&unpackConfine, "c", false, "unpack confine goroutine.")
@@ -45,19 +46,58 @@ func ParseCmdUnpack() {
 		os.Exit(1)
 	}
 	// handle command parameters
+	out := messageOutput(isStream(unpackDest))
 	err = handleCmdUnpack(unpackSrc, unpackDest, unpackTarget, unpackVerbose, unpackConfine)
 	if err != nil {
-		fmt.Print("\n")
-		fmt.Println("Unpack Failure:", err)
+		fmt.Fprint(out, "\n")
+		fmt.Fprintln(out, "Unpack Failure:", err)
 		os.Exit(1)
 	}
-	fmt.Print("\n")
-	fmt.Println("Unpack Success.")
+	fmt.Fprint(out, "\n")
+	fmt.Fprintln(out, "Unpack Success.")
 }
 
 func handleCmdUnpack(src string, dest string, target string, verbose bool, confine bool) (err error) {
 	var algorithm string
 	ch := make(chan bool)
+	// stdin input is unpacked directly into dest or stdout, verbose information,
+	// target file and process bar read the package file, so it is spooled
+	if isStream(src) && !verbose && (isStream(dest) || target == "") {
+		if isStream(dest) {
+			err = unpack.UnpackStreamToWriter(os.Stdin, target, os.Stdout)
+		} else {
+			err = unpack.UnpackStream(os.Stdin, dest)
+		}
+		if err != nil {
+			log.Println("Unpack stream failure:", err)
+			return err
+		}
+		log.Println("Unpack stream success.")
+		return err
+	}
+	if isStream(src) {
+		dir, err := ioutil.TempDir("", AppName)
+		if err != nil {
+			log.Println("Error create temp dir:", err)
+			return err
+		}
+		defer os.RemoveAll(dir)
+		src, err = unpack.SpoolPackage(os.Stdin, dir)
+		if err != nil {
+			log.Println("Error read stdin:", err)
+			return err
+		}
+	}
+	// stdout output write the target file directly without process bar
+	if isStream(dest) && !verbose {
+		err = unpack.UnpackToWriter(src, target, os.Stdout)
+		if err != nil {
+			log.Println("Unpack stream failure:", err)
+			return err
+		}
+		log.Println("Unpack stream success.")
+		return err
+	}
 	// whether look up verbose information
 	if verbose {
 		var files []string
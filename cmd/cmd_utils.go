@@ -1,6 +1,15 @@
 package cmd
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	"satellite/nets"
 	"strings"
 )
 
@@ -21,3 +30,193 @@ func (s *StrSlice) String() string {
 	*s = StrSlice{}
 	return ""
 }
+
+// diagFlags is the opt-in diagnostics listener of a server command
+type diagFlags struct {
+	ip    string
+	port  string
+	token string
+}
+
+// addDiagFlags register diagnostics flags into server command
+func addDiagFlags(fs *flag.FlagSet) *diagFlags {
+	d := &diagFlags{}
+	fs.StringVar(&d.ip, "diag-ip", "127.0.0.1", "diag ip: ipv4 address witch diagnostics server (pprof, expvar, metrics, health) listen")
+	fs.StringVar(&d.port, "diag-port", "", "diag port: port number witch diagnostics server listen, such as \"10514\", disabled when empty")
+	fs.StringVar(&d.token, "diag-token", "", "diag token: bearer token required by pprof, expvar and metrics, health endpoints stay open")
+	return d
+}
+
+// start diagnostics server in background when port given
+func (d *diagFlags) start() {
+	if d.port != "" {
+		go nets.StartDiagServer(d.ip, d.port, d.token)
+	}
+}
+
+// limitFlags is the request limits of REST server command
+type limitFlags struct {
+	limit nets.TNetsLimit
+}
+
+// addLimitFlags register request limit flags into server command
+func addLimitFlags(fs *flag.FlagSet) *limitFlags {
+	l := &limitFlags{}
+	fs.Float64Var(&l.limit.Rate, "rate", HttpRateLimit, "rate: requests per second of one client ip, 0 means unlimited")
+	fs.IntVar(&l.limit.Burst, "burst", HttpRateBurst, "burst: requests burst of one client ip")
+	fs.IntVar(&l.limit.MaxJobs, "max-jobs", HttpMaxJobs, "max jobs: pack, unpack, comp and decomp jobs run at the same time, 0 means unlimited")
+	fs.IntVar(&l.limit.MaxQueue, "max-queue", HttpMaxQueue, "max queue: jobs waiting for a slot, more get 429")
+	fs.IntVar(&l.limit.QueueTimeout, "queue-timeout", HttpQueueTimeout, "queue timeout: max wait time(Millisecond) of a job in queue")
+	fs.Int64Var(&l.limit.MaxBody, "max-body", HttpMaxBodySize, "max body: max request body size(Byte), 0 means unlimited")
+	return l
+}
+
+// apply request limits to REST server
+func (l *limitFlags) apply() {
+	nets.SetHttpLimit(nets.NewHttpLimiter(l.limit))
+}
+
+// discoverFlags is the discovery flags of server command
+type discoverFlags struct {
+	name string
+	addr string
+}
+
+// addDiscoverFlags register discovery flags into server command
+func addDiscoverFlags(fs *flag.FlagSet) *discoverFlags {
+	d := &discoverFlags{}
+	fs.StringVar(&d.name, "announce", "", "announce: node name witch server announced as to satellite discovery on LAN, disabled when empty")
+	fs.StringVar(&d.addr, "discover-addr", DiscoverAddr, "discover addr: multicast group or broadcast address with port of satellite discovery")
+	return d
+}
+
+// start announcing service in background when name given,
+// REST servers also expose discovered peers
+func (d *discoverFlags) start(service string, port string) {
+	if d.name == "" {
+		return
+	}
+	n, err := nets.NewDiscover(d.addr, d.name)
+	if err != nil {
+		fmt.Println("Error start discovery:", err)
+		os.Exit(1)
+	}
+	n.Announce(service, port)
+	if service == CmdHttp || service == CmdHttps {
+		nets.SetHttpDiscover(n)
+	}
+	go n.ListenAndServe()
+}
+
+// forwardFlags is the flags of tcp and udp forward command
+type forwardFlags struct {
+	fs         *flag.FlagSet
+	listen     string
+	to         string
+	maxConns   int
+	idle       int
+	cert       string
+	key        string
+	selfSigned bool
+}
+
+// addForwardFlags register forwarding flags into command, TLS flags for tcp only
+func addForwardFlags(fs *flag.FlagSet, tls bool) *forwardFlags {
+	f := &forwardFlags{fs: fs}
+	fs.StringVar(&f.listen, "listen", "", "listen: address witch forwarding listen, such as \":8000\"")
+	fs.StringVar(&f.to, "to", "", "to: target address witch traffic forwarded to, such as \"10.0.0.5:80\"")
+	fs.IntVar(&f.maxConns, "max-conns", ForwardMaxConns, "max conns: concurrent connections or udp sessions, 0 means unlimited")
+	fs.IntVar(&f.idle, "idle-timeout", ForwardIdleTimeout, "idle timeout: close connection or udp session without traffic after time(Millisecond), 0 means never")
+	if tls {
+		fs.StringVar(&f.cert, "cert", "", "cert: certificate file to terminate TLS on listening side")
+		fs.StringVar(&f.key, "key", "", "key: private key file of certificate")
+		fs.BoolVar(&f.selfSigned, "self-signed", false, "self signed: generate self-signed certificate into cert.pem and key.pem or -cert and -key")
+	}
+	return f
+}
+
+// parse forwarding flags, exit when address missing
+func (f *forwardFlags) parse(args []string) {
+	err := f.fs.Parse(args)
+	if err != nil {
+		log.Println("Error parse forward flags:", err)
+		os.Exit(1)
+	}
+	if f.listen == "" || f.to == "" {
+		fmt.Println("Listen and target address required, use -listen and -to.")
+		f.fs.Usage()
+		os.Exit(1)
+	}
+	if !f.selfSigned && (f.cert == "") != (f.key == "") {
+		fmt.Println("Certificate and key file required, or use -self-signed.")
+		os.Exit(1)
+	}
+}
+
+// config of forwarding, TLS when certificate given
+func (f *forwardFlags) config() nets.TNetsForwardConfig {
+	c := nets.TNetsForwardConfig{MaxConns: f.maxConns, IdleTimeout: f.idle}
+	if f.cert != "" || f.selfSigned {
+		c.TLS = &nets.TNetsHttpsConfig{Cert: f.cert, Key: f.key, SelfSigned: f.selfSigned}
+	}
+	return c
+}
+
+// exitOnServerError exit with code 1 when server stopped by error,
+// servers return nil when shutdown by signal
+func exitOnServerError(err error) {
+	if err != nil {
+		fmt.Println("Server stopped by error:", err)
+		os.Exit(1)
+	}
+}
+
+// isStream check whether the path means stdin or stdout
+func isStream(path string) bool {
+	return path == StreamPath
+}
+
+// messageOutput return where to print messages, when stdout is used
+// by stream data, messages should be printed into stderr
+func messageOutput(stream bool) io.Writer {
+	if stream {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// openStreamInput open the file or stdin when path is StreamPath
+func openStreamInput(path string) (io.ReadCloser, error) {
+	if isStream(path) {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// createStreamOutput create the file or return stdout when path is StreamPath
+func createStreamOutput(path string) (io.WriteCloser, error) {
+	if isStream(path) {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// spoolStdin save the stdin data into dir as StreamFileName
+func spoolStdin(dir string) (path string, err error) {
+	path = filepath.Join(dir, StreamFileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return path, err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, os.Stdin)
+	return path, err
+}
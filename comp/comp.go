@@ -1,8 +1,9 @@
 package comp
 
 import (
-	"errors"
 	"fmt"
+	"io"
+	. "satellite/utils"
 )
 
 func Compress(src []string, dest string, algorithm string) (err error) {
@@ -14,8 +15,52 @@ func Compress(src []string, dest string, algorithm string) (err error) {
 	case "zip":
 		err = CompressZip(src, dest)
 	default:
-		s := fmt.Sprint("Undefined compress algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined compress algorithm", ErrAlgorithm)
+	}
+	return err
+}
+
+// CompressStream compress src files into archive and write it into w
+// algorithm support 'tar', 'tar.gz' and 'zip'
+func CompressStream(src []string, w io.Writer, algorithm string) (err error) {
+	switch algorithm {
+	case "tar":
+		err = CompressTarStream(src, w)
+	case "tar.gz":
+		err = CompressTarGzStream(src, w)
+	case "zip":
+		err = CompressZipStream(src, w)
+	default:
+		err = fmt.Errorf("%w: undefined compress algorithm", ErrAlgorithm)
+	}
+	return err
+}
+
+// CompressDataStream compress the raw data read from r and write it into w
+// there is no archive entry, algorithm support 'gzip' and 'zlib'
+func CompressDataStream(r io.Reader, w io.Writer, algorithm string) (err error) {
+	switch algorithm {
+	case "gzip":
+		err = CompressGzipStream(r, w)
+	case "zlib":
+		err = CompressZlibStream(r, w)
+	default:
+		err = fmt.Errorf("%w: undefined compress algorithm", ErrAlgorithm)
+	}
+	return err
+}
+
+// CompressDataStreamReproducible same as CompressDataStream, but the output is
+// byte-identical for the same data, gzip header time is SOURCE_DATE_EPOCH and
+// zlib header has no time at all
+func CompressDataStreamReproducible(r io.Reader, w io.Writer, algorithm string) (err error) {
+	switch algorithm {
+	case "gzip":
+		err = CompressGzipStreamReproducible(r, w)
+	case "zlib":
+		err = CompressZlibStream(r, w)
+	default:
+		err = fmt.Errorf("%w: undefined compress algorithm", ErrAlgorithm)
 	}
 	return err
 }
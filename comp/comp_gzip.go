@@ -3,6 +3,7 @@ package comp
 import (
 	"compress/gzip"
 	"errors"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -89,3 +90,34 @@ func CompressGzip(src []string, dest string) (err error) {
 	}
 	return err
 }
+
+// CompressGzipStream compress the data read from r into gzip and write it into w
+func CompressGzipStream(r io.Reader, w io.Writer) (err error) {
+	return compressGzipStream(r, w, time.Time{})
+}
+
+// CompressGzipStreamReproducible same as CompressGzipStream, but the gzip header is fixed,
+// modification time is SOURCE_DATE_EPOCH (1980-01-01 by default)
+func CompressGzipStreamReproducible(r io.Reader, w io.Writer) (err error) {
+	epoch, err := SourceDateEpoch()
+	if err != nil {
+		log.Println("Error get source date epoch:", err)
+		return err
+	}
+	return compressGzipStream(r, w, epoch)
+}
+
+func compressGzipStream(r io.Reader, w io.Writer, modTime time.Time) (err error) {
+	// apply one gzip writer to write stream
+	gw := gzip.NewWriter(w)
+	gw.Comment = "gzip compress by satellite"
+	gw.ModTime = modTime
+	// write compress data into stream
+	_, err = io.Copy(gw, r)
+	if err != nil {
+		log.Println("Error write compress data into stream:", err)
+		gw.Close()
+		return err
+	}
+	return gw.Close()
+}
@@ -1,6 +1,12 @@
 package comp
 
-import "testing"
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
 
 func TestCompressGzip(t *testing.T) {
 	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
@@ -21,3 +27,30 @@ func BenchmarkCompressGzip(b *testing.B) {
 		}
 	}
 }
+
+func TestCompressGzipStream(t *testing.T) {
+	var buf bytes.Buffer
+	src := "hello,world!"
+	err := CompressGzipStream(strings.NewReader(src), &buf)
+	if err != nil {
+		t.Fatal("Error Compress gzip stream:", err)
+	}
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal("Error read gzip stream:", err)
+	}
+	dest, err := ioutil.ReadAll(gr)
+	if err != nil || string(dest) != src {
+		t.Fatalf("Error Compress gzip stream: %q, %v", dest, err)
+	}
+}
+
+func BenchmarkCompressGzipStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := "hello,world!"
+		err := CompressGzipStream(strings.NewReader(src), ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error Compress gzip stream:", err)
+		}
+	}
+}
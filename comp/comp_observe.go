@@ -0,0 +1,36 @@
+package comp
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	. "satellite/utils"
+)
+
+// CompressObserve function
+// it is same as Compress, but every file written into archive is reported
+// to observer when it starts and finishes
+// algorithm support 'tar', 'tar.gz' and 'zip'
+func CompressObserve(src []string, dest string, algorithm string, o Observer) (err error) {
+	var compress func([]string, io.Writer, Observer) error
+	switch algorithm {
+	case "tar":
+		compress = compressTarStream
+	case "tar.gz":
+		compress = compressTarGzStream
+	case "zip":
+		compress = compressZipStream
+	default:
+		err = fmt.Errorf("%w: undefined compress algorithm", ErrAlgorithm)
+		return err
+	}
+	// create the dest archive file...
+	file, err := os.Create(dest)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return err
+	}
+	defer file.Close()
+	return compress(src, file, o)
+}
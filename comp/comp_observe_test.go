@@ -0,0 +1,131 @@
+package comp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testObserver struct {
+	start  []string
+	finish []string
+}
+
+func (o *testObserver) EntryStart(name string, size int64) {
+	o.start = append(o.start, name)
+}
+
+func (o *testObserver) EntryFinish(name string, size int64, err error) {
+	if err == nil {
+		o.finish = append(o.finish, name)
+	}
+}
+
+func TestCompressObserve(t *testing.T) {
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt"}
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, algorithm := range []string{"tar", "tar.gz", "zip"} {
+		o := &testObserver{}
+		err = CompressObserve(src, filepath.Join(dir, "file."+algorithm), algorithm, o)
+		if err != nil {
+			t.Fatal("Error CompressObserve:", err)
+		}
+		if len(o.start) != len(src) || len(o.finish) != len(src) {
+			t.Errorf("%v observed %v start and %v finish", algorithm, len(o.start), len(o.finish))
+		}
+	}
+	err = CompressObserve(src, filepath.Join(dir, "file.rar"), "rar", &testObserver{})
+	if err == nil {
+		t.Error("Compress undefined algorithm without error")
+	}
+	// canceled observer stop the compress
+	err = CompressObserve(src, filepath.Join(dir, "cancel.tar"), "tar", &testCancelObserver{})
+	if err == nil || err.Error() != "canceled" {
+		t.Error("Compress with canceled observer error is", err)
+	}
+}
+
+type testCancelObserver struct {
+	testObserver
+}
+
+func (o *testCancelObserver) Err() error {
+	return errors.New("canceled")
+}
+
+func BenchmarkCompressObserve(b *testing.B) {
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt"}
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		err = CompressObserve(src, filepath.Join(dir, "file.zip"), "zip", &testObserver{})
+		if err != nil {
+			b.Fatal("Error CompressObserve:", err)
+		}
+	}
+}
+
+func TestCompressReproducibleObserve(t *testing.T) {
+	src := []string{"../test/data/comp/file_2.txt", "../test/data/comp/file_1.txt"}
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, algorithm := range []string{"tar", "tar.gz", "zip", "gzip"} {
+		files := src
+		if algorithm == "gzip" {
+			files = src[:1]
+		}
+		a := filepath.Join(dir, "a."+algorithm)
+		b := filepath.Join(dir, "b."+algorithm)
+		o := &testObserver{}
+		err = CompressReproducibleObserve(files, a, algorithm, o)
+		if err != nil {
+			t.Fatal("Error CompressReproducibleObserve:", err)
+		}
+		if len(o.start) != len(files) || len(o.finish) != len(files) {
+			t.Errorf("%v observed %v start and %v finish", algorithm, len(o.start), len(o.finish))
+		}
+		// same archive as without observer
+		err = CompressReproducible(files, b, algorithm)
+		if err != nil {
+			t.Fatal("Error CompressReproducible:", err)
+		}
+		x, _ := ioutil.ReadFile(a)
+		y, _ := ioutil.ReadFile(b)
+		if !bytes.Equal(x, y) {
+			t.Errorf("%v archive differ with observer", algorithm)
+		}
+	}
+	// canceled observer stop the compress
+	err = CompressReproducibleObserve(src, filepath.Join(dir, "cancel.zip"), "zip", &testCancelObserver{})
+	if err == nil || err.Error() != "canceled" {
+		t.Error("Compress with canceled observer error is", err)
+	}
+}
+
+func BenchmarkCompressReproducibleObserve(b *testing.B) {
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt"}
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		err = CompressReproducibleObserve(src, filepath.Join(dir, "file.tar.gz"), "tar.gz", &testObserver{})
+		if err != nil {
+			b.Fatal("Error CompressReproducibleObserve:", err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package comp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	"satellite/utils"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TReproducibleEntry is one file or directory in reproducible archive
+type TReproducibleEntry struct {
+	Path string // path on disk
+	Name string // slash separated name in archive
+	Info os.FileInfo
+}
+
+// CompressReproducible compress src files into archive which is byte-identical
+// for the same input tree. entries are sorted by name, timestamps are set to
+// SOURCE_DATE_EPOCH (1980-01-01 by default), ownership is dropped and
+// permissions are normalized to 0644/0755.
+// algorithm support 'tar', 'tar.gz' and 'zip', 'gzip' and 'zlib' compress
+// one file as data stream with fixed header
+func CompressReproducible(src []string, dest string, algorithm string) (err error) {
+	// create the dest file...
+	file, err := os.Create(dest)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return err
+	}
+	defer file.Close()
+	return compressReproducibleStream(src, file, algorithm, nil)
+}
+
+// CompressReproducibleObserve same as CompressReproducible, every file written
+// into archive is reported to observer o when it starts and finishes,
+// the work stop when o is a Canceler and return error
+func CompressReproducibleObserve(src []string, dest string, algorithm string, o utils.Observer) (err error) {
+	// create the dest file...
+	file, err := os.Create(dest)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return err
+	}
+	defer file.Close()
+	return compressReproducibleStream(src, file, algorithm, o)
+}
+
+// CompressReproducibleStream same as CompressReproducible, write archive into w
+func CompressReproducibleStream(src []string, w io.Writer, algorithm string) (err error) {
+	return compressReproducibleStream(src, w, algorithm, nil)
+}
+
+// compressReproducibleStream write reproducible archive into w and report
+// every file to observer o, o can be nil
+func compressReproducibleStream(src []string, w io.Writer, algorithm string, o utils.Observer) (err error) {
+	epoch, err := SourceDateEpoch()
+	if err != nil {
+		log.Println("Error get source date epoch:", err)
+		return err
+	}
+	entries, err := ReproducibleEntries(src)
+	if err != nil {
+		log.Println("Error list reproducible entries:", err)
+		return err
+	}
+	switch algorithm {
+	case "tar":
+		err = compressTarReproducible(entries, w, epoch, o)
+	case "tar.gz":
+		// fixed gzip header: no name, no comment and zero modification time
+		gw := gzip.NewWriter(w)
+		err = compressTarReproducible(entries, gw, epoch, o)
+		if err != nil {
+			gw.Close()
+			return err
+		}
+		err = gw.Close()
+	case "zip":
+		err = compressZipReproducible(entries, w, epoch, o)
+	case "gzip", "zlib":
+		err = compressDataReproducible(entries, w, algorithm, epoch, o)
+	default:
+		err = fmt.Errorf("%w: reproducible compress only support 'tar', 'tar.gz', 'zip', 'gzip' or 'zlib'", utils.ErrAlgorithm)
+	}
+	return err
+}
+
+// SourceDateEpoch return the timestamp used by reproducible archive
+// it read SOURCE_DATE_EPOCH environment variable, default is 1980-01-01 00:00:00 UTC
+// which is the earliest time zip format can store
+func SourceDateEpoch() (t time.Time, err error) {
+	v := os.Getenv(ReproducibleEpochEnv)
+	if v == "" {
+		return time.Unix(ReproducibleEpoch, 0).UTC(), nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		err = fmt.Errorf("illegal %v: %q", ReproducibleEpochEnv, v)
+		return t, err
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// ReproducibleEntries walk src files and return entries sorted by name
+// names are relative to the parent directory of each src
+func ReproducibleEntries(src []string) (entries []TReproducibleEntry, err error) {
+	names := make(map[string]bool)
+	for _, v := range src {
+		root := filepath.Dir(filepath.Clean(v))
+		err = filepath.Walk(v, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// only regular files and directories are stored
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				return err
+			}
+			name, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+			if names[name] {
+				err = fmt.Errorf("duplicate entry name: %v", name)
+				return err
+			}
+			names[name] = true
+			entries = append(entries, TReproducibleEntry{Path: path, Name: name, Info: info})
+			return err
+		})
+		if err != nil {
+			return entries, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, err
+}
+
+// reproducibleMode normalize permissions, 0755 for directories and
+// executable files, 0644 for the others
+func reproducibleMode(info os.FileInfo) int64 {
+	if info.IsDir() || info.Mode().Perm()&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
+func compressTarReproducible(entries []TReproducibleEntry, w io.Writer, epoch time.Time, o utils.Observer) (err error) {
+	tw := tar.NewWriter(w)
+	for _, v := range entries {
+		header := &tar.Header{
+			Name:    v.Name,
+			Mode:    reproducibleMode(v.Info),
+			ModTime: epoch,
+			Format:  tar.FormatPAX,
+		}
+		if v.Info.IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Size = v.Info.Size()
+		}
+		err = tw.WriteHeader(header)
+		if err != nil {
+			log.Println("Error write compress file header:", err)
+			tw.Close()
+			return err
+		}
+		if v.Info.IsDir() {
+			continue
+		}
+		err = copyReproducibleFile(tw, v, o)
+		if err != nil {
+			tw.Close()
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func compressZipReproducible(entries []TReproducibleEntry, w io.Writer, epoch time.Time, o utils.Observer) (err error) {
+	archive := zip.NewWriter(w)
+	for _, v := range entries {
+		header := &zip.FileHeader{
+			Name:     v.Name,
+			Method:   zip.Deflate,
+			Modified: epoch,
+		}
+		if v.Info.IsDir() {
+			header.Name += "/"
+			header.Method = zip.Store
+			header.SetMode(os.ModeDir | os.FileMode(reproducibleMode(v.Info)))
+		} else {
+			header.SetMode(os.FileMode(reproducibleMode(v.Info)))
+		}
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			log.Println("Error create compress file header:", err)
+			archive.Close()
+			return err
+		}
+		if v.Info.IsDir() {
+			continue
+		}
+		err = copyReproducibleFile(writer, v, o)
+		if err != nil {
+			archive.Close()
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func compressDataReproducible(entries []TReproducibleEntry, w io.Writer, algorithm string, epoch time.Time, o utils.Observer) (err error) {
+	if len(entries) != 1 || entries[0].Info.IsDir() {
+		err = errors.New("reproducible gzip or zlib compress only one file")
+		return err
+	}
+	// open the src file...
+	v := entries[0]
+	data, err := os.Open(v.Path)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer data.Close()
+	if o != nil {
+		o.EntryStart(v.Path, v.Info.Size())
+	}
+	if algorithm == "zlib" {
+		err = CompressZlibStream(utils.ObserverReader(o, data), w)
+	} else {
+		err = compressGzipStream(utils.ObserverReader(o, data), w, epoch)
+	}
+	if o != nil {
+		o.EntryFinish(v.Path, v.Info.Size(), err)
+	}
+	return err
+}
+
+// copyReproducibleFile copy file of entry v into w and report it to observer o,
+// o can be nil
+func copyReproducibleFile(w io.Writer, v TReproducibleEntry, o utils.Observer) (err error) {
+	// open the src file...
+	data, err := os.Open(v.Path)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer data.Close()
+	// write compress data into file
+	if o != nil {
+		o.EntryStart(v.Path, v.Info.Size())
+	}
+	_, err = io.Copy(w, utils.ObserverReader(o, data))
+	if o != nil {
+		o.EntryFinish(v.Path, v.Info.Size(), err)
+	}
+	if err != nil {
+		log.Println("Error write compress data into file:", err)
+		return err
+	}
+	return err
+}
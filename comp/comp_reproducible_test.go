@@ -0,0 +1,124 @@
+package comp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompressReproducible(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := []string{"../test/data/comp/file_3.txt", "../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt"}
+	for _, algorithm := range []string{"tar", "tar.gz", "zip"} {
+		a := filepath.Join(dir, "a."+algorithm)
+		b := filepath.Join(dir, "b."+algorithm)
+		err = CompressReproducible(src, a, algorithm)
+		if err != nil {
+			t.Fatal("Error Compress Reproducible:", err)
+		}
+		// touch the file and reorder the input, output should not change
+		now := time.Now()
+		err = os.Chtimes(src[0], now, now)
+		if err != nil {
+			t.Fatal("Error change file time:", err)
+		}
+		err = CompressReproducible([]string{src[2], src[1], src[0]}, b, algorithm)
+		if err != nil {
+			t.Fatal("Error Compress Reproducible:", err)
+		}
+		x, _ := ioutil.ReadFile(a)
+		y, _ := ioutil.ReadFile(b)
+		if !bytes.Equal(x, y) {
+			t.Fatalf("Error Compress Reproducible: %v archives are different", algorithm)
+		}
+	}
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	defer os.Unsetenv("SOURCE_DATE_EPOCH")
+	os.Unsetenv("SOURCE_DATE_EPOCH")
+	epoch, err := SourceDateEpoch()
+	if err != nil || epoch.Year() != 1980 {
+		t.Fatal("Error Source Date Epoch:", epoch, err)
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	epoch, err = SourceDateEpoch()
+	if err != nil || epoch.Unix() != 1700000000 {
+		t.Fatal("Error Source Date Epoch:", epoch, err)
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, err = SourceDateEpoch()
+	if err == nil {
+		t.Fatal("Error Source Date Epoch: illegal value should fail")
+	}
+}
+
+func TestCompressReproducibleData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := "../test/data/comp/file_1.txt"
+	for _, algorithm := range []string{"gzip", "zlib"} {
+		a := filepath.Join(dir, "a."+algorithm)
+		b := filepath.Join(dir, "b."+algorithm)
+		err = CompressReproducible([]string{src}, a, algorithm)
+		if err != nil {
+			t.Fatal("Error Compress Reproducible:", err)
+		}
+		// touch the file, output should not change
+		now := time.Now()
+		err = os.Chtimes(src, now, now)
+		if err != nil {
+			t.Fatal("Error change file time:", err)
+		}
+		var buf bytes.Buffer
+		data, _ := ioutil.ReadFile(src)
+		err = CompressDataStreamReproducible(bytes.NewReader(data), &buf, algorithm)
+		if err != nil {
+			t.Fatal("Error Compress Data Stream Reproducible:", err)
+		}
+		err = ioutil.WriteFile(b, buf.Bytes(), 0644)
+		if err != nil {
+			t.Fatal("Error write file:", err)
+		}
+		x, _ := ioutil.ReadFile(a)
+		y, _ := ioutil.ReadFile(b)
+		if !bytes.Equal(x, y) {
+			t.Fatalf("Error Compress Reproducible: %v streams are different", algorithm)
+		}
+		if algorithm == "gzip" {
+			gr, err := gzip.NewReader(bytes.NewReader(x))
+			if err != nil {
+				t.Fatal("Error open gzip:", err)
+			}
+			if gr.ModTime.Unix() != 315532800 || gr.Name != "" {
+				t.Fatal("Error Compress Reproducible: gzip header not fixed", gr.ModTime, gr.Name)
+			}
+		}
+	}
+	// gzip and zlib compress only one file
+	err = CompressReproducible([]string{src, "../test/data/comp/file_2.txt"}, filepath.Join(dir, "c.gzip"), "gzip")
+	if err == nil {
+		t.Fatal("Error Compress Reproducible: gzip of two files should fail")
+	}
+}
+
+func BenchmarkCompressReproducible(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		err := CompressReproducibleStream(src, ioutil.Discard, "tar.gz")
+		if err != nil {
+			b.Fatal("Error Compress Reproducible:", err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package comp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	. "satellite/utils"
+	"sort"
+	"time"
+)
+
+// TSnapshot is the manifest of one incremental run.
+// it is saved to the snapshot file after each run and also stored as the first
+// entry of the archive, so that a restore can replay the deletions in order.
+// ID is generated by the full run and carried by its incrementals, so archives
+// of another backup set are refused by restore.
+type TSnapshot struct {
+	ID       string                    `json:"id"`
+	Sequence int                       `json:"sequence"`
+	Level    string                    `json:"level"`
+	Time     int64                     `json:"time"`
+	Files    map[string]TSnapshotEntry `json:"files"`
+	Deleted  []string                  `json:"deleted"`
+}
+
+// TSnapshotEntry records the state of one file when the snapshot was taken.
+type TSnapshotEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Hash    string `json:"hash"`
+}
+
+func CompressIncremental(src []string, dest string, algorithm string, snapshot string) (err error) {
+	switch algorithm {
+	case "tar":
+		err = CompressTarIncremental(src, dest, snapshot)
+	case "tar.gz":
+		err = CompressTarGzIncremental(src, dest, snapshot)
+	default:
+		err = fmt.Errorf("%w: incremental compress only support 'tar' or 'tar.gz'", ErrAlgorithm)
+	}
+	return err
+}
+
+func CompressTarIncremental(src []string, dest string, snapshot string) (err error) {
+	// create the dest tar file...
+	file, err := os.Create(dest)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return err
+	}
+	// write incremental tar into file
+	next, err := compressTarIncremental(src, file, snapshot)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		log.Println("Error close file:", err)
+		return err
+	}
+	// archive is complete on disk, now the snapshot can move forward
+	return SaveSnapshot(snapshot, next)
+}
+
+func CompressTarGzIncremental(src []string, dest string, snapshot string) (err error) {
+	// create the dest tar.gz file...
+	file, err := os.Create(dest)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return err
+	}
+	// apply one gzip writer to write file
+	gw := gzip.NewWriter(file)
+	next, err := compressTarIncremental(src, gw, snapshot)
+	if err != nil {
+		gw.Close()
+		file.Close()
+		return err
+	}
+	err = gw.Close()
+	if err != nil {
+		log.Println("Error close gzip writer:", err)
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		log.Println("Error close file:", err)
+		return err
+	}
+	// archive is complete on disk, now the snapshot can move forward
+	return SaveSnapshot(snapshot, next)
+}
+
+func LoadSnapshot(path string) (s TSnapshot, err error) {
+	s.Files = make(map[string]TSnapshotEntry)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		// no snapshot yet, the next run will be a full one
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		log.Println("Error read snapshot file:", err)
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		log.Println("Error unmarshal snapshot file:", err)
+		return s, err
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]TSnapshotEntry)
+	}
+	return s, err
+}
+
+func SaveSnapshot(path string, s TSnapshot) (err error) {
+	data, err := json.MarshalIndent(&s, "", "\t")
+	if err != nil {
+		log.Println("Error marshal snapshot:", err)
+		return err
+	}
+	// write the manifest aside first, so a crash never leaves half of it
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		log.Println("Error write snapshot file:", err)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// compressTarIncremental write incremental tar of src into w and return the
+// next snapshot, the caller saves it once the archive is flushed and closed
+func compressTarIncremental(src []string, w io.Writer, snapshot string) (next TSnapshot, err error) {
+	// load the previous snapshot...
+	prev, err := LoadSnapshot(snapshot)
+	if err != nil {
+		return next, err
+	}
+	next = TSnapshot{
+		ID:       prev.ID,
+		Sequence: prev.Sequence + 1,
+		Level:    "incremental",
+		Time:     time.Now().Unix(),
+		Files:    make(map[string]TSnapshotEntry),
+	}
+	if len(prev.Files) == 0 && prev.Sequence == 0 {
+		next.Level = "full"
+		next.ID, err = newSnapshotID()
+		if err != nil {
+			return next, err
+		}
+	}
+	// scan src list files and find out which one changed
+	paths := make(map[string]string)
+	var changed []string
+	for _, v := range src {
+		root := filepath.Dir(filepath.Clean(v))
+		err = filepath.Walk(v, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				log.Println("Error scan file:", err)
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return err
+			}
+			name, err := filepath.Rel(root, path)
+			if err != nil {
+				log.Println("Error get relative path:", err)
+				return err
+			}
+			name = filepath.ToSlash(name)
+			entry := TSnapshotEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+			old, ok := prev.Files[name]
+			if ok && old.Size == entry.Size && old.ModTime == entry.ModTime {
+				// unchanged by size and mtime, keep the old hash
+				entry.Hash = old.Hash
+				next.Files[name] = entry
+				return err
+			}
+			entry.Hash, err = hashSnapshotFile(path)
+			if err != nil {
+				return err
+			}
+			next.Files[name] = entry
+			if ok && old.Size == entry.Size && old.Hash == entry.Hash {
+				// only touched, the content is the same
+				return err
+			}
+			paths[name] = path
+			changed = append(changed, name)
+			return err
+		})
+		if err != nil {
+			log.Println("Error scan file:", err)
+			return next, err
+		}
+	}
+	// record the files which disappeared since the last run
+	for name := range prev.Files {
+		if _, ok := next.Files[name]; !ok {
+			next.Deleted = append(next.Deleted, name)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(next.Deleted)
+	// apply one tar writer to write file
+	tw := tar.NewWriter(w)
+	// the manifest is always the first entry
+	manifest, err := json.Marshal(&next)
+	if err != nil {
+		log.Println("Error marshal snapshot:", err)
+		return next, err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:     SnapshotEntryName,
+		Mode:     0644,
+		Size:     int64(len(manifest)),
+		ModTime:  time.Unix(next.Time, 0),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		log.Println("Error write snapshot header:", err)
+		return next, err
+	}
+	_, err = tw.Write(manifest)
+	if err != nil {
+		log.Println("Error write snapshot data:", err)
+		return next, err
+	}
+	// write changed files
+	for _, name := range changed {
+		err = writeTarFile(tw, paths[name], name)
+		if err != nil {
+			return next, err
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		log.Println("Error close tar writer:", err)
+		return next, err
+	}
+	return next, err
+}
+
+func writeTarFile(tw *tar.Writer, path string, name string) (err error) {
+	// open the src file...
+	data, err := os.Open(path)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer data.Close()
+	info, err := data.Stat()
+	if err != nil {
+		log.Println("Error stat file:", err)
+		return err
+	}
+	// get file information header
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		log.Println("Error get file info header:", err)
+		return err
+	}
+	header.Name = name
+	err = tw.WriteHeader(header)
+	if err != nil {
+		log.Println("Error write compress file header:", err)
+		return err
+	}
+	// write compress data into file
+	_, err = io.Copy(tw, data)
+	if err != nil {
+		log.Println("Error write compress data into file:", err)
+		return err
+	}
+	return err
+}
+
+func newSnapshotID() (id string, err error) {
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		log.Println("Error create snapshot id:", err)
+		return id, err
+	}
+	return hex.EncodeToString(b), err
+}
+
+func hashSnapshotFile(path string) (hash string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return hash, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		log.Println("Error hash file:", err)
+		return hash, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), err
+}
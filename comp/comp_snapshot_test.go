@@ -0,0 +1,159 @@
+package comp
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressTarIncremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	data := filepath.Join(dir, "data")
+	snapshot := filepath.Join(dir, "snapshot.json")
+	_ = os.MkdirAll(filepath.Join(data, "sub"), 0755)
+	_ = ioutil.WriteFile(filepath.Join(data, "file_1.txt"), []byte("file_1"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(data, "sub", "file_2.txt"), []byte("file_2"), 0644)
+	// first run should be a full one
+	err = CompressTarIncremental([]string{data}, filepath.Join(dir, "full.tar"), snapshot)
+	if err != nil {
+		t.Fatal("Error Compress Tar Incremental:", err)
+	}
+	s, err := LoadSnapshot(snapshot)
+	if err != nil {
+		t.Fatal("Error Load Snapshot:", err)
+	}
+	if s.Level != "full" || s.Sequence != 1 || len(s.Files) != 2 {
+		t.Fatalf("Error full snapshot: %+v", s)
+	}
+	// second run should only record the changes
+	_ = ioutil.WriteFile(filepath.Join(data, "file_1.txt"), []byte("file_1 changed"), 0644)
+	_ = os.Remove(filepath.Join(data, "sub", "file_2.txt"))
+	err = CompressTarIncremental([]string{data}, filepath.Join(dir, "incr.tar"), snapshot)
+	if err != nil {
+		t.Fatal("Error Compress Tar Incremental:", err)
+	}
+	s, err = LoadSnapshot(snapshot)
+	if err != nil {
+		t.Fatal("Error Load Snapshot:", err)
+	}
+	if s.Level != "incremental" || s.Sequence != 2 || len(s.Files) != 1 {
+		t.Fatalf("Error incremental snapshot: %+v", s)
+	}
+	if len(s.Deleted) != 1 || s.Deleted[0] != "data/sub/file_2.txt" {
+		t.Fatalf("Error incremental deleted list: %v", s.Deleted)
+	}
+}
+
+// tTestFailWriter fail after n bytes written
+type tTestFailWriter struct {
+	n int
+}
+
+func (w *tTestFailWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return 0, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestCompressTarIncrementalSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := []string{"../test/data/comp/file_1.txt"}
+	snapshot := filepath.Join(dir, "snapshot.json")
+	// archive not written completely, snapshot not moved
+	_, err = compressTarIncremental(src, &tTestFailWriter{n: 1024}, snapshot)
+	if err == nil {
+		t.Error("Compress into failing writer without error")
+	}
+	// archive written but not closed by caller yet, snapshot not moved
+	next, err := compressTarIncremental(src, ioutil.Discard, snapshot)
+	if err != nil || next.Sequence != 1 {
+		t.Errorf("Next snapshot %+v: %v", next, err)
+	}
+	if _, err = os.Stat(snapshot); !os.IsNotExist(err) {
+		t.Errorf("Snapshot saved before archive closed: %v", err)
+	}
+}
+
+func BenchmarkCompressTarIncrementalSnapshot(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		_, err = compressTarIncremental([]string{"../test/data/comp/file_1.txt"}, ioutil.Discard, filepath.Join(dir, "snapshot.json"))
+		if err != nil {
+			b.Fatal("Error Compress Tar Incremental:", err)
+		}
+	}
+}
+
+func BenchmarkCompressTarIncremental(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		dest := filepath.Join(dir, "file.tar")
+		err := CompressTarIncremental(src, dest, filepath.Join(dir, "snapshot.json"))
+		if err != nil {
+			b.Fatal("Error Compress Tar Incremental:", err)
+		}
+	}
+}
+
+func TestCompressTarGzIncremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+	snapshot := filepath.Join(dir, "snapshot.json")
+	err = CompressTarGzIncremental(src, filepath.Join(dir, "full.tar.gz"), snapshot)
+	if err != nil {
+		t.Fatal("Error Compress Tar Gz Incremental:", err)
+	}
+	// nothing changed, nothing but the manifest should be written
+	err = CompressTarGzIncremental(src, filepath.Join(dir, "incr.tar.gz"), snapshot)
+	if err != nil {
+		t.Fatal("Error Compress Tar Gz Incremental:", err)
+	}
+	s, err := LoadSnapshot(snapshot)
+	if err != nil {
+		t.Fatal("Error Load Snapshot:", err)
+	}
+	if s.Sequence != 2 || len(s.Files) != len(src) || len(s.Deleted) != 0 {
+		t.Fatalf("Error incremental snapshot: %+v", s)
+	}
+}
+
+func BenchmarkCompressTarGzIncremental(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		dest := filepath.Join(dir, "file.tar.gz")
+		err := CompressTarGzIncremental(src, dest, filepath.Join(dir, "snapshot.json"))
+		if err != nil {
+			b.Fatal("Error Compress Tar Gz Incremental:", err)
+		}
+	}
+}
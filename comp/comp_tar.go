@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	. "satellite/utils"
 )
 
 func CompressTar(src []string, dest string) (err error) {
@@ -17,49 +18,7 @@ func CompressTar(src []string, dest string) (err error) {
 		return err
 	}
 	defer file.Close()
-	// apply one tar writer to write file
-	tw := tar.NewWriter(file)
-	defer tw.Close()
-	// loop compress src list files
-	for _, v := range src {
-		err = filepath.Walk(v, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				log.Println("Error compress file:", err)
-				return err
-			}
-			// get file information header
-			header, err := tar.FileInfoHeader(info, "")
-			if err != nil {
-				log.Println("Error get file info header:", err)
-				return err
-			}
-			// assign compress method
-			err = tw.WriteHeader(header)
-			if err != nil {
-				log.Println("Error write compress file header:", err)
-				return err
-			}
-			// open the src file...
-			data, err := os.Open(path)
-			if err != nil {
-				log.Println("Error open file:", err)
-				return err
-			}
-			defer data.Close()
-			// write compress data into file
-			_, err = io.Copy(tw, data)
-			if err != nil {
-				log.Println("Error write compress data into file:", err)
-				return err
-			}
-			return err
-		})
-		if err != nil {
-			log.Println("Error compress file:", err)
-			return err
-		}
-	}
-	return err
+	return CompressTarStream(src, file)
 }
 
 func CompressTarGz(src []string, dest string) (err error) {
@@ -70,12 +29,19 @@ func CompressTarGz(src []string, dest string) (err error) {
 		return err
 	}
 	defer file.Close()
-	// apply one gzip writer to write file
-	gw := gzip.NewWriter(file)
-	defer gw.Close()
-	// apply one tar writer to write file
-	tw := tar.NewWriter(gw)
-	defer tw.Close()
+	return CompressTarGzStream(src, file)
+}
+
+// CompressTarStream compress src files into tar and write it into w
+func CompressTarStream(src []string, w io.Writer) (err error) {
+	return compressTarStream(src, w, nil)
+}
+
+// compressTarStream compress src files and report every file to observer o,
+// o can be nil
+func compressTarStream(src []string, w io.Writer, o Observer) (err error) {
+	// apply one tar writer to write stream
+	tw := tar.NewWriter(w)
 	// loop compress src list files
 	for _, v := range src {
 		err = filepath.Walk(v, func(path string, info os.FileInfo, err error) error {
@@ -103,7 +69,13 @@ func CompressTarGz(src []string, dest string) (err error) {
 			}
 			defer data.Close()
 			// write compress data into file
-			_, err = io.Copy(tw, data)
+			if o != nil {
+				o.EntryStart(path, info.Size())
+			}
+			_, err = io.Copy(tw, ObserverReader(o, data))
+			if o != nil {
+				o.EntryFinish(path, info.Size(), err)
+			}
 			if err != nil {
 				log.Println("Error write compress data into file:", err)
 				return err
@@ -112,8 +84,27 @@ func CompressTarGz(src []string, dest string) (err error) {
 		})
 		if err != nil {
 			log.Println("Error compress file:", err)
+			tw.Close()
 			return err
 		}
 	}
-	return err
+	return tw.Close()
+}
+
+// CompressTarGzStream compress src files into tar.gz and write it into w
+func CompressTarGzStream(src []string, w io.Writer) (err error) {
+	return compressTarGzStream(src, w, nil)
+}
+
+// compressTarGzStream compress src files and report every file to observer o,
+// o can be nil
+func compressTarGzStream(src []string, w io.Writer, o Observer) (err error) {
+	// apply one gzip writer to write stream
+	gw := gzip.NewWriter(w)
+	err = compressTarStream(src, gw, o)
+	if err != nil {
+		gw.Close()
+		return err
+	}
+	return gw.Close()
 }
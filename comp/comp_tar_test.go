@@ -1,6 +1,13 @@
 package comp
 
-import "testing"
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
 
 func TestCompressTar(t *testing.T) {
 	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
@@ -41,3 +48,59 @@ func BenchmarkCompressTarGz(b *testing.B) {
 		}
 	}
 }
+
+func TestCompressTarStream(t *testing.T) {
+	var buf bytes.Buffer
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+	err := CompressTarStream(src, &buf)
+	if err != nil {
+		t.Fatal("Error Compress Tar Stream:", err)
+	}
+	tr := tar.NewReader(&buf)
+	for _, v := range src {
+		header, err := tr.Next()
+		if err != nil {
+			t.Fatal("Error read tar stream:", err)
+		}
+		if header.Name != filepath.Base(v) {
+			t.Fatalf("Error Compress Tar Stream: entry %v, want %v", header.Name, filepath.Base(v))
+		}
+	}
+}
+
+func BenchmarkCompressTarStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		err := CompressTarStream(src, ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error Compress Tar Stream:", err)
+		}
+	}
+}
+
+func TestCompressTarGzStream(t *testing.T) {
+	var buf bytes.Buffer
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+	err := CompressTarGzStream(src, &buf)
+	if err != nil {
+		t.Fatal("Error Compress Tar Gz Stream:", err)
+	}
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal("Error read tar gz stream:", err)
+	}
+	_, err = tar.NewReader(gr).Next()
+	if err != nil {
+		t.Fatal("Error read tar gz stream:", err)
+	}
+}
+
+func BenchmarkCompressTarGzStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		err := CompressTarGzStream(src, ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error Compress Tar Gz Stream:", err)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package comp
 
-import "testing"
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
 
 func TestCompress(t *testing.T) {
 	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
@@ -73,3 +77,36 @@ func BenchmarkCompress3(b *testing.B) {
 		}
 	}
 }
+
+func TestCompressStream(t *testing.T) {
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+	err := CompressStream(src, ioutil.Discard, "tar.gz")
+	if err != nil {
+		t.Fatal("Error Compress Stream:", err)
+	}
+	err = CompressStream(src, ioutil.Discard, "gzip")
+	if err == nil {
+		t.Fatal("Error Compress Stream: gzip is not archive algorithm")
+	}
+}
+
+func TestCompressDataStream(t *testing.T) {
+	err := CompressDataStream(strings.NewReader("hello,world!"), ioutil.Discard, "gzip")
+	if err != nil {
+		t.Fatal("Error Compress Data Stream:", err)
+	}
+	err = CompressDataStream(strings.NewReader("hello,world!"), ioutil.Discard, "zlib")
+	if err != nil {
+		t.Fatal("Error Compress Data Stream:", err)
+	}
+}
+
+func BenchmarkCompressStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		err := CompressStream(src, ioutil.Discard, "zip")
+		if err != nil {
+			b.Fatal("Error Compress Stream:", err)
+		}
+	}
+}
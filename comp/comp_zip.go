@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	. "satellite/utils"
 )
 
 func CompressZip(src []string, dest string) (err error) {
@@ -16,9 +17,20 @@ func CompressZip(src []string, dest string) (err error) {
 		return err
 	}
 	defer file.Close()
-	// apply one zip writer to write file
-	archive := zip.NewWriter(file)
-	defer archive.Close()
+	return CompressZipStream(src, file)
+}
+
+// CompressZipStream compress src files into zip and write it into w
+// w do not need to be seekable, so it can be stdout or network connection
+func CompressZipStream(src []string, w io.Writer) (err error) {
+	return compressZipStream(src, w, nil)
+}
+
+// compressZipStream compress src files and report every file to observer o,
+// o can be nil
+func compressZipStream(src []string, w io.Writer, o Observer) (err error) {
+	// apply one zip writer to write stream
+	archive := zip.NewWriter(w)
 	// loop compress src list files
 	for _, v := range src {
 		err = filepath.Walk(v, func(path string, info os.FileInfo, err error) error {
@@ -47,7 +59,13 @@ func CompressZip(src []string, dest string) (err error) {
 			}
 			defer data.Close()
 			// write compress data into file
-			_, err = io.Copy(writer, data)
+			if o != nil {
+				o.EntryStart(path, info.Size())
+			}
+			_, err = io.Copy(writer, ObserverReader(o, data))
+			if o != nil {
+				o.EntryFinish(path, info.Size(), err)
+			}
 			if err != nil {
 				log.Println("Error write compress data into file:", err)
 				return err
@@ -56,8 +74,9 @@ func CompressZip(src []string, dest string) (err error) {
 		})
 		if err != nil {
 			log.Println("Error compress file:", err)
+			archive.Close()
 			return err
 		}
 	}
-	return err
+	return archive.Close()
 }
@@ -0,0 +1,216 @@
+package comp
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	. "satellite/utils"
+)
+
+func CompressEncrypt(src []string, dest string, algorithm string, password string) (err error) {
+	switch algorithm {
+	case "zip":
+		err = CompressZipEncrypt(src, dest, password)
+	default:
+		err = fmt.Errorf("%w: encrypt compress only support 'zip'", ErrAlgorithm)
+	}
+	return err
+}
+
+// CompressZipEncrypt compress files into zip with WinZip AES-256 encryption (AE-2)
+// the output can be opened by WinZip, 7-Zip and other standard tools with the password
+func CompressZipEncrypt(src []string, dest string, password string) (err error) {
+	if password == "" {
+		err = errors.New("password can not be empty")
+		return err
+	}
+	// create the dest zip file...
+	file, err := os.Create(dest)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return err
+	}
+	defer file.Close()
+	// apply one zip writer to write file
+	archive := zip.NewWriter(file)
+	// loop compress src list files
+	for _, v := range src {
+		root := filepath.Dir(filepath.Clean(v))
+		err = filepath.Walk(v, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				log.Println("Error compress file:", err)
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return err
+			}
+			name, err := filepath.Rel(root, path)
+			if err != nil {
+				log.Println("Error get relative path:", err)
+				return err
+			}
+			return writeZipAESFile(archive, path, filepath.ToSlash(name), info, []byte(password))
+		})
+		if err != nil {
+			log.Println("Error compress file:", err)
+			archive.Close()
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeZipAESFile(archive *zip.Writer, path string, name string, info os.FileInfo, password []byte) (err error) {
+	const strength = 3
+	// open the src file...
+	data, err := os.Open(path)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer data.Close()
+	// size the deflated data first, the header needs it before the data and
+	// flate output is the same for the same input
+	size, csize, err := deflateZipAES(data, ioutil.Discard)
+	if err != nil {
+		return err
+	}
+	_, err = data.Seek(0, io.SeekStart)
+	if err != nil {
+		log.Println("Error seek file:", err)
+		return err
+	}
+	// derive keys from password and random salt
+	salt := make([]byte, WinZipAESSaltSize(strength))
+	_, err = rand.Read(salt)
+	if err != nil {
+		log.Println("Error generate random salt:", err)
+		return err
+	}
+	key, mac, verify, err := WinZipAESKeys(password, salt, strength)
+	if err != nil {
+		log.Println("Error derive winzip aes keys:", err)
+		return err
+	}
+	stream, err := NewWinZipAESStream(key)
+	if err != nil {
+		log.Println("Error new winzip aes stream:", err)
+		return err
+	}
+	// fill the zip header, AE-2 entries do not store crc32
+	header := &zip.FileHeader{
+		Name:               name,
+		Method:             WinZipAESMethod,
+		Flags:              0x1,
+		CompressedSize64:   uint64(int64(len(salt)+len(verify)+WinZipAESMacSize) + csize),
+		UncompressedSize64: uint64(size),
+		Extra:              winZipAESExtra(strength, zip.Deflate),
+	}
+	header.SetModTime(info.ModTime())
+	header.SetMode(info.Mode())
+	writer, err := archive.CreateRaw(header)
+	if err != nil {
+		log.Println("Error create compress file header:", err)
+		return err
+	}
+	// salt, password verification, encrypted data and authentication code
+	_, err = writer.Write(append(append([]byte{}, salt...), verify...))
+	if err != nil {
+		log.Println("Error write compress data into file:", err)
+		return err
+	}
+	ew := &zipAESWriter{w: writer, stream: stream, mac: hmac.New(sha1.New, mac)}
+	n, ncsize, err := deflateZipAES(data, ew)
+	if err != nil {
+		return err
+	}
+	if n != size || ncsize != csize {
+		err = fmt.Errorf("file %v changed while compressing", path)
+		log.Println("Error write compress data into file:", err)
+		return err
+	}
+	_, err = writer.Write(ew.mac.Sum(nil)[:WinZipAESMacSize])
+	if err != nil {
+		log.Println("Error write compress data into file:", err)
+		return err
+	}
+	return err
+}
+
+// deflateZipAES deflate r into w, return the size of data and deflated data.
+func deflateZipAES(r io.Reader, w io.Writer) (size int64, csize int64, err error) {
+	cw := &zipAESCountWriter{w: w}
+	fw, err := flate.NewWriter(cw, flate.DefaultCompression)
+	if err != nil {
+		log.Println("Error new flate writer:", err)
+		return size, csize, err
+	}
+	size, err = io.Copy(fw, r)
+	if err != nil {
+		log.Println("Error write compress data:", err)
+		return size, csize, err
+	}
+	err = fw.Close()
+	if err != nil {
+		log.Println("Error close flate writer:", err)
+		return size, csize, err
+	}
+	return size, cw.n, err
+}
+
+// zipAESCountWriter count the bytes written to w
+type zipAESCountWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *zipAESCountWriter) Write(p []byte) (n int, err error) {
+	n, err = c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// zipAESWriter encrypt data with the aes ctr stream and authenticate the encrypted data
+type zipAESWriter struct {
+	w      io.Writer
+	stream cipher.Stream
+	mac    hash.Hash
+	buf    []byte
+}
+
+func (z *zipAESWriter) Write(p []byte) (n int, err error) {
+	if cap(z.buf) < len(p) {
+		z.buf = make([]byte, len(p))
+	}
+	crypt := z.buf[:len(p)]
+	z.stream.XORKeyStream(crypt, p)
+	z.mac.Write(crypt)
+	_, err = z.w.Write(crypt)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), err
+}
+
+func winZipAESExtra(strength int, method uint16) []byte {
+	b := make([]byte, 11)
+	binary.LittleEndian.PutUint16(b[0:], WinZipAESExtraID)
+	binary.LittleEndian.PutUint16(b[2:], 7)
+	binary.LittleEndian.PutUint16(b[4:], 2) // AE-2
+	b[6], b[7] = 'A', 'E'
+	b[8] = byte(strength)
+	binary.LittleEndian.PutUint16(b[9:], method)
+	return b
+}
@@ -0,0 +1,27 @@
+package comp
+
+import "testing"
+
+func TestCompressZipEncrypt(t *testing.T) {
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+	dest := "../test/data/comp/file_aes.zip"
+	err := CompressZipEncrypt(src, dest, "satellite")
+	if err != nil {
+		t.Fatal("Error Compress Zip Encrypt:", err)
+	}
+	err = CompressZipEncrypt(src, dest, "")
+	if err == nil {
+		t.Fatal("Error Compress Zip Encrypt: empty password should fail")
+	}
+}
+
+func BenchmarkCompressZipEncrypt(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		dest := "../test/data/comp/file_aes.zip"
+		err := CompressZipEncrypt(src, dest, "satellite")
+		if err != nil {
+			b.Fatal("Error Compress Zip Encrypt:", err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package comp
 
 import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
 	"testing"
 )
 
@@ -23,3 +26,29 @@ func BenchmarkCompressZip(b *testing.B) {
 		}
 	}
 }
+
+func TestCompressZipStream(t *testing.T) {
+	var buf bytes.Buffer
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+	err := CompressZipStream(src, &buf)
+	if err != nil {
+		t.Fatal("Error Compress Zip Stream:", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("Error read zip stream:", err)
+	}
+	if len(reader.File) != len(src) {
+		t.Fatalf("Error Compress Zip Stream: %d entries, want %d", len(reader.File), len(src))
+	}
+}
+
+func BenchmarkCompressZipStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+		err := CompressZipStream(src, ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error Compress Zip Stream:", err)
+		}
+	}
+}
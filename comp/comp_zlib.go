@@ -3,6 +3,7 @@ package comp
 import (
 	"bytes"
 	"compress/zlib"
+	"io"
 	"log"
 )
 
@@ -22,3 +23,20 @@ func CompressZlib(src []byte) (dest []byte, err error) {
 	dest = in.Bytes()
 	return dest, err
 }
+
+// CompressZlibStream compress the data read from r into zlib and write it into w
+func CompressZlibStream(r io.Reader, w io.Writer) (err error) {
+	zw := zlib.NewWriter(w)
+	_, err = io.Copy(zw, r)
+	if err != nil {
+		log.Println("Error compress zlib:", err)
+		zw.Close()
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		log.Println("Error close zlib writer:", err)
+		return err
+	}
+	return err
+}
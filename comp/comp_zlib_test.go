@@ -1,7 +1,10 @@
 package comp
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
 )
 
@@ -24,3 +27,23 @@ func BenchmarkCompressZlib(b *testing.B) {
 		}
 	}
 }
+
+func TestCompressZlibStream(t *testing.T) {
+	var buf bytes.Buffer
+	src := "hello,world!"
+	err := CompressZlibStream(strings.NewReader(src), &buf)
+	if err != nil {
+		t.Fatal("Error Compress Zlib Stream:", err)
+	}
+	fmt.Println("After Compress Zlib Stream:", buf.String())
+}
+
+func BenchmarkCompressZlibStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := "hello,world!"
+		err := CompressZlibStream(strings.NewReader(src), ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error Compress Zlib Stream:", err)
+		}
+	}
+}
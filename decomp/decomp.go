@@ -1,8 +1,9 @@
 package decomp
 
 import (
-	"errors"
 	"fmt"
+	"io"
+	. "satellite/utils"
 )
 
 func DeCompress(src string, dest string, algorithm string) (err error) {
@@ -14,8 +15,41 @@ func DeCompress(src string, dest string, algorithm string) (err error) {
 	case "zip":
 		err = DeCompressZip(src, dest)
 	default:
-		s := fmt.Sprint("Undefined decompress algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined decompress algorithm", ErrAlgorithm)
+	}
+	return err
+}
+
+// DeCompressStream decompress archive read from r into dest
+// algorithm support 'tar', 'tar.gz', 'tar.bz2' and 'zip'
+func DeCompressStream(r io.Reader, dest string, algorithm string) (err error) {
+	switch algorithm {
+	case "tar":
+		err = DeCompressTarStream(r, dest)
+	case "tar.gz":
+		err = DeCompressTarGzStream(r, dest)
+	case "tar.bz2":
+		err = DeCompressTarBz2Stream(r, dest)
+	case "zip":
+		err = DeCompressZipStream(r, dest)
+	default:
+		err = fmt.Errorf("%w: undefined decompress algorithm", ErrAlgorithm)
+	}
+	return err
+}
+
+// DeCompressDataStream decompress the raw data read from r and write it into w
+// there is no archive entry, algorithm support 'gzip', 'zlib' and 'bzip2'
+func DeCompressDataStream(r io.Reader, w io.Writer, algorithm string) (err error) {
+	switch algorithm {
+	case "gzip":
+		err = DeCompressGzipStream(r, w)
+	case "zlib":
+		err = DeCompressZlibStream(r, w)
+	case "bzip2":
+		err = DeCompressBzip2Stream(r, w)
+	default:
+		err = fmt.Errorf("%w: undefined decompress algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -64,3 +64,16 @@ func DeCompressBzip2(src []string, dest string) (err error) {
 	}
 	return err
 }
+
+// DeCompressBzip2Stream decompress bzip2 read from r and write the data into w
+func DeCompressBzip2Stream(r io.Reader, w io.Writer) (err error) {
+	// apply one bzip2 reader to read stream
+	br := bzip2.NewReader(r)
+	// read decompress data into stream
+	_, err = io.Copy(w, br)
+	if err != nil {
+		log.Println("Error read decompress data into stream:", err)
+		return err
+	}
+	return err
+}
@@ -65,3 +65,21 @@ func DeCompressGzip(src []string, dest string) (err error) {
 	}
 	return err
 }
+
+// DeCompressGzipStream decompress gzip read from r and write the data into w
+func DeCompressGzipStream(r io.Reader, w io.Writer) (err error) {
+	// apply one gzip reader to read stream
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		log.Println("Error new gzip reader:", err)
+		return err
+	}
+	defer gr.Close()
+	// read decompress data into stream
+	_, err = io.Copy(w, gr)
+	if err != nil {
+		log.Println("Error read decompress data into stream:", err)
+		return err
+	}
+	return err
+}
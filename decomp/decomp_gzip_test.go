@@ -1,6 +1,11 @@
 package decomp
 
-import "testing"
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
 
 func TestDeCompressGzip(t *testing.T) {
 	src := []string{"../test/data/decomp/file_1.gz", "../test/data/decomp/file_2.gz", "../test/data/decomp/file_3.gz", "../test/data/decomp/file_4.gz", "../test/data/decomp/file_5.gz"}
@@ -21,3 +26,29 @@ func BenchmarkDeCompressGzip(b *testing.B) {
 		}
 	}
 }
+
+func TestDeCompressGzipStream(t *testing.T) {
+	var buf bytes.Buffer
+	file, err := os.Open("../test/data/decomp/file_1.gz")
+	if err != nil {
+		t.Fatal("Error open gzip file:", err)
+	}
+	defer file.Close()
+	err = DeCompressGzipStream(file, &buf)
+	if err != nil {
+		t.Fatal("Error DeCompress gzip stream:", err)
+	}
+}
+
+func BenchmarkDeCompressGzipStream(b *testing.B) {
+	data, err := ioutil.ReadFile("../test/data/decomp/file_1.gz")
+	if err != nil {
+		b.Fatal("Error read gzip file:", err)
+	}
+	for i := 0; i < b.N; i++ {
+		err := DeCompressGzipStream(bytes.NewReader(data), ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error DeCompress gzip stream:", err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package decomp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"satellite/comp"
+	. "satellite/global"
+	"satellite/utils"
+)
+
+// DeCompressRestore apply a chain of full and incremental archives in order.
+// the first archive should be the full one, the following archives should be
+// the incremental ones created with the same snapshot file. every manifest is
+// checked before the first archive is extracted.
+func DeCompressRestore(src []string, dest string, algorithm string) (err error) {
+	if len(src) == 0 {
+		err = errors.New("restore archive list can not be empty")
+		return err
+	}
+	if algorithm != "tar" && algorithm != "tar.gz" {
+		err = fmt.Errorf("%w: restore only support 'tar' or 'tar.gz'", utils.ErrAlgorithm)
+		return err
+	}
+	// read and check the chain of archives
+	chain := make([]comp.TSnapshot, len(src))
+	for k, v := range src {
+		chain[k], err = readRestoreManifest(v, algorithm)
+		if err != nil {
+			log.Println("Error read snapshot manifest:", err)
+			return err
+		}
+		if k == 0 && chain[k].Level != "full" {
+			err = fmt.Errorf("archive %v is not a full archive", v)
+			return err
+		}
+		if k != 0 && chain[k].Level != "incremental" {
+			err = fmt.Errorf("archive %v is not an incremental archive", v)
+			return err
+		}
+		if k != 0 && chain[k].ID != chain[0].ID {
+			err = fmt.Errorf("archive %v belongs to another backup set", v)
+			return err
+		}
+		if k != 0 && chain[k].Sequence != chain[k-1].Sequence+1 {
+			err = fmt.Errorf("archive %v is out of order: sequence %d after %d", v, chain[k].Sequence, chain[k-1].Sequence)
+			return err
+		}
+	}
+	// apply the archives
+	for k, v := range src {
+		err = deCompressRestoreOne(v, dest, algorithm, chain[k])
+		if err != nil {
+			log.Println("Error restore archive:", err)
+			return err
+		}
+	}
+	return err
+}
+
+// openRestoreTar open the tar reader of archive, close should be called after use.
+func openRestoreTar(src string, algorithm string) (tr *tar.Reader, close func(), err error) {
+	// open the src tar ball file...
+	file, err := os.Open(src)
+	if err != nil {
+		log.Println("Error open tar file:", err)
+		return nil, nil, err
+	}
+	if algorithm != "tar.gz" {
+		return tar.NewReader(file), func() { file.Close() }, err
+	}
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		log.Println("Error new gzip reader:", err)
+		return nil, nil, err
+	}
+	return tar.NewReader(gr), func() { gr.Close(); file.Close() }, err
+}
+
+// readRestoreManifest read the snapshot manifest, the first entry of archive.
+func readRestoreManifest(src string, algorithm string) (s comp.TSnapshot, err error) {
+	tr, close, err := openRestoreTar(src, algorithm)
+	if err != nil {
+		return s, err
+	}
+	defer close()
+	header, err := tr.Next()
+	if err != nil || header.Name != SnapshotEntryName {
+		err = fmt.Errorf("archive %v has no snapshot manifest", src)
+		return s, err
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(tr, SnapshotMaxSize))
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		log.Println("Error unmarshal snapshot manifest:", err)
+		return s, err
+	}
+	return s, err
+}
+
+func deCompressRestoreOne(src string, dest string, algorithm string, s comp.TSnapshot) (err error) {
+	tr, close, err := openRestoreTar(src, algorithm)
+	if err != nil {
+		return err
+	}
+	defer close()
+	// loop decompress src list files
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		// the manifest is already read
+		if header.Name == SnapshotEntryName {
+			continue
+		}
+		name, err := utils.ConfinePath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			log.Println("Skip non regular entry in archive:", header.Name)
+			continue
+		}
+		err = os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			log.Println("Error make dir all:", err)
+			return err
+		}
+		f, err := os.Create(name)
+		if err != nil {
+			log.Println("Error create name:", err)
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			log.Println("Error write decompress date:", err)
+			return err
+		}
+	}
+	// apply the deletions recorded in this archive
+	for _, v := range s.Deleted {
+		name, err := utils.ConfinePath(dest, v)
+		if err != nil {
+			return err
+		}
+		err = os.Remove(name)
+		if err != nil && !os.IsNotExist(err) {
+			log.Println("Error remove deleted file:", err)
+			return err
+		}
+	}
+	return nil
+}
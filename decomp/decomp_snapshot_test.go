@@ -0,0 +1,95 @@
+package decomp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"satellite/comp"
+	"testing"
+)
+
+func TestDeCompressRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	data := filepath.Join(dir, "data")
+	snapshot := filepath.Join(dir, "snapshot.json")
+	_ = os.MkdirAll(data, 0755)
+	_ = ioutil.WriteFile(filepath.Join(data, "file_1.txt"), []byte("file_1"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(data, "file_2.txt"), []byte("file_2"), 0644)
+	// build one full and one incremental archive
+	full := filepath.Join(dir, "full.tar.gz")
+	err = comp.CompressTarGzIncremental([]string{data}, full, snapshot)
+	if err != nil {
+		t.Fatal("Error Compress Tar Gz Incremental:", err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(data, "file_1.txt"), []byte("file_1 changed"), 0644)
+	_ = os.Remove(filepath.Join(data, "file_2.txt"))
+	_ = ioutil.WriteFile(filepath.Join(data, "file_3.txt"), []byte("file_3"), 0644)
+	incr := filepath.Join(dir, "incr.tar.gz")
+	err = comp.CompressTarGzIncremental([]string{data}, incr, snapshot)
+	if err != nil {
+		t.Fatal("Error Compress Tar Gz Incremental:", err)
+	}
+	// restore the chain and check the result
+	dest := filepath.Join(dir, "restore")
+	err = DeCompressRestore([]string{full, incr}, dest, "tar.gz")
+	if err != nil {
+		t.Fatal("Error DeCompress Restore:", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dest, "data", "file_1.txt"))
+	if err != nil || string(b) != "file_1 changed" {
+		t.Fatalf("Error restore changed file: %v %q", err, b)
+	}
+	if _, err = os.Stat(filepath.Join(dest, "data", "file_2.txt")); !os.IsNotExist(err) {
+		t.Fatal("Error restore deleted file:", err)
+	}
+	if _, err = os.Stat(filepath.Join(dest, "data", "file_3.txt")); err != nil {
+		t.Fatal("Error restore added file:", err)
+	}
+	// incremental archive alone can not be restored
+	err = DeCompressRestore([]string{incr}, filepath.Join(dir, "broken"), "tar.gz")
+	if err == nil {
+		t.Fatal("Error DeCompress Restore: incremental archive without full archive should fail")
+	}
+	// incremental archive of another backup set with the same sequence
+	other := filepath.Join(dir, "other.json")
+	_ = comp.CompressTarGzIncremental([]string{data}, filepath.Join(dir, "other_full.tar.gz"), other)
+	otherIncr := filepath.Join(dir, "other_incr.tar.gz")
+	err = comp.CompressTarGzIncremental([]string{data}, otherIncr, other)
+	if err != nil {
+		t.Fatal("Error Compress Tar Gz Incremental:", err)
+	}
+	for _, v := range [][]string{{full, otherIncr}, {full, incr, incr}} {
+		broken := filepath.Join(dir, "broken")
+		err = DeCompressRestore(v, broken, "tar.gz")
+		if err == nil {
+			t.Errorf("Error DeCompress Restore: broken chain %v should fail", v)
+		}
+		if _, err = os.Stat(broken); !os.IsNotExist(err) {
+			t.Errorf("Broken chain %v should be refused before extraction: %v", v, err)
+		}
+	}
+}
+
+func BenchmarkDeCompressRestore(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt", "../test/data/comp/file_3.txt", "../test/data/comp/file_4.txt", "../test/data/comp/file_5.txt"}
+	full := filepath.Join(dir, "full.tar")
+	err = comp.CompressTarIncremental(src, full, filepath.Join(dir, "snapshot.json"))
+	if err != nil {
+		b.Fatal("Error Compress Tar Incremental:", err)
+	}
+	for i := 0; i < b.N; i++ {
+		err := DeCompressRestore([]string{full}, filepath.Join(dir, "restore"), "tar")
+		if err != nil {
+			b.Fatal("Error DeCompress Restore:", err)
+		}
+	}
+}
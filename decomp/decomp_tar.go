@@ -7,7 +7,8 @@ import (
 	"io"
 	"log"
 	"os"
-	"strings"
+	"path/filepath"
+	"satellite/utils"
 )
 
 func DeCompressTar(src string, dest string) (err error) {
@@ -18,35 +19,7 @@ func DeCompressTar(src string, dest string) (err error) {
 		return err
 	}
 	defer file.Close()
-	tr := tar.NewReader(file)
-	// loop decompress src list files
-	for {
-		header, err := tr.Next()
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				return err
-			}
-		}
-		name := dest + header.Name
-		err = os.MkdirAll(string([]rune(name)[0:strings.LastIndex(name, "/")]), 0755)
-		if err != nil {
-			log.Println("Error make dir all:", err)
-			return err
-		}
-		f, err := os.Create(name)
-		if err != nil {
-			log.Println("Error create name:", err)
-			return err
-		}
-		_, err = io.Copy(f, tr)
-		if err != nil {
-			log.Println("Error write decompress date:", err)
-			return err
-		}
-	}
-	return err
+	return DeCompressTarStream(file, dest)
 }
 
 func DeCompressTarGz(src string, dest string) (err error) {
@@ -57,41 +30,7 @@ func DeCompressTarGz(src string, dest string) (err error) {
 		return err
 	}
 	defer file.Close()
-	gr, err := gzip.NewReader(file)
-	if err != nil {
-		log.Println("Error new gzip reader:", err)
-		return err
-	}
-	defer gr.Close()
-	tr := tar.NewReader(gr)
-	// loop decompress src list files
-	for {
-		header, err := tr.Next()
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				return err
-			}
-		}
-		name := dest + header.Name
-		err = os.MkdirAll(string([]rune(name)[0:strings.LastIndex(name, "/")]), 0755)
-		if err != nil {
-			log.Println("Error make dir all:", err)
-			return err
-		}
-		f, err := os.Create(name)
-		if err != nil {
-			log.Println("Error create name:", err)
-			return err
-		}
-		_, err = io.Copy(f, tr)
-		if err != nil {
-			log.Println("Error write decompress date:", err)
-			return err
-		}
-	}
-	return err
+	return DeCompressTarGzStream(file, dest)
 }
 
 func DeCompressTarBz2(src string, dest string) (err error) {
@@ -102,8 +41,12 @@ func DeCompressTarBz2(src string, dest string) (err error) {
 		return err
 	}
 	defer file.Close()
-	br := bzip2.NewReader(file)
-	tr := tar.NewReader(br)
+	return DeCompressTarBz2Stream(file, dest)
+}
+
+// DeCompressTarStream decompress tar read from r into dest
+func DeCompressTarStream(r io.Reader, dest string) (err error) {
+	tr := tar.NewReader(r)
 	// loop decompress src list files
 	for {
 		header, err := tr.Next()
@@ -114,8 +57,24 @@ func DeCompressTarBz2(src string, dest string) (err error) {
 				return err
 			}
 		}
-		name := dest + header.Name
-		err = os.MkdirAll(string([]rune(name)[0:strings.LastIndex(name, "/")]), 0755)
+		name, err := utils.ConfinePath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeDir {
+			err = os.MkdirAll(name, 0755)
+			if err != nil {
+				log.Println("Error make dir all:", err)
+				return err
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			// symlinks, hard links and devices are not extracted
+			log.Println("Skip non regular entry in tar:", header.Name)
+			continue
+		}
+		err = os.MkdirAll(filepath.Dir(name), 0755)
 		if err != nil {
 			log.Println("Error make dir all:", err)
 			return err
@@ -126,10 +85,28 @@ func DeCompressTarBz2(src string, dest string) (err error) {
 			return err
 		}
 		_, err = io.Copy(f, tr)
+		f.Close()
 		if err != nil {
 			log.Println("Error write decompress date:", err)
 			return err
 		}
 	}
-	return err
+	return nil
+}
+
+// DeCompressTarGzStream decompress tar.gz read from r into dest
+func DeCompressTarGzStream(r io.Reader, dest string) (err error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		log.Println("Error new gzip reader:", err)
+		return err
+	}
+	defer gr.Close()
+	return DeCompressTarStream(gr, dest)
+}
+
+// DeCompressTarBz2Stream decompress tar.bz2 read from r into dest
+func DeCompressTarBz2Stream(r io.Reader, dest string) (err error) {
+	br := bzip2.NewReader(r)
+	return DeCompressTarStream(br, dest)
 }
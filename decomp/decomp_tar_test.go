@@ -1,6 +1,13 @@
 package decomp
 
-import "testing"
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestDeCompressTar(t *testing.T) {
 	src := "../test/data/decomp/file.tar"
@@ -41,3 +48,109 @@ func BenchmarkDeCompressTarGz(b *testing.B) {
 		}
 	}
 }
+
+func TestDeCompressTarStream(t *testing.T) {
+	file, err := os.Open("../test/data/decomp/file.tar")
+	if err != nil {
+		t.Fatal("Error open tar file:", err)
+	}
+	defer file.Close()
+	dest := "../test/data/decomp/"
+	err = DeCompressTarStream(file, dest)
+	if err != nil {
+		t.Fatal("Error DeCompress Tar Stream:", err)
+	}
+}
+
+func BenchmarkDeCompressTarStream(b *testing.B) {
+	data, err := ioutil.ReadFile("../test/data/decomp/file.tar")
+	if err != nil {
+		b.Fatal("Error read tar file:", err)
+	}
+	for i := 0; i < b.N; i++ {
+		dest := "../test/data/decomp/"
+		err := DeCompressTarStream(bytes.NewReader(data), dest)
+		if err != nil {
+			b.Fatal("Error DeCompress Tar Stream:", err)
+		}
+	}
+}
+
+func TestDeCompressTarGzStream(t *testing.T) {
+	file, err := os.Open("../test/data/decomp/file.tar.gz")
+	if err != nil {
+		t.Fatal("Error open tar gz file:", err)
+	}
+	defer file.Close()
+	dest := "../test/data/decomp/"
+	err = DeCompressTarGzStream(file, dest)
+	if err != nil {
+		t.Fatal("Error DeCompress Tar Gz Stream:", err)
+	}
+}
+
+func BenchmarkDeCompressTarGzStream(b *testing.B) {
+	data, err := ioutil.ReadFile("../test/data/decomp/file.tar.gz")
+	if err != nil {
+		b.Fatal("Error read tar gz file:", err)
+	}
+	for i := 0; i < b.N; i++ {
+		dest := "../test/data/decomp/"
+		err := DeCompressTarGzStream(bytes.NewReader(data), dest)
+		if err != nil {
+			b.Fatal("Error DeCompress Tar Gz Stream:", err)
+		}
+	}
+}
+
+func TestDeCompressTarStreamConfine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "dest")
+	// symlink entry should not be extracted, so the next entry can not write through it
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: dir})
+	tw.WriteHeader(&tar.Header{Name: "link/evil.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	err = DeCompressTarStream(&buf, dest)
+	if err != nil {
+		t.Fatal("Error DeCompress Tar Stream:", err)
+	}
+	info, err := os.Lstat(filepath.Join(dest, "link"))
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatal("Error DeCompress Tar Stream: symlink extracted")
+	}
+	// entry climbing out of dest should fail
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "../evil.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	err = DeCompressTarStream(&buf, dest)
+	if err == nil {
+		t.Fatal("Error DeCompress Tar Stream: entry out of dest should fail")
+	}
+	// symlink already in dest should not be followed
+	err = os.Symlink(dir, filepath.Join(dest, "outside"))
+	if err != nil {
+		t.Fatal("Error create symlink:", err)
+	}
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "outside/evil.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	err = DeCompressTarStream(&buf, dest)
+	if err == nil {
+		t.Fatal("Error DeCompress Tar Stream: entry through symlink should fail")
+	}
+	_, err = os.Stat(filepath.Join(dir, "evil.txt"))
+	if err == nil {
+		t.Fatal("Error DeCompress Tar Stream: file written out of dest")
+	}
+}
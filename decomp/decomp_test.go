@@ -1,6 +1,11 @@
 package decomp
 
-import "testing"
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
 
 func TestDeCompress(t *testing.T) {
 	src := "../test/data/decomp/file.tar.gz"
@@ -61,3 +66,42 @@ func BenchmarkDeCompress3(b *testing.B) {
 		}
 	}
 }
+
+func TestDeCompressStream(t *testing.T) {
+	file, err := os.Open("../test/data/decomp/file.tar.gz")
+	if err != nil {
+		t.Fatal("Error open file:", err)
+	}
+	defer file.Close()
+	dest := "../test/data/decomp/"
+	err = DeCompressStream(file, dest, "tar.gz")
+	if err != nil {
+		t.Fatal("Error DeCompress Stream:", err)
+	}
+}
+
+func TestDeCompressDataStream(t *testing.T) {
+	file, err := os.Open("../test/data/decomp/file_1.gz")
+	if err != nil {
+		t.Fatal("Error open file:", err)
+	}
+	defer file.Close()
+	err = DeCompressDataStream(file, ioutil.Discard, "gzip")
+	if err != nil {
+		t.Fatal("Error DeCompress Data Stream:", err)
+	}
+}
+
+func BenchmarkDeCompressStream(b *testing.B) {
+	data, err := ioutil.ReadFile("../test/data/decomp/file.tar")
+	if err != nil {
+		b.Fatal("Error read file:", err)
+	}
+	for i := 0; i < b.N; i++ {
+		dest := "../test/data/decomp/"
+		err := DeCompressStream(bytes.NewReader(data), dest, "tar")
+		if err != nil {
+			b.Fatal("Error DeCompress Stream:", err)
+		}
+	}
+}
@@ -3,9 +3,12 @@ package decomp
 import (
 	"archive/zip"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	. "satellite/global"
+	"satellite/utils"
 )
 
 func DeCompressZip(src string, dest string) (err error) {
@@ -16,16 +19,47 @@ func DeCompressZip(src string, dest string) (err error) {
 		return err
 	}
 	defer reader.Close()
+	return deCompressZipReader(&reader.Reader, dest)
+}
+
+// DeCompressZipStream decompress zip read from r into dest
+// zip central directory is at the end of file, so the stream will be
+// saved into one temporary file before decompress
+func DeCompressZipStream(r io.Reader, dest string) (err error) {
+	file, err := ioutil.TempFile("", AppName)
+	if err != nil {
+		log.Println("Error create temp file:", err)
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	size, err := io.Copy(file, r)
+	if err != nil {
+		log.Println("Error write temp file:", err)
+		return err
+	}
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		log.Println("Error open zip reader:", err)
+		return err
+	}
+	return deCompressZipReader(reader, dest)
+}
+
+func deCompressZipReader(reader *zip.Reader, dest string) (err error) {
 	// loop decompress src list files
 	for _, file := range reader.File {
-		path := filepath.Join(dest, file.Name)
+		path, err := utils.ConfinePath(dest, file.Name)
+		if err != nil {
+			return err
+		}
 		if file.FileInfo().IsDir() {
 			err = os.MkdirAll(path, os.ModePerm)
 			if err != nil {
 				log.Println("Error make dir all:", err)
 				return err
 			}
-		} else {
+		} else if file.Mode().IsRegular() {
 			// make dir all path...
 			if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
 				log.Println("Error make dir all:", err)
@@ -51,7 +85,10 @@ func DeCompressZip(src string, dest string) (err error) {
 				log.Println("Error write decompress date:", err)
 				return err
 			}
+		} else {
+			// symlinks and devices are not extracted
+			log.Println("Skip non regular entry in zip:", file.Name)
 		}
 	}
-	return err
+	return nil
 }
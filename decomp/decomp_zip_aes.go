@@ -0,0 +1,298 @@
+package decomp
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	. "satellite/utils"
+)
+
+func DeCompressDecrypt(src string, dest string, algorithm string, password string) (err error) {
+	switch algorithm {
+	case "zip":
+		err = DeCompressZipDecrypt(src, dest, password)
+	default:
+		err = fmt.Errorf("%w: decrypt decompress only support 'zip'", ErrAlgorithm)
+	}
+	return err
+}
+
+// DeCompressZipDecrypt decompress zip with password
+// it support WinZip AES (AE-1/AE-2) and traditional ZipCrypto encrypted entries,
+// entries without encryption are decompressed as usual
+func DeCompressZipDecrypt(src string, dest string, password string) (err error) {
+	// open the zip reader...
+	reader, err := zip.OpenReader(src)
+	if err != nil {
+		log.Println("Error open zip reader:", err)
+		return err
+	}
+	defer reader.Close()
+	// loop decompress src list files
+	for _, file := range reader.File {
+		path, err := ConfinePath(dest, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			err = os.MkdirAll(path, os.ModePerm)
+			if err != nil {
+				log.Println("Error make dir all:", err)
+				return err
+			}
+			continue
+		}
+		// make dir all path...
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			log.Println("Error make dir all:", err)
+			return err
+		}
+		err = deCompressZipDecryptOne(file, path, []byte(password))
+		if err != nil {
+			log.Println("Error decompress zip entry:", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func deCompressZipDecryptOne(file *zip.File, path string, password []byte) (err error) {
+	var in io.Reader
+	switch {
+	case file.Method == WinZipAESMethod:
+		in, err = openZipAES(file, password)
+	case file.Flags&0x1 != 0:
+		in, err = openZipCrypto(file, password)
+	default:
+		var rc io.ReadCloser
+		rc, err = file.Open()
+		if err == nil {
+			defer rc.Close()
+		}
+		in = rc
+	}
+	if err != nil {
+		return err
+	}
+	// open the out file
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+	if err != nil {
+		log.Println("Error open the out file:", err)
+		return err
+	}
+	// write decompress data into file
+	_, err = io.Copy(out, in)
+	out.Close()
+	if err != nil {
+		log.Println("Error write decompress date:", err)
+		os.Remove(path)
+		return err
+	}
+	return err
+}
+
+func openZipAES(file *zip.File, password []byte) (r io.Reader, err error) {
+	// find the WinZip AES extra field
+	version, strength, method, err := parseZipAESExtra(file.Extra)
+	if err != nil {
+		return r, err
+	}
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return r, err
+	}
+	salt := WinZipAESSaltSize(strength)
+	if file.CompressedSize64 < uint64(salt+WinZipAESVerifySize+WinZipAESMacSize) {
+		err = fmt.Errorf("winzip aes entry %v is too short", file.Name)
+		return r, err
+	}
+	header := make([]byte, salt+WinZipAESVerifySize)
+	_, err = io.ReadFull(raw, header)
+	if err != nil {
+		return r, err
+	}
+	key, mac, verify, err := WinZipAESKeys(password, header[:salt], strength)
+	if err != nil {
+		return r, err
+	}
+	// check the password before decrypt, the authentication code is checked
+	// at the end of the encrypted data
+	if !bytes.Equal(verify, header[salt:]) {
+		err = fmt.Errorf("wrong password for %v", file.Name)
+		return r, err
+	}
+	stream, err := NewWinZipAESStream(key)
+	if err != nil {
+		return r, err
+	}
+	size := int64(file.CompressedSize64) - int64(len(header)+WinZipAESMacSize)
+	crypt := &zipAESReader{r: io.LimitReader(raw, size), raw: raw, stream: stream, mac: hmac.New(sha1.New, mac), name: file.Name}
+	r, err = newZipMethodReader(crypt, method, file.Name)
+	if err != nil {
+		return r, err
+	}
+	r = &zipAESDrainReader{r: r, crypt: crypt}
+	// AE-1 entries store crc32 of the data as well
+	if version == 1 {
+		r = &zipChecksumReader{r: r, hash: crc32.NewIEEE(), crc: file.CRC32, name: file.Name}
+	}
+	return r, err
+}
+
+// zipAESReader decrypt the aes ctr stream and verify the authentication code
+// stored after the encrypted data at the end of stream
+type zipAESReader struct {
+	r      io.Reader
+	raw    io.Reader
+	stream cipher.Stream
+	mac    hash.Hash
+	name   string
+}
+
+func (z *zipAESReader) Read(p []byte) (n int, err error) {
+	n, err = z.r.Read(p)
+	z.mac.Write(p[:n])
+	z.stream.XORKeyStream(p[:n], p[:n])
+	if err == io.EOF {
+		code := make([]byte, WinZipAESMacSize)
+		_, rerr := io.ReadFull(z.raw, code)
+		if rerr != nil || !hmac.Equal(z.mac.Sum(nil)[:WinZipAESMacSize], code) {
+			err = fmt.Errorf("authentication failed for %v", z.name)
+		}
+	}
+	return n, err
+}
+
+// zipAESDrainReader read the rest of encrypted data after the decompressed end,
+// so the authentication code is always verified
+type zipAESDrainReader struct {
+	r     io.Reader
+	crypt *zipAESReader
+}
+
+func (z *zipAESDrainReader) Read(p []byte) (n int, err error) {
+	n, err = z.r.Read(p)
+	if err == io.EOF {
+		_, derr := io.Copy(ioutil.Discard, z.crypt)
+		if derr != nil {
+			err = derr
+		}
+	}
+	return n, err
+}
+
+func parseZipAESExtra(extra []byte) (version int, strength int, method uint16, err error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:])
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if id == WinZipAESExtraID && size >= 7 {
+			version = int(binary.LittleEndian.Uint16(extra[4:]))
+			strength = int(extra[8])
+			method = binary.LittleEndian.Uint16(extra[9:])
+			return version, strength, method, nil
+		}
+		extra = extra[4+size:]
+	}
+	err = errors.New("winzip aes extra field not found")
+	return version, strength, method, err
+}
+
+func openZipCrypto(file *zip.File, password []byte) (r io.Reader, err error) {
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return r, err
+	}
+	zr := newZipCryptoReader(raw, password)
+	// the 12 bytes encryption header, last byte used to check password
+	header := make([]byte, 12)
+	_, err = io.ReadFull(zr, header)
+	if err != nil {
+		return r, err
+	}
+	check := byte(file.CRC32 >> 24)
+	if file.Flags&0x8 != 0 {
+		check = byte(file.ModifiedTime >> 8)
+	}
+	if header[11] != check {
+		err = fmt.Errorf("wrong password for %v", file.Name)
+		return r, err
+	}
+	r, err = newZipMethodReader(zr, file.Method, file.Name)
+	if err != nil {
+		return r, err
+	}
+	return &zipChecksumReader{r: r, hash: crc32.NewIEEE(), crc: file.CRC32, name: file.Name}, err
+}
+
+func newZipMethodReader(r io.Reader, method uint16, name string) (io.Reader, error) {
+	switch method {
+	case zip.Store:
+		return r, nil
+	case zip.Deflate:
+		return flate.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("unsupported compress method %d for %v", method, name)
+}
+
+// zipCryptoReader decrypt traditional PKWARE encryption stream
+type zipCryptoReader struct {
+	r    io.Reader
+	keys [3]uint32
+}
+
+func newZipCryptoReader(r io.Reader, password []byte) *zipCryptoReader {
+	z := &zipCryptoReader{r: r, keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for _, b := range password {
+		z.update(b)
+	}
+	return z
+}
+
+func (z *zipCryptoReader) update(b byte) {
+	z.keys[0] = crc32.IEEETable[byte(z.keys[0])^b] ^ (z.keys[0] >> 8)
+	z.keys[1] = (z.keys[1]+(z.keys[0]&0xff))*134775813 + 1
+	z.keys[2] = crc32.IEEETable[byte(z.keys[2])^byte(z.keys[1]>>24)] ^ (z.keys[2] >> 8)
+}
+
+func (z *zipCryptoReader) Read(p []byte) (n int, err error) {
+	n, err = z.r.Read(p)
+	for i := 0; i < n; i++ {
+		t := z.keys[2] | 2
+		p[i] ^= byte((t * (t ^ 1)) >> 8)
+		z.update(p[i])
+	}
+	return n, err
+}
+
+// zipChecksumReader verify crc32 of the entry at the end of stream
+type zipChecksumReader struct {
+	r    io.Reader
+	hash hash.Hash32
+	crc  uint32
+	name string
+}
+
+func (z *zipChecksumReader) Read(p []byte) (n int, err error) {
+	n, err = z.r.Read(p)
+	z.hash.Write(p[:n])
+	if err == io.EOF && z.hash.Sum32() != z.crc {
+		err = fmt.Errorf("checksum error for %v", z.name)
+	}
+	return n, err
+}
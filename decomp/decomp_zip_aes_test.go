@@ -0,0 +1,152 @@
+package decomp
+
+import (
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"satellite/comp"
+	"testing"
+)
+
+func TestDeCompressZipDecrypt(t *testing.T) {
+	src := "../test/data/decomp/file_aes.zip"
+	dest := "../test/data/decomp/"
+	err := DeCompressZipDecrypt(src, dest, "satellite")
+	if err != nil {
+		t.Fatal("Error DeCompress Zip Decrypt:", err)
+	}
+	err = DeCompressZipDecrypt(src, dest, "wrong")
+	if err == nil {
+		t.Fatal("Error DeCompress Zip Decrypt: wrong password should fail")
+	}
+}
+
+func BenchmarkDeCompressZipDecrypt(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := "../test/data/decomp/file_aes.zip"
+		dest := "../test/data/decomp/"
+		err := DeCompressZipDecrypt(src, dest, "satellite")
+		if err != nil {
+			b.Fatal("Error DeCompress Zip Decrypt:", err)
+		}
+	}
+}
+
+func TestDeCompressZipDecrypt2(t *testing.T) {
+	src := "../test/data/decomp/file_zipcrypto.zip"
+	dest := "../test/data/decomp/"
+	err := DeCompressZipDecrypt(src, dest, "satellite")
+	if err != nil {
+		t.Fatal("Error DeCompress Zip Decrypt:", err)
+	}
+	err = DeCompressZipDecrypt(src, dest, "wrong")
+	if err == nil {
+		t.Fatal("Error DeCompress Zip Decrypt: wrong password should fail")
+	}
+}
+
+func TestDeCompressZipDecrypt3(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// round trip with comp package
+	src := []string{"../test/data/comp/file_1.txt", "../test/data/comp/file_2.txt"}
+	dest := filepath.Join(dir, "file_aes.zip")
+	err = comp.CompressZipEncrypt(src, dest, "satellite")
+	if err != nil {
+		t.Fatal("Error Compress Zip Encrypt:", err)
+	}
+	err = DeCompressZipDecrypt(dest, dir, "satellite")
+	if err != nil {
+		t.Fatal("Error DeCompress Zip Decrypt:", err)
+	}
+	a, _ := ioutil.ReadFile(src[1])
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "file_2.txt"))
+	if string(a) != string(b) {
+		t.Fatalf("Error DeCompress Zip Decrypt: %q != %q", b, a)
+	}
+}
+
+// rewriteTestZipAES function
+// copy entries of WinZip AES archive with AE-1 version and crc32 of data
+func rewriteTestZipAES(t testing.TB, src string, dest string, crc uint32) {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal("Error open zip reader:", err)
+	}
+	defer r.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		t.Fatal("Error create file:", err)
+	}
+	defer out.Close()
+	w := zip.NewWriter(out)
+	for _, f := range r.File {
+		fh := f.FileHeader
+		fh.Extra = append([]byte{}, f.Extra...)
+		fh.Extra[4] = 1
+		fh.CRC32 = crc
+		raw, _ := f.OpenRaw()
+		fw, err := w.CreateRaw(&fh)
+		if err == nil {
+			_, err = io.Copy(fw, raw)
+		}
+		if err != nil {
+			t.Fatal("Error copy zip entry:", err)
+		}
+	}
+	_ = w.Close()
+}
+
+func TestDeCompressZipDecrypt4(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// large entry streamed through decryption
+	data := bytes.Repeat([]byte("satellite streaming zip aes\n"), 1<<16)
+	src := filepath.Join(dir, "big.txt")
+	_ = ioutil.WriteFile(src, data, 0644)
+	dest := filepath.Join(dir, "big.zip")
+	err = comp.CompressZipEncrypt([]string{src}, dest, "satellite")
+	if err != nil {
+		t.Fatal("Error Compress Zip Encrypt:", err)
+	}
+	out := filepath.Join(dir, "out")
+	err = DeCompressZipDecrypt(dest, out, "satellite")
+	got, _ := ioutil.ReadFile(filepath.Join(out, "big.txt"))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Error DeCompress Zip Decrypt: %v, %d bytes", err, len(got))
+	}
+	// AE-1 entries check crc32
+	ae1 := filepath.Join(dir, "ae1.zip")
+	rewriteTestZipAES(t, dest, ae1, crc32.ChecksumIEEE(data))
+	if err = DeCompressZipDecrypt(ae1, out, "satellite"); err != nil {
+		t.Error("Error DeCompress Zip Decrypt AE-1:", err)
+	}
+	rewriteTestZipAES(t, dest, ae1, 0)
+	if err = DeCompressZipDecrypt(ae1, out, "satellite"); err == nil {
+		t.Error("Error DeCompress Zip Decrypt: AE-1 entry with wrong crc32 should fail")
+	}
+	// tampered encrypted data is refused and output removed
+	r, _ := zip.OpenReader(dest)
+	offset, _ := r.File[0].DataOffset()
+	r.Close()
+	b, _ := ioutil.ReadFile(dest)
+	b[offset+16+2+100] ^= 0xff
+	_ = ioutil.WriteFile(dest, b, 0644)
+	tampered := filepath.Join(dir, "tampered")
+	if err = DeCompressZipDecrypt(dest, tampered, "satellite"); err == nil {
+		t.Error("Error DeCompress Zip Decrypt: tampered entry should fail")
+	}
+	if _, err = os.Stat(filepath.Join(tampered, "big.txt")); !os.IsNotExist(err) {
+		t.Error("Output of tampered entry should be removed:", err)
+	}
+}
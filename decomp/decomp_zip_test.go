@@ -1,6 +1,13 @@
 package decomp
 
-import "testing"
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestDeCompressZip(t *testing.T) {
 	src := "../test/data/decomp/file.zip"
@@ -21,3 +28,51 @@ func BenchmarkDeCompressZip(b *testing.B) {
 		}
 	}
 }
+
+func TestDeCompressZipStream(t *testing.T) {
+	file, err := os.Open("../test/data/decomp/file.zip")
+	if err != nil {
+		t.Fatal("Error open zip file:", err)
+	}
+	defer file.Close()
+	dest := "../test/data/decomp/"
+	err = DeCompressZipStream(file, dest)
+	if err != nil {
+		t.Fatal("Error DeCompress Zip Stream:", err)
+	}
+}
+
+func BenchmarkDeCompressZipStream(b *testing.B) {
+	data, err := ioutil.ReadFile("../test/data/decomp/file.zip")
+	if err != nil {
+		b.Fatal("Error read zip file:", err)
+	}
+	for i := 0; i < b.N; i++ {
+		dest := "../test/data/decomp/"
+		err := DeCompressZipStream(bytes.NewReader(data), dest)
+		if err != nil {
+			b.Fatal("Error DeCompress Zip Stream:", err)
+		}
+	}
+}
+
+func TestDeCompressZipStreamConfine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("../evil.txt")
+	w.Write([]byte("evil"))
+	zw.Close()
+	err = DeCompressZipStream(&buf, filepath.Join(dir, "dest"))
+	if err == nil {
+		t.Fatal("Error DeCompress Zip Stream: entry out of dest should fail")
+	}
+	_, err = os.Stat(filepath.Join(dir, "evil.txt"))
+	if err == nil {
+		t.Fatal("Error DeCompress Zip Stream: file written out of dest")
+	}
+}
@@ -23,3 +23,19 @@ func DeCompressZlib(src []byte) (dest []byte, err error) {
 	dest = out.Bytes()
 	return dest, err
 }
+
+// DeCompressZlibStream decompress zlib read from r and write the data into w
+func DeCompressZlibStream(r io.Reader, w io.Writer) (err error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		log.Println("Error decompress zlib:", err)
+		return err
+	}
+	defer zr.Close()
+	_, err = io.Copy(w, zr)
+	if err != nil {
+		log.Println("Error writer zlib:", err)
+		return err
+	}
+	return err
+}
@@ -1,7 +1,9 @@
 package decomp
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"testing"
 )
 
@@ -24,3 +26,25 @@ func BenchmarkDeCompressZlib(b *testing.B) {
 		}
 	}
 }
+
+func TestDeCompressZlibStream(t *testing.T) {
+	var buf bytes.Buffer
+	src := []byte{120, 156, 202, 72, 205, 201, 201, 215, 41, 207, 47, 202, 73, 81, 4, 4, 0, 0, 255, 255, 30, 221, 4, 138}
+	err := DeCompressZlibStream(bytes.NewReader(src), &buf)
+	if err != nil {
+		t.Fatal("Error DeCompress Zlib Stream:", err)
+	}
+	if buf.String() != "hello,world!" {
+		t.Fatal("Error DeCompress Zlib Stream:", buf.String())
+	}
+}
+
+func BenchmarkDeCompressZlibStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []byte{120, 156, 202, 72, 205, 201, 201, 215, 41, 207, 47, 202, 73, 81, 4, 4, 0, 0, 255, 255, 30, 221, 4, 138}
+		err := DeCompressZlibStream(bytes.NewReader(src), ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error DeCompress Zlib Stream:", err)
+		}
+	}
+}
@@ -18,6 +18,11 @@ const (
 	CmdHttps      = "https"
 	CmdFtp        = "ftp"
 	CmdRpc        = "rpc"
+	CmdRpcCall    = "call"
+	CmdTcpSend    = "send"
+	CmdTcpRecv    = "recv"
+	CmdForward    = "forward"
+	CmdDiscover   = "discover"
 	CmdQRCode     = "qrcode"
 	CmdShell      = "shell"
 	CmdParses     = "parses"
@@ -48,6 +53,23 @@ const (
 	HttpURLImagesQRCodeToMemory = HttpURLImagesQRCode + "/m"
 	HttpURLParses               = HttpURLSatellite + "/parses"
 	HttpURLParsesIni            = HttpURLParses + "/ini"
+	HttpURLOpenAPI              = HttpURLSatellite + "/openapi.json"
+	HttpURLExplorer             = HttpURLSatellite + "/explorer"
+	HttpURLMetrics              = HttpURLRoot + "metrics"
+	HttpURLJobs                 = HttpURLSatellite + "/jobs"
+	HttpURLJobEvents            = HttpURLJobs + "/{id}/events"
+	HttpURLRpc                  = HttpURLSatellite + "/rpc"
+	HttpURLPeers                = HttpURLSatellite + "/peers"
+)
+
+const (
+	HttpHeaderRequestID     = "X-Request-ID"          // Request id header, also logged
+	HttpHeaderAuthKey       = "X-Satellite-Key"       // HMAC signed request principal name
+	HttpHeaderAuthTimestamp = "X-Satellite-Timestamp" // HMAC signed request unix timestamp
+	HttpHeaderAuthSignature = "X-Satellite-Signature" // HMAC signed request signature
+	HttpHeaderAuthNonce     = "X-Satellite-Nonce"     // HMAC signed request nonce, used once in skew window
+	HttpAuthMaxSkew         = 300                     // HMAC signed request max clock skew(Second)
+	HttpAuthMaxNonce        = 64                      // HMAC signed request max nonce length(Byte)
 )
 
 const (
@@ -60,6 +82,30 @@ const (
 	ConfineBuffers   = 8192 // Confine go-routine concurrent buffers
 )
 
+const (
+	SnapshotEntryName = ".satellite.snapshot.json" // Incremental archive manifest entry name
+	SnapshotMaxSize   = 64 << 20                   // Incremental archive manifest max size(Byte)
+)
+
+const (
+	ReproducibleEpochEnv = "SOURCE_DATE_EPOCH" // Reproducible timestamp environment variable
+	ReproducibleEpoch    = 315532800           // Reproducible default timestamp(1980-01-01 00:00:00 UTC)
+)
+
+const (
+	StreamPath     = "-"             // Stream path means stdin(input) or stdout(output)
+	StreamPackName = "satellite.pak" // Stream package name written in package header
+	StreamFileName = "stdin"         // Stream file name of stdin data in package
+)
+
+const (
+	SfxMagic      = "satellite-sfx-v1" // Self-extracting executable trailer magic(16 bytes)
+	SfxIterations = 100000             // Self-extracting passphrase key derivation iteration count
+	SfxSaltSize   = 16                 // Self-extracting passphrase salt length
+	SfxChunkSize  = 64 << 10           // Self-extracting encrypted payload chunk size(Byte)
+	SfxStubName   = "satellite-sfx"    // Self-extracting default stub next to satellite executable
+)
+
 const (
 	TCPBufferSize    = 4096  // TCP buffer size(Receive)
 	UDPBufferSize    = 4096  // UDP buffer size(Receive)
@@ -68,5 +114,104 @@ const (
 )
 
 const (
-	NetHttpTimeout = 600 // Net HTTP timeout(100ms)
+	TcpTransferVersion    = 1        // TCP transfer protocol version
+	TcpTransferFrameSize  = 64 << 10 // TCP transfer max file data in one frame(Byte)
+	TcpTransferTimeout    = 30000    // TCP transfer frame read timeout(Millisecond)
+	TcpTransferRetries    = 5        // TCP transfer sender reconnect times after disconnect
+	TcpTransferRetryWait  = 1000     // TCP transfer sender wait before reconnect(Millisecond)
+	TcpTransferIterations = 100000   // TCP transfer passphrase key derivation iteration count
+	TcpTransferSaltSize   = 16       // TCP transfer passphrase salt length
+)
+
+const (
+	ForwardDialTimeout  = 5000     // Forward target dial timeout(Millisecond)
+	ForwardIdleTimeout  = 300000   // Forward close connection or udp session without traffic after(Millisecond)
+	ForwardMaxConns     = 256      // Forward max concurrent tcp connections or udp sessions
+	ForwardBufferSize   = 32 << 10 // Forward tcp copy buffer size(Byte)
+	ForwardDatagramSize = 65535    // Forward max udp datagram size(Byte)
+)
+
+const (
+	TcpHubMaxNick = 32 // TCP hub max nickname length
+	TcpHubQueue   = 64 // TCP hub lines queued for a client, slow clients over it are dropped
+)
+
+const (
+	UdpReliableMTU         = 1200     // Reliable UDP max message data in one packet(Byte)
+	UdpReliableWindow      = 64       // Reliable UDP packets sent before ack
+	UdpReliableRTO         = 200      // Reliable UDP retransmission timeout(Millisecond)
+	UdpReliableRetries     = 20       // Reliable UDP retransmissions of one packet before peer given up
+	UdpReliableMaxMessage  = 16 << 20 // Reliable UDP max message size(Byte)
+	UdpReliableQueueSize   = 17 << 20 // Reliable UDP received data buffered per connection, receive window closed over it(Byte)
+	UdpReliableBacklog     = 16       // Reliable UDP connections waiting for accept
+	UdpReliableMaxConns    = 64       // Reliable UDP max sessions of listener, packets of new sessions over it are dropped
+	UdpReliableIdleTimeout = 60000    // Reliable UDP close connection without packets of peer(Millisecond)
+)
+
+const (
+	DiscoverAddr     = "239.255.45.14:15514" // Discovery multicast group and port
+	DiscoverInterval = 5000                  // Discovery announce interval(Millisecond)
+	DiscoverTTL      = 15000                 // Discovery forget peer without announcement after(Millisecond)
+	DiscoverWait     = 2000                  // Discovery command wait for answers(Millisecond)
+	DiscoverMaxSize  = 4096                  // Discovery max announcement size(Byte)
+	DiscoverMaxPeers = 1024                  // Discovery max announcing nodes tracked
+)
+
+const (
+	FtpDataTimeout   = 30000  // FTP data connection accept, dial and read/write timeout(Millisecond)
+	FtpIdleTimeout   = 300000 // FTP close control connection without command after(Millisecond)
+	FtpMaxLoginFails = 3      // FTP login failures before control connection closed
+	FtpMaxConns      = 64     // FTP max concurrent control connections
+)
+
+const (
+	HttpsCertFile       = "cert.pem" // HTTPS self-signed certificate file
+	HttpsKeyFile        = "key.pem"  // HTTPS self-signed private key file
+	HttpsMinVersion     = "1.2"      // HTTPS default min TLS version
+	HttpsReloadInterval = 5          // HTTPS certificate file change check interval(Second)
+)
+
+const (
+	HttpRateLimit    = 10      // HTTP requests per second of one client
+	HttpRateBurst    = 20      // HTTP requests burst of one client
+	HttpMaxJobs      = 4       // HTTP concurrent heavy jobs(pack, unpack, comp, decomp)
+	HttpMaxQueue     = 16      // HTTP heavy jobs waiting for a slot
+	HttpQueueTimeout = 30000   // HTTP heavy job max wait time in queue(Millisecond)
+	HttpMaxBodySize  = 1 << 20 // HTTP max request body size(Byte)
+)
+
+const (
+	HttpJobEventHistory   = 1024  // HTTP job stream events kept for late subscribers
+	HttpJobEventRetention = 60000 // HTTP job stream kept after job finished(Millisecond)
+	HttpJobEventPing      = 15    // HTTP job stream heartbeat interval(Second)
+)
+
+const (
+	RpcChunkSize         = 1 << 20 // RPC max chunk size of Download and Upload(Byte)
+	RpcClientTimeout     = 30000   // RPC client call timeout(Millisecond)
+	RpcClientDialTimeout = 5000    // RPC client dial timeout(Millisecond)
+	RpcClientRetries     = 2       // RPC client retries on connection errors
+	RpcClientRetryWait   = 200     // RPC client wait before first retry, doubled each retry(Millisecond)
+	RpcClientPoolSize    = 4       // RPC client idle connections kept in pool
+	RpcMaxConns          = 128     // RPC server max concurrent connections
+	RpcIdleTimeout       = 300000  // RPC server close connection without call in progress after(Millisecond)
+	RpcHandshakeTimeout  = 5000    // RPC server token handshake timeout(Millisecond)
+)
+
+const (
+	JsonRpcVersion        = "2.0"  // JSON-RPC protocol version
+	JsonRpcMaxBatch       = 100    // JSON-RPC max calls in one batch
+	JsonRpcParseError     = -32700 // JSON-RPC invalid json
+	JsonRpcInvalidRequest = -32600 // JSON-RPC not a valid request object
+	JsonRpcMethodNotFound = -32601 // JSON-RPC method not exist
+	JsonRpcInvalidParams  = -32602 // JSON-RPC invalid method parameters
+	JsonRpcInternalError  = -32603 // JSON-RPC internal error
+	JsonRpcServerError    = -32000 // JSON-RPC method returned error
+	JsonRpcForbidden      = -32001 // JSON-RPC principal missing scope or path not allowed
+	JsonRpcTooManyJobs    = -32002 // JSON-RPC no heavy job slot
+)
+
+const (
+	NetHttpTimeout     = 600   // Net HTTP timeout(100ms)
+	NetShutdownTimeout = 30000 // Net server graceful shutdown timeout(Millisecond)
 )
@@ -5,4 +5,4 @@
 :: Satellite HTTPS Service - Start
 cd bin
 satellite
-satellite https -ip 127.0.0.1 -port 8080
\ No newline at end of file
+satellite https -ip 127.0.0.1 -port 8080 -self-signed
\ No newline at end of file
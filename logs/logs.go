@@ -112,7 +112,7 @@ func (l *Logger) Output(callDepth int, level int, format string, v ...interface{
 	if l.closed.Get() == 1 {
 		return
 	}
-	if l.level.Get() > level {
+	if level > l.level.Get() {
 		return
 	}
 	var s string
@@ -19,11 +19,11 @@ func (w *fileLogWriter) Close() error {
 
 func NewFileWriter(name string, flag int) (fw *fileLogWriter, err error) {
 	dir := path.Dir(name)
-	err = os.Mkdir(dir, 0777)
+	err = os.MkdirAll(dir, 0777)
 	if err != nil {
 		return nil, err
 	}
-	file, err := os.OpenFile(name, flag, 0)
+	file, err := os.OpenFile(name, flag, 0644)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,9 @@
 package logs
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -13,3 +15,23 @@ func TestConsoleLog(t *testing.T) {
 	l := NewDefaultLogger(w)
 	l.Info("hello,world!")
 }
+
+func TestFileLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// directory exists already
+	w, err := NewFileWriter(filepath.Join(dir, "test.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY)
+	if err != nil {
+		t.Fatal("Error new file writer:", err)
+	}
+	l := NewLogger(w, Llevel)
+	l.Info("hello,world!")
+	l.Close()
+	data, err := ioutil.ReadFile(filepath.Join(dir, "test.log"))
+	if err != nil || string(data) != "[I] hello,world!\n" {
+		t.Errorf("Log file content is %q: %v", data, err)
+	}
+}
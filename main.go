@@ -1,11 +1,8 @@
 package main
 
 import (
-	_ "expvar"
 	"flag"
 	"fmt"
-	"net/http"
-	_ "net/http/pprof"
 	"os"
 	"runtime"
 	"satellite/cmd"
@@ -17,10 +14,6 @@ func init() {
 	// start multi-cpu
 	core := runtime.NumCPU()
 	runtime.GOMAXPROCS(core)
-	// start debug pprof
-	go func() {
-		_ = http.ListenAndServe(":10514", nil)
-	}()
 }
 
 func main() {
@@ -60,6 +53,8 @@ func main() {
 		cmd.ParseCmdShell()
 	case CmdParses:
 		cmd.ParseCmdParses()
+	case CmdDiscover:
+		cmd.ParseCmdDiscover()
 	default:
 		fmt.Println("Unrecognized command~")
 		os.Exit(1)
@@ -0,0 +1,314 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefBuckets is the default histogram buckets(Second)
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type collector interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+type registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+var defaultRegistry = &registry{}
+
+// TVec is the labelled values shared by counters and gauges
+type TVec struct {
+	fullName string
+	help     string
+	kind     string
+	labels   []string
+	mu       sync.Mutex
+	values   map[string]float64
+}
+
+type TCounterVec struct {
+	TVec
+}
+
+type TGaugeVec struct {
+	TVec
+}
+
+type THistogramVec struct {
+	fullName string
+	help     string
+	labels   []string
+	buckets  []float64
+	mu       sync.Mutex
+	values   map[string]*tHistogram
+}
+
+type tHistogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewCounterVec function
+// create and register counter with label names
+func NewCounterVec(name string, help string, labels ...string) *TCounterVec {
+	c := &TCounterVec{TVec{fullName: name, help: help, kind: typeCounter, labels: labels, values: make(map[string]float64)}}
+	defaultRegistry.register(c)
+	return c
+}
+
+// NewGaugeVec function
+// create and register gauge with label names
+func NewGaugeVec(name string, help string, labels ...string) *TGaugeVec {
+	g := &TGaugeVec{TVec{fullName: name, help: help, kind: typeGauge, labels: labels, values: make(map[string]float64)}}
+	defaultRegistry.register(g)
+	return g
+}
+
+// NewHistogramVec function
+// create and register histogram with upper bounds and label names
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *THistogramVec {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &THistogramVec{fullName: name, help: help, labels: labels, buckets: b, values: make(map[string]*tHistogram)}
+	defaultRegistry.register(h)
+	return h
+}
+
+func (r *registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range r.collectors {
+		if v.name() == c.name() {
+			panic("metrics: duplicate metric " + c.name())
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// Inc function
+// counter add 1
+func (c *TCounterVec) Inc(values ...string) {
+	c.add(1, values...)
+}
+
+// Add function
+// counter add v, negative v is ignored
+func (c *TCounterVec) Add(v float64, values ...string) {
+	if v < 0 {
+		return
+	}
+	c.add(v, values...)
+}
+
+// Get function
+// current value of labels
+func (v *TVec) Get(values ...string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[v.key(values)]
+}
+
+// Inc function
+// gauge add 1
+func (g *TGaugeVec) Inc(values ...string) {
+	g.add(1, values...)
+}
+
+// Dec function
+// gauge sub 1
+func (g *TGaugeVec) Dec(values ...string) {
+	g.add(-1, values...)
+}
+
+// Set function
+// gauge set to v
+func (g *TGaugeVec) Set(v float64, values ...string) {
+	g.mu.Lock()
+	g.values[g.key(values)] = v
+	g.mu.Unlock()
+}
+
+func (v *TVec) add(n float64, values ...string) {
+	v.mu.Lock()
+	v.values[v.key(values)] += n
+	v.mu.Unlock()
+}
+
+func (v *TVec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s need %d label values, got %d", v.fullName, len(v.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (v *TVec) name() string {
+	return v.fullName
+}
+
+func (v *TVec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	writeHeader(w, v.fullName, v.help, v.kind)
+	for _, k := range sortedKeys(v.values) {
+		writeSample(w, v.fullName, v.labels, splitKey(k, len(v.labels)), "", "", v.values[k])
+	}
+}
+
+// Observe function
+// add one observation v to histogram
+func (h *THistogramVec) Observe(v float64, values ...string) {
+	if len(values) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s need %d label values, got %d", h.fullName, len(h.labels), len(values)))
+	}
+	k := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.values[k]
+	if !ok {
+		t = &tHistogram{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = t
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			t.counts[i]++
+		}
+	}
+	t.sum += v
+	t.count++
+}
+
+// Count function
+// observation count of labels
+func (h *THistogramVec) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t, ok := h.values[strings.Join(values, "\xff")]; ok {
+		return t.count
+	}
+	return 0
+}
+
+func (h *THistogramVec) name() string {
+	return h.fullName
+}
+
+func (h *THistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.fullName, h.help, typeHistogram)
+	for _, k := range sortedKeys(h.values) {
+		t := h.values[k]
+		values := splitKey(k, len(h.labels))
+		for i, b := range h.buckets {
+			writeSample(w, h.fullName+"_bucket", h.labels, values, "le", formatFloat(b), float64(t.counts[i]))
+		}
+		writeSample(w, h.fullName+"_bucket", h.labels, values, "le", "+Inf", float64(t.count))
+		writeSample(w, h.fullName+"_sum", h.labels, values, "", "", t.sum)
+		writeSample(w, h.fullName+"_count", h.labels, values, "", "", float64(t.count))
+	}
+}
+
+// WriteText function
+// write all registered metrics in Prometheus text format
+func WriteText(w io.Writer) error {
+	defaultRegistry.mu.Lock()
+	collectors := append([]collector(nil), defaultRegistry.collectors...)
+	defaultRegistry.mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler function
+// http handler of Prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WriteText(w)
+	})
+}
+
+func writeHeader(w *bufio.Writer, name string, help string, kind string) {
+	w.WriteString("# HELP " + name + " " + strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+func writeSample(w *bufio.Writer, name string, labels []string, values []string, extraLabel string, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraLabel + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer("\\", `\\`, "\"", `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func splitKey(k string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	return strings.Split(k, "\xff")
+}
+
+func sortedKeys(m interface{}) (keys []string) {
+	switch t := m.(type) {
+	case map[string]float64:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[string]*tHistogram:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
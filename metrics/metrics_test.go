@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testCounter = NewCounterVec("satellite_test_total", "Test counter.", "name")
+var testGauge = NewGaugeVec("satellite_test_active", "Test gauge.")
+var testHistogram = NewHistogramVec("satellite_test_seconds", "Test histogram.", []float64{0.1, 1}, "name")
+
+func TestWriteText(t *testing.T) {
+	testCounter.Inc("a\"b")
+	testCounter.Add(2, "a\"b")
+	testGauge.Inc()
+	testGauge.Dec()
+	testGauge.Inc()
+	testHistogram.Observe(0.05, "x")
+	testHistogram.Observe(0.5, "x")
+	testHistogram.Observe(5, "x")
+
+	var buf bytes.Buffer
+	err := WriteText(&buf)
+	if err != nil {
+		t.Fatal("Error write text:", err)
+	}
+	for _, v := range []string{
+		"# TYPE satellite_test_total counter\n",
+		`satellite_test_total{name="a\"b"} 3` + "\n",
+		"satellite_test_active 1\n",
+		"# TYPE satellite_test_seconds histogram\n",
+		`satellite_test_seconds_bucket{name="x",le="0.1"} 1` + "\n",
+		`satellite_test_seconds_bucket{name="x",le="1"} 2` + "\n",
+		`satellite_test_seconds_bucket{name="x",le="+Inf"} 3` + "\n",
+		`satellite_test_seconds_sum{name="x"} 5.55` + "\n",
+		`satellite_test_seconds_count{name="x"} 3` + "\n",
+	} {
+		if !strings.Contains(buf.String(), v) {
+			t.Errorf("Metrics text not contain %q:\n%s", v, buf.String())
+		}
+	}
+}
+
+func BenchmarkWriteText(b *testing.B) {
+	var buf bytes.Buffer
+	for i := 0; i < b.N; i++ {
+		testCounter.Inc("bench")
+		testHistogram.Observe(0.5, "bench")
+		buf.Reset()
+		err := WriteText(&buf)
+		if err != nil {
+			b.Error("Error write text:", err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package nets
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	. "satellite/global"
+	"satellite/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TNetsSubsystem is the state of one subsystem reported by /readyz
+type TNetsSubsystem struct {
+	Ready  bool   `json:"ready"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type TNetsHealth struct {
+	Status     string                    `json:"status"`
+	Subsystems map[string]TNetsSubsystem `json:"subsystems,omitempty"`
+}
+
+var subsystems = struct {
+	sync.Mutex
+	m map[string]TNetsSubsystem
+}{m: make(map[string]TNetsSubsystem)}
+
+// SetSubsystemState function
+// servers report whether they are ready to serve
+func SetSubsystemState(name string, ready bool, detail string) {
+	subsystems.Lock()
+	subsystems.m[name] = TNetsSubsystem{Ready: ready, Detail: detail}
+	subsystems.Unlock()
+}
+
+// SubsystemStates function
+// copy of all subsystem states
+func SubsystemStates() map[string]TNetsSubsystem {
+	subsystems.Lock()
+	defer subsystems.Unlock()
+	m := make(map[string]TNetsSubsystem, len(subsystems.m))
+	for k, v := range subsystems.m {
+		m[k] = v
+	}
+	return m
+}
+
+// createDiagRouter function
+// pprof, expvar and metrics need token when token not empty,
+// health endpoints are always open for probes
+func createDiagRouter(token string) *http.ServeMux {
+	protect := func(h http.Handler) http.Handler {
+		if token == "" {
+			return h
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="satellite-diag"`)
+				writeNetsError(w, r, http.StatusUnauthorized, "Unauthorized!", "")
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", protect(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", protect(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", protect(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", protect(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", protect(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/vars", protect(expvar.Handler()))
+	mux.Handle("/metrics", protect(metrics.Handler()))
+	mux.HandleFunc("/healthz", handleDiagHealthz)
+	mux.HandleFunc("/readyz", handleDiagReadyz)
+	return mux
+}
+
+func handleDiagHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(TNetsHealth{Status: "ok"})
+}
+
+// handleDiagReadyz function
+// 200 when every reported subsystem is ready, else 503
+func handleDiagReadyz(w http.ResponseWriter, r *http.Request) {
+	h := TNetsHealth{Status: "ready", Subsystems: SubsystemStates()}
+	names := make([]string, 0, len(h.Subsystems))
+	for k := range h.Subsystems {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	code := http.StatusOK
+	if len(names) == 0 {
+		h.Status = "starting"
+		code = http.StatusServiceUnavailable
+	}
+	for _, v := range names {
+		if !h.Subsystems[v].Ready {
+			h.Status = "not ready"
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(h)
+}
+
+// StartDiagServer function
+// serve diagnostics on ip:port, usually run in a goroutine,
+// failure only logged since diagnostics should not stop the service
+func StartDiagServer(ip string, port string, token string) {
+	l, err := net.Listen("tcp", ip+":"+port)
+	if err != nil {
+		fmt.Println("Error start diagnostics server:", err)
+		log.Println("Error start diagnostics server:", err)
+		return
+	}
+	server := http.Server{
+		Handler:     createDiagRouter(token),
+		ReadTimeout: HTTPReadTimeout * time.Millisecond,
+	}
+	fmt.Println("Start Diagnostics Listen And Server on ", l.Addr())
+	err = server.Serve(l)
+	if err != nil {
+		log.Println("Error diagnostics server:", err)
+	}
+}
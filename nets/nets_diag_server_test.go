@@ -0,0 +1,73 @@
+package nets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateDiagRouter(t *testing.T) {
+	h := createDiagRouter("secret")
+	writer := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/healthz", nil)
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusOK {
+		t.Errorf("Healthz response code is %v", writer.Code)
+	}
+	for _, v := range []string{"/debug/vars", "/debug/pprof/", "/metrics"} {
+		writer = httptest.NewRecorder()
+		request, _ = http.NewRequest("GET", v, nil)
+		h.ServeHTTP(writer, request)
+		if writer.Code != http.StatusUnauthorized {
+			t.Errorf("%v without token response code is %v", v, writer.Code)
+		}
+		writer = httptest.NewRecorder()
+		request.Header.Set("Authorization", "Bearer secret")
+		h.ServeHTTP(writer, request)
+		if writer.Code != http.StatusOK {
+			t.Errorf("%v with token response code is %v", v, writer.Code)
+		}
+	}
+}
+
+func BenchmarkCreateDiagRouter(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		createDiagRouter("secret")
+	}
+}
+
+func TestHandleDiagReadyz(t *testing.T) {
+	h := createDiagRouter("")
+	SetSubsystemState("test", false, "starting")
+	writer := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/readyz", nil)
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusServiceUnavailable {
+		t.Errorf("Readyz not ready response code is %v", writer.Code)
+	}
+	SetSubsystemState("test", true, "")
+	writer = httptest.NewRecorder()
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusOK {
+		t.Errorf("Readyz ready response code is %v", writer.Code)
+	}
+	var health TNetsHealth
+	err := json.Unmarshal(writer.Body.Bytes(), &health)
+	if err != nil {
+		t.Fatal("Error unmarshal readyz:", err)
+	}
+	if !health.Subsystems["test"].Ready {
+		t.Errorf("Subsystem test not ready: %v", health)
+	}
+}
+
+func BenchmarkHandleDiagReadyz(b *testing.B) {
+	h := createDiagRouter("")
+	SetSubsystemState("test", true, "")
+	for i := 0; i < b.N; i++ {
+		writer := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/readyz", nil)
+		h.ServeHTTP(writer, request)
+	}
+}
@@ -0,0 +1,356 @@
+package nets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	. "satellite/global"
+	"sort"
+	"sync"
+	"time"
+)
+
+// tNetsDiscoverMsg is announcement of node, query asks nodes to announce at once,
+// leave tells nodes to forget the announcing node
+type tNetsDiscoverMsg struct {
+	App      string            `json:"app"`
+	ID       string            `json:"id"`
+	Query    bool              `json:"query,omitempty"`
+	Leave    bool              `json:"leave,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Version  string            `json:"version,omitempty"`
+	Services map[string]string `json:"services,omitempty"`
+}
+
+// tNetsDiscoverPeer is last announcement of one node
+type tNetsDiscoverPeer struct {
+	msg  tNetsDiscoverMsg
+	addr string
+	seen time.Time
+}
+
+// TNetsDiscover announce services of node and track peers over udp multicast,
+// Addr is multicast group or broadcast address with port
+type TNetsDiscover struct {
+	Addr     string
+	Name     string
+	id       string
+	group    *net.UDPAddr
+	mutex    sync.Mutex
+	services map[string]string
+	peers    map[string]*tNetsDiscoverPeer
+	recv     *net.UDPConn
+	send     *net.UDPConn
+	stop     chan struct{}
+	closing  bool
+}
+
+var httpDiscover *TNetsDiscover
+
+var ErrDiscoverClosed = errors.New("discovery closed")
+
+// SetHttpDiscover function
+// expose peers of discovery on REST server, nil disable
+func SetHttpDiscover(d *TNetsDiscover) {
+	httpDiscover = d
+}
+
+// NewDiscover function
+// create discovery on addr, name default to host name
+func NewDiscover(addr string, name string) (*TNetsDiscover, error) {
+	group, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		log.Println("Error resolve ip address:", err)
+		return nil, err
+	}
+	if name == "" {
+		name, err = os.Hostname()
+		if err != nil {
+			log.Println("Error get host name:", err)
+			return nil, err
+		}
+	}
+	b := make([]byte, 8)
+	_, err = rand.Read(b)
+	if err != nil {
+		log.Println("Error create node id:", err)
+		return nil, err
+	}
+	return &TNetsDiscover{Addr: addr, Name: name, id: hex.EncodeToString(b), group: group, services: make(map[string]string),
+		peers: make(map[string]*tNetsDiscoverPeer), stop: make(chan struct{})}, nil
+}
+
+// Announce function
+// add service of node, announced at once when running
+func (d *TNetsDiscover) Announce(service string, port string) {
+	d.mutex.Lock()
+	d.services[service] = port
+	running := d.send != nil
+	d.mutex.Unlock()
+	if running {
+		d.announce(false)
+	}
+}
+
+// ListenAndServe function
+// announce services periodically and track peers until Shutdown
+func (d *TNetsDiscover) ListenAndServe() error {
+	err := d.listen()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Start Discovery on", d.Addr, "as", d.Name)
+	go d.announcer()
+	d.serve()
+	return nil
+}
+
+// Shutdown function
+// tell peers to forget node, close sockets
+func (d *TNetsDiscover) Shutdown(ctx context.Context) error {
+	d.mutex.Lock()
+	if d.closing {
+		d.mutex.Unlock()
+		return nil
+	}
+	d.closing = true
+	close(d.stop)
+	running := d.send != nil
+	d.mutex.Unlock()
+	if running {
+		d.announce(true)
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.recv != nil {
+		_ = d.recv.Close()
+		_ = d.send.Close()
+	}
+	return nil
+}
+
+// Query function
+// ask nodes to announce at once
+func (d *TNetsDiscover) Query() error {
+	return d.write(tNetsDiscoverMsg{App: AppName, ID: d.id, Query: true})
+}
+
+// Peers function
+// peers announced within ttl, announcements of services of one node
+// by name and address are merged, sorted by name and address
+func (d *TNetsDiscover) Peers() []TNetsPeer {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.expire(time.Now())
+	merged := make(map[string]*TNetsPeer)
+	var seen = make(map[string]time.Time)
+	for _, v := range d.peers {
+		key := v.msg.Name + "@" + v.addr
+		p, ok := merged[key]
+		if !ok {
+			p = &TNetsPeer{Name: v.msg.Name, Addr: v.addr, Services: make(map[string]string)}
+			merged[key] = p
+		}
+		if v.seen.After(seen[key]) {
+			seen[key] = v.seen
+			p.Version = v.msg.Version
+			p.LastSeen = v.seen.UTC().Format(time.RFC3339)
+		}
+		for k, s := range v.msg.Services {
+			p.Services[k] = s
+		}
+	}
+	peers := make([]TNetsPeer, 0, len(merged))
+	for _, v := range merged {
+		peers = append(peers, *v)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Name != peers[j].Name {
+			return peers[i].Name < peers[j].Name
+		}
+		return peers[i].Addr < peers[j].Addr
+	})
+	return peers
+}
+
+// listen function
+// open multicast or broadcast listener and sending socket
+func (d *TNetsDiscover) listen() error {
+	var recv *net.UDPConn
+	var err error
+	if d.group.IP.IsMulticast() {
+		recv, err = net.ListenMulticastUDP("udp4", nil, d.group)
+	} else {
+		recv, err = net.ListenUDP("udp4", &net.UDPAddr{Port: d.group.Port})
+	}
+	if err != nil {
+		fmt.Println("Error listen udp:", err)
+		log.Println("Error listen udp:", err)
+		return err
+	}
+	send, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		_ = recv.Close()
+		fmt.Println("Error listen udp:", err)
+		log.Println("Error listen udp:", err)
+		return err
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closing {
+		_ = recv.Close()
+		_ = send.Close()
+		return ErrDiscoverClosed
+	}
+	d.recv, d.send = recv, send
+	return nil
+}
+
+// serve function
+// handle messages until closed
+func (d *TNetsDiscover) serve() {
+	buf := make([]byte, DiscoverMaxSize)
+	for {
+		n, addr, err := d.recv.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-d.stop:
+			default:
+				log.Println("Error read udp:", err)
+			}
+			return
+		}
+		var m tNetsDiscoverMsg
+		if json.Unmarshal(buf[:n], &m) != nil || m.App != AppName || m.ID == "" || m.ID == d.id {
+			continue
+		}
+		if m.Query {
+			d.announce(false)
+			continue
+		}
+		d.record(m, addr.IP.String())
+	}
+}
+
+// announcer function
+// announce services every interval until stopped
+func (d *TNetsDiscover) announcer() {
+	t := time.NewTicker(DiscoverInterval * time.Millisecond)
+	defer t.Stop()
+	d.announce(false)
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-t.C:
+			d.announce(false)
+		}
+	}
+}
+
+// announce function
+// send announcement when node has services
+func (d *TNetsDiscover) announce(leave bool) {
+	d.mutex.Lock()
+	m := tNetsDiscoverMsg{App: AppName, ID: d.id, Leave: leave, Name: d.Name, Version: AppVersion, Services: make(map[string]string)}
+	for k, v := range d.services {
+		m.Services[k] = v
+	}
+	d.mutex.Unlock()
+	if len(m.Services) == 0 {
+		return
+	}
+	err := d.write(m)
+	if err != nil {
+		log.Println("Error send announcement:", err)
+	}
+}
+
+func (d *TNetsDiscover) write(m tNetsDiscoverMsg) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	d.mutex.Lock()
+	send := d.send
+	d.mutex.Unlock()
+	if send == nil {
+		return ErrDiscoverClosed
+	}
+	_, err = send.WriteToUDP(b, d.group)
+	return err
+}
+
+// record function
+// remember or forget announcing node
+func (d *TNetsDiscover) record(m tNetsDiscoverMsg, addr string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if m.Leave {
+		delete(d.peers, m.ID)
+		return
+	}
+	now := time.Now()
+	if _, ok := d.peers[m.ID]; !ok && len(d.peers) >= DiscoverMaxPeers {
+		d.expire(now)
+		if len(d.peers) >= DiscoverMaxPeers {
+			log.Printf("Drop announcement of %v: too many peers\n", addr)
+			return
+		}
+	}
+	d.peers[m.ID] = &tNetsDiscoverPeer{msg: m, addr: addr, seen: now}
+}
+
+// expire function
+// forget peers without announcement within ttl, mutex should be held
+func (d *TNetsDiscover) expire(now time.Time) {
+	for k, v := range d.peers {
+		if now.Sub(v.seen) > DiscoverTTL*time.Millisecond {
+			delete(d.peers, k)
+		}
+	}
+}
+
+// DiscoverPeers function
+// query nodes on addr and collect announcements within wait
+func DiscoverPeers(addr string, wait time.Duration) ([]TNetsPeer, error) {
+	d, err := NewDiscover(addr, "")
+	if err != nil {
+		return nil, err
+	}
+	err = d.listen()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Shutdown(context.Background())
+	go d.serve()
+	err = d.Query()
+	if err != nil {
+		log.Println("Error send query:", err)
+		return nil, err
+	}
+	time.Sleep(wait)
+	return d.Peers(), nil
+}
+
+// handleNetsPeers function
+// peers found by discovery of server
+func handleNetsPeers(w http.ResponseWriter, r *http.Request) {
+	d := httpDiscover
+	if d == nil {
+		writeNetsError(w, r, http.StatusNotFound, "Discovery not enabled!", "start server with -announce")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(TNetsPeersResp{Peers: d.Peers()})
+	if err != nil {
+		log.Println("Error write peers:", err)
+	}
+}
@@ -0,0 +1,106 @@
+package nets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	. "satellite/global"
+	"testing"
+	"time"
+)
+
+// startTestDiscover function
+// discovery of node announcing services on addr
+func startTestDiscover(t testing.TB, addr string, name string, services map[string]string) *TNetsDiscover {
+	d, err := NewDiscover(addr, name)
+	if err != nil {
+		t.Fatal("Error create discovery:", err)
+	}
+	for k, v := range services {
+		d.Announce(k, v)
+	}
+	go d.ListenAndServe()
+	return d
+}
+
+func TestDiscoverPeers(t *testing.T) {
+	addr := "239.255.45.14:15601"
+	alpha := startTestDiscover(t, addr, "alpha", map[string]string{"http": "14514"})
+	defer alpha.Shutdown(context.Background())
+	rpc := startTestDiscover(t, addr, "alpha", map[string]string{"rpc": "15514"})
+	defer rpc.Shutdown(context.Background())
+	beta := startTestDiscover(t, addr, "beta", map[string]string{"ftp": "16514"})
+	time.Sleep(100 * time.Millisecond)
+	peers, err := DiscoverPeers(addr, 300*time.Millisecond)
+	if err != nil {
+		t.Fatal("Error discover peers:", err)
+	}
+	if len(peers) != 2 || peers[0].Name != "alpha" || peers[1].Name != "beta" {
+		t.Fatalf("Discovered peers are %+v", peers)
+	}
+	if peers[0].Services["http"] != "14514" || peers[0].Services["rpc"] != "15514" || peers[0].Version != AppVersion {
+		t.Errorf("Services of one node should be merged: %+v", peers[0])
+	}
+	// node leave
+	_ = beta.Shutdown(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	for _, v := range alpha.Peers() {
+		if v.Name == "beta" {
+			t.Errorf("Peer should be forgotten after leave: %+v", v)
+		}
+	}
+	// expire peers, ignore own announcement
+	alpha.record(tNetsDiscoverMsg{App: AppName, ID: "x", Name: "gamma", Services: map[string]string{"http": "1"}}, "192.0.2.1")
+	alpha.mutex.Lock()
+	alpha.peers["x"].seen = time.Now().Add(-2 * DiscoverTTL * time.Millisecond)
+	alpha.mutex.Unlock()
+	for _, v := range alpha.Peers() {
+		if v.Name == "gamma" || v.Services["http"] != "" {
+			t.Errorf("Expired peer and own announcement should be ignored: %+v", v)
+		}
+	}
+}
+
+func BenchmarkDiscoverPeers(b *testing.B) {
+	d, _ := NewDiscover(DiscoverAddr, "alpha")
+	for i := 0; i < 100; i++ {
+		d.record(tNetsDiscoverMsg{App: AppName, ID: string(rune('a' + i)), Name: "node", Services: map[string]string{"http": "14514"}}, "192.0.2.1")
+	}
+	for i := 0; i < b.N; i++ {
+		_ = d.Peers()
+	}
+}
+
+func TestHandleNetsPeers(t *testing.T) {
+	h := createHttpRouter()
+	SetHttpDiscover(nil)
+	writer := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", HttpURLPeers, nil)
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusNotFound {
+		t.Errorf("Response code without discovery is %v", writer.Code)
+	}
+	d, _ := NewDiscover(DiscoverAddr, "alpha")
+	d.record(tNetsDiscoverMsg{App: AppName, ID: "b", Name: "beta", Version: AppVersion, Services: map[string]string{"http": "14514"}}, "192.0.2.1")
+	SetHttpDiscover(d)
+	defer SetHttpDiscover(nil)
+	writer = httptest.NewRecorder()
+	h.ServeHTTP(writer, request)
+	var resp TNetsPeersResp
+	err := json.Unmarshal(writer.Body.Bytes(), &resp)
+	if err != nil || writer.Code != http.StatusOK || len(resp.Peers) != 1 || resp.Peers[0].Addr != "192.0.2.1" || resp.Peers[0].Services["http"] != "14514" {
+		t.Errorf("Response code is %v, body %s", writer.Code, writer.Body.String())
+	}
+}
+
+func BenchmarkHandleNetsPeers(b *testing.B) {
+	h := createHttpRouter()
+	d, _ := NewDiscover(DiscoverAddr, "alpha")
+	SetHttpDiscover(d)
+	defer SetHttpDiscover(nil)
+	request, _ := http.NewRequest("GET", HttpURLPeers, nil)
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), request)
+	}
+}
@@ -0,0 +1,431 @@
+package nets
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	. "satellite/global"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TNetsForwardConfig describe limits and TLS of port forwarding
+// MaxConns limit concurrent tcp connections or udp sessions, 0 means unlimited
+// IdleTimeout(Millisecond) close connection or udp session without traffic, 0 means never
+// TLS terminate TLS on listening side, tcp only
+type TNetsForwardConfig struct {
+	MaxConns    int               `json:"max_conns"`
+	IdleTimeout int               `json:"idle_timeout"`
+	TLS         *TNetsHttpsConfig `json:"tls,omitempty"`
+}
+
+// TNetsForwardStat is byte counters of one connection or udp session,
+// Sent from client to target, Received from target to client
+type TNetsForwardStat struct {
+	Client   string    `json:"client"`
+	Sent     int64     `json:"sent"`
+	Received int64     `json:"received"`
+	Start    time.Time `json:"start"`
+}
+
+// tNetsForwardStats track counters of active connections or sessions
+type tNetsForwardStats struct {
+	mutex  sync.Mutex
+	active map[*TNetsForwardStat]struct{}
+}
+
+func (s *tNetsForwardStats) add(client string) *TNetsForwardStat {
+	stat := &TNetsForwardStat{Client: client, Start: time.Now()}
+	s.mutex.Lock()
+	if s.active == nil {
+		s.active = make(map[*TNetsForwardStat]struct{})
+	}
+	s.active[stat] = struct{}{}
+	s.mutex.Unlock()
+	return stat
+}
+
+func (s *tNetsForwardStats) remove(stat *TNetsForwardStat) {
+	s.mutex.Lock()
+	delete(s.active, stat)
+	s.mutex.Unlock()
+}
+
+// list function
+// snapshot of active counters sorted by start time
+func (s *tNetsForwardStats) list() (stats []TNetsForwardStat) {
+	s.mutex.Lock()
+	for k := range s.active {
+		stats = append(stats, TNetsForwardStat{Client: k.Client, Sent: atomic.LoadInt64(&k.Sent), Received: atomic.LoadInt64(&k.Received), Start: k.Start})
+	}
+	s.mutex.Unlock()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Start.Before(stats[j].Start) })
+	return stats
+}
+
+// TNetsTcpForward forward tcp connections of Addr to Target,
+// Shutdown close listener and all connections
+type TNetsTcpForward struct {
+	Addr     string
+	Target   string
+	config   TNetsForwardConfig
+	tls      *tls.Config
+	reloader *TNetsCertReloader
+	stop     chan struct{}
+	conns    tNetsConns
+	stats    tNetsForwardStats
+}
+
+// NewTcpForward function
+// create tcp forwarding from listen address to target address
+func NewTcpForward(listen string, target string, c TNetsForwardConfig) (*TNetsTcpForward, error) {
+	f := &TNetsTcpForward{Addr: listen, Target: target, config: c, stop: make(chan struct{})}
+	if c.TLS != nil {
+		if c.TLS.SelfSigned && len(c.TLS.Hosts) == 0 {
+			host, _, err := net.SplitHostPort(listen)
+			if err != nil {
+				return nil, err
+			}
+			c.TLS.Hosts = []string{host}
+		}
+		t, r, err := NewHttpsTLSConfig(*c.TLS)
+		if err != nil {
+			log.Println("Error create TLS config:", err)
+			return nil, err
+		}
+		f.tls, f.reloader = t, r
+	}
+	return f, nil
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (f *TNetsTcpForward) ListenAndServe() error {
+	l, err := net.Listen("tcp", f.Addr)
+	if err != nil {
+		fmt.Println("Error listen tcp:", err)
+		log.Println("Error listen tcp:", err)
+		return err
+	}
+	if f.tls != nil {
+		go f.reloader.Watch(HttpsReloadInterval*time.Second, f.stop)
+		l = tls.NewListener(l, f.tls)
+	}
+	if f.config.MaxConns > 0 {
+		l = &tNetsLimitListener{Listener: l, max: f.config.MaxConns}
+	}
+	if !f.conns.setListener(l) {
+		return l.Close()
+	}
+	fmt.Println("Forward tcp", l.Addr().String(), "to", f.Target)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if f.conns.isClosing() {
+				return nil
+			}
+			log.Println("Error accept connect:", err)
+			continue
+		}
+		if !f.conns.add(conn) {
+			_ = conn.Close()
+			continue
+		}
+		go func() {
+			defer f.conns.remove(conn)
+			f.serve(conn)
+		}()
+	}
+}
+
+// Shutdown function
+// close listener and connections, wait copies within ctx
+func (f *TNetsTcpForward) Shutdown(ctx context.Context) error {
+	select {
+	case <-f.stop:
+	default:
+		close(f.stop)
+	}
+	return f.conns.shutdown(ctx, false)
+}
+
+// Stats function
+// byte counters of active connections
+func (f *TNetsTcpForward) Stats() []TNetsForwardStat {
+	return f.stats.list()
+}
+
+// serve function
+// dial target and copy both directions until both closed
+func (f *TNetsTcpForward) serve(conn net.Conn) {
+	defer conn.Close()
+	target, err := net.DialTimeout("tcp", f.Target, ForwardDialTimeout*time.Millisecond)
+	if err != nil {
+		log.Println("Error dial forward target:", err)
+		return
+	}
+	defer target.Close()
+	stat := f.stats.add(conn.RemoteAddr().String())
+	defer f.stats.remove(stat)
+	metricForwardConns.Inc("tcp")
+	defer metricForwardConns.Dec("tcp")
+	idle := time.Duration(f.config.IdleTimeout) * time.Millisecond
+	last := time.Now().UnixNano()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pipeNetsForward(target, conn, idle, &last, &stat.Sent, "sent")
+	}()
+	go func() {
+		defer wg.Done()
+		pipeNetsForward(conn, target, idle, &last, &stat.Received, "received")
+	}()
+	wg.Wait()
+	log.Printf("Forward %v -> %v closed, sent %v bytes, received %v bytes in %v\n", stat.Client, f.Target, atomic.LoadInt64(&stat.Sent), atomic.LoadInt64(&stat.Received), time.Since(stat.Start).Round(time.Millisecond))
+}
+
+// pipeNetsForward function
+// copy src to dst, read timeout is extended while other direction has traffic,
+// half close dst on EOF, close both on error or idle
+func pipeNetsForward(dst net.Conn, src net.Conn, idle time.Duration, last *int64, n *int64, direction string) {
+	buf := make([]byte, ForwardBufferSize)
+	for {
+		if idle > 0 {
+			_ = src.SetReadDeadline(time.Unix(0, atomic.LoadInt64(last)).Add(idle))
+		}
+		nr, err := src.Read(buf)
+		if nr > 0 {
+			atomic.StoreInt64(last, time.Now().UnixNano())
+			_, werr := dst.Write(buf[:nr])
+			if werr != nil {
+				err = werr
+			} else {
+				atomic.AddInt64(n, int64(nr))
+				metricForwardBytes.Add(float64(nr), "tcp", direction)
+			}
+		}
+		if err == nil {
+			continue
+		}
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() && idle > 0 && time.Since(time.Unix(0, atomic.LoadInt64(last))) < idle {
+			continue
+		}
+		if err == io.EOF && closeWrite(dst) {
+			return
+		}
+		_ = src.Close()
+		_ = dst.Close()
+		return
+	}
+}
+
+// TNetsUdpForward forward udp datagrams of Addr to Target,
+// each client address has its own session socket to target
+type TNetsUdpForward struct {
+	Addr     string
+	Target   string
+	config   TNetsForwardConfig
+	mutex    sync.Mutex
+	conn     *net.UDPConn
+	sessions map[string]*tNetsUdpSession
+	closing  bool
+	wg       sync.WaitGroup
+	stats    tNetsForwardStats
+}
+
+// tNetsUdpSession is target socket of one client
+type tNetsUdpSession struct {
+	client   *net.UDPAddr
+	upstream *net.UDPConn
+	stat     *TNetsForwardStat
+	last     int64
+}
+
+// NewUdpForward function
+// create udp forwarding from listen address to target address
+func NewUdpForward(listen string, target string, c TNetsForwardConfig) (*TNetsUdpForward, error) {
+	if c.TLS != nil {
+		return nil, errors.New("TLS termination is not supported by udp forwarding")
+	}
+	return &TNetsUdpForward{Addr: listen, Target: target, config: c, sessions: make(map[string]*tNetsUdpSession)}, nil
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (f *TNetsUdpForward) ListenAndServe() error {
+	addr, err := net.ResolveUDPAddr("udp", f.Addr)
+	if err != nil {
+		fmt.Println("Error resolve ip address:", err)
+		log.Println("Error resolve ip address:", err)
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		fmt.Println("Error listen udp:", err)
+		log.Println("Error listen udp:", err)
+		return err
+	}
+	f.mutex.Lock()
+	f.conn = conn
+	closing := f.closing
+	f.mutex.Unlock()
+	if closing {
+		return conn.Close()
+	}
+	fmt.Println("Forward udp", conn.LocalAddr().String(), "to", f.Target)
+	buf := make([]byte, ForwardDatagramSize)
+	for {
+		n, client, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			f.mutex.Lock()
+			closing := f.closing
+			f.mutex.Unlock()
+			if closing {
+				return nil
+			}
+			log.Println("Error read udp:", err)
+			continue
+		}
+		s := f.session(client)
+		if s == nil {
+			continue
+		}
+		atomic.StoreInt64(&s.last, time.Now().UnixNano())
+		_, err = s.upstream.Write(buf[:n])
+		if err != nil {
+			log.Println("Error write forward target:", err)
+			continue
+		}
+		atomic.AddInt64(&s.stat.Sent, int64(n))
+		metricForwardBytes.Add(float64(n), "udp", "sent")
+	}
+}
+
+// session function
+// session of client, created when absent and under limit
+func (f *TNetsUdpForward) session(client *net.UDPAddr) *tNetsUdpSession {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if s, ok := f.sessions[client.String()]; ok {
+		return s
+	}
+	if f.closing {
+		return nil
+	}
+	if f.config.MaxConns > 0 && len(f.sessions) >= f.config.MaxConns {
+		log.Printf("Drop udp datagram of %v: %v\n", client, ErrConnLimit)
+		return nil
+	}
+	addr, err := net.ResolveUDPAddr("udp", f.Target)
+	if err != nil {
+		log.Println("Error resolve forward target:", err)
+		return nil
+	}
+	upstream, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		log.Println("Error dial forward target:", err)
+		return nil
+	}
+	s := &tNetsUdpSession{client: client, upstream: upstream, stat: f.stats.add(client.String()), last: time.Now().UnixNano()}
+	f.sessions[client.String()] = s
+	f.wg.Add(1)
+	metricForwardConns.Inc("udp")
+	go f.reply(s)
+	return s
+}
+
+// reply function
+// copy datagrams of target back to client until idle or closed
+func (f *TNetsUdpForward) reply(s *tNetsUdpSession) {
+	defer f.wg.Done()
+	defer metricForwardConns.Dec("udp")
+	idle := time.Duration(f.config.IdleTimeout) * time.Millisecond
+	buf := make([]byte, ForwardDatagramSize)
+	for {
+		if idle > 0 {
+			_ = s.upstream.SetReadDeadline(time.Unix(0, atomic.LoadInt64(&s.last)).Add(idle))
+		}
+		n, err := s.upstream.Read(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() && time.Since(time.Unix(0, atomic.LoadInt64(&s.last))) < idle {
+				continue
+			}
+			break
+		}
+		atomic.StoreInt64(&s.last, time.Now().UnixNano())
+		f.mutex.Lock()
+		conn := f.conn
+		f.mutex.Unlock()
+		_, err = conn.WriteToUDP(buf[:n], s.client)
+		if err != nil {
+			log.Println("Error write udp client:", err)
+			continue
+		}
+		atomic.AddInt64(&s.stat.Received, int64(n))
+		metricForwardBytes.Add(float64(n), "udp", "received")
+	}
+	f.mutex.Lock()
+	delete(f.sessions, s.client.String())
+	f.mutex.Unlock()
+	_ = s.upstream.Close()
+	f.stats.remove(s.stat)
+	log.Printf("Forward udp %v -> %v closed, sent %v bytes, received %v bytes in %v\n", s.stat.Client, f.Target, atomic.LoadInt64(&s.stat.Sent), atomic.LoadInt64(&s.stat.Received), time.Since(s.stat.Start).Round(time.Millisecond))
+}
+
+// Shutdown function
+// close socket and sessions, wait session goroutines within ctx
+func (f *TNetsUdpForward) Shutdown(ctx context.Context) error {
+	f.mutex.Lock()
+	f.closing = true
+	if f.conn != nil {
+		_ = f.conn.Close()
+	}
+	for _, v := range f.sessions {
+		_ = v.upstream.Close()
+	}
+	f.mutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats function
+// byte counters of active sessions
+func (f *TNetsUdpForward) Stats() []TNetsForwardStat {
+	return f.stats.list()
+}
+
+func StartTcpForward(listen string, target string, c TNetsForwardConfig) error {
+	f, err := NewTcpForward(listen, target, c)
+	if err != nil {
+		fmt.Println("Error create tcp forward:", err)
+		return err
+	}
+	return RunServer(f, NetShutdownTimeout*time.Millisecond)
+}
+
+func StartUdpForward(listen string, target string, c TNetsForwardConfig) error {
+	f, err := NewUdpForward(listen, target, c)
+	if err != nil {
+		fmt.Println("Error create udp forward:", err)
+		return err
+	}
+	return RunServer(f, NetShutdownTimeout*time.Millisecond)
+}
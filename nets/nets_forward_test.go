@@ -0,0 +1,296 @@
+package nets
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startTestEchoTcp function
+// tcp echo server, closed with returned listener
+func startTestEchoTcp(t testing.TB, addr string) net.Listener {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal("Error listen echo:", err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l
+}
+
+// startTestEchoUdp function
+// udp echo server, closed with returned connection
+func startTestEchoUdp(t testing.TB, addr string) *net.UDPConn {
+	a, _ := net.ResolveUDPAddr("udp", addr)
+	conn, err := net.ListenUDP("udp", a)
+	if err != nil {
+		t.Fatal("Error listen echo:", err)
+	}
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, client, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteToUDP(buf[:n], client)
+		}
+	}()
+	return conn
+}
+
+// dialTestForward function
+// dial forwarding until it listens
+func dialTestForward(t testing.TB, addr string, c *tls.Config) net.Conn {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if c != nil {
+			conn, err = tls.Dial("tcp", addr, c)
+		} else {
+			conn, err = net.Dial("tcp", addr)
+		}
+		if err == nil {
+			return conn
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Error dial forward:", err)
+	return nil
+}
+
+// echoTestForward function
+// write line and read it back
+func echoTestForward(t testing.TB, conn net.Conn, line string) {
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, err := conn.Write([]byte(line + "\n"))
+	if err != nil {
+		t.Fatal("Error write forward:", err)
+	}
+	got, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || got != line+"\n" {
+		t.Errorf("Echo through forward is %q: %v", got, err)
+	}
+}
+
+func TestTcpForward(t *testing.T) {
+	echo := startTestEchoTcp(t, "127.0.0.1:11524")
+	defer echo.Close()
+	f, err := NewTcpForward("127.0.0.1:11525", "127.0.0.1:11524", TNetsForwardConfig{})
+	if err != nil {
+		t.Fatal("Error create tcp forward:", err)
+	}
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	conn := dialTestForward(t, "127.0.0.1:11525", nil)
+	echoTestForward(t, conn, "hello satellite")
+	stats := f.Stats()
+	if len(stats) != 1 || stats[0].Sent != 16 || stats[0].Received != 16 {
+		t.Errorf("Forward stats are %+v", stats)
+	}
+	// half close pass through to target, echo then closes
+	_ = conn.(*net.TCPConn).CloseWrite()
+	_, err = ioutil.ReadAll(conn)
+	if err != nil {
+		t.Errorf("Error read after half close: %v", err)
+	}
+	conn.Close()
+	for i := 0; i < 50 && len(f.Stats()) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(f.Stats()) != 0 {
+		t.Errorf("Closed connection still in stats: %+v", f.Stats())
+	}
+}
+
+func BenchmarkTcpForward(b *testing.B) {
+	echo := startTestEchoTcp(b, "127.0.0.1:11526")
+	defer echo.Close()
+	f, _ := NewTcpForward("127.0.0.1:11527", "127.0.0.1:11526", TNetsForwardConfig{})
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	conn := dialTestForward(b, "127.0.0.1:11527", nil)
+	defer conn.Close()
+	for i := 0; i < b.N; i++ {
+		echoTestForward(b, conn, "hello satellite")
+	}
+}
+
+func TestTcpForwardTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	echo := startTestEchoTcp(t, "127.0.0.1:11528")
+	defer echo.Close()
+	c := TNetsForwardConfig{TLS: &TNetsHttpsConfig{Cert: filepath.Join(dir, "cert.pem"), Key: filepath.Join(dir, "key.pem"), SelfSigned: true}}
+	f, err := NewTcpForward("127.0.0.1:11529", "127.0.0.1:11528", c)
+	if err != nil {
+		t.Fatal("Error create tcp forward:", err)
+	}
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	conn := dialTestForward(t, "127.0.0.1:11529", &tls.Config{InsecureSkipVerify: true})
+	defer conn.Close()
+	echoTestForward(t, conn, "hello tls")
+	// plain client fail on TLS listener
+	plain := dialTestForward(t, "127.0.0.1:11529", nil)
+	defer plain.Close()
+	_ = plain.SetDeadline(time.Now().Add(time.Second))
+	_, _ = plain.Write([]byte("hello plain\n"))
+	got, _ := bufio.NewReader(plain).ReadString('\n')
+	if got == "hello plain\n" {
+		t.Error("Plain text should not be forwarded by TLS listener")
+	}
+}
+
+func BenchmarkTcpForwardTLS(b *testing.B) {
+	dir, _ := ioutil.TempDir("", "satellite")
+	defer os.RemoveAll(dir)
+	echo := startTestEchoTcp(b, "127.0.0.1:11548")
+	defer echo.Close()
+	c := TNetsForwardConfig{TLS: &TNetsHttpsConfig{Cert: filepath.Join(dir, "cert.pem"), Key: filepath.Join(dir, "key.pem"), SelfSigned: true}}
+	f, err := NewTcpForward("127.0.0.1:11549", "127.0.0.1:11548", c)
+	if err != nil {
+		b.Fatal("Error create tcp forward:", err)
+	}
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	conn := dialTestForward(b, "127.0.0.1:11549", &tls.Config{InsecureSkipVerify: true})
+	defer conn.Close()
+	for i := 0; i < b.N; i++ {
+		echoTestForward(b, conn, "hello tls")
+	}
+}
+
+func TestTcpForwardLimits(t *testing.T) {
+	echo := startTestEchoTcp(t, "127.0.0.1:11530")
+	defer echo.Close()
+	f, err := NewTcpForward("127.0.0.1:11531", "127.0.0.1:11530", TNetsForwardConfig{MaxConns: 1, IdleTimeout: 200})
+	if err != nil {
+		t.Fatal("Error create tcp forward:", err)
+	}
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	first := dialTestForward(t, "127.0.0.1:11531", nil)
+	defer first.Close()
+	echoTestForward(t, first, "first")
+	// second connection refused by limit
+	second := dialTestForward(t, "127.0.0.1:11531", nil)
+	defer second.Close()
+	_ = second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = second.Read(make([]byte, 1)); err == nil {
+		t.Error("Connection over limit should be closed")
+	}
+	// first closed when idle
+	_ = first.SetReadDeadline(time.Now().Add(time.Second))
+	start := time.Now()
+	if _, err = first.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Idle connection read error is %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Idle connection should be closed after timeout")
+	}
+}
+
+func BenchmarkTcpForwardLimits(b *testing.B) {
+	echo := startTestEchoTcp(b, "127.0.0.1:11550")
+	defer echo.Close()
+	f, err := NewTcpForward("127.0.0.1:11551", "127.0.0.1:11550", TNetsForwardConfig{MaxConns: 1, IdleTimeout: 10000})
+	if err != nil {
+		b.Fatal("Error create tcp forward:", err)
+	}
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	first := dialTestForward(b, "127.0.0.1:11551", nil)
+	defer first.Close()
+	for i := 0; i < b.N; i++ {
+		// echo refresh the idle timeout, connection over limit is closed
+		echoTestForward(b, first, "first")
+		second := dialTestForward(b, "127.0.0.1:11551", nil)
+		_ = second.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err = second.Read(make([]byte, 1)); err == nil {
+			b.Error("Connection over limit should be closed")
+		}
+		second.Close()
+	}
+}
+
+func TestUdpForward(t *testing.T) {
+	echo := startTestEchoUdp(t, "127.0.0.1:11532")
+	defer echo.Close()
+	f, err := NewUdpForward("127.0.0.1:11533", "127.0.0.1:11532", TNetsForwardConfig{MaxConns: 1, IdleTimeout: 200})
+	if err != nil {
+		t.Fatal("Error create udp forward:", err)
+	}
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	conn, err := net.Dial("udp", "127.0.0.1:11533")
+	if err != nil {
+		t.Fatal("Error dial udp forward:", err)
+	}
+	defer conn.Close()
+	buf := make([]byte, 64)
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, _ = conn.Write([]byte("hello udp"))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "hello udp" {
+		t.Errorf("Echo through udp forward is %q: %v", buf[:n], err)
+	}
+	stats := f.Stats()
+	if len(stats) != 1 || stats[0].Sent != 9 || stats[0].Received != 9 {
+		t.Errorf("Forward stats are %+v", stats)
+	}
+	// other client dropped by limit
+	other, _ := net.Dial("udp", "127.0.0.1:11533")
+	defer other.Close()
+	_ = other.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _ = other.Write([]byte("hello udp"))
+	if _, err = other.Read(buf); err == nil {
+		t.Error("Session over limit should be dropped")
+	}
+	// session removed when idle
+	time.Sleep(300 * time.Millisecond)
+	if len(f.Stats()) != 0 {
+		t.Errorf("Idle session still in stats: %+v", f.Stats())
+	}
+	if _, err = NewUdpForward("127.0.0.1:11533", "127.0.0.1:11532", TNetsForwardConfig{TLS: &TNetsHttpsConfig{}}); err == nil {
+		t.Error("Udp forward should reject TLS")
+	}
+}
+
+func BenchmarkUdpForward(b *testing.B) {
+	echo := startTestEchoUdp(b, "127.0.0.1:11534")
+	defer echo.Close()
+	f, _ := NewUdpForward("127.0.0.1:11535", "127.0.0.1:11534", TNetsForwardConfig{})
+	go f.ListenAndServe()
+	defer f.Shutdown(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	conn, _ := net.Dial("udp", "127.0.0.1:11535")
+	defer conn.Close()
+	buf := make([]byte, 64)
+	for i := 0; i < b.N; i++ {
+		_ = conn.SetDeadline(time.Now().Add(time.Second))
+		_, _ = conn.Write([]byte("hello udp"))
+		_, _ = conn.Read(buf)
+	}
+}
@@ -1,16 +1,1042 @@
 package nets
 
 import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"net/http"
+	mrand "math/rand"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	. "satellite/global"
+	. "satellite/utils"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func StartFtpServer(ip string, port string) {
-	http.Handle("/", http.FileServer(http.Dir(".")))
-	fmt.Println("Start FTP Listen And Server on ", ip+":"+port)
-	err := http.ListenAndServe(ip+":"+port, nil)
+// TNetsFtpConfig describe root, users and TLS of ftp server
+// Root is directory clients are confined to, default current directory
+// Users map user name to password, users can read and write
+// Anonymous allow user "anonymous" or "ftp" with any password, read only
+// TLS enable explicit FTPS by AUTH TLS, RequireTLS refuse login and data without it
+// PasvMinPort and PasvMaxPort limit passive ports, 0 means any port
+// PublicIP is address in PASV reply when server is behind NAT
+type TNetsFtpConfig struct {
+	Root        string            `json:"root"`
+	Users       map[string]string `json:"users"`
+	Anonymous   bool              `json:"anonymous"`
+	TLS         *TNetsHttpsConfig `json:"tls,omitempty"`
+	RequireTLS  bool              `json:"require_tls"`
+	PasvMinPort int               `json:"pasv_min_port"`
+	PasvMaxPort int               `json:"pasv_max_port"`
+	PublicIP    string            `json:"public_ip"`
+	MaxConns    int               `json:"max_conns"`
+}
+
+var (
+	ErrFtpRoot     = errors.New("ftp root should be a directory")
+	ErrFtpNoUsers  = errors.New("no ftp users, add users or allow anonymous")
+	ErrFtpEscape   = errors.New("path escape ftp root")
+	ErrFtpPasv     = errors.New("no free passive port")
+	ErrFtpDataAddr = errors.New("data connection not from client address")
+)
+
+// TNetsFtpServer is RFC 959 ftp server confined to root directory,
+// Shutdown close listener, control and data connections
+type TNetsFtpServer struct {
+	Addr     string
+	config   TNetsFtpConfig
+	root     string
+	tls      *tls.Config
+	reloader *TNetsCertReloader
+	stop     chan struct{}
+	conns    tNetsConns
+}
+
+// NewFtpServer function
+// create ftp server of current directory for anonymous read only access
+func NewFtpServer(ip string, port string) *TNetsFtpServer {
+	return &TNetsFtpServer{Addr: ip + ":" + port, config: TNetsFtpConfig{Root: ".", Anonymous: true}, stop: make(chan struct{})}
+}
+
+// NewFtpServerWithConfig function
+// create ftp server listen on ip:port with users, root and TLS in config
+func NewFtpServerWithConfig(ip string, port string, c TNetsFtpConfig) (*TNetsFtpServer, error) {
+	if len(c.Users) == 0 && !c.Anonymous {
+		return nil, ErrFtpNoUsers
+	}
+	if c.RequireTLS && c.TLS == nil {
+		return nil, errors.New("TLS required without certificate")
+	}
+	s := &TNetsFtpServer{Addr: ip + ":" + port, config: c, stop: make(chan struct{})}
+	if c.TLS != nil {
+		if c.TLS.SelfSigned && len(c.TLS.Hosts) == 0 {
+			c.TLS.Hosts = []string{ip}
+		}
+		t, r, err := NewHttpsTLSConfig(*c.TLS)
+		if err != nil {
+			log.Println("Error create TLS config:", err)
+			return nil, err
+		}
+		s.tls, s.reloader = t, r
+	}
+	return s, nil
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (s *TNetsFtpServer) ListenAndServe() error {
+	root := s.config.Root
+	if root == "" {
+		root = "."
+	}
+	root, err := RealPath(root)
+	if err == nil {
+		var info os.FileInfo
+		info, err = os.Stat(root)
+		if err == nil && !info.IsDir() {
+			err = ErrFtpRoot
+		}
+	}
+	if err != nil {
+		fmt.Println("Error open ftp root:", err)
+		log.Println("Error open ftp root:", err)
+		return err
+	}
+	s.root = root
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		fmt.Println("Error listen tcp:", err)
+		log.Println("Error listen tcp:", err)
+		return err
+	}
+	if s.reloader != nil {
+		go s.reloader.Watch(HttpsReloadInterval*time.Second, s.stop)
+	}
+	if s.config.MaxConns > 0 {
+		l = &tNetsLimitListener{Listener: l, max: s.config.MaxConns}
+	}
+	if !s.conns.setListener(l) {
+		return l.Close()
+	}
+	fmt.Println("Start FTP Server on", l.Addr().String(), "root", root)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.conns.isClosing() {
+				return nil
+			}
+			log.Println("Error accept connect:", err)
+			continue
+		}
+		if !s.conns.add(conn) {
+			_ = conn.Close()
+			continue
+		}
+		go func() {
+			defer s.conns.remove(conn)
+			s.serve(conn)
+		}()
+	}
+}
+
+// Shutdown function
+// close listener, control and data connections
+func (s *TNetsFtpServer) Shutdown(ctx context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	return s.conns.shutdown(ctx, false)
+}
+
+// authenticate function
+// check password of user, return whether user may write
+func (s *TNetsFtpServer) authenticate(user string, pass string) (ok bool, write bool) {
+	if v, found := s.config.Users[user]; found {
+		return subtle.ConstantTimeCompare([]byte(v), []byte(pass)) == 1, true
+	}
+	if s.config.Anonymous && (user == "anonymous" || user == "ftp") {
+		return true, false
+	}
+	return false, false
+}
+
+// listenPasv function
+// listen data connection on host, in passive port range when configured
+func (s *TNetsFtpServer) listenPasv(host string) (net.Listener, error) {
+	min, max := s.config.PasvMinPort, s.config.PasvMaxPort
+	if min <= 0 || max < min {
+		return net.Listen("tcp", net.JoinHostPort(host, "0"))
+	}
+	n := max - min + 1
+	start := mrand.Intn(n)
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(min+(start+i)%n)))
+		if err == nil {
+			return l, nil
+		}
+	}
+	return nil, ErrFtpPasv
+}
+
+func (s *TNetsFtpServer) serve(conn net.Conn) {
+	c := &tNetsFtpSession{s: s, ctrl: conn, r: bufio.NewReaderSize(conn, TCPBufferSize), cwd: "/"}
+	defer c.close()
+	c.reply(220, "Satellite FTP server ready")
+	for !c.quit {
+		_ = c.ctrl.SetReadDeadline(time.Now().Add(FtpIdleTimeout * time.Millisecond))
+		line, err := c.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			c.reply(500, "Command line too long")
+			return
+		}
+		if err != nil {
+			if err != io.EOF && !s.conns.isClosing() {
+				log.Println("Error read ftp command:", err)
+			}
+			return
+		}
+		fields := strings.SplitN(strings.TrimRight(string(line), "\r\n"), " ", 2)
+		arg := ""
+		if len(fields) == 2 {
+			arg = strings.TrimSpace(fields[1])
+		}
+		c.handle(strings.ToUpper(fields[0]), arg)
+	}
+}
+
+// tNetsFtpCommand is handler of ftp command, login commands need logged in user,
+// write commands need write permission, arg commands need argument
+type tNetsFtpCommand struct {
+	login  bool
+	write  bool
+	arg    bool
+	handle func(c *tNetsFtpSession, arg string)
+}
+
+var ftpCommands map[string]tNetsFtpCommand
+
+func init() {
+	ftpCommands = map[string]tNetsFtpCommand{
+		"USER": {arg: true, handle: (*tNetsFtpSession).cmdUser},
+		"PASS": {handle: (*tNetsFtpSession).cmdPass},
+		"AUTH": {arg: true, handle: (*tNetsFtpSession).cmdAuth},
+		"PBSZ": {arg: true, handle: (*tNetsFtpSession).cmdPbsz},
+		"PROT": {arg: true, handle: (*tNetsFtpSession).cmdProt},
+		"FEAT": {handle: (*tNetsFtpSession).cmdFeat},
+		"OPTS": {arg: true, handle: (*tNetsFtpSession).cmdOpts},
+		"SYST": {handle: func(c *tNetsFtpSession, arg string) { c.reply(215, "UNIX Type: L8") }},
+		"NOOP": {handle: func(c *tNetsFtpSession, arg string) { c.reply(200, "OK") }},
+		"HELP": {handle: func(c *tNetsFtpSession, arg string) { c.reply(214, "See RFC 959, RFC 2428, RFC 3659 and RFC 4217") }},
+		"QUIT": {handle: (*tNetsFtpSession).cmdQuit},
+		"PWD":  {login: true, handle: (*tNetsFtpSession).cmdPwd},
+		"XPWD": {login: true, handle: (*tNetsFtpSession).cmdPwd},
+		"CWD":  {login: true, arg: true, handle: (*tNetsFtpSession).cmdCwd},
+		"XCWD": {login: true, arg: true, handle: (*tNetsFtpSession).cmdCwd},
+		"CDUP": {login: true, handle: func(c *tNetsFtpSession, arg string) { c.cmdCwd("..") }},
+		"XCUP": {login: true, handle: func(c *tNetsFtpSession, arg string) { c.cmdCwd("..") }},
+		"TYPE": {login: true, arg: true, handle: (*tNetsFtpSession).cmdType},
+		"MODE": {login: true, arg: true, handle: (*tNetsFtpSession).cmdMode},
+		"STRU": {login: true, arg: true, handle: (*tNetsFtpSession).cmdStru},
+		"ALLO": {login: true, handle: func(c *tNetsFtpSession, arg string) { c.reply(202, "No storage allocation necessary") }},
+		"PASV": {login: true, handle: (*tNetsFtpSession).cmdPasv},
+		"EPSV": {login: true, handle: (*tNetsFtpSession).cmdEpsv},
+		"PORT": {login: true, arg: true, handle: (*tNetsFtpSession).cmdPort},
+		"EPRT": {login: true, arg: true, handle: (*tNetsFtpSession).cmdEprt},
+		"LIST": {login: true, handle: (*tNetsFtpSession).cmdList},
+		"NLST": {login: true, handle: (*tNetsFtpSession).cmdNlst},
+		"MLSD": {login: true, handle: (*tNetsFtpSession).cmdMlsd},
+		"MLST": {login: true, handle: (*tNetsFtpSession).cmdMlst},
+		"SIZE": {login: true, arg: true, handle: (*tNetsFtpSession).cmdSize},
+		"MDTM": {login: true, arg: true, handle: (*tNetsFtpSession).cmdMdtm},
+		"REST": {login: true, arg: true, handle: (*tNetsFtpSession).cmdRest},
+		"RETR": {login: true, arg: true, handle: (*tNetsFtpSession).cmdRetr},
+		"STOR": {login: true, write: true, arg: true, handle: func(c *tNetsFtpSession, arg string) { c.store(arg, false) }},
+		"APPE": {login: true, write: true, arg: true, handle: func(c *tNetsFtpSession, arg string) { c.store(arg, true) }},
+		"MKD":  {login: true, write: true, arg: true, handle: (*tNetsFtpSession).cmdMkd},
+		"XMKD": {login: true, write: true, arg: true, handle: (*tNetsFtpSession).cmdMkd},
+		"RMD":  {login: true, write: true, arg: true, handle: (*tNetsFtpSession).cmdRmd},
+		"XRMD": {login: true, write: true, arg: true, handle: (*tNetsFtpSession).cmdRmd},
+		"DELE": {login: true, write: true, arg: true, handle: (*tNetsFtpSession).cmdDele},
+		"RNFR": {login: true, write: true, arg: true, handle: (*tNetsFtpSession).cmdRnfr},
+		"RNTO": {login: true, write: true, arg: true, handle: (*tNetsFtpSession).cmdRnto},
+		"ABOR": {login: true, handle: func(c *tNetsFtpSession, arg string) { c.reply(226, "No transfer to abort") }},
+	}
+}
+
+// tNetsFtpSession is state of one control connection,
+// cwd is virtual path in root, pasv or active is next data connection
+type tNetsFtpSession struct {
+	s      *TNetsFtpServer
+	ctrl   net.Conn
+	r      *bufio.Reader
+	user   string
+	login  bool
+	write  bool
+	fails  int
+	secure bool
+	prot   bool
+	quit   bool
+	cwd    string
+	pasv   net.Listener
+	active string
+	rest   int64
+	rnfr   string
+}
+
+// handle function
+// check login, permission and argument then run command
+func (c *tNetsFtpSession) handle(name string, arg string) {
+	cmd, ok := ftpCommands[name]
+	switch {
+	case !ok:
+		c.reply(502, "Command not implemented")
+	case cmd.login && !c.login:
+		c.reply(530, "Please login with USER and PASS")
+	case cmd.write && !c.write:
+		c.reply(550, "Permission denied")
+	case cmd.arg && arg == "":
+		c.reply(501, "Syntax error in parameters or arguments")
+	default:
+		cmd.handle(c, arg)
+	}
+	if name != "REST" && name != "PASV" && name != "EPSV" && name != "PORT" && name != "EPRT" {
+		c.rest = 0
+	}
+	if name != "RNFR" {
+		c.rnfr = ""
+	}
+}
+
+func (c *tNetsFtpSession) reply(code int, msg string) {
+	_, err := fmt.Fprintf(c.ctrl, "%d %s\r\n", code, msg)
+	if err != nil {
+		c.quit = true
+	}
+}
+
+// replyLines function
+// multi-line reply, lines are indented by one space
+func (c *tNetsFtpSession) replyLines(code int, first string, lines []string, last string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d-%s\r\n", code, first)
+	for _, v := range lines {
+		b.WriteString(" " + v + "\r\n")
+	}
+	fmt.Fprintf(&b, "%d %s\r\n", code, last)
+	_, err := io.WriteString(c.ctrl, b.String())
+	if err != nil {
+		c.quit = true
+	}
+}
+
+func (c *tNetsFtpSession) close() {
+	c.closeData()
+	_ = c.ctrl.Close()
+}
+
+// path function
+// virtual path of argument and real path confined in root,
+// final symlink not followed when follow false
+func (c *tNetsFtpSession) path(arg string, follow bool) (string, string, error) {
+	v := arg
+	if !strings.HasPrefix(v, "/") {
+		v = c.cwd + "/" + v
+	}
+	v = path.Clean("/" + v)
+	real := filepath.Join(c.s.root, filepath.FromSlash(v))
+	var err error
+	if follow || v == "/" {
+		real, err = RealPath(real)
+	} else {
+		var dir string
+		dir, err = RealPath(filepath.Dir(real))
+		real = filepath.Join(dir, filepath.Base(real))
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !PathWithin(c.s.root, real) {
+		return "", "", ErrFtpEscape
+	}
+	return v, real, nil
+}
+
+// quote path in reply, double quotes doubled
+func quoteFtpPath(v string) string {
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
+
+func (c *tNetsFtpSession) cmdUser(arg string) {
+	if c.s.config.RequireTLS && !c.secure {
+		c.reply(530, "TLS required, use AUTH TLS first")
+		return
+	}
+	c.user, c.login, c.write = arg, false, false
+	c.reply(331, "Password required for "+arg)
+}
+
+func (c *tNetsFtpSession) cmdPass(arg string) {
+	if c.user == "" || c.login {
+		c.reply(503, "Login with USER first")
+		return
+	}
+	ok, write := c.s.authenticate(c.user, arg)
+	if !ok {
+		c.fails++
+		log.Printf("FTP login failed for %v from %v\n", c.user, c.ctrl.RemoteAddr())
+		c.reply(530, "Login incorrect")
+		c.quit = c.fails >= FtpMaxLoginFails
+		return
+	}
+	c.login, c.write = true, write
+	log.Printf("FTP login %v from %v\n", c.user, c.ctrl.RemoteAddr())
+	c.reply(230, "User logged in")
+}
+
+// cmdAuth function
+// upgrade control connection to TLS, login state reset
+func (c *tNetsFtpSession) cmdAuth(arg string) {
+	switch strings.ToUpper(arg) {
+	case "TLS", "TLS-C", "SSL":
+	default:
+		c.reply(504, "AUTH must be TLS")
+		return
+	}
+	if c.s.tls == nil {
+		c.reply(502, "TLS not configured")
+		return
+	}
+	if c.secure {
+		c.reply(503, "Already using TLS")
+		return
+	}
+	c.reply(234, "AUTH TLS successful")
+	conn := tls.Server(c.ctrl, c.s.tls)
+	_ = conn.SetDeadline(time.Now().Add(FtpDataTimeout * time.Millisecond))
+	err := conn.Handshake()
+	_ = conn.SetDeadline(time.Time{})
+	if err != nil {
+		log.Println("Error TLS handshake:", err)
+		c.quit = true
+		return
+	}
+	c.ctrl, c.r, c.secure = conn, bufio.NewReaderSize(conn, TCPBufferSize), true
+	c.user, c.login, c.write = "", false, false
+}
+
+func (c *tNetsFtpSession) cmdPbsz(arg string) {
+	if !c.secure {
+		c.reply(503, "PBSZ requires AUTH TLS")
+		return
+	}
+	c.reply(200, "PBSZ=0")
+}
+
+func (c *tNetsFtpSession) cmdProt(arg string) {
+	switch strings.ToUpper(arg) {
+	case "P":
+		if !c.secure {
+			c.reply(503, "PROT requires AUTH TLS")
+			return
+		}
+		c.prot = true
+	case "C":
+		if c.s.config.RequireTLS {
+			c.reply(534, "Protection level C not allowed")
+			return
+		}
+		c.prot = false
+	default:
+		c.reply(504, "PROT must be C or P")
+		return
+	}
+	c.reply(200, "Protection level set to "+strings.ToUpper(arg))
+}
+
+func (c *tNetsFtpSession) cmdFeat(arg string) {
+	features := []string{"EPSV", "MDTM", "MLST type*;size*;modify*;", "PASV", "REST STREAM", "SIZE", "UTF8"}
+	if c.s.tls != nil {
+		features = append([]string{"AUTH TLS", "PBSZ", "PROT"}, features...)
+	}
+	c.replyLines(211, "Features:", features, "End")
+}
+
+func (c *tNetsFtpSession) cmdOpts(arg string) {
+	if strings.ToUpper(arg) == "UTF8 ON" {
+		c.reply(200, "UTF8 mode enabled")
+		return
+	}
+	c.reply(501, "Option not supported")
+}
+
+func (c *tNetsFtpSession) cmdQuit(arg string) {
+	c.reply(221, "Goodbye")
+	c.quit = true
+}
+
+func (c *tNetsFtpSession) cmdPwd(arg string) {
+	c.reply(257, quoteFtpPath(c.cwd)+" is current directory")
+}
+
+func (c *tNetsFtpSession) cmdCwd(arg string) {
+	v, real, err := c.path(arg, true)
+	if err == nil {
+		var info os.FileInfo
+		info, err = os.Stat(real)
+		if err == nil && !info.IsDir() {
+			err = ErrFtpRoot
+		}
+	}
+	if err != nil {
+		c.reply(550, "No such directory")
+		return
+	}
+	c.cwd = v
+	c.reply(250, "Directory changed to "+v)
+}
+
+// cmdType function
+// ascii and image types both transfer bytes unchanged
+func (c *tNetsFtpSession) cmdType(arg string) {
+	switch strings.ToUpper(arg) {
+	case "A", "A N", "I", "L 8":
+		c.reply(200, "Type set to "+strings.ToUpper(arg))
+	default:
+		c.reply(504, "Type not supported")
+	}
+}
+
+func (c *tNetsFtpSession) cmdMode(arg string) {
+	if strings.ToUpper(arg) != "S" {
+		c.reply(504, "Only stream mode supported")
+		return
+	}
+	c.reply(200, "Mode set to S")
+}
+
+func (c *tNetsFtpSession) cmdStru(arg string) {
+	if strings.ToUpper(arg) != "F" {
+		c.reply(504, "Only file structure supported")
+		return
+	}
+	c.reply(200, "Structure set to F")
+}
+
+// pasvListen function
+// listen next data connection on address of control connection
+func (c *tNetsFtpSession) pasvListen() (*net.TCPAddr, bool) {
+	c.closeData()
+	host, _, _ := net.SplitHostPort(c.ctrl.LocalAddr().String())
+	l, err := c.s.listenPasv(host)
+	if err != nil {
+		log.Println("Error listen passive port:", err)
+		c.reply(425, "Can't open passive connection")
+		return nil, false
+	}
+	c.pasv = l
+	return l.Addr().(*net.TCPAddr), true
+}
+
+func (c *tNetsFtpSession) cmdPasv(arg string) {
+	host, _, _ := net.SplitHostPort(c.ctrl.LocalAddr().String())
+	if c.s.config.PublicIP != "" {
+		host = c.s.config.PublicIP
+	}
+	ip := net.ParseIP(host).To4()
+	if ip == nil {
+		c.reply(425, "PASV needs IPv4, use EPSV")
+		return
+	}
+	addr, ok := c.pasvListen()
+	if !ok {
+		return
+	}
+	c.reply(227, fmt.Sprintf("Entering Passive Mode (%d,%d,%d,%d,%d,%d)", ip[0], ip[1], ip[2], ip[3], addr.Port>>8, addr.Port&0xff))
+}
+
+func (c *tNetsFtpSession) cmdEpsv(arg string) {
+	if strings.ToUpper(arg) == "ALL" {
+		c.reply(200, "EPSV ALL ok")
+		return
+	}
+	addr, ok := c.pasvListen()
+	if !ok {
+		return
+	}
+	c.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|)", addr.Port))
+}
+
+// setActive function
+// use client address for next data connection,
+// other addresses are refused against bounce attacks
+func (c *tNetsFtpSession) setActive(ip net.IP, port int) {
+	client, _, _ := net.SplitHostPort(c.ctrl.RemoteAddr().String())
+	if ip == nil || port <= 0 || port > 65535 || !ip.Equal(net.ParseIP(client)) {
+		c.reply(501, "Data address should be client address")
+		return
+	}
+	c.closeData()
+	c.active = net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	c.reply(200, "Active mode data connection to "+c.active)
+}
+
+func (c *tNetsFtpSession) cmdPort(arg string) {
+	fields := strings.Split(arg, ",")
+	if len(fields) != 6 {
+		c.reply(501, "PORT should be h1,h2,h3,h4,p1,p2")
+		return
+	}
+	b := make([]int, 6)
+	for i, v := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 || n > 255 {
+			c.reply(501, "PORT should be h1,h2,h3,h4,p1,p2")
+			return
+		}
+		b[i] = n
+	}
+	c.setActive(net.IPv4(byte(b[0]), byte(b[1]), byte(b[2]), byte(b[3])), b[4]<<8|b[5])
+}
+
+func (c *tNetsFtpSession) cmdEprt(arg string) {
+	fields := strings.Split(arg, arg[:1])
+	if len(fields) != 5 || (fields[1] != "1" && fields[1] != "2") {
+		c.reply(522, "Network protocol not supported, use (1,2)")
+		return
+	}
+	port, err := strconv.Atoi(fields[3])
+	if err != nil {
+		c.reply(501, "EPRT should be |proto|address|port|")
+		return
+	}
+	c.setActive(net.ParseIP(fields[2]), port)
+}
+
+// closeData function
+// drop prepared data connection
+func (c *tNetsFtpSession) closeData() {
+	if c.pasv != nil {
+		_ = c.pasv.Close()
+		c.pasv = nil
+	}
+	c.active = ""
+}
+
+// openData function
+// reply 150 and open prepared data connection, TLS when PROT P,
+// reply error and return nil when failed
+func (c *tNetsFtpSession) openData(msg string) net.Conn {
+	if c.s.config.RequireTLS && !c.prot {
+		c.reply(521, "Data connections must be protected, use PROT P")
+		return nil
+	}
+	if c.pasv == nil && c.active == "" {
+		c.reply(425, "Use PASV, EPSV or PORT first")
+		return nil
+	}
+	c.reply(150, msg)
+	conn, err := c.dialData()
+	if err != nil {
+		log.Println("Error open data connection:", err)
+		c.reply(425, "Can't open data connection")
+		return nil
+	}
+	if !c.s.conns.add(conn) {
+		_ = conn.Close()
+		c.reply(421, "Server shutting down")
+		return nil
+	}
+	var data net.Conn = &tNetsFtpDataConn{Conn: conn, s: c.s}
+	if c.prot {
+		// handshake at once, empty transfers write nothing
+		t := tls.Server(data, c.s.tls)
+		err = t.Handshake()
+		if err != nil {
+			_ = data.Close()
+			log.Println("Error TLS handshake:", err)
+			c.reply(425, "Can't open data connection")
+			return nil
+		}
+		data = t
+	}
+	return data
+}
+
+func (c *tNetsFtpSession) dialData() (net.Conn, error) {
+	defer c.closeData()
+	if c.pasv == nil {
+		return net.DialTimeout("tcp", c.active, FtpDataTimeout*time.Millisecond)
+	}
+	_ = c.pasv.(*net.TCPListener).SetDeadline(time.Now().Add(FtpDataTimeout * time.Millisecond))
+	conn, err := c.pasv.Accept()
+	if err != nil {
+		return nil, err
+	}
+	client, _, _ := net.SplitHostPort(c.ctrl.RemoteAddr().String())
+	remote, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	if client != remote {
+		_ = conn.Close()
+		return nil, ErrFtpDataAddr
+	}
+	return conn, nil
+}
+
+// tNetsFtpDataConn extend deadline on every read and write,
+// removed from server connections when closed
+type tNetsFtpDataConn struct {
+	net.Conn
+	s *TNetsFtpServer
+}
+
+func (c *tNetsFtpDataConn) Read(b []byte) (int, error) {
+	_ = c.Conn.SetReadDeadline(time.Now().Add(FtpDataTimeout * time.Millisecond))
+	return c.Conn.Read(b)
+}
+
+func (c *tNetsFtpDataConn) Write(b []byte) (int, error) {
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(FtpDataTimeout * time.Millisecond))
+	return c.Conn.Write(b)
+}
+
+func (c *tNetsFtpDataConn) Close() error {
+	c.s.conns.remove(c.Conn)
+	return c.Conn.Close()
+}
+
+// finishData function
+// close data connection and reply result of transfer
+func (c *tNetsFtpSession) finishData(conn net.Conn, err error) {
+	cerr := conn.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Println("Error transfer data:", err)
+		c.reply(426, "Connection closed, transfer aborted")
+		return
+	}
+	c.reply(226, "Transfer complete")
+}
+
+// listArgs function
+// path argument of listing commands without options such as "-la"
+func listArgs(arg string) string {
+	fields := strings.Fields(arg)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
+
+// entries function
+// file infos of directory, or the file itself
+func (c *tNetsFtpSession) entries(arg string) ([]os.FileInfo, bool, error) {
+	_, real, err := c.path(listArgs(arg), true)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		return []os.FileInfo{info}, false, nil
+	}
+	dir, err := os.ReadDir(real)
+	if err != nil {
+		return nil, true, err
+	}
+	infos := make([]os.FileInfo, 0, len(dir))
+	for _, v := range dir {
+		i, err := v.Info()
+		if err == nil {
+			infos = append(infos, i)
+		}
+	}
+	return infos, true, nil
+}
+
+// list function
+// send lines of entries over data connection
+func (c *tNetsFtpSession) list(arg string, dirOnly bool, line func(os.FileInfo) string) {
+	infos, dir, err := c.entries(arg)
+	if err != nil {
+		c.reply(550, "No such file or directory")
+		return
+	}
+	if dirOnly && !dir {
+		c.reply(501, "Not a directory")
+		return
+	}
+	conn := c.openData("Opening data connection for directory list")
+	if conn == nil {
+		return
+	}
+	w := bufio.NewWriter(conn)
+	for _, v := range infos {
+		_, _ = w.WriteString(line(v) + "\r\n")
+	}
+	c.finishData(conn, w.Flush())
+}
+
+func (c *tNetsFtpSession) cmdList(arg string) {
+	now := time.Now()
+	c.list(arg, false, func(info os.FileInfo) string {
+		mode := []byte(info.Mode().Perm().String())
+		if info.IsDir() {
+			mode[0] = 'd'
+		}
+		layout := "Jan _2 15:04"
+		if now.Sub(info.ModTime()) > 180*24*time.Hour || info.ModTime().After(now) {
+			layout = "Jan _2  2006"
+		}
+		return fmt.Sprintf("%s 1 ftp ftp %12d %s %s", mode, info.Size(), info.ModTime().Format(layout), info.Name())
+	})
+}
+
+func (c *tNetsFtpSession) cmdNlst(arg string) {
+	c.list(arg, false, func(info os.FileInfo) string {
+		return info.Name()
+	})
+}
+
+func (c *tNetsFtpSession) cmdMlsd(arg string) {
+	c.list(arg, true, func(info os.FileInfo) string {
+		return mlstFacts(info) + " " + info.Name()
+	})
+}
+
+// mlstFacts function
+// RFC 3659 facts of file
+func mlstFacts(info os.FileInfo) string {
+	kind := "file"
+	if info.IsDir() {
+		kind = "dir"
+	}
+	return fmt.Sprintf("type=%s;size=%d;modify=%s;", kind, info.Size(), info.ModTime().UTC().Format("20060102150405"))
+}
+
+func (c *tNetsFtpSession) cmdMlst(arg string) {
+	v, real, err := c.path(listArgs(arg), true)
+	var info os.FileInfo
+	if err == nil {
+		info, err = os.Stat(real)
+	}
+	if err != nil {
+		c.reply(550, "No such file or directory")
+		return
+	}
+	c.replyLines(250, "Listing "+v, []string{mlstFacts(info) + " " + v}, "End")
+}
+
+// stat function
+// info of regular file, reply 550 when not
+func (c *tNetsFtpSession) stat(arg string) (string, os.FileInfo, bool) {
+	v, real, err := c.path(arg, true)
+	var info os.FileInfo
+	if err == nil {
+		info, err = os.Stat(real)
+	}
+	if err != nil || !info.Mode().IsRegular() {
+		c.reply(550, "File not available")
+		return "", nil, false
+	}
+	return v, info, true
+}
+
+func (c *tNetsFtpSession) cmdSize(arg string) {
+	if _, info, ok := c.stat(arg); ok {
+		c.reply(213, strconv.FormatInt(info.Size(), 10))
+	}
+}
+
+func (c *tNetsFtpSession) cmdMdtm(arg string) {
+	if _, info, ok := c.stat(arg); ok {
+		c.reply(213, info.ModTime().UTC().Format("20060102150405"))
+	}
+}
+
+func (c *tNetsFtpSession) cmdRest(arg string) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || n < 0 {
+		c.reply(501, "REST should be a byte offset")
+		return
+	}
+	c.rest = n
+	c.reply(350, "Restarting at "+arg)
+}
+
+func (c *tNetsFtpSession) cmdRetr(arg string) {
+	v, _, ok := c.stat(arg)
+	if !ok {
+		return
+	}
+	_, real, _ := c.path(arg, true)
+	f, err := os.Open(real)
+	if err != nil {
+		c.reply(550, "File not available")
+		return
+	}
+	defer f.Close()
+	if c.rest > 0 {
+		_, err = f.Seek(c.rest, io.SeekStart)
+		if err != nil {
+			c.reply(550, "Can't restart at "+strconv.FormatInt(c.rest, 10))
+			return
+		}
+	}
+	conn := c.openData("Opening data connection for " + v)
+	if conn == nil {
+		return
+	}
+	n, err := io.Copy(conn, f)
+	log.Printf("FTP %v RETR %v %v bytes\n", c.user, v, n)
+	c.finishData(conn, err)
+}
+
+// store function
+// STOR write file from REST offset, APPE append to file
+func (c *tNetsFtpSession) store(arg string, appendFile bool) {
+	v, real, err := c.path(arg, true)
+	if err != nil {
+		c.reply(553, "File name not allowed")
+		return
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendFile {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	} else if c.rest > 0 {
+		flag = os.O_WRONLY | os.O_CREATE
+	}
+	f, err := os.OpenFile(real, flag, 0644)
+	if err != nil {
+		c.reply(550, "Can't open file")
+		return
+	}
+	defer f.Close()
+	if c.rest > 0 && !appendFile {
+		err = f.Truncate(c.rest)
+		if err == nil {
+			_, err = f.Seek(c.rest, io.SeekStart)
+		}
+		if err != nil {
+			c.reply(550, "Can't restart at "+strconv.FormatInt(c.rest, 10))
+			return
+		}
+	}
+	conn := c.openData("Opening data connection for " + v)
+	if conn == nil {
+		return
+	}
+	n, err := io.Copy(f, conn)
+	if err == nil {
+		err = f.Close()
+	}
+	log.Printf("FTP %v STOR %v %v bytes\n", c.user, v, n)
+	c.finishData(conn, err)
+}
+
+func (c *tNetsFtpSession) cmdMkd(arg string) {
+	v, real, err := c.path(arg, true)
+	if err == nil {
+		err = os.Mkdir(real, 0755)
+	}
+	if err != nil {
+		c.reply(550, "Can't create directory")
+		return
+	}
+	c.reply(257, quoteFtpPath(v)+" created")
+}
+
+func (c *tNetsFtpSession) cmdRmd(arg string) {
+	v, real, err := c.path(arg, false)
+	var info os.FileInfo
+	if err == nil && v != "/" {
+		info, err = os.Lstat(real)
+		if err == nil && !info.IsDir() {
+			err = ErrFtpRoot
+		}
+		if err == nil {
+			err = os.Remove(real)
+		}
+	}
+	if err != nil || v == "/" {
+		c.reply(550, "Can't remove directory")
+		return
+	}
+	c.reply(250, "Directory removed")
+}
+
+func (c *tNetsFtpSession) cmdDele(arg string) {
+	v, real, err := c.path(arg, false)
+	var info os.FileInfo
+	if err == nil {
+		info, err = os.Lstat(real)
+		if err == nil && info.IsDir() {
+			err = ErrFtpRoot
+		}
+		if err == nil {
+			err = os.Remove(real)
+		}
+	}
+	if err != nil {
+		c.reply(550, "Can't delete file")
+		return
+	}
+	log.Printf("FTP %v DELE %v\n", c.user, v)
+	c.reply(250, "File deleted")
+}
+
+func (c *tNetsFtpSession) cmdRnfr(arg string) {
+	v, real, err := c.path(arg, false)
+	if err == nil && v != "/" {
+		_, err = os.Lstat(real)
+	}
+	if err != nil || v == "/" {
+		c.reply(550, "File not available")
+		return
+	}
+	c.rnfr = real
+	c.reply(350, "Ready for RNTO")
+}
+
+func (c *tNetsFtpSession) cmdRnto(arg string) {
+	if c.rnfr == "" {
+		c.reply(503, "RNFR required first")
+		return
+	}
+	v, real, err := c.path(arg, false)
+	if err == nil && v != "/" {
+		err = os.Rename(c.rnfr, real)
+	}
+	if err != nil || v == "/" {
+		c.reply(550, "Can't rename file")
+		return
+	}
+	c.reply(250, "File renamed")
+}
+
+func StartFtpServer(ip string, port string) error {
+	return RunServer(NewFtpServer(ip, port), NetShutdownTimeout*time.Millisecond)
+}
+
+// StartFtpServerWithConfig function
+// start ftp server with users, root and TLS in config
+func StartFtpServerWithConfig(ip string, port string, c TNetsFtpConfig) error {
+	s, err := NewFtpServerWithConfig(ip, port, c)
 	if err != nil {
-		log.Println("Error Listen And Server:", err)
+		fmt.Println("Error create ftp server:", err)
+		return err
 	}
+	return RunServer(s, NetShutdownTimeout*time.Millisecond)
 }
@@ -1,23 +1,388 @@
 package nets
 
 import (
-	"net/http"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jlaffaye/ftp"
 )
 
-func TestStartFtpServer(t *testing.T) {
-	ip := "127.0.0.1"
-	port := "10021"
-	// skip this test case
-	t.Skip("IGNORE: TestStartFtpServer")
-	// start ftp server
-	go StartFtpServer(ip, port)
-	// start ftp client
-	r, err := http.Get("http://" + ip + ":" + port)
+// startTestFtp function
+// ftp server on port with temp root, users alice and anonymous
+func startTestFtp(t testing.TB, port string, c TNetsFtpConfig) (*TNetsFtpServer, string) {
+	dir, err := ioutil.TempDir("", "satellite-ftp")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	c.Root = filepath.Join(dir, "root")
+	_ = os.Mkdir(c.Root, 0755)
+	if c.Users == nil {
+		c.Users = map[string]string{"alice": "secret"}
+	}
+	if c.TLS != nil {
+		c.TLS.Cert, c.TLS.Key = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	}
+	s, err := NewFtpServerWithConfig("127.0.0.1", port, c)
+	if err != nil {
+		t.Fatal("Error create ftp server:", err)
+	}
+	go s.ListenAndServe()
+	for i := 0; i < 50; i++ {
+		conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+		if err == nil {
+			_ = conn.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return s, dir
+}
+
+// dialTestFtp function
+// logged in ftp client
+func dialTestFtp(t testing.TB, port string, user string, pass string, options ...ftp.DialOption) *ftp.ServerConn {
+	options = append(options, ftp.DialWithTimeout(5*time.Second))
+	c, err := ftp.Dial("127.0.0.1:"+port, options...)
+	if err != nil {
+		t.Fatal("Error dial ftp server:", err)
+	}
+	err = c.Login(user, pass)
+	if err != nil {
+		t.Fatal("Error login ftp server:", err)
+	}
+	return c
+}
+
+func readTestFtp(t testing.TB, c *ftp.ServerConn, path string, offset uint64) string {
+	r, err := c.RetrFrom(path, offset)
+	if err != nil {
+		t.Fatalf("Error retrieve %v: %v", path, err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Error read %v: %v", path, err)
+	}
+	return string(b)
+}
+
+func TestFtpServer(t *testing.T) {
+	s, dir := startTestFtp(t, "11536", TNetsFtpConfig{})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	c := dialTestFtp(t, "11536", "alice", "secret")
+	defer c.Quit()
+	// upload, resume and append
+	if err := c.Stor("hello.txt", strings.NewReader("hello satellite")); err != nil {
+		t.Fatal("Error store file:", err)
+	}
+	if err := c.StorFrom("hello.txt", strings.NewReader("ftp"), 6); err != nil {
+		t.Error("Error store file from offset:", err)
+	}
+	if err := c.Append("hello.txt", strings.NewReader("!")); err != nil {
+		t.Error("Error append file:", err)
+	}
+	if got := readTestFtp(t, c, "hello.txt", 0); got != "hello ftp!" {
+		t.Errorf("Retrieved file is %q", got)
+	}
+	if got := readTestFtp(t, c, "/hello.txt", 6); got != "ftp!" {
+		t.Errorf("Retrieved file from offset is %q", got)
+	}
+	if n, err := c.FileSize("hello.txt"); err != nil || n != 10 {
+		t.Errorf("File size is %v: %v", n, err)
+	}
+	// directories
+	if err := c.MakeDir("docs"); err != nil {
+		t.Fatal("Error make directory:", err)
+	}
+	if err := c.ChangeDir("docs"); err != nil {
+		t.Fatal("Error change directory:", err)
+	}
+	if dir, err := c.CurrentDir(); err != nil || dir != "/docs" {
+		t.Errorf("Current directory is %v: %v", dir, err)
+	}
+	if err := c.Rename("/hello.txt", "moved.txt"); err != nil {
+		t.Error("Error rename file:", err)
+	}
+	entries, err := c.List("")
+	if err != nil || len(entries) != 1 || entries[0].Name != "moved.txt" || entries[0].Size != 10 || entries[0].Type != ftp.EntryTypeFile {
+		t.Errorf("Listed entries are %+v: %v", entries, err)
+	}
+	if e, err := c.GetEntry("moved.txt"); err != nil || e.Size != 10 {
+		t.Errorf("Entry is %+v: %v", e, err)
+	}
+	if err := c.ChangeDirToParent(); err != nil {
+		t.Error("Error change to parent directory:", err)
+	}
+	if err := c.RemoveDir("docs"); err == nil {
+		t.Error("Not empty directory should not be removed")
+	}
+	if err := c.Delete("docs/moved.txt"); err != nil {
+		t.Error("Error delete file:", err)
+	}
+	if err := c.RemoveDir("docs"); err != nil {
+		t.Error("Error remove directory:", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "root", "docs")); !os.IsNotExist(err) {
+		t.Error("Directory should be removed from root")
+	}
+}
+
+func BenchmarkFtpServer(b *testing.B) {
+	s, dir := startTestFtp(b, "11537", TNetsFtpConfig{})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	c := dialTestFtp(b, "11537", "alice", "secret")
+	defer c.Quit()
+	data := bytes.Repeat([]byte("satellite"), 1024)
+	for i := 0; i < b.N; i++ {
+		_ = c.Stor("bench.txt", bytes.NewReader(data))
+		_ = readTestFtp(b, c, "bench.txt", 0)
+	}
+}
+
+func TestFtpServerList(t *testing.T) {
+	s, dir := startTestFtp(t, "11538", TNetsFtpConfig{})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	_ = ioutil.WriteFile(filepath.Join(dir, "root", "a.txt"), []byte("abc"), 0644)
+	_ = os.Mkdir(filepath.Join(dir, "root", "sub"), 0755)
+	// LIST in unix format without MLSD
+	c := dialTestFtp(t, "11538", "alice", "secret", ftp.DialWithDisabledMLSD(true))
+	defer c.Quit()
+	entries, err := c.List("/")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Listed entries are %+v: %v", entries, err)
+	}
+	if entries[0].Name != "a.txt" || entries[0].Size != 3 || entries[0].Type != ftp.EntryTypeFile || entries[1].Name != "sub" || entries[1].Type != ftp.EntryTypeFolder {
+		t.Errorf("Listed entries are %+v %+v", entries[0], entries[1])
+	}
+	names, err := c.NameList("")
+	if err != nil || len(names) != 2 || names[0] != "a.txt" {
+		t.Errorf("Listed names are %v: %v", names, err)
+	}
+}
+
+func BenchmarkFtpServerList(b *testing.B) {
+	s, dir := startTestFtp(b, "11539", TNetsFtpConfig{})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	for i := 0; i < 100; i++ {
+		_ = ioutil.WriteFile(filepath.Join(dir, "root", fmt.Sprintf("%v.txt", i)), []byte("abc"), 0644)
+	}
+	c := dialTestFtp(b, "11539", "alice", "secret")
+	defer c.Quit()
+	for i := 0; i < b.N; i++ {
+		_, _ = c.List("")
+	}
+}
+
+func TestFtpServerRoot(t *testing.T) {
+	s, dir := startTestFtp(t, "11540", TNetsFtpConfig{Anonymous: true})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	_ = ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("outside"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(dir, "root", "public.txt"), []byte("inside"), 0644)
+	_ = os.Symlink(dir, filepath.Join(dir, "root", "link"))
+	c := dialTestFtp(t, "11540", "alice", "secret")
+	defer c.Quit()
+	// paths confined to root
+	if got := readTestFtp(t, c, "../../public.txt", 0); got != "inside" {
+		t.Errorf("Parent of root should be root, file is %q", got)
+	}
+	if _, err := c.Retr("link/secret.txt"); err == nil {
+		t.Error("File outside root should not be retrieved by symlink")
+	}
+	if err := c.ChangeDir("link"); err == nil {
+		t.Error("Directory outside root should not be entered by symlink")
+	}
+	if err := c.Stor("link/new.txt", strings.NewReader("x")); err == nil {
+		t.Error("File outside root should not be stored by symlink")
+	}
+	if err := c.Delete("link"); err != nil {
+		t.Error("Symlink itself should be deleted:", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret.txt")); err != nil {
+		t.Error("Symlink target should be kept:", err)
+	}
+	// anonymous read only
+	a := dialTestFtp(t, "11540", "anonymous", "guest@example.com")
+	defer a.Quit()
+	if got := readTestFtp(t, a, "public.txt", 0); got != "inside" {
+		t.Errorf("Anonymous retrieved file is %q", got)
+	}
+	if err := a.Stor("upload.txt", strings.NewReader("x")); err == nil {
+		t.Error("Anonymous should not store file")
+	}
+	if err := a.Delete("public.txt"); err == nil {
+		t.Error("Anonymous should not delete file")
+	}
+	// wrong password
+	w, err := ftp.Dial("127.0.0.1:11540", ftp.DialWithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal("Error dial ftp server:", err)
+	}
+	defer w.Quit()
+	if err = w.Login("alice", "wrong"); err == nil {
+		t.Error("Login with wrong password should fail")
+	}
+}
+
+func BenchmarkFtpServerRoot(b *testing.B) {
+	s := &tNetsFtpSession{s: &TNetsFtpServer{root: os.TempDir()}, cwd: "/docs"}
+	for i := 0; i < b.N; i++ {
+		_, _, _ = s.path("../a/./b.txt", false)
+	}
+}
+
+func TestFtpServerTLS(t *testing.T) {
+	s, dir := startTestFtp(t, "11541", TNetsFtpConfig{TLS: &TNetsHttpsConfig{SelfSigned: true}, RequireTLS: true})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	c := dialTestFtp(t, "11541", "alice", "secret", ftp.DialWithExplicitTLS(&tls.Config{InsecureSkipVerify: true}))
+	defer c.Quit()
+	if err := c.Stor("tls.txt", strings.NewReader("hello tls")); err != nil {
+		t.Fatal("Error store file over TLS:", err)
+	}
+	if err := c.Stor("empty.txt", strings.NewReader("")); err != nil {
+		t.Error("Error store empty file over TLS:", err)
+	}
+	if got := readTestFtp(t, c, "tls.txt", 0); got != "hello tls" {
+		t.Errorf("Retrieved file over TLS is %q", got)
+	}
+	if got := readTestFtp(t, c, "empty.txt", 0); got != "" {
+		t.Errorf("Retrieved empty file over TLS is %q", got)
+	}
+	// plain login refused
+	p, err := ftp.Dial("127.0.0.1:11541", ftp.DialWithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal("Error dial ftp server:", err)
+	}
+	defer p.Quit()
+	if err = p.Login("alice", "secret"); err == nil {
+		t.Error("Plain login should be refused when TLS required")
+	}
+}
+
+func BenchmarkFtpServerTLS(b *testing.B) {
+	s, dir := startTestFtp(b, "11542", TNetsFtpConfig{TLS: &TNetsHttpsConfig{SelfSigned: true}})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	for i := 0; i < b.N; i++ {
+		c := dialTestFtp(b, "11542", "alice", "secret", ftp.DialWithExplicitTLS(&tls.Config{InsecureSkipVerify: true}))
+		_ = c.Quit()
+	}
+}
+
+// cmdTestFtp function
+// send command on raw control connection and read reply
+func cmdTestFtp(t testing.TB, c *textproto.Conn, expect int, format string, args ...interface{}) string {
+	id, err := c.Cmd(format, args...)
+	if err != nil {
+		t.Fatal("Error send command:", err)
+	}
+	c.StartResponse(id)
+	defer c.EndResponse(id)
+	_, msg, err := c.ReadResponse(expect)
+	if err != nil {
+		t.Fatalf("Reply of %v is %v", fmt.Sprintf(format, args...), err)
+	}
+	return msg
+}
+
+func TestFtpServerActive(t *testing.T) {
+	s, dir := startTestFtp(t, "11543", TNetsFtpConfig{})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	_ = ioutil.WriteFile(filepath.Join(dir, "root", "active.txt"), []byte("hello active"), 0644)
+	c, err := textproto.Dial("tcp", "127.0.0.1:11543")
+	if err != nil {
+		t.Fatal("Error dial ftp server:", err)
+	}
+	defer c.Close()
+	_, _, _ = c.ReadResponse(220)
+	cmdTestFtp(t, c, 530, "RETR active.txt")
+	cmdTestFtp(t, c, 331, "USER alice")
+	cmdTestFtp(t, c, 230, "PASS secret")
+	cmdTestFtp(t, c, 502, "SITE CHMOD 777 active.txt")
+	// bounce to other address refused
+	cmdTestFtp(t, c, 501, "PORT 192,0,2,1,4,0")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error listen tcp:", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	cmdTestFtp(t, c, 200, "PORT 127,0,0,1,%d,%d", port>>8, port&0xff)
+	cmdTestFtp(t, c, 150, "RETR active.txt")
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal("Error accept data connection:", err)
+	}
+	b, _ := io.ReadAll(conn)
+	_ = conn.Close()
+	if _, _, err = c.ReadResponse(226); err != nil || string(b) != "hello active" {
+		t.Errorf("Active mode file is %q: %v", b, err)
+	}
+	cmdTestFtp(t, c, 200, "EPRT |1|127.0.0.1|%d|", port)
+	cmdTestFtp(t, c, 150, "STOR up.txt")
+	conn, err = l.Accept()
+	if err != nil {
+		t.Fatal("Error accept data connection:", err)
+	}
+	_, _ = conn.Write([]byte("uploaded"))
+	_ = conn.Close()
+	if _, _, err = c.ReadResponse(226); err != nil {
+		t.Error("Error store in active mode:", err)
+	}
+	if b, _ = ioutil.ReadFile(filepath.Join(dir, "root", "up.txt")); string(b) != "uploaded" {
+		t.Errorf("Stored file in active mode is %q", b)
+	}
+	cmdTestFtp(t, c, 221, "QUIT")
+}
+
+func BenchmarkFtpServerActive(b *testing.B) {
+	s, dir := startTestFtp(b, "11544", TNetsFtpConfig{})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	_ = ioutil.WriteFile(filepath.Join(dir, "root", "active.txt"), []byte("hello active"), 0644)
+	c, err := textproto.Dial("tcp", "127.0.0.1:11544")
+	if err != nil {
+		b.Fatal("Error dial ftp server:", err)
+	}
+	defer c.Close()
+	_, _, _ = c.ReadResponse(220)
+	cmdTestFtp(b, c, 331, "USER alice")
+	cmdTestFtp(b, c, 230, "PASS secret")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		t.Errorf("Error GET ftp server url:%v", err)
+		b.Fatal("Error listen tcp:", err)
 	}
-	if r.StatusCode != http.StatusOK {
-		t.Errorf("Error GET ftp server response")
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	for i := 0; i < b.N; i++ {
+		// server connect to client listener for every transfer
+		cmdTestFtp(b, c, 200, "PORT 127,0,0,1,%d,%d", port>>8, port&0xff)
+		cmdTestFtp(b, c, 150, "RETR active.txt")
+		conn, err := l.Accept()
+		if err != nil {
+			b.Fatal("Error accept data connection:", err)
+		}
+		data, _ := io.ReadAll(conn)
+		_ = conn.Close()
+		if _, _, err = c.ReadResponse(226); err != nil || string(data) != "hello active" {
+			b.Errorf("Active mode file is %q: %v", data, err)
+		}
 	}
 }
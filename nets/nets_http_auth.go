@@ -0,0 +1,399 @@
+package nets
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	. "satellite/global"
+	"satellite/pack"
+	. "satellite/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TNetsAuthPrincipal describe a client allowed to use the REST server
+// Token is the static api token, Secret is the HMAC signing secret and
+// Subject is the common name of mTLS client certificate
+// Scopes such as "pack:write", "parses:read", "pack:*" or "*"
+// Dirs is the allowlist of base directories the principal may touch
+type TNetsAuthPrincipal struct {
+	Name    string   `json:"name"`
+	Token   string   `json:"token,omitempty"`
+	Secret  string   `json:"secret,omitempty"`
+	Subject string   `json:"subject,omitempty"`
+	Scopes  []string `json:"scopes"`
+	Dirs    []string `json:"dirs"`
+}
+
+type TNetsAuth struct {
+	Principals     []TNetsAuthPrincipal `json:"principals"`
+	Authenticators []Authenticator      `json:"-"`
+}
+
+// Authenticator interface
+// Authenticate return nil principal and nil error when the request
+// carry no credentials of its kind, error when credentials are invalid
+type Authenticator interface {
+	Authenticate(r *http.Request) (*TNetsAuthPrincipal, error)
+}
+
+type netsAuthKey struct{}
+
+var httpAuth *TNetsAuth
+
+var (
+	ErrAuthToken     = errors.New("invalid api token")
+	ErrAuthSignature = errors.New("invalid request signature")
+	ErrAuthExpired   = errors.New("request timestamp out of range")
+	ErrAuthReplay    = errors.New("request nonce already used")
+	ErrAuthCert      = errors.New("unknown client certificate")
+)
+
+// NewHttpAuth function
+// create auth with token, HMAC and mTLS client certificate authenticators
+func NewHttpAuth(principals []TNetsAuthPrincipal) *TNetsAuth {
+	a := &TNetsAuth{Principals: principals}
+	a.Authenticators = []Authenticator{
+		&TNetsAuthToken{auth: a},
+		&TNetsAuthHMAC{auth: a, seen: make(map[string]int64)},
+		&TNetsAuthCert{auth: a},
+	}
+	return a
+}
+
+// LoadHttpAuth function
+// load auth principals from json config file
+func LoadHttpAuth(path string) (a *TNetsAuth, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println("Error read auth config:", err)
+		return nil, err
+	}
+	var t TNetsAuth
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		log.Println("Error unmarshal auth config:", err)
+		return nil, err
+	}
+	return NewHttpAuth(t.Principals), nil
+}
+
+// SetHttpAuth function
+// enable auth middleware for http and https server, nil to disable
+func SetHttpAuth(a *TNetsAuth) {
+	httpAuth = a
+}
+
+// Authenticate function
+// try authenticators in order, the first principal found wins
+func (a *TNetsAuth) Authenticate(r *http.Request) (*TNetsAuthPrincipal, error) {
+	for _, v := range a.Authenticators {
+		p, err := v.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// Middleware function
+// reject requests without valid credentials or required scope
+func (a *TNetsAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			tpl, _ := route.GetPathTemplate()
+			scope = httpRouteScope(tpl, r.Method)
+		}
+		// public routes
+		if scope == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, err := a.Authenticate(r)
+		var m *http.MaxBytesError
+		if errors.As(err, &m) {
+			writeNetsError(w, r, http.StatusRequestEntityTooLarge, "Request body too large!", err.Error())
+			return
+		}
+		if err != nil || p == nil {
+			if err != nil {
+				log.Println("Error authenticate request:", err)
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="satellite"`)
+			writeNetsError(w, r, http.StatusUnauthorized, "Unauthorized!", "")
+			return
+		}
+		if !p.HasScope(scope) {
+			log.Printf("Principal %v missing scope %v\n", p.Name, scope)
+			writeNetsError(w, r, http.StatusForbidden, "Forbidden!", "missing scope "+scope)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), netsAuthKey{}, p)))
+	})
+}
+
+// HasScope function
+// scope "*" match all, scope "pack:*" match all pack scopes
+func (p *TNetsAuthPrincipal) HasScope(scope string) bool {
+	for _, v := range p.Scopes {
+		if v == "*" || v == scope {
+			return true
+		}
+		if strings.HasSuffix(v, ":*") && strings.HasPrefix(scope, strings.TrimSuffix(v, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowPath function
+// symlinks are resolved before compare with allowlist directories
+func (p *TNetsAuthPrincipal) AllowPath(path string) bool {
+	path, err := RealPath(path)
+	if err != nil {
+		log.Println("Error resolve path:", err)
+		return false
+	}
+	for _, v := range p.Dirs {
+		dir, err := RealPath(v)
+		if err != nil {
+			log.Println("Error resolve path:", err)
+			continue
+		}
+		if PathWithin(dir, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpRouteScope function
+// return the scope required by route, empty string for public routes
+func httpRouteScope(path string, method string) string {
+	switch path {
+	case HttpURLRoot, HttpURLSatellite, HttpURLOpenAPI, HttpURLExplorer:
+		return ""
+	case HttpURLMetrics:
+		return "metrics:read"
+	case HttpURLPack:
+		return "pack:write"
+	case HttpURLPackProcess:
+		return "pack:read"
+	case HttpURLUnpack, HttpURLUnpackConfine, HttpURLUnpackToFile, HttpURLUnpackToFileConfine:
+		return "unpack:write"
+	case HttpURLUnpackVerbose, HttpURLUnpackProcess, HttpURLUnpackToMemory:
+		return "unpack:read"
+	case HttpURLComp:
+		return "comp:write"
+	case HttpURLDecomp:
+		return "decomp:write"
+	case HttpURLImagesQRCodeToFile:
+		return "images:write"
+	case HttpURLImagesQRCodeToMemory:
+		return "images:read"
+	case HttpURLParsesIni:
+		if method == http.MethodPut {
+			return "parses:write"
+		}
+		return "parses:read"
+	case HttpURLJobEvents:
+		return "jobs:read"
+	case HttpURLRpc:
+		// each method also needs its own scope
+		return "rpc:call"
+	case HttpURLPeers:
+		return "peers:read"
+	}
+	// unknown routes need full access
+	return "*"
+}
+
+// checkNetsAuthPaths function
+// check request paths against principal allowlist directories,
+// write 403 response and return false when any path not allowed,
+// requests without principal come from server without auth
+func checkNetsAuthPaths(w http.ResponseWriter, r *http.Request, paths ...string) bool {
+	p, ok := r.Context().Value(netsAuthKey{}).(*TNetsAuthPrincipal)
+	if !ok {
+		return true
+	}
+	for _, v := range paths {
+		if v != "" && !p.AllowPath(v) {
+			log.Printf("Principal %v not allowed path: '%v'\n", p.Name, v)
+			writeNetsError(w, r, http.StatusForbidden, "Forbidden path!", v)
+			return false
+		}
+	}
+	return true
+}
+
+type TNetsAuthToken struct {
+	auth *TNetsAuth
+}
+
+// Authenticate function
+// static api token in "Authorization: Bearer <token>" header
+func (a *TNetsAuthToken) Authenticate(r *http.Request) (*TNetsAuthPrincipal, error) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return nil, nil
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	for i, v := range a.auth.Principals {
+		if v.Token != "" && subtle.ConstantTimeCompare([]byte(v.Token), []byte(token)) == 1 {
+			return &a.auth.Principals[i], nil
+		}
+	}
+	return nil, ErrAuthToken
+}
+
+// TNetsAuthHMAC remember the (key, nonce) pairs of accepted requests until their
+// timestamp leave the skew window, so that one signed request is accepted once
+type TNetsAuthHMAC struct {
+	auth  *TNetsAuth
+	mu    sync.Mutex
+	seen  map[string]int64 // key and nonce, expire unix time
+	swept int64
+}
+
+// Authenticate function
+// HMAC signed request, see SignHttpRequest
+func (a *TNetsAuthHMAC) Authenticate(r *http.Request) (*TNetsAuthPrincipal, error) {
+	key := r.Header.Get(HttpHeaderAuthKey)
+	if key == "" {
+		return nil, nil
+	}
+	ts := r.Header.Get(HttpHeaderAuthTimestamp)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrAuthSignature
+	}
+	nonce := r.Header.Get(HttpHeaderAuthNonce)
+	if nonce == "" || len(nonce) > HttpAuthMaxNonce {
+		return nil, ErrAuthSignature
+	}
+	now := time.Now().Unix()
+	skew := now - sec
+	if skew > HttpAuthMaxSkew || skew < -HttpAuthMaxSkew {
+		return nil, ErrAuthExpired
+	}
+	body, err := readHttpAuthBody(r)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range a.auth.Principals {
+		if v.Secret == "" || v.Name != key {
+			continue
+		}
+		sign := pack.HMAC_SHA256(httpAuthCanonical(r.Method, r.URL.RequestURI(), ts, nonce, body), v.Secret)
+		if subtle.ConstantTimeCompare([]byte(sign), []byte(r.Header.Get(HttpHeaderAuthSignature))) == 1 {
+			if !a.remember(key, nonce, sec+HttpAuthMaxSkew, now) {
+				return nil, ErrAuthReplay
+			}
+			return &a.auth.Principals[i], nil
+		}
+		break
+	}
+	return nil, ErrAuthSignature
+}
+
+// remember function
+// record the nonce of key until expire, return false when it was already seen
+func (a *TNetsAuthHMAC) remember(key string, nonce string, expire int64, now int64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// forget the nonces whose timestamp can not pass skew check any more
+	if now > a.swept {
+		for k, v := range a.seen {
+			if v < now {
+				delete(a.seen, k)
+			}
+		}
+		a.swept = now
+	}
+	id := key + "\n" + nonce
+	if _, ok := a.seen[id]; ok {
+		return false
+	}
+	a.seen[id] = expire
+	return true
+}
+
+type TNetsAuthCert struct {
+	auth *TNetsAuth
+}
+
+// Authenticate function
+// verified mTLS client certificate, common name match principal subject
+func (a *TNetsAuthCert) Authenticate(r *http.Request) (*TNetsAuthPrincipal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	for i, v := range a.auth.Principals {
+		if v.Subject != "" && v.Subject == cn {
+			return &a.auth.Principals[i], nil
+		}
+	}
+	return nil, ErrAuthCert
+}
+
+// SignHttpRequest function
+// sign request with principal name and HMAC secret, each signed request
+// carry one random nonce and is accepted only once
+func SignHttpRequest(r *http.Request, key string, secret string) error {
+	body, err := readHttpAuthBody(r)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(b)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(HttpHeaderAuthKey, key)
+	r.Header.Set(HttpHeaderAuthTimestamp, ts)
+	r.Header.Set(HttpHeaderAuthNonce, nonce)
+	r.Header.Set(HttpHeaderAuthSignature, pack.HMAC_SHA256(httpAuthCanonical(r.Method, r.URL.RequestURI(), ts, nonce, body), secret))
+	return nil
+}
+
+// httpAuthCanonical function
+// method, request uri, timestamp, nonce and body sha256 joined by newline
+func httpAuthCanonical(method string, uri string, ts string, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return method + "\n" + uri + "\n" + ts + "\n" + nonce + "\n" + hex.EncodeToString(sum[:])
+}
+
+// readHttpAuthBody function
+// read request body and put it back for the handler
+func readHttpAuthBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,198 @@
+package nets
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	. "satellite/global"
+	"strings"
+	"testing"
+)
+
+const testAuthIniBody = `{"src": "../test/data/parses/test_simple.ini", "mode": "get", "section": "BOOL", "name": "Switch_On", "type": "bool", "value": ""}`
+
+func newTestAuthRouter() http.Handler {
+	SetHttpAuth(NewHttpAuth([]TNetsAuthPrincipal{
+		{Name: "reader", Token: "reader-token", Secret: "reader-secret", Subject: "reader.satellite", Scopes: []string{"parses:read"}, Dirs: []string{"../test/data/parses"}},
+		{Name: "outsider", Token: "outsider-token", Scopes: []string{"parses:*"}, Dirs: []string{"../test/data/pack"}},
+	}))
+	defer SetHttpAuth(nil)
+	return createHttpRouter()
+}
+
+func serveTestAuth(h http.Handler, request *http.Request) int {
+	writer := httptest.NewRecorder()
+	h.ServeHTTP(writer, request)
+	return writer.Code
+}
+
+func TestHttpAuthToken(t *testing.T) {
+	h := newTestAuthRouter()
+
+	request, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	if code := serveTestAuth(h, request); code != http.StatusUnauthorized {
+		t.Errorf("Response code without token is %v", code)
+	}
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	request.Header.Set("Authorization", "Bearer wrong-token")
+	if code := serveTestAuth(h, request); code != http.StatusUnauthorized {
+		t.Errorf("Response code with wrong token is %v", code)
+	}
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	request.Header.Set("Authorization", "Bearer reader-token")
+	if code := serveTestAuth(h, request); code != http.StatusOK {
+		t.Errorf("Response code with token is %v", code)
+	}
+	request, _ = http.NewRequest("GET", HttpURLSatellite, nil)
+	if code := serveTestAuth(h, request); code != http.StatusOK {
+		t.Errorf("Response code of public route is %v", code)
+	}
+}
+
+func BenchmarkHttpAuthToken(b *testing.B) {
+	h := newTestAuthRouter()
+	for i := 0; i < b.N; i++ {
+		request, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+		request.Header.Set("Authorization", "Bearer reader-token")
+		if code := serveTestAuth(h, request); code != http.StatusOK {
+			b.Errorf("Response code with token is %v", code)
+		}
+	}
+}
+
+func TestHttpAuthHMAC(t *testing.T) {
+	h := newTestAuthRouter()
+
+	request, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	err := SignHttpRequest(request, "reader", "reader-secret")
+	if err != nil {
+		t.Error("Error sign request:", err)
+	}
+	if code := serveTestAuth(h, request); code != http.StatusOK {
+		t.Errorf("Response code with signature is %v", code)
+	}
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	_ = SignHttpRequest(request, "reader", "wrong-secret")
+	if code := serveTestAuth(h, request); code != http.StatusUnauthorized {
+		t.Errorf("Response code with wrong signature is %v", code)
+	}
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	_ = SignHttpRequest(request, "reader", "reader-secret")
+	request.Header.Set(HttpHeaderAuthTimestamp, "0")
+	if code := serveTestAuth(h, request); code != http.StatusUnauthorized {
+		t.Errorf("Response code with expired signature is %v", code)
+	}
+	// replayed request with the same nonce should be rejected
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	_ = SignHttpRequest(request, "reader", "reader-secret")
+	replay, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	replay.Header = request.Header.Clone()
+	if code := serveTestAuth(h, request); code != http.StatusOK {
+		t.Errorf("Response code with signature is %v", code)
+	}
+	if code := serveTestAuth(h, replay); code != http.StatusUnauthorized {
+		t.Errorf("Response code of replayed request is %v", code)
+	}
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	_ = SignHttpRequest(request, "reader", "reader-secret")
+	request.Header.Del(HttpHeaderAuthNonce)
+	if code := serveTestAuth(h, request); code != http.StatusUnauthorized {
+		t.Errorf("Response code without nonce is %v", code)
+	}
+}
+
+func BenchmarkHttpAuthHMAC(b *testing.B) {
+	h := newTestAuthRouter()
+	for i := 0; i < b.N; i++ {
+		request, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+		_ = SignHttpRequest(request, "reader", "reader-secret")
+		if code := serveTestAuth(h, request); code != http.StatusOK {
+			b.Errorf("Response code with signature is %v", code)
+		}
+	}
+}
+
+func TestHttpAuthCert(t *testing.T) {
+	h := newTestAuthRouter()
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "reader.satellite"}}
+	request, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	request.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	if code := serveTestAuth(h, request); code != http.StatusOK {
+		t.Errorf("Response code with client certificate is %v", code)
+	}
+	cert = &x509.Certificate{Subject: pkix.Name{CommonName: "unknown.satellite"}}
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	request.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	if code := serveTestAuth(h, request); code != http.StatusUnauthorized {
+		t.Errorf("Response code with unknown client certificate is %v", code)
+	}
+}
+
+func BenchmarkHttpAuthCert(b *testing.B) {
+	h := newTestAuthRouter()
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "reader.satellite"}}
+	for i := 0; i < b.N; i++ {
+		request, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+		request.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		if code := serveTestAuth(h, request); code != http.StatusOK {
+			b.Errorf("Response code with client certificate is %v", code)
+		}
+	}
+}
+
+func TestHttpAuthForbidden(t *testing.T) {
+	h := newTestAuthRouter()
+
+	// missing scope
+	body := strings.NewReader(`{"src": "../test/data/parses/test_simple.ini", "mode": "set", "section": "BOOL", "name": "Switch_On", "type": "bool", "value": "true"}`)
+	request, _ := http.NewRequest("PUT", HttpURLParsesIni, body)
+	request.Header.Set("Authorization", "Bearer reader-token")
+	if code := serveTestAuth(h, request); code != http.StatusForbidden {
+		t.Errorf("Response code without scope is %v", code)
+	}
+	// path outside allowed directories
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+	request.Header.Set("Authorization", "Bearer outsider-token")
+	if code := serveTestAuth(h, request); code != http.StatusForbidden {
+		t.Errorf("Response code outside allowed directories is %v", code)
+	}
+}
+
+func BenchmarkHttpAuthForbidden(b *testing.B) {
+	h := newTestAuthRouter()
+	for i := 0; i < b.N; i++ {
+		request, _ := http.NewRequest("GET", HttpURLParsesIni, strings.NewReader(testAuthIniBody))
+		request.Header.Set("Authorization", "Bearer outsider-token")
+		if code := serveTestAuth(h, request); code != http.StatusForbidden {
+			b.Errorf("Response code outside allowed directories is %v", code)
+		}
+	}
+}
+
+func TestHttpAuthAllowPath(t *testing.T) {
+	p := TNetsAuthPrincipal{Dirs: []string{"../test/data/parses"}}
+	if !p.AllowPath("../test/data/parses/test.ini") {
+		t.Error("Path inside allowed directory is denied")
+	}
+	if !p.AllowPath("../test/data/parses/new/file.ini") {
+		t.Error("Not exist path inside allowed directory is denied")
+	}
+	if p.AllowPath("../test/data/parses/../pack/file.txt") {
+		t.Error("Path outside allowed directory is allowed")
+	}
+	if p.AllowPath("../test/data/parses2") {
+		t.Error("Sibling path with same prefix is allowed")
+	}
+}
+
+func BenchmarkHttpAuthAllowPath(b *testing.B) {
+	p := TNetsAuthPrincipal{Dirs: []string{"../test/data/parses"}}
+	for i := 0; i < b.N; i++ {
+		if !p.AllowPath("../test/data/parses/test.ini") {
+			b.Error("Path inside allowed directory is denied")
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package nets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	. "satellite/global"
+	. "satellite/utils"
+	"strconv"
+)
+
+// TNetsError is the error envelope of all REST API error responses
+type TNetsError struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id"`
+}
+
+// TNetsHttpError is returned by handlers to choose the response status
+type TNetsHttpError struct {
+	Status  int
+	Message string
+	Details string
+}
+
+type netsRequestIDKey struct{}
+
+var ErrNetsTimeout = NewNetsError(http.StatusGatewayTimeout, "Request timeout!", "job not finished in time")
+
+// NewNetsError function
+// create handler error with response status, message and details
+func NewNetsError(status int, message string, details string) error {
+	return &TNetsHttpError{Status: status, Message: message, Details: details}
+}
+
+func (e *TNetsHttpError) Error() string {
+	if e.Details == "" {
+		return e.Message
+	}
+	return e.Message + " " + e.Details
+}
+
+// writeNetsError function
+// write error envelope as json response body
+func writeNetsError(w http.ResponseWriter, r *http.Request, status int, message string, details string) {
+	id := netsRequestID(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	observeNetsError(status)
+	err := json.NewEncoder(w).Encode(TNetsError{Code: status, Message: message, Details: details, RequestID: id})
+	if err != nil {
+		log.Println("Error encode error response:", err)
+	}
+	log.Printf("%d %s [%s]", status, http.StatusText(status), id)
+}
+
+// handleNetsError function
+// map handler error to response status and write error envelope,
+// internal errors are only logged against the request id, not echoed
+func handleNetsError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *TNetsHttpError
+	var m *http.MaxBytesError
+	var n *strconv.NumError
+	switch {
+	case errors.As(err, &e):
+		writeNetsError(w, r, e.Status, e.Message, e.Details)
+	case errors.As(err, &m):
+		writeNetsError(w, r, http.StatusRequestEntityTooLarge, "Request body too large!", err.Error())
+	case errors.Is(err, os.ErrNotExist):
+		// resolved paths of storage roots stay in the log
+		log.Printf("Error file not exist [%s]: %v", netsRequestID(r), err)
+		writeNetsError(w, r, http.StatusNotFound, "File not exist!", "")
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		writeNetsError(w, r, http.StatusGatewayTimeout, "Request timeout!", "")
+	case errors.Is(err, ErrAlgorithm):
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Algorithm not support!", err.Error())
+	case errors.As(err, &n):
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", n.Num)
+	default:
+		log.Printf("Error internal [%s]: %v", netsRequestID(r), err)
+		writeNetsError(w, r, http.StatusInternalServerError, "Internal server error!", "")
+	}
+}
+
+func handleNetsNotFound(w http.ResponseWriter, r *http.Request) {
+	writeNetsError(w, r, http.StatusNotFound, "Not found!", r.URL.Path)
+}
+
+func handleNetsMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeNetsError(w, r, http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
+}
+
+// netsRequestID function
+// request id from context set by middleware, or from request header
+func netsRequestID(r *http.Request) string {
+	if id, ok := r.Context().Value(netsRequestIDKey{}).(string); ok {
+		return id
+	}
+	return r.Header.Get(HttpHeaderRequestID)
+}
+
+// requestIDMiddleware function
+// keep client request id if valid, or generate a new one,
+// set it to response header and log it with the request
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HttpHeaderRequestID)
+		if !validRequestID(id) {
+			b := make([]byte, 16)
+			_, _ = rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set(HttpHeaderRequestID, id)
+		log.Printf("Request %s %s %s", id, r.Method, r.RequestURI)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), netsRequestIDKey{}, id)))
+	})
+}
+
+// validRequestID function
+// at most 128 characters of letters, digits, '-', '_' and '.'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package nets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"satellite/comp"
+	"satellite/decomp"
+	. "satellite/global"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func serveTestError(method string, url string, body string, id string) (*httptest.ResponseRecorder, TNetsError) {
+	writer := httptest.NewRecorder()
+	request, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if id != "" {
+		request.Header.Set(HttpHeaderRequestID, id)
+	}
+	createHttpRouter().ServeHTTP(writer, request)
+	var e TNetsError
+	_ = json.Unmarshal(writer.Body.Bytes(), &e)
+	return writer, e
+}
+
+func TestHttpErrorEnvelope(t *testing.T) {
+	cases := []struct {
+		method string
+		url    string
+		body   string
+		code   int
+	}{
+		{"POST", HttpURLPack, `{`, http.StatusBadRequest},
+		{"POST", HttpURLPack, `{"src": ["../test/data/pack/not_exist.txt"], "dest": "../test/data/pack/file.txt.pak", "type": "AES"}`, http.StatusNotFound},
+		{"POST", HttpURLPack, `{"src": ["../test/data/pack/file.txt"], "dest": "../test/data/pack/file.txt.pak", "type": "ROT13"}`, http.StatusUnprocessableEntity},
+		{"GET", HttpURLPack, ``, http.StatusMethodNotAllowed},
+		{"GET", HttpURLSatellite + "/not_exist", ``, http.StatusNotFound},
+	}
+	for _, v := range cases {
+		writer, e := serveTestError(v.method, v.url, v.body, "satellite-test-1")
+		if writer.Code != v.code || e.Code != v.code {
+			t.Errorf("%v %v response code is %v, envelope code is %v", v.method, v.url, writer.Code, e.Code)
+		}
+		if e.Message == "" || e.RequestID != "satellite-test-1" {
+			t.Errorf("%v %v envelope is %+v", v.method, v.url, e)
+		}
+		if writer.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%v %v content type is %v", v.method, v.url, writer.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func BenchmarkHttpErrorEnvelope(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		writer, e := serveTestError("POST", HttpURLPack, `{`, "")
+		if writer.Code != http.StatusBadRequest || e.Code != http.StatusBadRequest {
+			b.Errorf("Response code is %v", writer.Code)
+		}
+	}
+}
+
+func TestHandleNetsError(t *testing.T) {
+	_, num := strconv.Atoi("one")
+	undefined := comp.Compress(nil, "", "rar")
+	restore := decomp.DeCompressRestore([]string{"../test/data/decomp/file.zip"}, "", "zip")
+	cases := []struct {
+		err     error
+		code    int
+		details string
+	}{
+		{undefined, http.StatusUnprocessableEntity, undefined.Error()},
+		{restore, http.StatusUnprocessableEntity, restore.Error()},
+		{fmt.Errorf("unpack job: %w", undefined), http.StatusUnprocessableEntity, "unpack job: " + undefined.Error()},
+		// algorithm like text without ErrAlgorithm is internal
+		{errors.New("Undefined unpack algorithm."), http.StatusInternalServerError, ""},
+		{num, http.StatusUnprocessableEntity, "one"},
+		{errors.New("open /srv/secret/key.pem: permission denied"), http.StatusInternalServerError, ""},
+	}
+	for _, v := range cases {
+		writer := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", HttpURLSatellite, nil)
+		handleNetsError(writer, request, v.err)
+		var e TNetsError
+		_ = json.Unmarshal(writer.Body.Bytes(), &e)
+		if writer.Code != v.code || e.Details != v.details {
+			t.Errorf("Error %v response code is %v, details is %q", v.err, writer.Code, e.Details)
+		}
+	}
+}
+
+func TestHttpRequestID(t *testing.T) {
+	writer, _ := serveTestError("GET", HttpURLSatellite, ``, "satellite-test-2")
+	if writer.Header().Get(HttpHeaderRequestID) != "satellite-test-2" {
+		t.Errorf("Request id is %v", writer.Header().Get(HttpHeaderRequestID))
+	}
+	// illegal client request id is replaced
+	writer, _ = serveTestError("GET", HttpURLSatellite, ``, "bad id\r\n")
+	if id := writer.Header().Get(HttpHeaderRequestID); len(id) != 32 {
+		t.Errorf("Generated request id is %v", id)
+	}
+}
+
+func BenchmarkHttpRequestID(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		writer, _ := serveTestError("GET", HttpURLSatellite, ``, "")
+		if id := writer.Header().Get(HttpHeaderRequestID); len(id) != 32 {
+			b.Errorf("Generated request id is %v", id)
+		}
+	}
+}
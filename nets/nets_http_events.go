@@ -0,0 +1,105 @@
+package nets
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	. "satellite/global"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// handleNetsJobEvents function
+// stream events of job started with the same X-Request-ID as Server-Sent Events,
+// replay history after Last-Event-ID, heartbeat until job result sent
+func handleNetsJobEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !validRequestID(id) {
+		writeNetsError(w, r, http.StatusBadRequest, "Illegal job id!", id)
+		return
+	}
+	var last int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeNetsError(w, r, http.StatusBadRequest, "Illegal Last-Event-ID!", err.Error())
+			return
+		}
+		last = n
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeNetsError(w, r, http.StatusInternalServerError, "Streaming not supported!", "")
+		return
+	}
+	s := netsJobsOf(r).subscribe(id)
+	if !allowNetsJobOwner(r, s.jobOwner()) {
+		writeNetsError(w, r, http.StatusNotFound, "Job not found!", id)
+		return
+	}
+	// stream lives longer than server write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	ping := time.NewTicker(HttpJobEventPing * time.Second)
+	defer ping.Stop()
+	wait := time.NewTimer(HttpJobEventRetention * time.Millisecond)
+	defer wait.Stop()
+	for {
+		events, notify, started, finish := s.since(last)
+		if !allowNetsJobOwner(r, s.jobOwner()) {
+			return
+		}
+		for _, v := range events {
+			data, err := json.Marshal(v)
+			if err != nil {
+				log.Println("Error marshal job event:", err)
+				return
+			}
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", v.Seq, v.Type, data)
+			if err != nil {
+				log.Println("Error write job event:", err)
+				return
+			}
+			last = v.Seq
+		}
+		flusher.Flush()
+		if finish {
+			log.Printf("%d Ok", http.StatusOK)
+			return
+		}
+		select {
+		case <-notify:
+		case <-ping.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			if err != nil {
+				return
+			}
+		case <-wait.C:
+			// job never started with this id
+			if !started {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// allowNetsJobOwner function
+// principal of request may read events of job owned by it,
+// everyone may read on server without auth
+func allowNetsJobOwner(r *http.Request, owner string) bool {
+	p, ok := r.Context().Value(netsAuthKey{}).(*TNetsAuthPrincipal)
+	if !ok || owner == "" {
+		return true
+	}
+	return p.Name == owner
+}
@@ -0,0 +1,152 @@
+package nets
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	"strings"
+	"testing"
+)
+
+// readNetsJobEvents function
+// read data of every event in stream until closed
+func readNetsJobEvents(resp *http.Response) (events []TNetsJobEvent, err error) {
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e TNetsJobEvent
+		err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+	return events, sc.Err()
+}
+
+func TestHandleNetsJobEvents(t *testing.T) {
+	defer func() { netsJobs = NewJobRegistry() }()
+	netsJobs = NewJobRegistry()
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	ts := httptest.NewServer(createHttpRouter())
+	defer ts.Close()
+	// subscribe before job start
+	resp, err := http.Get(ts.URL + strings.Replace(HttpURLJobEvents, "{id}", "sse-test", 1))
+	if err != nil {
+		t.Fatal("Error subscribe job events:", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Content-Type is %v", resp.Header.Get("Content-Type"))
+	}
+	src := []string{"../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt", "../test/data/pack/file_3.txt"}
+	body, _ := json.Marshal(TNetsPack{Src: src, Dest: filepath.Join(dir, "file.pak"), Type: "AES"})
+	req, _ := http.NewRequest("POST", ts.URL+HttpURLPack, bytes.NewReader(body))
+	req.Header.Set(HttpHeaderRequestID, "sse-test")
+	pr, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Error post pack:", err)
+	}
+	pr.Body.Close()
+	if pr.StatusCode != http.StatusOK {
+		t.Fatalf("Pack status code is %v", pr.StatusCode)
+	}
+	events, err := readNetsJobEvents(resp)
+	if err != nil {
+		t.Fatal("Error read job events:", err)
+	}
+	count := make(map[string]int)
+	for _, v := range events {
+		count[v.Type]++
+	}
+	if count["start"] != 1 || count["entry_start"] != len(src) || count["entry_finish"] != len(src) || count["result"] != 1 {
+		t.Errorf("Job events count %v", count)
+	}
+	last := events[len(events)-1]
+	if last.Type != "result" || last.Error != "" || last.Done != last.Work || last.Work == 0 {
+		t.Errorf("Job result event %+v", last)
+	}
+	// replay after Last-Event-ID
+	req, _ = http.NewRequest("GET", ts.URL+strings.Replace(HttpURLJobEvents, "{id}", "sse-test", 1), nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Error subscribe job events:", err)
+	}
+	defer resp.Body.Close()
+	replay, err := readNetsJobEvents(resp)
+	if err != nil {
+		t.Fatal("Error read job events:", err)
+	}
+	if len(replay) != len(events)-1 || replay[0].Seq != 2 {
+		t.Errorf("Replay %v events after seq 1 of %v", len(replay), len(events))
+	}
+}
+
+func TestHandleNetsJobEventsOwner(t *testing.T) {
+	defer func() { netsJobs = NewJobRegistry() }()
+	netsJobs = NewJobRegistry()
+	job, err := netsJobs.StartOwner("owned", "pack", "alice", nil)
+	if err != nil {
+		t.Fatal("Error start job:", err)
+	}
+	job.Done(nil)
+	r := httptest.NewRequest("GET", "/satellite/jobs/owned/events", nil)
+	if !allowNetsJobOwner(r, "alice") {
+		t.Error("Request without auth not allowed")
+	}
+	SetHttpAuth(NewHttpAuth([]TNetsAuthPrincipal{
+		{Name: "alice", Token: "a", Scopes: []string{"jobs:read"}},
+		{Name: "bob", Token: "b", Scopes: []string{"jobs:read"}},
+	}))
+	defer SetHttpAuth(nil)
+	ts := httptest.NewServer(createHttpRouter())
+	defer ts.Close()
+	for k, v := range map[string]int{"a": http.StatusOK, "b": http.StatusNotFound} {
+		req, _ := http.NewRequest("GET", ts.URL+"/satellite/jobs/owned/events", nil)
+		req.Header.Set("Authorization", "Bearer "+k)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("Error subscribe job events:", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != v {
+			t.Errorf("Token %v status code is %v, expect %v", k, resp.StatusCode, v)
+		}
+	}
+}
+
+func BenchmarkHandleNetsJobEvents(b *testing.B) {
+	defer func() { netsJobs = NewJobRegistry() }()
+	netsJobs = NewJobRegistry()
+	ts := httptest.NewServer(createHttpRouter())
+	defer ts.Close()
+	for i := 0; i < b.N; i++ {
+		job, err := netsJobs.Start("bench", "pack", nil)
+		if err != nil {
+			b.Fatal("Error start job:", err)
+		}
+		job.EntryStart("file.txt", 1)
+		job.EntryFinish("file.txt", 1, nil)
+		job.Done(nil)
+		resp, err := http.Get(ts.URL + "/satellite/jobs/bench/events")
+		if err != nil {
+			b.Fatal("Error subscribe job events:", err)
+		}
+		_, _ = readNetsJobEvents(resp)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,89 @@
+package nets
+
+// httpExplorerPage is a minimal HTTP explorer of openapi.json,
+// pick an operation, edit the JSON body and send it with optional token
+const httpExplorerPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Satellite API Explorer</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+select, input, textarea { width: 100%; box-sizing: border-box; margin: 0.3em 0; font-family: monospace; }
+textarea { height: 12em; }
+pre { background: #f4f4f4; padding: 1em; white-space: pre-wrap; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>Satellite API Explorer</h1>
+<label>Operation</label>
+<select id="op"></select>
+<label>Bearer token (optional)</label>
+<input id="token" type="password">
+<label>Request body</label>
+<textarea id="body"></textarea>
+<button id="send">Send</button>
+<pre id="result"></pre>
+<script>
+var spec = null;
+var ops = [];
+
+function example(schema) {
+	if (!schema) return null;
+	if (schema.$ref) return example(spec.components.schemas[schema.$ref.split("/").pop()]);
+	switch (schema.type) {
+	case "object":
+		var o = {};
+		for (var k in schema.properties) o[k] = example(schema.properties[k]);
+		return o;
+	case "array": return [example(schema.items)];
+	case "integer": case "number": return 0;
+	case "boolean": return false;
+	default: return "";
+	}
+}
+
+function select() {
+	var op = ops[document.getElementById("op").selectedIndex];
+	var body = op.op.requestBody;
+	var schema = body ? body.content["application/json"].schema : null;
+	document.getElementById("body").value = schema ? JSON.stringify(example(schema), null, 2) : "";
+}
+
+fetch("openapi.json").then(function (r) { return r.json(); }).then(function (s) {
+	spec = s;
+	var sel = document.getElementById("op");
+	Object.keys(s.paths).sort().forEach(function (path) {
+		Object.keys(s.paths[path]).forEach(function (method) {
+			var op = s.paths[path][method];
+			ops.push({method: method.toUpperCase(), path: path, op: op});
+			var opt = document.createElement("option");
+			opt.text = method.toUpperCase() + " " + path + " - " + op.summary + (op["x-satellite-scope"] ? " [" + op["x-satellite-scope"] + "]" : "");
+			sel.add(opt);
+		});
+	});
+	sel.onchange = select;
+	select();
+});
+
+document.getElementById("send").onclick = function () {
+	var op = ops[document.getElementById("op").selectedIndex];
+	var headers = {"Content-Type": "application/json"};
+	var token = document.getElementById("token").value;
+	if (token) headers["Authorization"] = "Bearer " + token;
+	var body = document.getElementById("body").value;
+	var result = document.getElementById("result");
+	result.textContent = "...";
+	// browsers refuse GET with body, send those as POST when the route allows
+	var method = op.method;
+	if (method === "GET" && body && spec.paths[op.path].post) method = "POST";
+	fetch(op.path, {method: method, headers: headers, body: method === "GET" ? undefined : body}).then(function (r) {
+		return r.text().then(function (t) {
+			result.textContent = r.status + " " + r.statusText + "\nX-Request-ID: " + r.headers.get("X-Request-ID") + "\n\n" + t;
+		});
+	}).catch(function (e) { result.textContent = String(e); });
+};
+</script>
+</body>
+</html>
+`
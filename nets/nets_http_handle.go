@@ -2,7 +2,6 @@ package nets
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -27,10 +26,11 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	case "GET":
 		log.Printf("GET %s", r.RequestURI)
 		err = handleGetRoot(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -41,10 +41,11 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 	case "GET":
 		log.Printf("GET %s", r.RequestURI)
 		err = handleGetIndex(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -55,10 +56,11 @@ func handleNetsPack(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsPack(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -69,10 +71,11 @@ func handleNetsUnpack(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsUnpack(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -86,10 +89,11 @@ func handleNetsPackProcess(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsPackProcess(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -103,10 +107,11 @@ func handleNetsUnpackVerbose(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsUnpackVerbose(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -120,10 +125,11 @@ func handleNetsUnpackProcess(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsUnpackProcess(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -134,10 +140,11 @@ func handleNetsUnpackConfine(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsUnpackConfine(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -148,10 +155,11 @@ func handleNetsUnpackToFile(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsUnpackToFile(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -162,10 +170,11 @@ func handleNetsUnpackToFileConfine(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsUnpackToFileConfine(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -179,10 +188,11 @@ func handleNetsUnpackToMemory(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsUnpackToMemory(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -193,10 +203,11 @@ func handleNetsComp(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsComp(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -207,10 +218,11 @@ func handleNetsDecomp(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsDecomp(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -221,10 +233,11 @@ func handleNetsImagesQRCodeToFile(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsImagesQRCodeToFile(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -235,10 +248,11 @@ func handleNetsImagesQRCodeToMemory(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		log.Printf("POST %s", r.RequestURI)
 		err = handlePostNetsImagesQRCodeToMemory(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -252,10 +266,11 @@ func handleNetsParsesIniValue(w http.ResponseWriter, r *http.Request) {
 	case "PUT":
 		log.Printf("PUT %s", r.RequestURI)
 		err = handlePutNetsParsesIniValue(w, r)
+	default:
+		err = NewNetsError(http.StatusMethodNotAllowed, "Method not allowed!", r.Method)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("%d Internal Server Error", http.StatusInternalServerError)
+		handleNetsError(w, r, err)
 		return
 	}
 }
@@ -275,9 +290,7 @@ func handleGetIndex(w http.ResponseWriter, r *http.Request) (err error) {
 func handlePostNetsPack(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -286,9 +299,12 @@ func handlePostNetsPack(w http.ResponseWriter, r *http.Request) (err error) {
 	var t TNetsPack
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, append(netsPathRefs(t.Src), &t.Dest)...) {
 		return nil
 	}
 	// check request parameters
@@ -298,9 +314,12 @@ func handlePostNetsPack(w http.ResponseWriter, r *http.Request) (err error) {
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, append([]string{t.Dest}, t.Src...)...) {
 		return nil
 	}
 	// refactor source files
@@ -309,25 +328,41 @@ func handlePostNetsPack(w http.ResponseWriter, r *http.Request) (err error) {
 		log.Println("Error refactor source files:", err)
 		return err
 	}
+	// check source files inside storage roots
+	if !checkNetsRootPaths(w, r, t.Src...) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "pack", t.Dest)
+	if !ok {
+		return nil
+	}
 	// start pack files
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func() {
-		err = pack.Pack(t.Src, t.Dest, t.Type)
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
+		job.SetWork(netsFileSize(t.Src...))
+		if t.Reproducible {
+			err = pack.PackReproducibleObserve(t.Src, t.Dest, t.Type, job)
+		} else {
+			err = pack.PackObserve(t.Src, t.Dest, t.Type, job)
+		}
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
+		observeNetsBytes("pack", t.Type, t.Src...)
 		return
 	}()
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Pack failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Pack failure:", e)
+				return e
 			}
 			log.Println("Pack success.")
 			finish = true
@@ -340,8 +375,7 @@ func handlePostNetsPack(w http.ResponseWriter, r *http.Request) (err error) {
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "text/plain")
@@ -352,9 +386,7 @@ func handlePostNetsPack(w http.ResponseWriter, r *http.Request) (err error) {
 func handlePostNetsUnpack(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -363,9 +395,12 @@ func handlePostNetsUnpack(w http.ResponseWriter, r *http.Request) (err error) {
 	var t TNetsUnpack
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src, &t.Dest) {
 		return nil
 	}
 	// check request parameters
@@ -375,30 +410,41 @@ func handlePostNetsUnpack(w http.ResponseWriter, r *http.Request) (err error) {
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src, t.Dest) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "unpack", t.Dest)
+	if !ok {
 		return nil
 	}
 	// start unpack files
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func() {
-		err = unpack.Unpack(t.Src, t.Dest)
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
+		job.SetWork(netsPackageSize(t.Src))
+		err = unpack.UnpackObserve(t.Src, t.Dest, job)
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
+		observeNetsBytes("unpack", netsPackageAlgorithm(t.Src), t.Src)
 		return
 	}()
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Unpack failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Unpack failure:", e)
+				return e
 			}
 			log.Println("Unpack success.")
 			finish = true
@@ -411,8 +457,7 @@ func handlePostNetsUnpack(w http.ResponseWriter, r *http.Request) (err error) {
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "text/plain")
@@ -423,9 +468,7 @@ func handlePostNetsUnpack(w http.ResponseWriter, r *http.Request) (err error) {
 func handleGetNetsPackProcess(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -434,9 +477,12 @@ func handleGetNetsPackProcess(w http.ResponseWriter, r *http.Request) (err error
 	var t TNetsPackProcessReq
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, netsPathRefs(t.Src)...) {
 		return nil
 	}
 	// check request parameters
@@ -446,23 +492,30 @@ func handleGetNetsPackProcess(w http.ResponseWriter, r *http.Request) (err error
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src...) {
 		return nil
 	}
 	// pack file process information
 	var resp TNetsPackProcessResp
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func(resp *TNetsPackProcessResp) {
+		var err error
+		defer func() { ch <- err }()
 		var work int64
 		// work value
 		err = pack.WorkCalculate(t.Src, t.Type, &work)
 		if err != nil || work <= 0 {
 			log.Println("Error calculate pack work")
-			ch <- false
+			if err == nil {
+				err = NewNetsError(http.StatusUnprocessableEntity, "Illegal parameters!", "pack work is empty")
+			}
 			return
 		}
 		// done value
@@ -470,14 +523,13 @@ func handleGetNetsPackProcess(w http.ResponseWriter, r *http.Request) (err error
 		// assignment
 		(*resp).Done = done
 		(*resp).Work = work
-		ch <- true
 	}(&resp)
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Pack process failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Pack process failure:", e)
+				return e
 			}
 			log.Println("Pack process success.")
 			finish = true
@@ -490,8 +542,7 @@ func handleGetNetsPackProcess(w http.ResponseWriter, r *http.Request) (err error
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	// marshal json
@@ -513,9 +564,7 @@ func handlePostNetsPackProcess(w http.ResponseWriter, r *http.Request) (err erro
 func handleGetNetsUnpackVerbose(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -524,9 +573,12 @@ func handleGetNetsUnpackVerbose(w http.ResponseWriter, r *http.Request) (err err
 	var t TNetsUnpackVerboseReq
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src) {
 		return nil
 	}
 	// check request parameters
@@ -536,24 +588,28 @@ func handleGetNetsUnpackVerbose(w http.ResponseWriter, r *http.Request) (err err
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src) {
 		return nil
 	}
 	// unpack file verbose information
 	var resp TNetsUnpackVerboseResp
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func(resp *TNetsUnpackVerboseResp) {
+		var err error
+		defer func() { ch <- err }()
 		var files []string
 		var sizes []int
 		var algorithm string
 		err = unpack.ExtractInfo(t.Src, &files, &sizes, &algorithm)
 		if err != nil {
 			log.Println("Error extract unpack information")
-			ch <- false
 			return
 		}
 		for k, v := range files {
@@ -563,15 +619,14 @@ func handleGetNetsUnpackVerbose(w http.ResponseWriter, r *http.Request) (err err
 			t.Type = algorithm
 			(*resp).Files = append((*resp).Files, t)
 		}
-		ch <- true
 		return
 	}(&resp)
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Unpack verbose failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Unpack verbose failure:", e)
+				return e
 			}
 			log.Println("Unpack verbose success.")
 			finish = true
@@ -584,8 +639,7 @@ func handleGetNetsUnpackVerbose(w http.ResponseWriter, r *http.Request) (err err
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	// marshal json
@@ -607,9 +661,7 @@ func handlePostNetsUnpackVerbose(w http.ResponseWriter, r *http.Request) (err er
 func handleGetNetsUnpackProcess(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -618,9 +670,12 @@ func handleGetNetsUnpackProcess(w http.ResponseWriter, r *http.Request) (err err
 	var t TNetsUnpackProcessReq
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src) {
 		return nil
 	}
 	// check request parameters
@@ -630,24 +685,31 @@ func handleGetNetsUnpackProcess(w http.ResponseWriter, r *http.Request) (err err
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src) {
 		return nil
 	}
 	// unpack file process information
 	var resp TNetsUnpackProcessResp
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func(resp *TNetsUnpackProcessResp) {
+		var err error
+		defer func() { ch <- err }()
 		var algorithm string
 		var work int64
 		// work value
 		err = unpack.WorkCalculate(t.Src, &algorithm, &work)
 		if err != nil || work <= 0 {
 			log.Println("Error calculate unpack work")
-			ch <- false
+			if err == nil {
+				err = NewNetsError(http.StatusUnprocessableEntity, "Illegal parameters!", "unpack work is empty")
+			}
 			return
 		}
 		// done value
@@ -655,14 +717,13 @@ func handleGetNetsUnpackProcess(w http.ResponseWriter, r *http.Request) (err err
 		// assignment
 		(*resp).Done = done
 		(*resp).Work = work
-		ch <- true
 	}(&resp)
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Unpack process failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Unpack process failure:", e)
+				return e
 			}
 			log.Println("Unpack process success.")
 			finish = true
@@ -675,8 +736,7 @@ func handleGetNetsUnpackProcess(w http.ResponseWriter, r *http.Request) (err err
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	// marshal json
@@ -698,9 +758,7 @@ func handlePostNetsUnpackProcess(w http.ResponseWriter, r *http.Request) (err er
 func handlePostNetsUnpackConfine(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -709,9 +767,12 @@ func handlePostNetsUnpackConfine(w http.ResponseWriter, r *http.Request) (err er
 	var t TNetsUnpack
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src, &t.Dest) {
 		return nil
 	}
 	// check request parameters
@@ -721,30 +782,40 @@ func handlePostNetsUnpackConfine(w http.ResponseWriter, r *http.Request) (err er
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src, t.Dest) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "unpack", t.Dest)
+	if !ok {
 		return nil
 	}
 	// start unpack files
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func() {
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
 		err = unpack.UnpackConfine(t.Src, t.Dest)
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
+		observeNetsBytes("unpack", netsPackageAlgorithm(t.Src), t.Src)
 		return
 	}()
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Unpack confine failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Unpack confine failure:", e)
+				return e
 			}
 			log.Println("Unpack confine success.")
 			finish = true
@@ -757,8 +828,7 @@ func handlePostNetsUnpackConfine(w http.ResponseWriter, r *http.Request) (err er
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "text/plain")
@@ -769,9 +839,7 @@ func handlePostNetsUnpackConfine(w http.ResponseWriter, r *http.Request) (err er
 func handlePostNetsUnpackToFile(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -780,9 +848,12 @@ func handlePostNetsUnpackToFile(w http.ResponseWriter, r *http.Request) (err err
 	var t TNetsUnpackToFile
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src, &t.Dest) {
 		return nil
 	}
 	// check request parameters
@@ -792,30 +863,39 @@ func handlePostNetsUnpackToFile(w http.ResponseWriter, r *http.Request) (err err
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src, t.Dest) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "unpack", t.Dest)
+	if !ok {
 		return nil
 	}
 	// unpack file to file
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func() {
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
 		err = unpack.UnpackToFile(t.Src, t.Target, t.Dest)
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
 		return
 	}()
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Unpack to file failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Unpack to file failure:", e)
+				return e
 			}
 			log.Println("Unpack to file success.")
 			finish = true
@@ -828,8 +908,7 @@ func handlePostNetsUnpackToFile(w http.ResponseWriter, r *http.Request) (err err
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -840,9 +919,7 @@ func handlePostNetsUnpackToFile(w http.ResponseWriter, r *http.Request) (err err
 func handlePostNetsUnpackToFileConfine(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -851,9 +928,12 @@ func handlePostNetsUnpackToFileConfine(w http.ResponseWriter, r *http.Request) (
 	var t TNetsUnpackToFile
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src, &t.Dest) {
 		return nil
 	}
 	// check request parameters
@@ -863,30 +943,39 @@ func handlePostNetsUnpackToFileConfine(w http.ResponseWriter, r *http.Request) (
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src, t.Dest) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "unpack", t.Dest)
+	if !ok {
 		return nil
 	}
 	// unpack file to file
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func() {
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
 		err = unpack.UnpackToFileConfine(t.Src, t.Target, t.Dest)
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
 		return
 	}()
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Unpack to file failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Unpack to file failure:", e)
+				return e
 			}
 			log.Println("Unpack to file success.")
 			finish = true
@@ -899,8 +988,7 @@ func handlePostNetsUnpackToFileConfine(w http.ResponseWriter, r *http.Request) (
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -911,9 +999,7 @@ func handlePostNetsUnpackToFileConfine(w http.ResponseWriter, r *http.Request) (
 func handleGetNetsUnpackToMemory(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -922,9 +1008,12 @@ func handleGetNetsUnpackToMemory(w http.ResponseWriter, r *http.Request) (err er
 	var t TNetsUnpackToMemory
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src) {
 		return nil
 	}
 	// check request parameters
@@ -934,31 +1023,41 @@ func handleGetNetsUnpackToMemory(w http.ResponseWriter, r *http.Request) (err er
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "unpack")
+	if !ok {
 		return nil
 	}
 	// unpack file to memory
 	var dest []byte
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func(resp *[]byte) {
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
 		err = unpack.UnpackToMemory(t.Src, t.Target, resp)
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
+		observeNetsMemoryBytes("unpack", netsPackageAlgorithm(t.Src), *resp)
 		return
 	}(&dest)
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Unpack to memory failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Unpack to memory failure:", e)
+				return e
 			}
 			log.Println("Unpack to memory success.")
 			finish = true
@@ -971,8 +1070,7 @@ func handleGetNetsUnpackToMemory(w http.ResponseWriter, r *http.Request) (err er
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -988,9 +1086,7 @@ func handlePostNetsUnpackToMemory(w http.ResponseWriter, r *http.Request) (err e
 func handlePostNetsComp(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -999,9 +1095,12 @@ func handlePostNetsComp(w http.ResponseWriter, r *http.Request) (err error) {
 	var t TNetsComp
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, append(netsPathRefs(t.Src), &t.Dest)...) {
 		return nil
 	}
 	// check request parameters
@@ -1011,9 +1110,12 @@ func handlePostNetsComp(w http.ResponseWriter, r *http.Request) (err error) {
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, append([]string{t.Dest}, t.Src...)...) {
 		return nil
 	}
 	// refactor source files
@@ -1022,25 +1124,43 @@ func handlePostNetsComp(w http.ResponseWriter, r *http.Request) (err error) {
 		log.Println("Error refactor source files:", err)
 		return err
 	}
+	// check source files inside storage roots
+	if !checkNetsRootPaths(w, r, t.Src...) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "comp", t.Dest)
+	if !ok {
+		return nil
+	}
 	// start compress files
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func() {
-		err = comp.Compress(t.Src, t.Dest, t.Type)
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
+		job.SetWork(netsFileSize(t.Src...))
+		if t.Password != "" {
+			err = comp.CompressEncrypt(t.Src, t.Dest, t.Type, t.Password)
+		} else if t.Reproducible {
+			err = comp.CompressReproducibleObserve(t.Src, t.Dest, t.Type, job)
+		} else {
+			err = comp.CompressObserve(t.Src, t.Dest, t.Type, job)
+		}
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
+		observeNetsBytes("comp", t.Type, t.Src...)
 		return
 	}()
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Compress failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Compress failure:", e)
+				return e
 			}
 			log.Println("Compress success.")
 			finish = true
@@ -1053,8 +1173,7 @@ func handlePostNetsComp(w http.ResponseWriter, r *http.Request) (err error) {
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "text/plain")
@@ -1065,9 +1184,7 @@ func handlePostNetsComp(w http.ResponseWriter, r *http.Request) (err error) {
 func handlePostNetsDecomp(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -1076,9 +1193,12 @@ func handlePostNetsDecomp(w http.ResponseWriter, r *http.Request) (err error) {
 	var t TNetsDecomp
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src, &t.Dest) {
 		return nil
 	}
 	// check request parameters
@@ -1088,30 +1208,44 @@ func handlePostNetsDecomp(w http.ResponseWriter, r *http.Request) (err error) {
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src, t.Dest) {
+		return nil
+	}
+	// start job, wait for a heavy job slot
+	job, ok := startNetsJob(w, r, "decomp", t.Dest)
+	if !ok {
 		return nil
 	}
 	// start decompress files
-	ch := make(chan bool)
+	ch := make(chan error, 1)
 	count := 0
 	finish := false
 	go func() {
-		err = decomp.DeCompress(t.Src, t.Dest, t.Type)
+		var err error
+		defer func() { ch <- err }()
+		defer func() { job.Done(err) }()
+		if t.Password != "" {
+			err = decomp.DeCompressDecrypt(t.Src, t.Dest, t.Type, t.Password)
+		} else {
+			err = decomp.DeCompress(t.Src, t.Dest, t.Type)
+		}
 		if err != nil {
-			ch <- false
 			return
 		}
-		ch <- true
+		observeNetsBytes("decomp", t.Type, t.Src)
 		return
 	}()
 	for {
 		select {
-		case r := <-ch:
-			if r == false {
-				log.Println("Decompress failure:", err)
-				return err
+		case e := <-ch:
+			if e != nil {
+				log.Println("Decompress failure:", e)
+				return e
 			}
 			log.Println("Decompress success.")
 			finish = true
@@ -1124,8 +1258,7 @@ func handlePostNetsDecomp(w http.ResponseWriter, r *http.Request) (err error) {
 			break
 		}
 		if count >= NetHttpTimeout {
-			err = errors.New("timeout")
-			return err
+			return ErrNetsTimeout
 		}
 	}
 	w.Header().Set("Content-Type", "text/plain")
@@ -1136,9 +1269,7 @@ func handlePostNetsDecomp(w http.ResponseWriter, r *http.Request) (err error) {
 func handlePostNetsImagesQRCodeToFile(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -1147,9 +1278,12 @@ func handlePostNetsImagesQRCodeToFile(w http.ResponseWriter, r *http.Request) (e
 	var t TNetsImagesQRCodeToFile
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Dest) {
 		return nil
 	}
 	// check request parameters
@@ -1159,9 +1293,12 @@ func handlePostNetsImagesQRCodeToFile(w http.ResponseWriter, r *http.Request) (e
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Dest) {
 		return nil
 	}
 	// generate qrcode
@@ -1180,9 +1317,7 @@ func handlePostNetsImagesQRCodeToFile(w http.ResponseWriter, r *http.Request) (e
 func handlePostNetsImagesQRCodeToMemory(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -1191,9 +1326,8 @@ func handlePostNetsImagesQRCodeToMemory(w http.ResponseWriter, r *http.Request)
 	var t TNetsImagesQRCodeToMemory
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
 		return nil
 	}
 	// check request parameters
@@ -1203,9 +1337,8 @@ func handlePostNetsImagesQRCodeToMemory(w http.ResponseWriter, r *http.Request)
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
 		return nil
 	}
 	// generate qrcode
@@ -1225,9 +1358,7 @@ func handlePostNetsImagesQRCodeToMemory(w http.ResponseWriter, r *http.Request)
 func handleGetNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -1236,9 +1367,12 @@ func handleGetNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 	var t TNetsParsesIni
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src) {
 		return nil
 	}
 	// check request parameters
@@ -1248,9 +1382,12 @@ func handleGetNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src) {
 		return nil
 	}
 	// parses ini get value
@@ -1286,11 +1423,11 @@ func handleGetNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 			}
 			t.Value = strconv.FormatBool(value)
 		default:
-			err = errors.New("unrecognized type name")
+			err = NewNetsError(http.StatusUnprocessableEntity, "Unrecognized type name!", t.Type)
 			return err
 		}
 	default:
-		err = errors.New("unrecognized type name")
+		err = NewNetsError(http.StatusUnprocessableEntity, "Unrecognized mode name!", t.Mode)
 		return err
 	}
 	// marshal json
@@ -1309,9 +1446,7 @@ func handleGetNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 func handlePutNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err error) {
 	defer r.Body.Close()
 	// read request body
-	len := r.ContentLength
-	body := make([]byte, len)
-	body, err = ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error read request body:", err)
 		return err
@@ -1320,9 +1455,12 @@ func handlePutNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 	var t TNetsParsesIni
 	err = json.Unmarshal(body, &t)
 	if err != nil {
-		http.Error(w, "Incorrect request body!", http.StatusBadRequest)
 		log.Println("Error unmarshal json body:", err)
-		log.Printf("%d Bad Request", http.StatusBadRequest)
+		writeNetsError(w, r, http.StatusBadRequest, "Incorrect request body!", err.Error())
+		return nil
+	}
+	// resolve request paths through storage roots
+	if !resolveNetsPaths(w, r, &t.Src) {
 		return nil
 	}
 	// check request parameters
@@ -1332,9 +1470,12 @@ func handlePutNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 		return err
 	}
 	if !b {
-		http.Error(w, "Illegal parameters!", http.StatusUnprocessableEntity)
 		log.Println("Illegal parameters")
-		log.Printf("%d Unprocessable Entity", http.StatusUnprocessableEntity)
+		writeNetsError(w, r, http.StatusUnprocessableEntity, "Illegal parameters!", "")
+		return nil
+	}
+	// check request paths
+	if !checkNetsAuthPaths(w, r, t.Src) {
 		return nil
 	}
 	// parses ini get value
@@ -1352,7 +1493,7 @@ func handlePutNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 			var value int
 			value, err = strconv.Atoi(t.Value)
 			if err != nil {
-				return err
+				return NewNetsError(http.StatusUnprocessableEntity, "Illegal value!", err.Error())
 			}
 			err = parses.SetValueTo(t.Src, t.Section, t.Name, value)
 			if err != nil {
@@ -1362,7 +1503,7 @@ func handlePutNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 			var value float64
 			value, err = strconv.ParseFloat(t.Value, 64)
 			if err != nil {
-				return err
+				return NewNetsError(http.StatusUnprocessableEntity, "Illegal value!", err.Error())
 			}
 			err = parses.SetValueTo(t.Src, t.Section, t.Name, value)
 			if err != nil {
@@ -1372,17 +1513,17 @@ func handlePutNetsParsesIniValue(w http.ResponseWriter, r *http.Request) (err er
 			var value bool
 			value, err = strconv.ParseBool(t.Value)
 			if err != nil {
-				return err
+				return NewNetsError(http.StatusUnprocessableEntity, "Illegal value!", err.Error())
 			}
 			err = parses.SetValueTo(t.Src, t.Section, t.Name, value)
 			if err != nil {
 				return err
 			}
 		default:
-			err = errors.New("unrecognized type name")
+			err = NewNetsError(http.StatusUnprocessableEntity, "Unrecognized type name!", t.Type)
 		}
 	default:
-		err = errors.New("unrecognized type name")
+		err = NewNetsError(http.StatusUnprocessableEntity, "Unrecognized mode name!", t.Mode)
 		return err
 	}
 	// response
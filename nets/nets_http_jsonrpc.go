@@ -0,0 +1,246 @@
+package nets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"reflect"
+	. "satellite/global"
+	"strings"
+)
+
+// tNetsJsonRpcMethod describe GoApi method served over JSON-RPC,
+// Scope is required from principal, Heavy method take a job slot
+type tNetsJsonRpcMethod struct {
+	Scope string
+	Heavy bool
+}
+
+// jsonRpcMethods keyed by GoApi method name,
+// methods not listed need full access
+var jsonRpcMethods = map[string]tNetsJsonRpcMethod{
+	"MD5Encode":   {Scope: "hash:read"},
+	"MD5Equal":    {Scope: "hash:read"},
+	"SHAEncode":   {Scope: "hash:read"},
+	"SHAEqual":    {Scope: "hash:read"},
+	"HMACEncode":  {Scope: "hash:read"},
+	"Pack":        {Scope: "pack:write", Heavy: true},
+	"Unpack":      {Scope: "unpack:write", Heavy: true},
+	"ExtractInfo": {Scope: "unpack:read"},
+	"Compress":    {Scope: "comp:write", Heavy: true},
+	"Decompress":  {Scope: "decomp:write", Heavy: true},
+	"IniGet":      {Scope: "parses:read"},
+	"IniSet":      {Scope: "parses:write"},
+	"QRCode":      {Scope: "images:write"},
+	"Download":    {Scope: "files:read"},
+	"Upload":      {Scope: "files:write"},
+}
+
+var goApiValue = reflect.ValueOf(new(GoApi))
+
+// handleNetsJsonRpc function
+// serve JSON-RPC 2.0 single or batch call of GoApi methods,
+// notifications get no response, 204 when nothing to answer
+func handleNetsJsonRpc(w http.ResponseWriter, r *http.Request) {
+	log.Printf("POST %s", r.RequestURI)
+	defer r.Body.Close()
+	// read request body
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error read request body:", err)
+		handleNetsError(w, r, err)
+		return
+	}
+	body = bytes.TrimSpace(body)
+	// single call
+	if len(body) == 0 || body[0] != '[' {
+		resp := callNetsJsonRpc(r, body)
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeNetsJsonRpc(w, resp)
+		return
+	}
+	// batch call
+	var batch []json.RawMessage
+	err = json.Unmarshal(body, &batch)
+	if err != nil {
+		log.Println("Error unmarshal json body:", err)
+		writeNetsJsonRpc(w, newNetsJsonRpcError(nil, JsonRpcParseError, "Parse error", err.Error()))
+		return
+	}
+	if len(batch) == 0 {
+		writeNetsJsonRpc(w, newNetsJsonRpcError(nil, JsonRpcInvalidRequest, "Invalid Request", "empty batch"))
+		return
+	}
+	if len(batch) > JsonRpcMaxBatch {
+		writeNetsJsonRpc(w, newNetsJsonRpcError(nil, JsonRpcInvalidRequest, "Invalid Request", "too many calls in batch"))
+		return
+	}
+	resps := make([]*TNetsJsonRpcResp, 0, len(batch))
+	for _, v := range batch {
+		if resp := callNetsJsonRpc(r, v); resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeNetsJsonRpc(w, resps)
+}
+
+// callNetsJsonRpc function
+// call one GoApi method, return nil for notification
+func callNetsJsonRpc(r *http.Request, body []byte) *TNetsJsonRpcResp {
+	var t TNetsJsonRpcReq
+	err := json.Unmarshal(body, &t)
+	if err != nil {
+		var s *json.SyntaxError
+		if errors.As(err, &s) || len(body) == 0 {
+			return newNetsJsonRpcError(nil, JsonRpcParseError, "Parse error", err.Error())
+		}
+		return newNetsJsonRpcError(nil, JsonRpcInvalidRequest, "Invalid Request", err.Error())
+	}
+	if t.JsonRpc != JsonRpcVersion || t.Method == "" || !validNetsJsonRpcID(t.ID) {
+		return newNetsJsonRpcError(t.ID, JsonRpcInvalidRequest, "Invalid Request", "")
+	}
+	resp := dispatchNetsJsonRpc(r, t)
+	if t.ID == nil {
+		return nil
+	}
+	return resp
+}
+
+// dispatchNetsJsonRpc function
+// check method, params, scope and paths, then call GoApi method
+func dispatchNetsJsonRpc(r *http.Request, t TNetsJsonRpcReq) *TNetsJsonRpcResp {
+	// look up method
+	name := strings.TrimPrefix(t.Method, "GoApi.")
+	m := goApiValue.MethodByName(name)
+	if name == t.Method || !m.IsValid() || m.Type().NumIn() != 2 || m.Type().In(1).Kind() != reflect.Ptr {
+		return newNetsJsonRpcError(t.ID, JsonRpcMethodNotFound, "Method not found", t.Method)
+	}
+	// decode params, an object or an array of one object
+	params := bytes.TrimSpace(t.Params)
+	if len(params) > 0 && params[0] == '[' {
+		var a []json.RawMessage
+		err := json.Unmarshal(params, &a)
+		if err != nil || len(a) > 1 {
+			return newNetsJsonRpcError(t.ID, JsonRpcInvalidParams, "Invalid params", "params should be an object or an array of one object")
+		}
+		params = nil
+		if len(a) == 1 {
+			params = a[0]
+		}
+	}
+	req := reflect.New(m.Type().In(0))
+	if len(params) > 0 && string(params) != "null" {
+		err := json.Unmarshal(params, req.Interface())
+		if err != nil {
+			return newNetsJsonRpcError(t.ID, JsonRpcInvalidParams, "Invalid params", err.Error())
+		}
+	}
+	// check principal scope and paths
+	method, ok := jsonRpcMethods[name]
+	if !ok {
+		method.Scope = "*"
+	}
+	if p, ok := r.Context().Value(netsAuthKey{}).(*TNetsAuthPrincipal); ok {
+		if !p.HasScope(method.Scope) {
+			log.Printf("Principal %v missing scope %v\n", p.Name, method.Scope)
+			return newNetsJsonRpcError(t.ID, JsonRpcForbidden, "Forbidden", "missing scope "+method.Scope)
+		}
+		for _, v := range jsonRpcPaths(req.Interface()) {
+			path, err := ResolveRootPath(v)
+			if err != nil {
+				return newNetsJsonRpcError(t.ID, JsonRpcInvalidParams, "Invalid params", err.Error())
+			}
+			if path != "" && !p.AllowPath(path) {
+				log.Printf("Principal %v not allowed path: '%v'\n", p.Name, path)
+				return newNetsJsonRpcError(t.ID, JsonRpcForbidden, "Forbidden path", v)
+			}
+		}
+	}
+	// take heavy job slot
+	if method.Heavy && httpLimit != nil {
+		release, err := httpLimit.Acquire(r.Context())
+		if err != nil {
+			log.Println("Error acquire job slot:", err)
+			return newNetsJsonRpcError(t.ID, JsonRpcTooManyJobs, "Too many jobs", err.Error())
+		}
+		defer release()
+	}
+	// call method
+	resp := reflect.New(m.Type().In(1).Elem())
+	out := m.Call([]reflect.Value{req.Elem(), resp})
+	if err, _ := out[0].Interface().(error); err != nil {
+		if errors.Is(err, ErrRpcParameters) {
+			return newNetsJsonRpcError(t.ID, JsonRpcInvalidParams, "Invalid params", err.Error())
+		}
+		if errors.Is(err, ErrRootRequired) {
+			return newNetsJsonRpcError(t.ID, JsonRpcForbidden, "Forbidden path", err.Error())
+		}
+		return newNetsJsonRpcError(t.ID, JsonRpcServerError, "Server error", err.Error())
+	}
+	return &TNetsJsonRpcResp{JsonRpc: JsonRpcVersion, Result: resp.Interface(), ID: t.ID}
+}
+
+// jsonRpcPaths function
+// file paths of GoApi request, checked against principal allowlist
+func jsonRpcPaths(req interface{}) []string {
+	switch v := req.(type) {
+	case *TNetsRpcPackReq:
+		return append([]string{v.Dest}, v.Src...)
+	case *TNetsRpcUnpackReq:
+		return []string{v.Src, v.Dest}
+	case *TNetsRpcExtractInfoReq:
+		return []string{v.Src}
+	case *TNetsRpcCompReq:
+		return append([]string{v.Dest}, v.Src...)
+	case *TNetsRpcDecompReq:
+		return []string{v.Src, v.Dest}
+	case *TNetsRpcIniReq:
+		return []string{v.Src}
+	case *TNetsRpcQRCodeReq:
+		return []string{v.Dest}
+	case *TNetsRpcDownloadReq:
+		return []string{v.Path}
+	case *TNetsRpcUploadReq:
+		return []string{v.Path}
+	}
+	return nil
+}
+
+// validNetsJsonRpcID function
+// id should be a string, a number or null
+func validNetsJsonRpcID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v interface{}
+	if json.Unmarshal(id, &v) != nil {
+		return false
+	}
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+func newNetsJsonRpcError(id json.RawMessage, code int, message string, data string) *TNetsJsonRpcResp {
+	return &TNetsJsonRpcResp{JsonRpc: JsonRpcVersion, Error: &TNetsJsonRpcError{Code: code, Message: message, Data: data}, ID: id}
+}
+
+func writeNetsJsonRpc(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Println("Error encode json-rpc response:", err)
+	}
+}
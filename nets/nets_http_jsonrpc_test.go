@@ -0,0 +1,155 @@
+package nets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	. "satellite/global"
+	"strings"
+	"testing"
+)
+
+const testJsonRpcMD5 = `{"jsonrpc": "2.0", "method": "GoApi.MD5Encode", "params": {"src": "Satellite"}, "id": 1}`
+
+func serveTestJsonRpc(h http.Handler, body string, token string) *httptest.ResponseRecorder {
+	writer := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", HttpURLRpc, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	h.ServeHTTP(writer, request)
+	return writer
+}
+
+func TestHandleNetsJsonRpc(t *testing.T) {
+	h := createHttpRouter()
+	// single call
+	writer := serveTestJsonRpc(h, testJsonRpcMD5, "")
+	var resp struct {
+		JsonRpc string                    `json:"jsonrpc"`
+		Result  TNetsRpcPackMD5EncodeResp `json:"result"`
+		ID      int                       `json:"id"`
+	}
+	err := json.Unmarshal(writer.Body.Bytes(), &resp)
+	if err != nil || writer.Code != http.StatusOK {
+		t.Fatalf("Response code is %v, body %s", writer.Code, writer.Body.String())
+	}
+	if resp.JsonRpc != JsonRpcVersion || resp.ID != 1 || resp.Result.Dest != "c2b5e73361a4bf9d26a73413d0abee5e" {
+		t.Errorf("Wrong response: %+v", resp)
+	}
+	// params in array as Go jsonrpc clients send
+	writer = serveTestJsonRpc(h, `{"jsonrpc": "2.0", "method": "GoApi.MD5Encode", "params": [{"src": "Satellite"}], "id": "a"}`, "")
+	if !strings.Contains(writer.Body.String(), "c2b5e73361a4bf9d26a73413d0abee5e") {
+		t.Errorf("Wrong response of array params: %s", writer.Body.String())
+	}
+	// notification
+	writer = serveTestJsonRpc(h, `{"jsonrpc": "2.0", "method": "GoApi.MD5Encode", "params": {"src": "Satellite"}}`, "")
+	if writer.Code != http.StatusNoContent || writer.Body.Len() != 0 {
+		t.Errorf("Notification response code is %v, body %s", writer.Code, writer.Body.String())
+	}
+}
+
+func BenchmarkHandleNetsJsonRpc(b *testing.B) {
+	h := createHttpRouter()
+	for i := 0; i < b.N; i++ {
+		writer := serveTestJsonRpc(h, testJsonRpcMD5, "")
+		if writer.Code != http.StatusOK {
+			b.Errorf("Response code is %v", writer.Code)
+		}
+	}
+}
+
+func TestHandleNetsJsonRpcBatch(t *testing.T) {
+	h := createHttpRouter()
+	body := `[` + testJsonRpcMD5 + `,
+		{"jsonrpc": "2.0", "method": "GoApi.MD5Encode", "params": {"src": "Satellite"}},
+		{"jsonrpc": "2.0", "method": "GoApi.NotExist", "id": 3},
+		{"jsonrpc": "1.0", "method": "GoApi.MD5Encode", "id": 4},
+		{"jsonrpc": "2.0", "method": "GoApi.MD5Encode", "params": "Satellite", "id": 5},
+		{"jsonrpc": "2.0", "method": "GoApi.QRCode", "params": {"content": "", "size": 256}, "id": 6},
+		{"jsonrpc": "2.0", "method": "GoApi.ExtractInfo", "params": {"src": "not_exist.pak"}, "id": 7},
+		1]`
+	writer := serveTestJsonRpc(h, body, "")
+	var resps []TNetsJsonRpcResp
+	err := json.Unmarshal(writer.Body.Bytes(), &resps)
+	if err != nil {
+		t.Fatalf("Error unmarshal batch response %s: %v", writer.Body.String(), err)
+	}
+	// notification has no response
+	codes := []int{0, JsonRpcMethodNotFound, JsonRpcInvalidRequest, JsonRpcInvalidParams, JsonRpcInvalidParams, JsonRpcForbidden, JsonRpcInvalidRequest}
+	if len(resps) != len(codes) {
+		t.Fatalf("Batch response count is %v: %s", len(resps), writer.Body.String())
+	}
+	for i, v := range resps {
+		code := 0
+		if v.Error != nil {
+			code = v.Error.Code
+		}
+		if code != codes[i] {
+			t.Errorf("Response %v error code is %v, should be %v", i, code, codes[i])
+		}
+	}
+	if string(resps[6].ID) != "null" {
+		t.Errorf("Invalid request id is %s", resps[6].ID)
+	}
+	// parse error and empty batch
+	for k, v := range map[string]int{`{"jsonrpc": "2.0", "method"`: JsonRpcParseError, `[`: JsonRpcParseError, `[]`: JsonRpcInvalidRequest} {
+		writer = serveTestJsonRpc(h, k, "")
+		var resp TNetsJsonRpcResp
+		_ = json.Unmarshal(writer.Body.Bytes(), &resp)
+		if resp.Error == nil || resp.Error.Code != v {
+			t.Errorf("Body %q response is %s", k, writer.Body.String())
+		}
+	}
+}
+
+func BenchmarkHandleNetsJsonRpcBatch(b *testing.B) {
+	h := createHttpRouter()
+	for i := 0; i < b.N; i++ {
+		writer := serveTestJsonRpc(h, `[`+testJsonRpcMD5+`,`+testJsonRpcMD5+`]`, "")
+		if writer.Code != http.StatusOK {
+			b.Errorf("Response code is %v", writer.Code)
+		}
+	}
+}
+
+func TestHandleNetsJsonRpcAuth(t *testing.T) {
+	SetHttpAuth(NewHttpAuth([]TNetsAuthPrincipal{
+		{Name: "caller", Token: "caller-token", Scopes: []string{"rpc:call", "parses:read"}, Dirs: []string{"../test/data/parses"}},
+		{Name: "hasher", Token: "hasher-token", Scopes: []string{"hash:read"}},
+	}))
+	h := createHttpRouter()
+	SetHttpAuth(nil)
+	err := SetHttpRoots([]TNetsRoot{{Name: "data", Path: "../test/data"}})
+	if err != nil {
+		t.Fatal("Error set roots:", err)
+	}
+	defer SetHttpRoots(nil)
+	// route scope
+	if code := serveTestJsonRpc(h, testJsonRpcMD5, "hasher-token").Code; code != http.StatusForbidden {
+		t.Errorf("Response code without rpc scope is %v", code)
+	}
+	// method scope
+	for k, v := range map[string]int{
+		testJsonRpcMD5: JsonRpcForbidden,
+		`{"jsonrpc": "2.0", "method": "GoApi.IniGet", "params": {"src": "data:parses/test_simple.ini", "section": "BOOL", "name": "Switch_On", "type": "bool"}, "id": 1}`: 0,
+		`{"jsonrpc": "2.0", "method": "GoApi.IniGet", "params": {"src": "data:pack/file_1.txt", "section": "BOOL", "name": "Switch_On", "type": "bool"}, "id": 1}`:        JsonRpcForbidden,
+	} {
+		writer := serveTestJsonRpc(h, k, "caller-token")
+		var resp TNetsJsonRpcResp
+		_ = json.Unmarshal(writer.Body.Bytes(), &resp)
+		code := 0
+		if resp.Error != nil {
+			code = resp.Error.Code
+		}
+		if writer.Code != http.StatusOK || code != v {
+			t.Errorf("Body %q response is %s", k, writer.Body.String())
+		}
+	}
+}
+
+func BenchmarkHandleNetsJsonRpcAuth(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		jsonRpcPaths(&TNetsRpcPackReq{Src: []string{"a", "b"}, Dest: "c"})
+	}
+}
@@ -0,0 +1,194 @@
+package nets
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TNetsLimit describe request limits of the REST server
+// Rate and Burst are token bucket of each client ip, Rate 0 disable it
+// MaxJobs heavy jobs run at the same time, MaxQueue more may wait
+// QueueTimeout(Millisecond) a waiting job give up with 429
+// MaxBody(Byte) cap request body size, 0 disable it
+type TNetsLimit struct {
+	Rate         float64 `json:"rate"`
+	Burst        int     `json:"burst"`
+	MaxJobs      int     `json:"max_jobs"`
+	MaxQueue     int     `json:"max_queue"`
+	QueueTimeout int     `json:"queue_timeout"`
+	MaxBody      int64   `json:"max_body"`
+}
+
+type TNetsLimiter struct {
+	Limit   TNetsLimit
+	mutex   sync.Mutex
+	buckets map[string]*tNetsBucket
+	sweep   time.Time
+	jobs    chan struct{}
+	waiting int32
+}
+
+// tNetsBucket is token bucket of one client
+type tNetsBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+var httpLimit *TNetsLimiter
+
+var (
+	ErrLimitQueueFull    = errors.New("job queue full")
+	ErrLimitQueueTimeout = errors.New("job queue timeout")
+)
+
+// NewHttpLimiter function
+// create limiter, burst is at least 1 when rate enabled
+func NewHttpLimiter(l TNetsLimit) *TNetsLimiter {
+	if l.Rate > 0 && l.Burst < 1 {
+		l.Burst = 1
+	}
+	limiter := &TNetsLimiter{Limit: l, buckets: make(map[string]*tNetsBucket), sweep: time.Now()}
+	if l.MaxJobs > 0 {
+		limiter.jobs = make(chan struct{}, l.MaxJobs)
+	}
+	return limiter
+}
+
+// SetHttpLimit function
+// set limiter used by http and https server, nil disable limits
+func SetHttpLimit(l *TNetsLimiter) {
+	httpLimit = l
+}
+
+// Allow function
+// take one token of client, return wait time when bucket empty
+func (l *TNetsLimiter) Allow(client string) (bool, time.Duration) {
+	if l.Limit.Rate <= 0 {
+		return true, 0
+	}
+	now := time.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.sweepBuckets(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tNetsBucket{tokens: float64(l.Limit.Burst), last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(float64(l.Limit.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.Limit.Rate * float64(time.Second))
+}
+
+// sweepBuckets function
+// drop buckets already refilled, at most once a minute
+func (l *TNetsLimiter) sweepBuckets(now time.Time) {
+	if now.Sub(l.sweep) < time.Minute {
+		return
+	}
+	l.sweep = now
+	for k, v := range l.buckets {
+		if v.tokens+now.Sub(v.last).Seconds()*l.Limit.Rate >= float64(l.Limit.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// Middleware function
+// reject clients over rate with 429 and bodies over size with 413
+func (l *TNetsLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.Allow(netsClientIP(r))
+		if !ok {
+			writeNetsRetry(w, r, http.StatusTooManyRequests, "Too many requests!", "rate limit exceeded", wait)
+			return
+		}
+		if l.Limit.MaxBody > 0 {
+			if r.ContentLength > l.Limit.MaxBody {
+				writeNetsError(w, r, http.StatusRequestEntityTooLarge, "Request body too large!", "max "+strconv.FormatInt(l.Limit.MaxBody, 10)+" bytes")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, l.Limit.MaxBody)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Acquire function
+// wait for a heavy job slot, return function to release it
+func (l *TNetsLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l.jobs == nil {
+		return func() {}, nil
+	}
+	release := func() { <-l.jobs }
+	select {
+	case l.jobs <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if int(atomic.AddInt32(&l.waiting, 1)) > l.Limit.MaxQueue {
+		atomic.AddInt32(&l.waiting, -1)
+		return nil, ErrLimitQueueFull
+	}
+	defer atomic.AddInt32(&l.waiting, -1)
+	timer := time.NewTimer(time.Duration(l.Limit.QueueTimeout) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case l.jobs <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrLimitQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// acquireNetsJob function
+// take heavy job slot for request, write 429 when no slot,
+// release should be deferred in the job goroutine
+func acquireNetsJob(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	if httpLimit == nil {
+		return func() {}, true
+	}
+	release, err := httpLimit.Acquire(r.Context())
+	if err != nil {
+		log.Println("Error acquire job slot:", err)
+		wait := time.Duration(httpLimit.Limit.QueueTimeout) * time.Millisecond
+		writeNetsRetry(w, r, http.StatusTooManyRequests, "Too many jobs!", err.Error(), wait)
+		return nil, false
+	}
+	return release, true
+}
+
+// writeNetsRetry function
+// write error envelope with Retry-After in whole seconds
+func writeNetsRetry(w http.ResponseWriter, r *http.Request, status int, message string, details string, wait time.Duration) {
+	sec := int(math.Ceil(wait.Seconds()))
+	if sec < 1 {
+		sec = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(sec))
+	writeNetsError(w, r, status, message, details)
+}
+
+// netsClientIP function
+// client ip of request remote address
+func netsClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
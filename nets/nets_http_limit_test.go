@@ -0,0 +1,128 @@
+package nets
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	. "satellite/global"
+	"strings"
+	"testing"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	l := NewHttpLimiter(TNetsLimit{Rate: 1, Burst: 2})
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("127.0.0.1"); !ok {
+			t.Errorf("Request %v in burst not allowed", i)
+		}
+	}
+	ok, wait := l.Allow("127.0.0.1")
+	if ok || wait <= 0 {
+		t.Errorf("Request over burst allowed: %v, wait %v", ok, wait)
+	}
+	if ok, _ := l.Allow("127.0.0.2"); !ok {
+		t.Error("Request of other client not allowed")
+	}
+}
+
+func BenchmarkLimiterAllow(b *testing.B) {
+	l := NewHttpLimiter(TNetsLimit{Rate: 1e9, Burst: 1e9})
+	for i := 0; i < b.N; i++ {
+		l.Allow("127.0.0.1")
+	}
+}
+
+func TestLimiterMiddleware(t *testing.T) {
+	SetHttpLimit(NewHttpLimiter(TNetsLimit{Rate: 1, Burst: 1, MaxBody: 16}))
+	defer SetHttpLimit(nil)
+	h := createHttpRouter()
+	// body over size by content length
+	writer := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", HttpURLPack, strings.NewReader(strings.Repeat("a", 17)))
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Large body response code is %v", writer.Code)
+	}
+	// over rate
+	writer = httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", HttpURLSatellite, nil)
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusTooManyRequests {
+		t.Errorf("Over rate response code is %v", writer.Code)
+	}
+	if writer.Header().Get("Retry-After") == "" {
+		t.Error("Over rate response without Retry-After")
+	}
+}
+
+func BenchmarkLimiterMiddleware(b *testing.B) {
+	SetHttpLimit(NewHttpLimiter(TNetsLimit{Rate: 1e9, Burst: 1e9, MaxBody: 1 << 20}))
+	defer SetHttpLimit(nil)
+	h := createHttpRouter()
+	for i := 0; i < b.N; i++ {
+		writer := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", HttpURLSatellite, nil)
+		h.ServeHTTP(writer, request)
+	}
+}
+
+func TestLimiterMaxBytes(t *testing.T) {
+	SetHttpLimit(NewHttpLimiter(TNetsLimit{MaxBody: 16}))
+	defer SetHttpLimit(nil)
+	h := createHttpRouter()
+	// body over size without content length
+	writer := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", HttpURLPack, ioutil.NopCloser(strings.NewReader(strings.Repeat("a", 17))))
+	request.ContentLength = -1
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Large chunked body response code is %v", writer.Code)
+	}
+}
+
+func BenchmarkLimiterMaxBytes(b *testing.B) {
+	SetHttpLimit(NewHttpLimiter(TNetsLimit{MaxBody: 16}))
+	defer SetHttpLimit(nil)
+	h := createHttpRouter()
+	for i := 0; i < b.N; i++ {
+		writer := httptest.NewRecorder()
+		request, _ := http.NewRequest("POST", HttpURLPack, strings.NewReader(strings.Repeat("a", 17)))
+		h.ServeHTTP(writer, request)
+	}
+}
+
+func TestLimiterAcquire(t *testing.T) {
+	l := NewHttpLimiter(TNetsLimit{MaxJobs: 1, MaxQueue: 1, QueueTimeout: 50})
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal("Error acquire job slot:", err)
+	}
+	_, err = l.Acquire(context.Background())
+	if err != ErrLimitQueueTimeout {
+		t.Errorf("Acquire busy slot error is %v", err)
+	}
+	l.Limit.MaxQueue = 0
+	_, err = l.Acquire(context.Background())
+	if err != ErrLimitQueueFull {
+		t.Errorf("Acquire with full queue error is %v", err)
+	}
+	release()
+	release, err = l.Acquire(context.Background())
+	if err != nil {
+		t.Errorf("Error acquire released slot: %v", err)
+	}
+	release()
+}
+
+func BenchmarkLimiterAcquire(b *testing.B) {
+	l := NewHttpLimiter(TNetsLimit{MaxJobs: 1})
+	for i := 0; i < b.N; i++ {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			b.Error("Error acquire job slot:", err)
+			continue
+		}
+		release()
+	}
+}
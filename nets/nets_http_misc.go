@@ -3,6 +3,7 @@ package nets
 import (
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	. "satellite/utils"
@@ -24,6 +25,7 @@ func checkNetsPackParameters(t TNetsPack) (b bool, err error) {
 		}
 		if !b {
 			log.Printf("Source file path not exist: '%v'\n", v)
+			err = NewNetsError(http.StatusNotFound, "Source file not exist!", v)
 			return b, err
 		}
 	}
@@ -37,6 +39,7 @@ func checkNetsPackParameters(t TNetsPack) (b bool, err error) {
 	default:
 		b = false
 		fmt.Printf("Algorithm %v not support.\n", t.Type)
+		err = NewNetsError(http.StatusUnprocessableEntity, "Algorithm not support!", t.Type)
 	}
 	return b, err
 }
@@ -56,6 +59,7 @@ func checkNetsUnpackParameters(t TNetsUnpack) (b bool, err error) {
 	}
 	if !b {
 		log.Println("Source file path not exist.")
+		err = NewNetsError(http.StatusNotFound, "Source file not exist!", t.Src)
 		return b, err
 	}
 	return b, err
@@ -77,6 +81,7 @@ func checkNetsPackProcessParameters(t TNetsPackProcessReq) (b bool, err error) {
 		}
 		if !b {
 			log.Printf("Source file path not exist: '%v'\n", v)
+			err = NewNetsError(http.StatusNotFound, "Source file not exist!", v)
 			return b, err
 		}
 	}
@@ -90,6 +95,7 @@ func checkNetsPackProcessParameters(t TNetsPackProcessReq) (b bool, err error) {
 	default:
 		b = false
 		fmt.Printf("Algorithm %v not support.\n", t.Type)
+		err = NewNetsError(http.StatusUnprocessableEntity, "Algorithm not support!", t.Type)
 	}
 	return b, err
 }
@@ -109,6 +115,7 @@ func checkNetsUnpackVerboseParameters(t TNetsUnpackVerboseReq) (b bool, err erro
 	}
 	if !b {
 		log.Println("Source file path not exist.")
+		err = NewNetsError(http.StatusNotFound, "Source file not exist!", t.Src)
 		return b, err
 	}
 	return b, err
@@ -129,6 +136,7 @@ func checkNetsUnpackProcessParameters(t TNetsUnpackProcessReq) (b bool, err erro
 	}
 	if !b {
 		log.Println("Source file path not exist.")
+		err = NewNetsError(http.StatusNotFound, "Source file not exist!", t.Src)
 		return b, err
 	}
 	return b, err
@@ -149,6 +157,7 @@ func checkNetsUnpackToFileParameters(t TNetsUnpackToFile) (b bool, err error) {
 	}
 	if !b {
 		log.Println("Source file path not exist.")
+		err = NewNetsError(http.StatusNotFound, "Source file not exist!", t.Src)
 		return b, err
 	}
 	return b, err
@@ -169,6 +178,7 @@ func checkNetsUnpackToMemoryParameters(t TNetsUnpackToMemory) (b bool, err error
 	}
 	if !b {
 		log.Println("Source file path not exist.")
+		err = NewNetsError(http.StatusNotFound, "Source file not exist!", t.Src)
 		return b, err
 	}
 	return b, err
@@ -190,6 +200,7 @@ func checkNetsCompParameters(t TNetsComp) (b bool, err error) {
 		}
 		if !b {
 			log.Printf("Source file path not exist: '%v'\n", v)
+			err = NewNetsError(http.StatusNotFound, "Source file not exist!", v)
 			return b, err
 		}
 	}
@@ -201,6 +212,17 @@ func checkNetsCompParameters(t TNetsComp) (b bool, err error) {
 	default:
 		b = false
 		fmt.Printf("Algorithm %v not support.\n", t.Type)
+		err = NewNetsError(http.StatusUnprocessableEntity, "Algorithm not support!", t.Type)
+	}
+	// check password, only zip support encryption
+	if t.Password != "" && t.Type != "ZIP" && t.Type != "zip" {
+		b = false
+		fmt.Printf("Algorithm %v not support password.\n", t.Type)
+	}
+	// check reproducible, encryption always use random salt
+	if t.Password != "" && t.Reproducible {
+		b = false
+		fmt.Println("Password not support reproducible.")
 	}
 	return b, err
 }
@@ -220,6 +242,7 @@ func checkNetsDecompParameters(t TNetsDecomp) (b bool, err error) {
 	}
 	if !b {
 		log.Println("Source file path not exist.")
+		err = NewNetsError(http.StatusNotFound, "Source file not exist!", t.Src)
 		return b, err
 	}
 	// check algorithm
@@ -230,6 +253,12 @@ func checkNetsDecompParameters(t TNetsDecomp) (b bool, err error) {
 	default:
 		b = false
 		fmt.Printf("Algorithm %v not support.\n", t.Type)
+		err = NewNetsError(http.StatusUnprocessableEntity, "Algorithm not support!", t.Type)
+	}
+	// check password, only zip support encryption
+	if t.Password != "" && t.Type != "ZIP" && t.Type != "zip" {
+		b = false
+		fmt.Printf("Algorithm %v not support password.\n", t.Type)
 	}
 	return b, err
 }
@@ -283,6 +312,7 @@ func checkNetsParsesIniValueParameters(t TNetsParsesIni) (b bool, err error) {
 	}
 	if !b {
 		log.Println("Source file path not exist.")
+		err = NewNetsError(http.StatusNotFound, "Source file not exist!", t.Src)
 		return b, err
 	}
 	// check parses mode
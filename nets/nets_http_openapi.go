@@ -0,0 +1,281 @@
+package nets
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	. "satellite/global"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TNetsRouteDoc describe one operation of the REST API
+// Request and Response are zero values of body types, nil for no body
+// Produces is the response content type
+type TNetsRouteDoc struct {
+	Summary  string
+	Request  interface{}
+	Response interface{}
+	Produces string
+}
+
+type tNetsRawBody struct{}
+
+// httpRouteDocs keyed by "METHOD path template",
+// every route in createHttpRouter must be documented here
+var httpRouteDocs = map[string]TNetsRouteDoc{
+	"GET " + HttpURLRoot:                  {Summary: "Hello world", Produces: "text/plain"},
+	"GET " + HttpURLSatellite:             {Summary: "Service name", Produces: "text/plain"},
+	"GET " + HttpURLOpenAPI:               {Summary: "OpenAPI specification of this API", Produces: "application/json"},
+	"GET " + HttpURLExplorer:              {Summary: "Minimal HTTP explorer page", Produces: "text/html"},
+	"GET " + HttpURLMetrics:               {Summary: "Prometheus metrics", Produces: "text/plain"},
+	"POST " + HttpURLPack:                 {Summary: "Pack files", Request: TNetsPack{}, Produces: "text/plain"},
+	"POST " + HttpURLUnpack:               {Summary: "Unpack package", Request: TNetsUnpack{}, Produces: "text/plain"},
+	"GET " + HttpURLPackProcess:           {Summary: "Pack process", Request: TNetsPackProcessReq{}, Response: TNetsPackProcessResp{}, Produces: "application/json"},
+	"POST " + HttpURLPackProcess:          {Summary: "Pack process", Request: TNetsPackProcessReq{}, Response: TNetsPackProcessResp{}, Produces: "application/json"},
+	"GET " + HttpURLUnpackVerbose:         {Summary: "List package files", Request: TNetsUnpackVerboseReq{}, Response: TNetsUnpackVerboseResp{}, Produces: "application/json"},
+	"POST " + HttpURLUnpackVerbose:        {Summary: "List package files", Request: TNetsUnpackVerboseReq{}, Response: TNetsUnpackVerboseResp{}, Produces: "application/json"},
+	"GET " + HttpURLUnpackProcess:         {Summary: "Unpack process", Request: TNetsUnpackProcessReq{}, Response: TNetsUnpackProcessResp{}, Produces: "application/json"},
+	"POST " + HttpURLUnpackProcess:        {Summary: "Unpack process", Request: TNetsUnpackProcessReq{}, Response: TNetsUnpackProcessResp{}, Produces: "application/json"},
+	"POST " + HttpURLUnpackConfine:        {Summary: "Unpack package with confined concurrency", Request: TNetsUnpack{}, Produces: "text/plain"},
+	"POST " + HttpURLUnpackToFile:         {Summary: "Unpack one file of package", Request: TNetsUnpackToFile{}, Produces: "application/json"},
+	"POST " + HttpURLUnpackToFileConfine:  {Summary: "Unpack one file of package with confined concurrency", Request: TNetsUnpackToFile{}, Produces: "application/json"},
+	"GET " + HttpURLUnpackToMemory:        {Summary: "Unpack one file of package into response body", Request: TNetsUnpackToMemory{}, Response: tNetsRawBody{}, Produces: "application/json"},
+	"POST " + HttpURLUnpackToMemory:       {Summary: "Unpack one file of package into response body", Request: TNetsUnpackToMemory{}, Response: tNetsRawBody{}, Produces: "application/json"},
+	"POST " + HttpURLComp:                 {Summary: "Compress files", Request: TNetsComp{}, Produces: "text/plain"},
+	"POST " + HttpURLDecomp:               {Summary: "Decompress archive", Request: TNetsDecomp{}, Produces: "text/plain"},
+	"POST " + HttpURLImagesQRCodeToFile:   {Summary: "Generate QR code PNG file", Request: TNetsImagesQRCodeToFile{}, Produces: "text/plain"},
+	"POST " + HttpURLImagesQRCodeToMemory: {Summary: "Generate QR code PNG into response body", Request: TNetsImagesQRCodeToMemory{}, Response: tNetsRawBody{}, Produces: "application/json"},
+	"GET " + HttpURLParsesIni:             {Summary: "Get INI value", Request: TNetsParsesIni{}, Response: TNetsParsesIni{}, Produces: "application/json"},
+	"PUT " + HttpURLParsesIni:             {Summary: "Set INI value", Request: TNetsParsesIni{}, Produces: "application/json"},
+	"GET " + HttpURLJobEvents:             {Summary: "Server-Sent Events of job started with X-Request-ID id", Response: TNetsJobEvent{}, Produces: "text/event-stream"},
+	"POST " + HttpURLRpc:                  {Summary: "JSON-RPC 2.0 call of GoApi methods, a batch is an array of requests", Request: TNetsJsonRpcReq{}, Response: TNetsJsonRpcResp{}, Produces: "application/json"},
+	"GET " + HttpURLPeers:                 {Summary: "Satellite nodes found by discovery of server started with -announce", Response: TNetsPeersResp{}, Produces: "application/json"},
+}
+
+// GenerateOpenAPI function
+// generate OpenAPI 3 document from routes of createHttpRouter
+// and request/response types in httpRouteDocs
+func GenerateOpenAPI() (spec []byte, err error) {
+	schemas := make(map[string]interface{})
+	openAPISchema(reflect.TypeOf(TNetsError{}), schemas)
+	paths := make(map[string]interface{})
+	err = createHttpRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		ops, ok := paths[path].(map[string]interface{})
+		if !ok {
+			ops = make(map[string]interface{})
+			paths[path] = ops
+		}
+		for _, m := range methods {
+			doc, ok := httpRouteDocs[m+" "+path]
+			if !ok {
+				return errors.New("route not documented: " + m + " " + path)
+			}
+			ops[strings.ToLower(m)] = openAPIOperation(m, path, doc, schemas)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Error walk http router:", err)
+		return nil, err
+	}
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Satellite REST API",
+			"version":     AppVersion,
+			"description": "Request bodies are JSON even for GET. Errors use the TNetsError envelope. Secured operations accept a bearer token, an HMAC signed request or a verified mTLS client certificate.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error envelope, the request id is also in X-Request-ID header",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": openAPIRef("TNetsError")},
+					},
+				},
+			},
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+				"hmacAuth": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        HttpHeaderAuthKey,
+					"description": "HMAC-SHA256 of method, request uri, " + HttpHeaderAuthTimestamp + ", " + HttpHeaderAuthNonce + " and body sha256 joined by newline, in " + HttpHeaderAuthSignature + ", each nonce is accepted once",
+				},
+			},
+		},
+	}
+	spec, err = json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(spec, '\n'), nil
+}
+
+// openAPIOperation function
+// describe one operation, collect body types into schemas
+func openAPIOperation(method string, path string, doc TNetsRouteDoc, schemas map[string]interface{}) map[string]interface{} {
+	op := map[string]interface{}{
+		"summary":     doc.Summary,
+		"operationId": openAPIOperationID(method, path),
+	}
+	var params []interface{}
+	for _, v := range strings.Split(path, "/") {
+		if strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}") {
+			params = append(params, map[string]interface{}{
+				"name":     strings.Trim(v, "{}"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if doc.Request != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": openAPISchema(reflect.TypeOf(doc.Request), schemas)},
+			},
+		}
+	}
+	ok := map[string]interface{}{"description": "OK"}
+	content := map[string]interface{}{}
+	switch doc.Response.(type) {
+	case nil:
+		content["schema"] = map[string]interface{}{"type": "string"}
+	case tNetsRawBody:
+		content["schema"] = map[string]interface{}{"type": "string", "format": "binary"}
+	default:
+		content["schema"] = openAPISchema(reflect.TypeOf(doc.Response), schemas)
+	}
+	ok["content"] = map[string]interface{}{doc.Produces: content}
+	op["responses"] = map[string]interface{}{
+		"200":     ok,
+		"default": map[string]interface{}{"$ref": "#/components/responses/Error"},
+	}
+	if scope := httpRouteScope(path, method); scope != "" {
+		op["security"] = []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"hmacAuth": []string{}},
+		}
+		op["x-satellite-scope"] = scope
+	}
+	return op
+}
+
+// openAPISchema function
+// convert go type to schema, structs are put into schemas and referenced
+func openAPISchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	// raw json accept any value
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openAPISchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(t.Elem(), schemas)}
+	case reflect.Ptr:
+		return openAPISchema(t.Elem(), schemas)
+	case reflect.Struct:
+		if _, ok := schemas[t.Name()]; !ok {
+			// placeholder first, struct may reference itself
+			schemas[t.Name()] = nil
+			schemas[t.Name()] = openAPIStructSchema(t, schemas)
+		}
+		return openAPIRef(t.Name())
+	}
+	return map[string]interface{}{}
+}
+
+// openAPIStructSchema function
+// object schema of struct fields by json tags,
+// fields without omitempty are required
+func openAPIStructSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if tag[0] == "-" || f.PkgPath != "" {
+			continue
+		}
+		name := tag[0]
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = openAPISchema(f.Type, schemas)
+		if len(tag) < 2 || tag[1] != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+// openAPIOperationID function
+// such as "postSatellitePackP" for "POST /satellite/pack/p"
+// and "getSatelliteJobsIdEvents" for "GET /satellite/jobs/{id}/events"
+func openAPIOperationID(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, v := range strings.FieldsFunc(path, func(c rune) bool { return c == '/' || c == '.' || c == '{' || c == '}' }) {
+		id += strings.ToUpper(v[:1]) + v[1:]
+	}
+	return id
+}
+
+func openAPIRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func handleNetsOpenAPI(w http.ResponseWriter, r *http.Request) {
+	spec, err := GenerateOpenAPI()
+	if err != nil {
+		handleNetsError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+	log.Printf("%d Ok", http.StatusOK)
+}
+
+func handleNetsExplorer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(httpExplorerPage))
+	log.Printf("%d Ok", http.StatusOK)
+}
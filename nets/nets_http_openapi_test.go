@@ -0,0 +1,92 @@
+package nets
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	. "satellite/global"
+	"testing"
+)
+
+// run with SATELLITE_UPDATE_OPENAPI=1 to regenerate the golden file
+const testOpenAPIGolden = "../doc/openapi.json"
+
+func TestGenerateOpenAPI(t *testing.T) {
+	spec, err := GenerateOpenAPI()
+	if err != nil {
+		t.Fatal("Error generate openapi:", err)
+	}
+	if os.Getenv("SATELLITE_UPDATE_OPENAPI") != "" {
+		err = ioutil.WriteFile(testOpenAPIGolden, spec, 0644)
+		if err != nil {
+			t.Fatal("Error write openapi:", err)
+		}
+	}
+	golden, err := ioutil.ReadFile(testOpenAPIGolden)
+	if err != nil {
+		t.Fatal("Error read openapi:", err)
+	}
+	if !bytes.Equal(spec, golden) {
+		t.Error("Routes or types changed, regenerate doc/openapi.json with SATELLITE_UPDATE_OPENAPI=1")
+	}
+	// every request/response type should be documented
+	var doc struct {
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	_ = json.Unmarshal(spec, &doc)
+	f, err := parser.ParseFile(token.NewFileSet(), "nets_http_type.go", nil, 0)
+	if err != nil {
+		t.Fatal("Error parse types:", err)
+	}
+	for _, v := range f.Decls {
+		g, ok := v.(*ast.GenDecl)
+		if !ok || g.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range g.Specs {
+			name := s.(*ast.TypeSpec).Name.Name
+			if _, ok := doc.Components.Schemas[name]; !ok {
+				t.Errorf("Type %v not in openapi schemas", name)
+			}
+		}
+	}
+}
+
+func BenchmarkGenerateOpenAPI(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, err := GenerateOpenAPI()
+		if err != nil {
+			b.Error("Error generate openapi:", err)
+		}
+	}
+}
+
+func TestHandleNetsOpenAPI(t *testing.T) {
+	for _, v := range []string{HttpURLOpenAPI, HttpURLExplorer} {
+		writer := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", v, nil)
+		createHttpRouter().ServeHTTP(writer, request)
+		if writer.Code != http.StatusOK || writer.Body.Len() == 0 {
+			t.Errorf("%v response code is %v", v, writer.Code)
+		}
+	}
+}
+
+func BenchmarkHandleNetsOpenAPI(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		writer := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", HttpURLOpenAPI, nil)
+		createHttpRouter().ServeHTTP(writer, request)
+		if writer.Code != http.StatusOK {
+			b.Errorf("Response code is %v", writer.Code)
+		}
+	}
+}
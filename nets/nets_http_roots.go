@@ -0,0 +1,203 @@
+package nets
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	. "satellite/utils"
+	"sort"
+	"strings"
+)
+
+// TNetsRoot describe a named storage root
+// requests reference paths as "name:relative/path"
+type TNetsRoot struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+var httpRoots map[string]string
+
+var (
+	ErrRootFormat   = errors.New("path should be in form of root:relative/path")
+	ErrRootNotExist = errors.New("storage root not exist")
+	ErrRootEscape   = errors.New("path escape storage root")
+	ErrRootRequired = errors.New("storage roots required for path parameters")
+)
+
+// ParseHttpRoots function
+// parse roots string such as "inbox=/srv/inbox,outbox=/srv/outbox"
+func ParseHttpRoots(s string) (roots []TNetsRoot, err error) {
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" || strings.ContainsAny(kv[0], ":/\\") {
+			return nil, errors.New("illegal storage root: " + v)
+		}
+		roots = append(roots, TNetsRoot{Name: kv[0], Path: kv[1]})
+	}
+	return roots, nil
+}
+
+// SetHttpRoots function
+// confine path-taking endpoints to storage roots, nil to disable
+// root directories are created when not exist
+func SetHttpRoots(roots []TNetsRoot) error {
+	if roots == nil {
+		httpRoots = nil
+		return nil
+	}
+	m := make(map[string]string)
+	for _, v := range roots {
+		if _, ok := m[v.Name]; ok {
+			return errors.New("duplicate storage root: " + v.Name)
+		}
+		err := os.MkdirAll(v.Path, os.ModePerm)
+		if err != nil {
+			log.Println("Error create storage root:", err)
+			return err
+		}
+		path, err := filepath.Abs(v.Path)
+		if err != nil {
+			return err
+		}
+		path, err = filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		m[v.Name] = path
+	}
+	httpRoots = m
+	return nil
+}
+
+// HttpRoots function
+// return configured storage roots sorted by name
+func HttpRoots() (roots []TNetsRoot) {
+	for k, v := range httpRoots {
+		roots = append(roots, TNetsRoot{Name: k, Path: v})
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Name < roots[j].Name })
+	return roots
+}
+
+// ResolveRootPath function
+// resolve "root:relative/path" to real path inside the root,
+// path is returned as it is when no storage roots configured
+// trailing separator is kept since unpack dest depend on it
+func ResolveRootPath(path string) (string, error) {
+	if httpRoots == nil || path == "" {
+		return path, nil
+	}
+	kv := strings.SplitN(path, ":", 2)
+	if len(kv) != 2 {
+		return "", ErrRootFormat
+	}
+	root, ok := httpRoots[kv[0]]
+	if !ok {
+		return "", ErrRootNotExist
+	}
+	rel := filepath.FromSlash(kv[1])
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return "", ErrRootFormat
+	}
+	dest, err := RealPath(filepath.Join(root, rel))
+	if err != nil {
+		return "", err
+	}
+	if !PathWithin(root, dest) {
+		return "", ErrRootEscape
+	}
+	if strings.HasSuffix(kv[1], "/") || strings.HasSuffix(kv[1], "\\") {
+		dest += string(filepath.Separator)
+	}
+	return dest, nil
+}
+
+// netsPathRefs function
+// return pointers of path list elements for resolveNetsPaths
+func netsPathRefs(paths []string) (refs []*string) {
+	for i := range paths {
+		refs = append(refs, &paths[i])
+	}
+	return refs
+}
+
+// resolveNetsPaths function
+// resolve request paths in place through storage roots,
+// write 403 response and return false when any path rejected
+func resolveNetsPaths(w http.ResponseWriter, r *http.Request, paths ...*string) bool {
+	err := resolveRootPaths(paths...)
+	if err != nil {
+		writeNetsError(w, r, http.StatusForbidden, "Forbidden path!", err.Error())
+		return false
+	}
+	return true
+}
+
+// resolveRootPaths function
+// resolve paths in place through storage roots, error when any path rejected
+func resolveRootPaths(paths ...*string) error {
+	for _, v := range paths {
+		path, err := ResolveRootPath(*v)
+		if err != nil {
+			log.Printf("Error resolve path '%v': %v\n", *v, err)
+			return err
+		}
+		*v = path
+	}
+	return nil
+}
+
+// resolveRpcPaths function
+// same as resolveRootPaths, but refuse when no storage roots configured,
+// rpc methods would read and write any path of the server otherwise
+func resolveRpcPaths(paths ...*string) error {
+	if httpRoots == nil {
+		log.Println("Error resolve rpc path:", ErrRootRequired)
+		return ErrRootRequired
+	}
+	return resolveRootPaths(paths...)
+}
+
+// checkNetsRootPaths function
+// check expanded source files still inside storage roots,
+// symlinks inside source directories may point anywhere
+func checkNetsRootPaths(w http.ResponseWriter, r *http.Request, paths ...string) bool {
+	err := checkRootPaths(paths...)
+	if err != nil {
+		writeNetsError(w, r, http.StatusForbidden, "Forbidden path!", err.Error())
+		return false
+	}
+	return true
+}
+
+// checkRootPaths function
+// same as checkNetsRootPaths, return error instead of response
+func checkRootPaths(paths ...string) error {
+	if httpRoots == nil {
+		return nil
+	}
+	for _, v := range paths {
+		path, err := RealPath(v)
+		if err == nil {
+			err = ErrRootEscape
+			for _, root := range httpRoots {
+				if PathWithin(root, path) {
+					err = nil
+					break
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("Error check path '%v': %v\n", v, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package nets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	"strings"
+	"testing"
+)
+
+func setTestHttpRoots(t testing.TB) (inbox string, outside string) {
+	dir, err := ioutil.TempDir("", "satellite-roots")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	inbox = filepath.Join(dir, "inbox")
+	outside = filepath.Join(dir, "outside")
+	_ = os.MkdirAll(outside, os.ModePerm)
+	err = SetHttpRoots([]TNetsRoot{{Name: "inbox", Path: inbox}, {Name: "parses", Path: "../test/data/parses"}})
+	if err != nil {
+		t.Fatal("Error set storage roots:", err)
+	}
+	return inbox, outside
+}
+
+func TestResolveRootPath(t *testing.T) {
+	inbox, outside := setTestHttpRoots(t)
+	defer os.RemoveAll(filepath.Dir(inbox))
+	defer SetHttpRoots(nil)
+
+	inbox, _ = filepath.EvalSymlinks(inbox)
+	path, err := ResolveRootPath("inbox:dir/file.txt")
+	if err != nil || path != filepath.Join(inbox, "dir", "file.txt") {
+		t.Errorf("Resolve path is %v, error %v", path, err)
+	}
+	path, err = ResolveRootPath("inbox:dir/")
+	if err != nil || path != filepath.Join(inbox, "dir")+string(filepath.Separator) {
+		t.Errorf("Resolve path with trailing separator is %v, error %v", path, err)
+	}
+	for _, v := range []string{"inbox:../outside/file.txt", "inbox:/etc/passwd", "unknown:file.txt", "/etc/passwd"} {
+		_, err = ResolveRootPath(v)
+		if err == nil {
+			t.Errorf("Resolve path '%v' should fail", v)
+		}
+	}
+	// symlink escape
+	err = os.Symlink(outside, filepath.Join(inbox, "link"))
+	if err != nil {
+		t.Skip("Symlink not supported:", err)
+	}
+	_, err = ResolveRootPath("inbox:link/file.txt")
+	if err != ErrRootEscape {
+		t.Errorf("Resolve symlink escape path error is %v", err)
+	}
+}
+
+func BenchmarkResolveRootPath(b *testing.B) {
+	inbox, _ := setTestHttpRoots(b)
+	defer os.RemoveAll(filepath.Dir(inbox))
+	defer SetHttpRoots(nil)
+	for i := 0; i < b.N; i++ {
+		_, err := ResolveRootPath("inbox:dir/file.txt")
+		if err != nil {
+			b.Error("Error resolve path:", err)
+		}
+	}
+}
+
+func TestHandleNetsRoots(t *testing.T) {
+	inbox, _ := setTestHttpRoots(t)
+	defer os.RemoveAll(filepath.Dir(inbox))
+	defer SetHttpRoots(nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc(HttpURLParsesIni, handleNetsParsesIniValue)
+
+	writer := httptest.NewRecorder()
+	body := strings.NewReader(`{"src": "parses:test_simple.ini", "mode": "get", "section": "BOOL", "name": "Switch_On", "type": "bool", "value": ""}`)
+	request, _ := http.NewRequest("GET", HttpURLParsesIni, body)
+	mux.ServeHTTP(writer, request)
+	if writer.Code != http.StatusOK {
+		t.Errorf("Response code is %v", writer.Code)
+	}
+
+	writer = httptest.NewRecorder()
+	body = strings.NewReader(`{"src": "../test/data/parses/test_simple.ini", "mode": "get", "section": "BOOL", "name": "Switch_On", "type": "bool", "value": ""}`)
+	request, _ = http.NewRequest("GET", HttpURLParsesIni, body)
+	mux.ServeHTTP(writer, request)
+	if writer.Code != http.StatusForbidden {
+		t.Errorf("Response code without root is %v", writer.Code)
+	}
+}
+
+func BenchmarkHandleNetsRoots(b *testing.B) {
+	inbox, _ := setTestHttpRoots(b)
+	defer os.RemoveAll(filepath.Dir(inbox))
+	defer SetHttpRoots(nil)
+	for i := 0; i < b.N; i++ {
+		mux := http.NewServeMux()
+		mux.HandleFunc(HttpURLParsesIni, handleNetsParsesIniValue)
+
+		writer := httptest.NewRecorder()
+		body := strings.NewReader(`{"src": "parses:test_simple.ini", "mode": "get", "section": "BOOL", "name": "Switch_On", "type": "bool", "value": ""}`)
+		request, _ := http.NewRequest("GET", HttpURLParsesIni, body)
+		mux.ServeHTTP(writer, request)
+		if writer.Code != http.StatusOK {
+			b.Errorf("Response code is %v", writer.Code)
+		}
+	}
+}
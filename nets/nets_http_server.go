@@ -1,23 +1,85 @@
 package nets
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	. "satellite/global"
 	"time"
 )
 
-func StartHttpServer(ip string, port string) {
-	server := http.Server{
-		Addr:         ip + ":" + port,
-		WriteTimeout: HTTPWriteTimeout * time.Millisecond,
-		ReadTimeout:  HTTPReadTimeout * time.Millisecond,
-		Handler:      createHttpRouter(),
+// TNetsHttpServer is REST server over http or https,
+// Shutdown stop accepting, drain handlers and jobs within ctx,
+// jobs are registered per server so shutdown leave other servers running
+type TNetsHttpServer struct {
+	Name     string
+	server   *http.Server
+	reloader *TNetsCertReloader
+	jobs     *TNetsJobs
+	stop     chan struct{}
+}
+
+// NewHttpServer function
+// create http server listen on ip:port
+func NewHttpServer(ip string, port string) *TNetsHttpServer {
+	jobs := NewJobRegistry()
+	return &TNetsHttpServer{
+		Name: CmdHttp,
+		server: &http.Server{
+			Addr:         ip + ":" + port,
+			WriteTimeout: HTTPWriteTimeout * time.Millisecond,
+			ReadTimeout:  HTTPReadTimeout * time.Millisecond,
+			Handler:      withNetsJobs(jobs, createHttpRouter()),
+		},
+		jobs: jobs,
+		stop: make(chan struct{}),
 	}
-	fmt.Println("Start Listen And Server on ", ip+":"+port)
-	err := server.ListenAndServe()
+}
+
+// ListenAndServe function
+// serve until Shutdown, https when server has TLS config
+func (s *TNetsHttpServer) ListenAndServe() error {
+	fmt.Println("Start Listen And Server on ", s.server.Addr)
+	l, err := net.Listen("tcp", s.server.Addr)
 	if err != nil {
+		SetSubsystemState(s.Name, false, err.Error())
 		log.Println("Error Listen And Server:", err)
+		return err
+	}
+	SetSubsystemState(s.Name, true, "listening on "+l.Addr().String())
+	if s.server.TLSConfig != nil {
+		go s.reloader.Watch(HttpsReloadInterval*time.Second, s.stop)
+		err = s.server.ServeTLS(l, "", "")
+	} else {
+		err = s.server.Serve(l)
+	}
+	SetSubsystemState(s.Name, false, "stopped")
+	if err == http.ErrServerClosed {
+		return nil
 	}
+	log.Println("Error Listen And Server:", err)
+	return err
+}
+
+// Shutdown function
+// stop accepting and wait running requests and jobs,
+// jobs still running when ctx done are aborted
+func (s *TNetsHttpServer) Shutdown(ctx context.Context) error {
+	SetSubsystemState(s.Name, false, "shutting down")
+	err := s.server.Shutdown(ctx)
+	if e := s.jobs.Shutdown(ctx); err == nil {
+		err = e
+	}
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	return err
+}
+
+func StartHttpServer(ip string, port string) error {
+	return RunServer(NewHttpServer(ip, port), NetShutdownTimeout*time.Millisecond)
 }
@@ -1,9 +1,12 @@
 package nets
 
+import "encoding/json"
+
 type TNetsPack struct {
-	Src  []string `json:"src"`
-	Dest string   `json:"dest"`
-	Type string   `json:"type"`
+	Src          []string `json:"src"`
+	Dest         string   `json:"dest"`
+	Type         string   `json:"type"`
+	Reproducible bool     `json:"reproducible,omitempty"`
 }
 
 type TNetsUnpack struct {
@@ -56,15 +59,18 @@ type TNetsUnpackToMemory struct {
 }
 
 type TNetsComp struct {
-	Src  []string `json:"src"`
-	Dest string   `json:"dest"`
-	Type string   `json:"type"`
+	Src          []string `json:"src"`
+	Dest         string   `json:"dest"`
+	Type         string   `json:"type"`
+	Password     string   `json:"password,omitempty"`
+	Reproducible bool     `json:"reproducible,omitempty"`
 }
 
 type TNetsDecomp struct {
-	Src  string `json:"src"`
-	Dest string `json:"dest"`
-	Type string `json:"type"`
+	Src      string `json:"src"`
+	Dest     string `json:"dest"`
+	Type     string `json:"type"`
+	Password string `json:"password,omitempty"`
 }
 
 type TNetsImagesQRCodeToMemory struct {
@@ -86,3 +92,54 @@ type TNetsParsesIni struct {
 	Type    string `json:"type"`
 	Value   string `json:"value"`
 }
+
+// TNetsJobEvent is one event of job stream, Type is one of
+// "start", "entry_start", "entry_finish", "progress" and "result"
+type TNetsJobEvent struct {
+	Seq       int64  `json:"seq"`
+	Type      string `json:"type"`
+	Operation string `json:"operation"`
+	Entry     string `json:"entry,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	Done      int64  `json:"done"`
+	Work      int64  `json:"work"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TNetsJsonRpcReq is JSON-RPC 2.0 request, a batch is an array of them,
+// Method such as "GoApi.MD5Encode", Params is request object of method
+// or an array of it, request without ID is a notification
+type TNetsJsonRpcReq struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// TNetsJsonRpcResp is JSON-RPC 2.0 response, Result or Error is set
+type TNetsJsonRpcResp struct {
+	JsonRpc string             `json:"jsonrpc"`
+	Result  interface{}        `json:"result,omitempty"`
+	Error   *TNetsJsonRpcError `json:"error,omitempty"`
+	ID      json.RawMessage    `json:"id"`
+}
+
+type TNetsJsonRpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// TNetsPeer is satellite node found by discovery,
+// Services map service name such as "http" to port
+type TNetsPeer struct {
+	Name     string            `json:"name"`
+	Addr     string            `json:"addr"`
+	Version  string            `json:"version"`
+	Services map[string]string `json:"services"`
+	LastSeen string            `json:"last_seen"`
+}
+
+type TNetsPeersResp struct {
+	Peers []TNetsPeer `json:"peers"`
+}
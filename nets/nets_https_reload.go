@@ -0,0 +1,98 @@
+package nets
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TNetsCertReloader hold certificate loaded from PEM files,
+// handshakes always get the latest loaded certificate
+type TNetsCertReloader struct {
+	certFile string
+	keyFile  string
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modTime  time.Time
+}
+
+// NewCertReloader function
+// load certificate and key file for the first time
+func NewCertReloader(certFile string, keyFile string) (r *TNetsCertReloader, err error) {
+	r = &TNetsCertReloader{certFile: certFile, keyFile: keyFile}
+	err = r.Reload()
+	if err != nil {
+		log.Println("Error load certificate:", err)
+		return nil, err
+	}
+	return r, err
+}
+
+// Reload function
+// load certificate and key file again, keep the old one on error
+func (r *TNetsCertReloader) Reload() error {
+	modTime := r.fileModTime()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate function
+// used as tls config GetCertificate
+func (r *TNetsCertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch function
+// reload certificate on SIGHUP or certificate file change until stop closed
+func (r *TNetsCertReloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sig:
+		case <-ticker.C:
+			r.mu.RLock()
+			changed := !r.fileModTime().Equal(r.modTime)
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+		}
+		err := r.Reload()
+		if err != nil {
+			log.Println("Error reload certificate:", err)
+			continue
+		}
+		log.Println("Certificate reloaded:", r.certFile)
+	}
+}
+
+// fileModTime function
+// the latest modify time of certificate and key file
+func (r *TNetsCertReloader) fileModTime() (t time.Time) {
+	for _, v := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(v)
+		if err == nil && info.ModTime().After(t) {
+			t = info.ModTime()
+		}
+	}
+	return t
+}
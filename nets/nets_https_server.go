@@ -1,27 +1,179 @@
 package nets
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
-	"net/http"
 	. "satellite/global"
+	"strings"
 	"time"
 )
 
-func StartHttpsServer(ip string, port string) {
-	server := http.Server{
-		Addr:         ip + ":" + port,
-		WriteTimeout: HTTPWriteTimeout * time.Millisecond,
-		ReadTimeout:  HTTPReadTimeout * time.Millisecond,
-		Handler:      createHttpRouter(),
+// TNetsHttpsConfig describe certificate and TLS policy of https server
+// Cert and Key are PEM files, reloaded on SIGHUP or file change
+// SelfSigned generate certificate for Hosts into Cert and Key
+// MinVersion such as "1.2", "1.3"
+// CipherSuites such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+// TLS 1.3 cipher suites are not configurable
+// ClientCA is PEM CA bundle used to verify mTLS client certificates
+type TNetsHttpsConfig struct {
+	Cert              string   `json:"cert"`
+	Key               string   `json:"key"`
+	SelfSigned        bool     `json:"self_signed"`
+	Hosts             []string `json:"hosts"`
+	MinVersion        string   `json:"min_version"`
+	CipherSuites      []string `json:"cipher_suites"`
+	ClientCA          string   `json:"client_ca"`
+	RequireClientCert bool     `json:"require_client_cert"`
+}
+
+// LoadHttpsConfig function
+// load https config from json file
+func LoadHttpsConfig(path string) (c TNetsHttpsConfig, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println("Error read https config:", err)
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		log.Println("Error unmarshal https config:", err)
+		return c, err
+	}
+	return c, err
+}
+
+// ParseTLSVersion function
+// convert version string such as "1.2" to tls version
+func ParseTLSVersion(s string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	}
+	return 0, errors.New("unknown TLS version: " + s)
+}
+
+// ParseCipherSuites function
+// convert cipher suite names to ids, insecure suites are refused
+func ParseCipherSuites(names []string) (ids []uint16, err error) {
+	suites := make(map[string]uint16)
+	for _, v := range tls.CipherSuites() {
+		suites[v.Name] = v.ID
+	}
+	for _, v := range names {
+		id, ok := suites[strings.TrimSpace(v)]
+		if !ok {
+			return nil, errors.New("unknown or insecure cipher suite: " + v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// NewHttpsTLSConfig function
+// create tls config and certificate reloader from https config
+func NewHttpsTLSConfig(c TNetsHttpsConfig) (t *tls.Config, r *TNetsCertReloader, err error) {
+	if c.SelfSigned {
+		if c.Cert == "" {
+			c.Cert = HttpsCertFile
+		}
+		if c.Key == "" {
+			c.Key = HttpsKeyFile
+		}
+		err = GenerateCertificate(c.Hosts, c.Cert, c.Key)
+		if err != nil {
+			log.Println("Error generate self-signed certificate:", err)
+			return nil, nil, err
+		}
+	}
+	if c.Cert == "" || c.Key == "" {
+		return nil, nil, errors.New("certificate and key file required, or use self-signed certificate")
 	}
-	fmt.Println("Start Listen And Server on ", ip+":"+port)
-	err := GenerateCA(ip)
+	r, err = NewCertReloader(c.Cert, c.Key)
 	if err != nil {
-		log.Println("Error Create CA:", err)
+		return nil, nil, err
 	}
-	err = server.ListenAndServeTLS("cert.pem", "key.pem")
+	if c.MinVersion == "" {
+		c.MinVersion = HttpsMinVersion
+	}
+	version, err := ParseTLSVersion(c.MinVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	suites, err := ParseCipherSuites(c.CipherSuites)
+	if err != nil {
+		return nil, nil, err
+	}
+	t = &tls.Config{
+		GetCertificate: r.GetCertificate,
+		MinVersion:     version,
+		CipherSuites:   suites,
+	}
+	// client certificates are optional unless required,
+	// auth decide which certificate subjects allowed
+	if c.ClientCA != "" {
+		data, err := ioutil.ReadFile(c.ClientCA)
+		if err != nil {
+			log.Println("Error read client CA:", err)
+			return nil, nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, nil, errors.New("no certificate found in client CA")
+		}
+		t.ClientCAs = pool
+		t.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.RequireClientCert {
+			t.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if c.RequireClientCert {
+		return nil, nil, errors.New("client CA required to verify client certificates")
+	}
+	return t, r, nil
+}
+
+// NewHttpsServer function
+// create https server listen on ip:port with certificate and TLS policy in config
+func NewHttpsServer(ip string, port string, c TNetsHttpsConfig) (*TNetsHttpServer, error) {
+	if c.SelfSigned && len(c.Hosts) == 0 {
+		c.Hosts = []string{ip}
+	}
+	t, r, err := NewHttpsTLSConfig(c)
+	if err != nil {
+		SetSubsystemState(CmdHttps, false, err.Error())
+		log.Println("Error create TLS config:", err)
+		return nil, err
+	}
+	s := NewHttpServer(ip, port)
+	s.Name = CmdHttps
+	s.server.TLSConfig = t
+	s.reloader = r
+	return s, nil
+}
+
+// StartHttpsServer function
+// start https server with self-signed certificate for ip
+func StartHttpsServer(ip string, port string) error {
+	return StartHttpsServerWithConfig(ip, port, TNetsHttpsConfig{SelfSigned: true, Hosts: []string{ip}})
+}
+
+// StartHttpsServerWithConfig function
+// start https server with certificate and TLS policy in config
+func StartHttpsServerWithConfig(ip string, port string, c TNetsHttpsConfig) error {
+	s, err := NewHttpsServer(ip, port, c)
 	if err != nil {
-		log.Println("Error Listen And Server:", err)
+		fmt.Println("Error create TLS config:", err)
+		return err
 	}
+	return RunServer(s, NetShutdownTimeout*time.Millisecond)
 }
@@ -0,0 +1,139 @@
+package nets
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "satellite-https")
+	defer os.RemoveAll(dir)
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	err := GenerateCertificate([]string{"127.0.0.1", "::1", "localhost", "satellite.local"}, cert, key)
+	if err != nil {
+		t.Fatal("Error generate certificate:", err)
+	}
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal("Error load certificate:", err)
+	}
+	c, _ := x509.ParseCertificate(pair.Certificate[0])
+	for _, v := range []string{"127.0.0.1", "::1", "localhost", "satellite.local"} {
+		if c.VerifyHostname(v) != nil {
+			t.Errorf("Certificate not valid for %v", v)
+		}
+	}
+}
+
+func BenchmarkGenerateCertificate(b *testing.B) {
+	dir, _ := ioutil.TempDir("", "satellite-https")
+	defer os.RemoveAll(dir)
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for i := 0; i < b.N; i++ {
+		err := GenerateCertificate([]string{"127.0.0.1", "localhost"}, cert, key)
+		if err != nil {
+			b.Error("Error generate certificate:", err)
+		}
+	}
+}
+
+func TestNewHttpsTLSConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "satellite-https")
+	defer os.RemoveAll(dir)
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	c, _, err := NewHttpsTLSConfig(TNetsHttpsConfig{
+		Cert:         cert,
+		Key:          key,
+		SelfSigned:   true,
+		Hosts:        []string{"localhost"},
+		MinVersion:   "1.3",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	})
+	if err != nil {
+		t.Fatal("Error create TLS config:", err)
+	}
+	if c.MinVersion != tls.VersionTLS13 || len(c.CipherSuites) != 1 {
+		t.Errorf("TLS config min version %x cipher suites %v", c.MinVersion, c.CipherSuites)
+	}
+	// existing certificate
+	_, _, err = NewHttpsTLSConfig(TNetsHttpsConfig{Cert: cert, Key: key})
+	if err != nil {
+		t.Error("Error create TLS config with existing certificate:", err)
+	}
+	// illegal policy
+	for _, v := range []TNetsHttpsConfig{
+		{},
+		{Cert: cert, Key: key, MinVersion: "2.0"},
+		{Cert: cert, Key: key, CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		{Cert: cert, Key: key, RequireClientCert: true},
+	} {
+		_, _, err = NewHttpsTLSConfig(v)
+		if err == nil {
+			t.Errorf("Create TLS config %+v should fail", v)
+		}
+	}
+}
+
+func BenchmarkNewHttpsTLSConfig(b *testing.B) {
+	dir, _ := ioutil.TempDir("", "satellite-https")
+	defer os.RemoveAll(dir)
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	_ = GenerateCertificate([]string{"localhost"}, cert, key)
+	for i := 0; i < b.N; i++ {
+		_, _, err := NewHttpsTLSConfig(TNetsHttpsConfig{Cert: cert, Key: key})
+		if err != nil {
+			b.Error("Error create TLS config:", err)
+		}
+	}
+}
+
+func TestCertReloaderWatch(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "satellite-https")
+	defer os.RemoveAll(dir)
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	_ = GenerateCertificate([]string{"localhost"}, cert, key)
+
+	r, err := NewCertReloader(cert, key)
+	if err != nil {
+		t.Fatal("Error load certificate:", err)
+	}
+	old, _ := r.GetCertificate(nil)
+	stop := make(chan struct{})
+	defer close(stop)
+	go r.Watch(10*time.Millisecond, stop)
+
+	// make sure modify time changed on coarse file systems
+	time.Sleep(20 * time.Millisecond)
+	_ = GenerateCertificate([]string{"satellite.local"}, cert, key)
+	future := time.Now().Add(time.Second)
+	_ = os.Chtimes(cert, future, future)
+	for i := 0; i < 100; i++ {
+		c, _ := r.GetCertificate(nil)
+		if string(c.Certificate[0]) != string(old.Certificate[0]) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Certificate not reloaded after file change")
+}
+
+func BenchmarkCertReloaderReload(b *testing.B) {
+	dir, _ := ioutil.TempDir("", "satellite-https")
+	defer os.RemoveAll(dir)
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	_ = GenerateCertificate([]string{"localhost"}, cert, key)
+	r, _ := NewCertReloader(cert, key)
+	for i := 0; i < b.N; i++ {
+		err := r.Reload()
+		if err != nil {
+			b.Error("Error reload certificate:", err)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package nets
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	"sync"
+	"time"
+)
+
+// TNetsJob is a heavy job registered for graceful shutdown,
+// outputs created or modified by a failed job are removed,
+// progress is published to the event stream of job id,
+// ctx is canceled when shutdown abort the job
+type TNetsJob struct {
+	ID        string
+	Operation string
+	Start     time.Time
+	ctx       context.Context
+	cancel    context.CancelFunc
+	stream    *tNetsJobStream
+	outputs   []tNetsJobOutput
+	release   func()
+	finish    func()
+	jobs      *TNetsJobs
+	once      sync.Once
+}
+
+// tNetsJobOutput is snapshot of an output path before job start
+// entries are names inside an existing output directory
+type tNetsJobOutput struct {
+	path    string
+	exist   bool
+	modTime time.Time
+	entries map[string]bool
+}
+
+// tNetsJobStream is event history of job id, notify is closed
+// and replaced on every event to wake up all subscribers
+type tNetsJobStream struct {
+	mutex   sync.Mutex
+	events  []TNetsJobEvent
+	seq     int64
+	done    int64
+	work    int64
+	owner   string
+	started bool
+	finish  bool
+	expire  time.Time
+	notify  chan struct{}
+}
+
+// TNetsJobs is registry of running jobs and their event streams
+type TNetsJobs struct {
+	mutex   sync.Mutex
+	jobs    map[*TNetsJob]struct{}
+	streams map[string]*tNetsJobStream
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// netsJobs is registry of routers not served by TNetsHttpServer,
+// every server has its own registry in request context
+var netsJobs = NewJobRegistry()
+
+// netsJobsKey is context key of job registry of server
+type netsJobsKey struct{}
+
+// withNetsJobs function
+// serve next with job registry in request context
+func withNetsJobs(jobs *TNetsJobs, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), netsJobsKey{}, jobs)))
+	})
+}
+
+// netsJobsOf function
+// job registry of server handling request
+func netsJobsOf(r *http.Request) *TNetsJobs {
+	if jobs, ok := r.Context().Value(netsJobsKey{}).(*TNetsJobs); ok {
+		return jobs
+	}
+	return netsJobs
+}
+
+var (
+	ErrJobsClosed  = errors.New("server is shutting down")
+	ErrJobsAborted = errors.New("job aborted by shutdown")
+	ErrJobsRunning = errors.New("job id already running")
+)
+
+// NewJobRegistry function
+// create empty job registry
+func NewJobRegistry() *TNetsJobs {
+	return &TNetsJobs{jobs: make(map[*TNetsJob]struct{}), streams: make(map[string]*tNetsJobStream)}
+}
+
+// Start function
+// register job of operation, release is called when job done,
+// outputs are files or directories the job writes
+func (j *TNetsJobs) Start(id string, operation string, release func(), outputs ...string) (*TNetsJob, error) {
+	return j.StartOwner(id, operation, "", release, outputs...)
+}
+
+// StartOwner function
+// same as Start, owner is the principal name allowed to subscribe job events,
+// empty owner for server without auth, id of a running job is refused
+func (j *TNetsJobs) StartOwner(id string, operation string, owner string, release func(), outputs ...string) (*TNetsJob, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.closing {
+		return nil, ErrJobsClosed
+	}
+	// a finished stream of the same id is replaced by the new job
+	s := j.stream(id)
+	s.mutex.Lock()
+	if s.started && !s.finish {
+		s.mutex.Unlock()
+		return nil, ErrJobsRunning
+	}
+	if s.finish {
+		s.mutex.Unlock()
+		s = &tNetsJobStream{notify: make(chan struct{})}
+		j.streams[id] = s
+		s.mutex.Lock()
+	}
+	s.owner = owner
+	s.started = true
+	s.expire = time.Time{}
+	s.mutex.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &TNetsJob{ID: id, Operation: operation, Start: time.Now(), ctx: ctx, cancel: cancel, stream: s, release: release, jobs: j}
+	job.publish(TNetsJobEvent{Type: "start"})
+	for _, v := range outputs {
+		if v != "" {
+			job.outputs = append(job.outputs, snapshotJobOutput(v))
+		}
+	}
+	job.finish = observeNetsJob(operation)
+	j.jobs[job] = struct{}{}
+	j.wg.Add(1)
+	return job, nil
+}
+
+// Jobs function
+// running jobs
+func (j *TNetsJobs) Jobs() (jobs []*TNetsJob) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for k := range j.jobs {
+		jobs = append(jobs, k)
+	}
+	return jobs
+}
+
+// subscribe function
+// event stream of job id, created before the job starts
+// so clients may subscribe first and start the job later
+func (j *TNetsJobs) subscribe(id string) *tNetsJobStream {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.stream(id)
+}
+
+// stream function
+// get or create stream of id and drop expired streams, j.mutex must be held
+func (j *TNetsJobs) stream(id string) *tNetsJobStream {
+	now := time.Now()
+	for k, v := range j.streams {
+		v.mutex.Lock()
+		expired := !v.expire.IsZero() && now.After(v.expire)
+		v.mutex.Unlock()
+		if expired {
+			delete(j.streams, k)
+		}
+	}
+	s, ok := j.streams[id]
+	if !ok {
+		s = &tNetsJobStream{notify: make(chan struct{}), expire: now.Add(HttpJobEventRetention * time.Millisecond)}
+		j.streams[id] = s
+	}
+	return s
+}
+
+// since function
+// events after seq, channel closed on next event,
+// whether job started and whether job finished
+func (s *tNetsJobStream) since(seq int64) (events []TNetsJobEvent, notify <-chan struct{}, started bool, finish bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, v := range s.events {
+		if v.Seq > seq {
+			events = append(events, v)
+		}
+	}
+	return events, s.notify, s.started, s.finish
+}
+
+// jobOwner function
+// principal name of job, empty when server without auth
+func (s *tNetsJobStream) jobOwner() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.owner
+}
+
+// Shutdown function
+// refuse new jobs and wait running jobs, when ctx done cancel the rest
+// and wait their workers stop, the workers remove partial outputs in Done
+func (j *TNetsJobs) Shutdown(ctx context.Context) error {
+	j.mutex.Lock()
+	j.closing = true
+	j.mutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	for _, v := range j.Jobs() {
+		log.Printf("Abort job %v %v\n", v.Operation, v.ID)
+		v.cancel()
+	}
+	<-done
+	return ctx.Err()
+}
+
+// Context function
+// context of job, canceled when shutdown abort the job
+func (job *TNetsJob) Context() context.Context {
+	return job.ctx
+}
+
+// Err function
+// implement Canceler, ErrJobsAborted once shutdown abort the job,
+// pack, unpack and comp stop at the next entry or read
+func (job *TNetsJob) Err() error {
+	if job.ctx.Err() != nil {
+		return ErrJobsAborted
+	}
+	return nil
+}
+
+// SetWork function
+// total bytes of job, published as progress event
+func (job *TNetsJob) SetWork(work int64) {
+	job.stream.mutex.Lock()
+	job.stream.work = work
+	job.stream.mutex.Unlock()
+	job.publish(TNetsJobEvent{Type: "progress"})
+}
+
+// EntryStart function
+// implement Observer, publish entry start event
+func (job *TNetsJob) EntryStart(name string, size int64) {
+	job.publish(TNetsJobEvent{Type: "entry_start", Entry: name, Size: size})
+}
+
+// EntryFinish function
+// implement Observer, publish entry finish and progress events
+func (job *TNetsJob) EntryFinish(name string, size int64, err error) {
+	e := TNetsJobEvent{Type: "entry_finish", Entry: name, Size: size}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		job.stream.mutex.Lock()
+		job.stream.done += size
+		job.stream.mutex.Unlock()
+	}
+	job.publish(e)
+	job.publish(TNetsJobEvent{Type: "progress"})
+}
+
+// publish function
+// append event to stream with next seq, current done and work,
+// drop oldest events over history limit and wake up subscribers
+func (job *TNetsJob) publish(e TNetsJobEvent) {
+	s := job.stream
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.seq++
+	e.Seq = s.seq
+	e.Operation = job.Operation
+	e.Done = s.done
+	e.Work = s.work
+	s.events = append(s.events, e)
+	if len(s.events) > HttpJobEventHistory {
+		s.events = s.events[len(s.events)-HttpJobEventHistory:]
+	}
+	if e.Type == "result" {
+		s.finish = true
+		s.expire = time.Now().Add(HttpJobEventRetention * time.Millisecond)
+	}
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// Done function
+// unregister job, remove partial outputs when err not nil,
+// publish result event, only the first call takes effect
+func (job *TNetsJob) Done(err error) {
+	job.once.Do(func() {
+		e := TNetsJobEvent{Type: "result"}
+		if err != nil {
+			e.Error = err.Error()
+			for _, v := range job.outputs {
+				v.remove()
+			}
+		}
+		job.publish(e)
+		job.cancel()
+		if job.release != nil {
+			job.release()
+		}
+		job.finish()
+		job.jobs.mutex.Lock()
+		delete(job.jobs.jobs, job)
+		job.jobs.mutex.Unlock()
+		job.jobs.wg.Done()
+	})
+}
+
+// snapshotJobOutput function
+// record whether output exist, its modify time and directory entries
+func snapshotJobOutput(path string) tNetsJobOutput {
+	o := tNetsJobOutput{path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return o
+	}
+	o.exist = true
+	o.modTime = info.ModTime()
+	if info.IsDir() {
+		o.entries = make(map[string]bool)
+		files, _ := ioutil.ReadDir(path)
+		for _, v := range files {
+			o.entries[v.Name()] = true
+		}
+	}
+	return o
+}
+
+// remove function
+// remove output created by job, new entries of existing directory
+// or existing file modified by job, keep everything else untouched
+func (o tNetsJobOutput) remove() {
+	info, err := os.Stat(o.path)
+	if err != nil {
+		return
+	}
+	var paths []string
+	switch {
+	case !o.exist:
+		paths = append(paths, o.path)
+	case o.entries != nil && info.IsDir():
+		files, _ := ioutil.ReadDir(o.path)
+		for _, v := range files {
+			if !o.entries[v.Name()] {
+				paths = append(paths, filepath.Join(o.path, v.Name()))
+			}
+		}
+	case o.entries == nil && !info.IsDir() && !info.ModTime().Equal(o.modTime):
+		paths = append(paths, o.path)
+	}
+	for _, v := range paths {
+		log.Println("Remove partial output:", v)
+		err = os.RemoveAll(v)
+		if err != nil {
+			log.Println("Error remove partial output:", err)
+		}
+	}
+}
+
+// startNetsJob function
+// wait for a heavy job slot and register job of request,
+// write 409, 429 or 503 and return false when job can not start
+func startNetsJob(w http.ResponseWriter, r *http.Request, operation string, outputs ...string) (*TNetsJob, bool) {
+	release, ok := acquireNetsJob(w, r)
+	if !ok {
+		return nil, false
+	}
+	owner := ""
+	if p, ok := r.Context().Value(netsAuthKey{}).(*TNetsAuthPrincipal); ok {
+		owner = p.Name
+	}
+	job, err := netsJobsOf(r).StartOwner(netsRequestID(r), operation, owner, release, outputs...)
+	if err == ErrJobsRunning {
+		release()
+		writeNetsError(w, r, http.StatusConflict, "Job already running!", netsRequestID(r))
+		return nil, false
+	}
+	if err != nil {
+		release()
+		writeNetsRetry(w, r, http.StatusServiceUnavailable, "Service unavailable!", err.Error(), time.Second)
+		return nil, false
+	}
+	return job, true
+}
@@ -0,0 +1,145 @@
+package nets
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJobDone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	keep := filepath.Join(dir, "keep.txt")
+	dest := filepath.Join(dir, "new.pak")
+	_ = ioutil.WriteFile(keep, []byte("keep"), 0644)
+	jobs := NewJobRegistry()
+	// successful job keep outputs
+	job, err := jobs.Start("1", "pack", nil, dest)
+	if err != nil {
+		t.Fatal("Error start job:", err)
+	}
+	_ = ioutil.WriteFile(dest, []byte("pack"), 0644)
+	job.Done(nil)
+	if _, err = os.Stat(dest); err != nil {
+		t.Errorf("Output of successful job removed: %v", err)
+	}
+	_ = os.Remove(dest)
+	// failed job remove outputs it created
+	job, err = jobs.Start("2", "unpack", nil, dest, dir)
+	if err != nil {
+		t.Fatal("Error start job:", err)
+	}
+	_ = ioutil.WriteFile(dest, []byte("pack"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(dir, "partial.txt"), []byte("part"), 0644)
+	job.Done(errors.New("failure"))
+	if _, err = os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("Partial output %v not removed", dest)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "partial.txt")); !os.IsNotExist(err) {
+		t.Error("Partial output partial.txt not removed")
+	}
+	if _, err = os.Stat(keep); err != nil {
+		t.Errorf("Existing file removed: %v", err)
+	}
+	if len(jobs.Jobs()) != 0 {
+		t.Errorf("Jobs still registered: %v", jobs.Jobs())
+	}
+}
+
+func BenchmarkJobDone(b *testing.B) {
+	jobs := NewJobRegistry()
+	for i := 0; i < b.N; i++ {
+		job, err := jobs.Start("1", "pack", nil, "../test/data/pack/file.txt")
+		if err != nil {
+			b.Fatal("Error start job:", err)
+		}
+		job.Done(nil)
+	}
+}
+
+func TestJobsRunningID(t *testing.T) {
+	jobs := NewJobRegistry()
+	job, err := jobs.StartOwner("1", "pack", "alice", nil)
+	if err != nil {
+		t.Fatal("Error start job:", err)
+	}
+	_, err = jobs.StartOwner("1", "unpack", "bob", nil)
+	if err != ErrJobsRunning {
+		t.Errorf("Start job with running id error is %v", err)
+	}
+	if owner := job.stream.jobOwner(); owner != "alice" {
+		t.Errorf("Owner of running job changed to %v", owner)
+	}
+	job.Done(nil)
+	// finished id can be used again
+	job, err = jobs.StartOwner("1", "unpack", "bob", nil)
+	if err != nil {
+		t.Fatal("Error start job with finished id:", err)
+	}
+	job.Done(nil)
+}
+
+func TestJobsShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	jobs := NewJobRegistry()
+	released := false
+	output := filepath.Join(dir, "out.bin")
+	job, err := jobs.Start("1", "pack", func() { released = true }, output)
+	if err != nil {
+		t.Fatal("Error start job:", err)
+	}
+	// worker keep writing output until the job is canceled
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		f, _ := os.Create(output)
+		for job.Err() == nil {
+			f.Write([]byte("satellite"))
+			time.Sleep(time.Millisecond)
+		}
+		f.Close()
+		job.Done(job.Err())
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = jobs.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Shutdown with running job error is %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Shutdown returned before worker stopped")
+	}
+	if !released || len(jobs.Jobs()) != 0 {
+		t.Error("Running job not aborted")
+	}
+	_, err = os.Stat(output)
+	if !os.IsNotExist(err) {
+		t.Error("Partial output of aborted job not removed")
+	}
+	_, err = jobs.Start("2", "pack", nil)
+	if err != ErrJobsClosed {
+		t.Errorf("Start job after shutdown error is %v", err)
+	}
+}
+
+func BenchmarkJobsShutdown(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		jobs := NewJobRegistry()
+		job, _ := jobs.Start("1", "pack", nil)
+		job.Done(nil)
+		_ = jobs.Shutdown(context.Background())
+	}
+}
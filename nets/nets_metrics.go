@@ -0,0 +1,163 @@
+package nets
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"satellite/metrics"
+	"satellite/unpack"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	metricHttpRequests = metrics.NewCounterVec("satellite_http_requests_total", "HTTP requests by route, method and status code.", "route", "method", "code")
+	metricHttpDuration = metrics.NewHistogramVec("satellite_http_request_duration_seconds", "HTTP request latency by route and method.", metrics.DefBuckets, "route", "method")
+	metricBytes        = metrics.NewCounterVec("satellite_bytes_total", "Source bytes processed by operation and algorithm.", "operation", "algorithm")
+	metricActiveJobs   = metrics.NewGaugeVec("satellite_active_jobs", "Jobs in progress by operation.", "operation")
+	metricErrors       = metrics.NewCounterVec("satellite_errors_total", "Error responses by type.", "type")
+	metricRpcCalls     = metrics.NewCounterVec("satellite_rpc_calls_total", "GoApi RPC calls by method and status.", "method", "status")
+	metricRpcDuration  = metrics.NewHistogramVec("satellite_rpc_call_duration_seconds", "GoApi RPC call latency by method.", metrics.DefBuckets, "method")
+	metricForwardBytes = metrics.NewCounterVec("satellite_forward_bytes_total", "Forwarded bytes by protocol and direction.", "protocol", "direction")
+	metricForwardConns = metrics.NewGaugeVec("satellite_forward_active", "Forwarded tcp connections or udp sessions in progress by protocol.", "protocol")
+)
+
+// netsStatusWriter record response status code for metrics
+type netsStatusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *netsStatusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *netsStatusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *netsStatusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap function
+// let http.ResponseController reach the underlying writer
+func (w *netsStatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// metricsMiddleware function
+// count requests and observe latency by route template
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		if v := mux.CurrentRoute(r); v != nil {
+			route, _ = v.GetPathTemplate()
+		}
+		start := time.Now()
+		sw := &netsStatusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.code == 0 {
+			sw.code = http.StatusOK
+		}
+		metricHttpRequests.Inc(route, r.Method, strconv.Itoa(sw.code))
+		metricHttpDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+func handleNetsMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics.Handler().ServeHTTP(w, r)
+}
+
+// observeNetsJob function
+// count active job, return function to call when job finished
+func observeNetsJob(operation string) func() {
+	metricActiveJobs.Inc(operation)
+	return func() {
+		metricActiveJobs.Dec(operation)
+	}
+}
+
+// observeNetsBytes function
+// add source file sizes processed by operation and algorithm
+func observeNetsBytes(operation string, algorithm string, paths ...string) {
+	metricBytes.Add(float64(netsFileSize(paths...)), operation, strings.ToLower(algorithm))
+}
+
+// netsFileSize function
+// total size of regular files in paths
+func netsFileSize(paths ...string) (n int64) {
+	for _, v := range paths {
+		info, err := os.Stat(v)
+		if err == nil && !info.IsDir() {
+			n += info.Size()
+		}
+	}
+	return n
+}
+
+// observeNetsMemoryBytes function
+// add bytes in memory processed by operation and algorithm
+func observeNetsMemoryBytes(operation string, algorithm string, b []byte) {
+	metricBytes.Add(float64(len(b)), operation, strings.ToLower(algorithm))
+}
+
+// netsPackageAlgorithm function
+// algorithm in package header, "unknown" when not readable
+func netsPackageAlgorithm(src string) string {
+	f, err := os.Open(src)
+	if err != nil {
+		return "unknown"
+	}
+	defer f.Close()
+	head := make([]byte, 56)
+	_, err = io.ReadFull(f, head)
+	if err != nil {
+		return "unknown"
+	}
+	return strings.ToLower(strings.TrimRight(string(head[48:56]), "\x00 "))
+}
+
+// netsPackageSize function
+// total origin size of files in package, 0 when not readable
+func netsPackageSize(src string) (n int64) {
+	var files []string
+	var sizes []int
+	var algorithm string
+	err := unpack.ExtractInfo(src, &files, &sizes, &algorithm)
+	if err != nil {
+		return 0
+	}
+	for _, v := range sizes {
+		n += int64(v)
+	}
+	return n
+}
+
+// observeNetsError function
+// count error response by status text such as "not_found"
+func observeNetsError(code int) {
+	metricErrors.Inc(strings.Replace(strings.ToLower(http.StatusText(code)), " ", "_", -1))
+}
+
+// observeRpcCall function
+// used as defer observeRpcCall("Method", time.Now(), &err)
+func observeRpcCall(method string, start time.Time, err *error) {
+	status := "ok"
+	if *err != nil {
+		status = "error"
+	}
+	metricRpcCalls.Inc(method, status)
+	metricRpcDuration.Observe(time.Since(start).Seconds(), method)
+}
@@ -0,0 +1,64 @@
+package nets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	. "satellite/global"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleNetsMetrics(t *testing.T) {
+	h := createHttpRouter()
+	request, _ := http.NewRequest("GET", HttpURLSatellite, nil)
+	h.ServeHTTP(httptest.NewRecorder(), request)
+	request, _ = http.NewRequest("POST", HttpURLPack, strings.NewReader(`{`))
+	h.ServeHTTP(httptest.NewRecorder(), request)
+	observeNetsBytes("pack", "AES", "../test/data/pack/file.txt")
+	var err error
+	observeRpcCall("MD5Encode", time.Now(), &err)
+
+	writer := httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", HttpURLMetrics, nil)
+	h.ServeHTTP(writer, request)
+	if writer.Code != http.StatusOK {
+		t.Errorf("Response code is %v", writer.Code)
+	}
+	for _, v := range []string{
+		`satellite_http_requests_total{route="/satellite",method="GET",code="200"}`,
+		`satellite_http_request_duration_seconds_bucket{route="/satellite",method="GET",le="+Inf"}`,
+		`satellite_http_requests_total{route="/satellite/pack",method="POST",code="400"}`,
+		`satellite_errors_total{type="bad_request"}`,
+		`satellite_bytes_total{operation="pack",algorithm="aes"}`,
+		`satellite_rpc_calls_total{method="MD5Encode",status="ok"}`,
+	} {
+		if !strings.Contains(writer.Body.String(), v) {
+			t.Errorf("Metrics not contain %v", v)
+		}
+	}
+}
+
+func BenchmarkHandleNetsMetrics(b *testing.B) {
+	h := createHttpRouter()
+	for i := 0; i < b.N; i++ {
+		writer := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", HttpURLMetrics, nil)
+		h.ServeHTTP(writer, request)
+		if writer.Code != http.StatusOK {
+			b.Errorf("Response code is %v", writer.Code)
+		}
+	}
+}
+
+func TestNetsPackageAlgorithm(t *testing.T) {
+	if v := netsPackageAlgorithm("../test/data/pack/not_exist.pak"); v != "unknown" {
+		t.Errorf("Algorithm of not exist package is %v", v)
+	}
+}
+
+func BenchmarkNetsPackageAlgorithm(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		netsPackageAlgorithm("../test/data/pack/file.txt")
+	}
+}
@@ -2,13 +2,30 @@ package nets
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"satellite/comp"
+	"satellite/decomp"
+	. "satellite/global"
+	"satellite/images"
 	"satellite/pack"
+	"satellite/parses"
+	"satellite/unpack"
+	"strconv"
+	"time"
+
+	"github.com/skip2/go-qrcode"
 )
 
 type GoApi struct {
 }
 
+var ErrRpcParameters = errors.New("illegal parameters")
+
 func (api *GoApi) MD5Encode(request TNetsRpcPackMD5EncodeReq, response *TNetsRpcPackMD5EncodeResp) (err error) {
+	defer observeRpcCall("MD5Encode", time.Now(), &err)
 	if request.Src == "" {
 		err = errors.New("source string can not be empty")
 		return err
@@ -18,6 +35,7 @@ func (api *GoApi) MD5Encode(request TNetsRpcPackMD5EncodeReq, response *TNetsRpc
 }
 
 func (api *GoApi) MD5Equal(request TNetsRpcPackMD5EqualReq, response *TNetsRpcPackMD5EqualResp) (err error) {
+	defer observeRpcCall("MD5Equal", time.Now(), &err)
 	if request.Src == "" || request.Dest == "" {
 		err = errors.New("source or destination string can not be empty")
 		return err
@@ -25,3 +43,417 @@ func (api *GoApi) MD5Equal(request TNetsRpcPackMD5EqualReq, response *TNetsRpcPa
 	response.Equal = pack.MD5Check(request.Src, request.Dest)
 	return err
 }
+
+// Pack function
+// pack source files or directories into dest package
+func (api *GoApi) Pack(request TNetsRpcPackReq, response *TNetsRpcPackResp) (err error) {
+	defer observeRpcCall("Pack", time.Now(), &err)
+	// resolve and check request paths
+	err = resolveRpcPaths(append(netsPathRefs(request.Src), &request.Dest)...)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsPackParameters(TNetsPack{Src: request.Src, Dest: request.Dest, Type: request.Type}))
+	if err != nil {
+		return err
+	}
+	// refactor source files
+	src, err := refactorNetsPackSource(request.Src)
+	if err != nil {
+		log.Println("Error refactor source files:", err)
+		return err
+	}
+	err = checkRootPaths(src...)
+	if err != nil {
+		return err
+	}
+	// start pack files
+	if request.Reproducible {
+		err = pack.PackReproducible(src, request.Dest, request.Type)
+	} else {
+		err = pack.Pack(src, request.Dest, request.Type)
+	}
+	if err != nil {
+		log.Println("Pack failure:", err)
+		return err
+	}
+	observeNetsBytes("pack", request.Type, src...)
+	response.Files = src
+	response.Size = netsFileSize(src...)
+	return err
+}
+
+// Unpack function
+// unpack whole package or one target file into dest directory
+func (api *GoApi) Unpack(request TNetsRpcUnpackReq, response *TNetsRpcUnpackResp) (err error) {
+	defer observeRpcCall("Unpack", time.Now(), &err)
+	// resolve and check request paths
+	err = resolveRpcPaths(&request.Src, &request.Dest)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsUnpackParameters(TNetsUnpack{Src: request.Src, Dest: request.Dest}))
+	if err != nil {
+		return err
+	}
+	// start unpack files
+	if request.Target != "" {
+		err = unpack.UnpackToFile(request.Src, request.Target, request.Dest)
+		response.Files = []string{request.Target}
+	} else {
+		var sizes []int
+		var algorithm string
+		err = unpack.ExtractInfo(request.Src, &response.Files, &sizes, &algorithm)
+		if err == nil {
+			err = unpack.Unpack(request.Src, request.Dest)
+		}
+	}
+	if err != nil {
+		log.Println("Unpack failure:", err)
+		return err
+	}
+	observeNetsBytes("unpack", netsPackageAlgorithm(request.Src), request.Src)
+	return err
+}
+
+// ExtractInfo function
+// list algorithm, file names and origin sizes in package
+func (api *GoApi) ExtractInfo(request TNetsRpcExtractInfoReq, response *TNetsRpcExtractInfoResp) (err error) {
+	defer observeRpcCall("ExtractInfo", time.Now(), &err)
+	err = resolveRpcPaths(&request.Src)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsUnpackVerboseParameters(TNetsUnpackVerboseReq{Src: request.Src}))
+	if err != nil {
+		return err
+	}
+	var files []string
+	var sizes []int
+	err = unpack.ExtractInfo(request.Src, &files, &sizes, &response.Type)
+	if err != nil {
+		log.Println("Error extract info:", err)
+		return err
+	}
+	for k, v := range files {
+		response.Files = append(response.Files, TNetsRpcFileInfo{Name: v, Size: int64(sizes[k])})
+	}
+	return err
+}
+
+// Compress function
+// compress source files or directories into dest archive
+func (api *GoApi) Compress(request TNetsRpcCompReq, response *TNetsRpcCompResp) (err error) {
+	defer observeRpcCall("Compress", time.Now(), &err)
+	// resolve and check request paths
+	err = resolveRpcPaths(append(netsPathRefs(request.Src), &request.Dest)...)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsCompParameters(TNetsComp{Src: request.Src, Dest: request.Dest, Type: request.Type, Password: request.Password}))
+	if err != nil {
+		return err
+	}
+	// refactor source files
+	src, err := refactorNetsCompSource(request.Src)
+	if err != nil {
+		log.Println("Error refactor source files:", err)
+		return err
+	}
+	err = checkRootPaths(src...)
+	if err != nil {
+		return err
+	}
+	// start compress files
+	if request.Password != "" {
+		err = comp.CompressEncrypt(src, request.Dest, request.Type, request.Password)
+	} else if request.Reproducible {
+		err = comp.CompressReproducible(src, request.Dest, request.Type)
+	} else {
+		err = comp.Compress(src, request.Dest, request.Type)
+	}
+	if err != nil {
+		log.Println("Compress failure:", err)
+		return err
+	}
+	observeNetsBytes("comp", request.Type, src...)
+	response.Files = src
+	response.Size = netsFileSize(src...)
+	return err
+}
+
+// Decompress function
+// decompress archive into dest directory
+func (api *GoApi) Decompress(request TNetsRpcDecompReq, response *TNetsRpcDecompResp) (err error) {
+	defer observeRpcCall("Decompress", time.Now(), &err)
+	err = resolveRpcPaths(&request.Src, &request.Dest)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsDecompParameters(TNetsDecomp{Src: request.Src, Dest: request.Dest, Type: request.Type, Password: request.Password}))
+	if err != nil {
+		return err
+	}
+	if request.Password != "" {
+		err = decomp.DeCompressDecrypt(request.Src, request.Dest, request.Type, request.Password)
+	} else {
+		err = decomp.DeCompress(request.Src, request.Dest, request.Type)
+	}
+	if err != nil {
+		log.Println("Decompress failure:", err)
+		return err
+	}
+	observeNetsBytes("decomp", request.Type, request.Src)
+	response.Dest = request.Dest
+	return err
+}
+
+// IniGet function
+// get value of section and name in ini file as string
+func (api *GoApi) IniGet(request TNetsRpcIniReq, response *TNetsRpcIniResp) (err error) {
+	defer observeRpcCall("IniGet", time.Now(), &err)
+	err = resolveRpcPaths(&request.Src)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsParsesIniValueParameters(TNetsParsesIni{Src: request.Src, Mode: "get", Section: request.Section, Name: request.Name, Type: request.Type}))
+	if err != nil {
+		return err
+	}
+	switch request.Type {
+	case "string":
+		err = parses.GetValueFrom(request.Src, request.Section, request.Name, &response.Value)
+	case "int":
+		var value int
+		err = parses.GetValueFrom(request.Src, request.Section, request.Name, &value)
+		response.Value = strconv.Itoa(value)
+	case "float64":
+		var value float64
+		err = parses.GetValueFrom(request.Src, request.Section, request.Name, &value)
+		response.Value = strconv.FormatFloat(value, 'f', -1, 64)
+	case "bool":
+		var value bool
+		err = parses.GetValueFrom(request.Src, request.Section, request.Name, &value)
+		response.Value = strconv.FormatBool(value)
+	}
+	return err
+}
+
+// IniSet function
+// set value of section and name in ini file, value is parsed by type
+func (api *GoApi) IniSet(request TNetsRpcIniReq, response *TNetsRpcIniResp) (err error) {
+	defer observeRpcCall("IniSet", time.Now(), &err)
+	err = resolveRpcPaths(&request.Src)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsParsesIniValueParameters(TNetsParsesIni{Src: request.Src, Mode: "set", Section: request.Section, Name: request.Name, Type: request.Type, Value: request.Value}))
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	switch request.Type {
+	case "string":
+		value = request.Value
+	case "int":
+		value, err = strconv.Atoi(request.Value)
+	case "float64":
+		value, err = strconv.ParseFloat(request.Value, 64)
+	case "bool":
+		value, err = strconv.ParseBool(request.Value)
+	}
+	if err != nil {
+		return fmt.Errorf("illegal value: %v", err)
+	}
+	err = parses.SetValueTo(request.Src, request.Section, request.Name, value)
+	if err != nil {
+		return err
+	}
+	response.Value = request.Value
+	return err
+}
+
+// QRCode function
+// generate QR code PNG to dest file, or into response when dest empty
+func (api *GoApi) QRCode(request TNetsRpcQRCodeReq, response *TNetsRpcQRCodeResp) (err error) {
+	defer observeRpcCall("QRCode", time.Now(), &err)
+	if request.Dest == "" {
+		err = checkRpcParameters(checkNetsImagesQRCodeParameters(TNetsImagesQRCodeToMemory{Content: request.Content, Size: request.Size}))
+		if err != nil {
+			return err
+		}
+		response.Data, err = images.QRCodeGenerateToMemory(request.Content, qrcode.Highest, request.Size)
+		return err
+	}
+	err = resolveRpcPaths(&request.Dest)
+	if err != nil {
+		return err
+	}
+	err = checkRpcParameters(checkNetsImagesQRCodeToFileParameters(TNetsImagesQRCodeToFile{Content: request.Content, Size: request.Size, Dest: request.Dest}))
+	if err != nil {
+		return err
+	}
+	return images.QRCodeGenerateToFile(request.Content, qrcode.Highest, request.Size, request.Dest)
+}
+
+// SHAEncode function
+// hex digest of source string by sha1, sha256 or sha512
+func (api *GoApi) SHAEncode(request TNetsRpcHashReq, response *TNetsRpcHashResp) (err error) {
+	defer observeRpcCall("SHAEncode", time.Now(), &err)
+	if request.Src == "" {
+		err = errors.New("source string can not be empty")
+		return err
+	}
+	switch request.Type {
+	case "sha1":
+		response.Dest = pack.SHA1Encode(request.Src)
+	case "sha256":
+		response.Dest = pack.SHA256Encode(request.Src)
+	case "sha512":
+		response.Dest = pack.SHA512Encode(request.Src)
+	default:
+		err = fmt.Errorf("hash type %v not support", request.Type)
+	}
+	return err
+}
+
+// SHAEqual function
+// check source string match hex digest by sha1, sha256 or sha512
+func (api *GoApi) SHAEqual(request TNetsRpcHashEqualReq, response *TNetsRpcHashEqualResp) (err error) {
+	defer observeRpcCall("SHAEqual", time.Now(), &err)
+	if request.Src == "" || request.Dest == "" {
+		err = errors.New("source or destination string can not be empty")
+		return err
+	}
+	switch request.Type {
+	case "sha1":
+		response.Equal = pack.SHA1Check(request.Src, request.Dest)
+	case "sha256":
+		response.Equal = pack.SHA256Check(request.Src, request.Dest)
+	case "sha512":
+		response.Equal = pack.SHA512Check(request.Src, request.Dest)
+	default:
+		err = fmt.Errorf("hash type %v not support", request.Type)
+	}
+	return err
+}
+
+// HMACEncode function
+// hex HMAC of source string with key by sha1, sha256 or sha512
+func (api *GoApi) HMACEncode(request TNetsRpcHashReq, response *TNetsRpcHashResp) (err error) {
+	defer observeRpcCall("HMACEncode", time.Now(), &err)
+	if request.Src == "" || request.Key == "" {
+		err = errors.New("source string or key can not be empty")
+		return err
+	}
+	switch request.Type {
+	case "sha1":
+		response.Dest = pack.HMAC_SHA1(request.Src, request.Key)
+	case "sha256":
+		response.Dest = pack.HMAC_SHA256(request.Src, request.Key)
+	case "sha512":
+		response.Dest = pack.HMAC_SHA512(request.Src, request.Key)
+	default:
+		err = fmt.Errorf("hash type %v not support", request.Type)
+	}
+	return err
+}
+
+// Download function
+// read one chunk of file from offset, call again with offset
+// plus length of data until eof
+func (api *GoApi) Download(request TNetsRpcDownloadReq, response *TNetsRpcDownloadResp) (err error) {
+	defer observeRpcCall("Download", time.Now(), &err)
+	err = resolveRpcPaths(&request.Path)
+	if err != nil {
+		return err
+	}
+	err = checkRootPaths(request.Path)
+	if err != nil {
+		return err
+	}
+	size := request.Size
+	if size <= 0 || size > RpcChunkSize {
+		size = RpcChunkSize
+	}
+	if request.Offset < 0 {
+		err = errors.New("offset can not be negative")
+		return err
+	}
+	file, err := os.Open(request.Path)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	response.Total = info.Size()
+	response.Data = make([]byte, size)
+	n, err := file.ReadAt(response.Data, request.Offset)
+	if err != nil && err != io.EOF {
+		log.Println("Error read file:", err)
+		return err
+	}
+	response.Data = response.Data[:n]
+	response.EOF = request.Offset+int64(n) >= response.Total
+	return nil
+}
+
+// Upload function
+// append one chunk to file, offset 0 create or truncate the file,
+// other offset must equal current file size
+func (api *GoApi) Upload(request TNetsRpcUploadReq, response *TNetsRpcUploadResp) (err error) {
+	defer observeRpcCall("Upload", time.Now(), &err)
+	err = resolveRpcPaths(&request.Path)
+	if err != nil {
+		return err
+	}
+	if request.Path == "" {
+		err = errors.New("path can not be empty")
+		return err
+	}
+	if len(request.Data) > RpcChunkSize {
+		err = fmt.Errorf("chunk size %v over limit %v", len(request.Data), RpcChunkSize)
+		return err
+	}
+	flag := os.O_WRONLY | os.O_CREATE
+	if request.Offset == 0 {
+		flag |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(request.Path, flag, 0644)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != request.Offset {
+		err = fmt.Errorf("upload offset %v not equal file size %v", request.Offset, info.Size())
+		return err
+	}
+	n, err := file.WriteAt(request.Data, request.Offset)
+	if err != nil {
+		log.Println("Error write file:", err)
+		return err
+	}
+	response.Size = request.Offset + int64(n)
+	return err
+}
+
+// checkRpcParameters function
+// turn result of http parameters check into rpc error
+func checkRpcParameters(b bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !b {
+		return ErrRpcParameters
+	}
+	return nil
+}
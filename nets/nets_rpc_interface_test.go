@@ -0,0 +1,394 @@
+package nets
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setTestRpcRoots confine rpc paths to dir as "box" and test data as "data"
+func setTestRpcRoots(tb testing.TB, dir string) {
+	err := SetHttpRoots([]TNetsRoot{{Name: "box", Path: dir}, {Name: "data", Path: "../test/data"}})
+	if err != nil {
+		tb.Fatal("Error set roots:", err)
+	}
+}
+
+func TestGoApiPackUnpack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	setTestRpcRoots(t, dir)
+	defer SetHttpRoots(nil)
+	api := new(GoApi)
+	names := []string{"file_1.txt", "file_2.txt", "file_3.txt"}
+	var src []string
+	for _, v := range names {
+		src = append(src, "data:pack/"+v)
+	}
+	dest := "box:file.pak"
+	_ = os.MkdirAll(filepath.Join(dir, "out"), os.ModePerm)
+	var pr TNetsRpcPackResp
+	err = api.Pack(TNetsRpcPackReq{Src: src, Dest: dest, Type: "AES"}, &pr)
+	if err != nil {
+		t.Fatal("Error rpc pack:", err)
+	}
+	if len(pr.Files) != len(src) || pr.Size == 0 {
+		t.Errorf("Pack response %+v", pr)
+	}
+	var ir TNetsRpcExtractInfoResp
+	err = api.ExtractInfo(TNetsRpcExtractInfoReq{Src: dest}, &ir)
+	if err != nil {
+		t.Fatal("Error rpc extract info:", err)
+	}
+	if ir.Type != "aes" || len(ir.Files) != len(src) {
+		t.Errorf("Extract info response %+v", ir)
+	}
+	var ur TNetsRpcUnpackResp
+	err = api.Unpack(TNetsRpcUnpackReq{Src: dest, Dest: "box:out/"}, &ur)
+	if err != nil {
+		t.Fatal("Error rpc unpack:", err)
+	}
+	for _, v := range names {
+		origin, _ := ioutil.ReadFile(filepath.Join("../test/data/pack", v))
+		data, err := ioutil.ReadFile(filepath.Join(dir, "out", v))
+		if err != nil || !bytes.Equal(origin, data) {
+			t.Errorf("Unpack file %v not equal: %v", v, err)
+		}
+	}
+	err = api.Pack(TNetsRpcPackReq{Src: src, Dest: dest, Type: "MD4"}, &pr)
+	if err == nil {
+		t.Error("Pack undefined algorithm without error")
+	}
+}
+
+func BenchmarkGoApiPackUnpack(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	setTestRpcRoots(b, dir)
+	defer SetHttpRoots(nil)
+	_ = os.MkdirAll(filepath.Join(dir, "out"), os.ModePerm)
+	api := new(GoApi)
+	for i := 0; i < b.N; i++ {
+		err = api.Pack(TNetsRpcPackReq{Src: []string{"data:pack/file_1.txt"}, Dest: "box:file.pak", Type: "AES"}, &TNetsRpcPackResp{})
+		if err != nil {
+			b.Fatal("Error rpc pack:", err)
+		}
+		err = api.Unpack(TNetsRpcUnpackReq{Src: "box:file.pak", Dest: "box:out/"}, &TNetsRpcUnpackResp{})
+		if err != nil {
+			b.Fatal("Error rpc unpack:", err)
+		}
+	}
+}
+
+func TestGoApiCompDecomp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	setTestRpcRoots(t, dir)
+	defer SetHttpRoots(nil)
+	api := new(GoApi)
+	var cr TNetsRpcCompResp
+	err = api.Compress(TNetsRpcCompReq{Src: []string{"data:comp/file_1.txt", "data:comp/file_2.txt"}, Dest: "box:file.tar.gz", Type: "tar.gz"}, &cr)
+	if err != nil {
+		t.Fatal("Error rpc compress:", err)
+	}
+	var dr TNetsRpcDecompResp
+	err = api.Decompress(TNetsRpcDecompReq{Src: "box:file.tar.gz", Dest: "box:out/", Type: "tar.gz"}, &dr)
+	if err != nil {
+		t.Fatal("Error rpc decompress:", err)
+	}
+	origin, _ := ioutil.ReadFile("../test/data/comp/file_2.txt")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "out", "file_2.txt"))
+	if err != nil || !bytes.Equal(origin, data) {
+		t.Errorf("Decompress file not equal: %v", err)
+	}
+}
+
+func BenchmarkGoApiCompDecomp(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	setTestRpcRoots(b, dir)
+	defer SetHttpRoots(nil)
+	api := new(GoApi)
+	for i := 0; i < b.N; i++ {
+		err = api.Compress(TNetsRpcCompReq{Src: []string{"data:comp/file_1.txt"}, Dest: "box:file.zip", Type: "zip"}, &TNetsRpcCompResp{})
+		if err != nil {
+			b.Fatal("Error rpc compress:", err)
+		}
+		err = api.Decompress(TNetsRpcDecompReq{Src: "box:file.zip", Dest: "box:out/", Type: "zip"}, &TNetsRpcDecompResp{})
+		if err != nil {
+			b.Fatal("Error rpc decompress:", err)
+		}
+	}
+}
+
+func TestGoApiIni(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "file.ini")
+	_ = ioutil.WriteFile(src, []byte("[server]\nport = 8080\n"), 0644)
+	setTestRpcRoots(t, dir)
+	defer SetHttpRoots(nil)
+	src = "box:file.ini"
+	api := new(GoApi)
+	var r TNetsRpcIniResp
+	err = api.IniSet(TNetsRpcIniReq{Src: src, Section: "server", Name: "port", Type: "int", Value: "9090"}, &r)
+	if err != nil {
+		t.Fatal("Error rpc ini set:", err)
+	}
+	err = api.IniGet(TNetsRpcIniReq{Src: src, Section: "server", Name: "port", Type: "int"}, &r)
+	if err != nil {
+		t.Fatal("Error rpc ini get:", err)
+	}
+	if r.Value != "9090" {
+		t.Errorf("Ini value is %v", r.Value)
+	}
+	err = api.IniSet(TNetsRpcIniReq{Src: src, Section: "server", Name: "port", Type: "int", Value: "port"}, &r)
+	if err == nil {
+		t.Error("Set illegal int value without error")
+	}
+}
+
+func BenchmarkGoApiIni(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "file.ini")
+	_ = ioutil.WriteFile(src, []byte("[server]\nport = 8080\n"), 0644)
+	setTestRpcRoots(b, dir)
+	defer SetHttpRoots(nil)
+	src = "box:file.ini"
+	api := new(GoApi)
+	for i := 0; i < b.N; i++ {
+		err = api.IniGet(TNetsRpcIniReq{Src: src, Section: "server", Name: "port", Type: "int"}, &TNetsRpcIniResp{})
+		if err != nil {
+			b.Fatal("Error rpc ini get:", err)
+		}
+	}
+}
+
+func TestGoApiQRCode(t *testing.T) {
+	api := new(GoApi)
+	var r TNetsRpcQRCodeResp
+	err := api.QRCode(TNetsRpcQRCodeReq{Content: "Satellite", Size: 256}, &r)
+	if err != nil {
+		t.Fatal("Error rpc qrcode:", err)
+	}
+	if !bytes.HasPrefix(r.Data, []byte("\x89PNG")) {
+		t.Error("QR code is not PNG")
+	}
+}
+
+func BenchmarkGoApiQRCode(b *testing.B) {
+	api := new(GoApi)
+	for i := 0; i < b.N; i++ {
+		err := api.QRCode(TNetsRpcQRCodeReq{Content: "Satellite", Size: 256}, &TNetsRpcQRCodeResp{})
+		if err != nil {
+			b.Fatal("Error rpc qrcode:", err)
+		}
+	}
+}
+
+func TestGoApiHash(t *testing.T) {
+	api := new(GoApi)
+	for _, v := range []string{"sha1", "sha256", "sha512"} {
+		var r TNetsRpcHashResp
+		err := api.SHAEncode(TNetsRpcHashReq{Src: "Satellite", Type: v}, &r)
+		if err != nil {
+			t.Fatal("Error rpc sha encode:", err)
+		}
+		var e TNetsRpcHashEqualResp
+		err = api.SHAEqual(TNetsRpcHashEqualReq{Src: "Satellite", Dest: r.Dest, Type: v}, &e)
+		if err != nil || !e.Equal {
+			t.Errorf("%v digest not equal: %v", v, err)
+		}
+		err = api.HMACEncode(TNetsRpcHashReq{Src: "Satellite", Type: v, Key: "key"}, &r)
+		if err != nil || r.Dest == "" {
+			t.Errorf("%v hmac empty: %v", v, err)
+		}
+	}
+	err := api.SHAEncode(TNetsRpcHashReq{Src: "Satellite", Type: "md4"}, &TNetsRpcHashResp{})
+	if err == nil {
+		t.Error("Hash undefined type without error")
+	}
+}
+
+func BenchmarkGoApiHash(b *testing.B) {
+	api := new(GoApi)
+	for i := 0; i < b.N; i++ {
+		err := api.HMACEncode(TNetsRpcHashReq{Src: "Satellite", Type: "sha256", Key: "key"}, &TNetsRpcHashResp{})
+		if err != nil {
+			b.Fatal("Error rpc hmac encode:", err)
+		}
+	}
+}
+
+func TestGoApiRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// path methods are refused without storage roots
+	api := new(GoApi)
+	dest := filepath.Join(dir, "file.txt")
+	err = api.Upload(TNetsRpcUploadReq{Path: dest, Data: []byte("Satellite")}, &TNetsRpcUploadResp{})
+	if err != ErrRootRequired {
+		t.Errorf("Upload without roots error is %v", err)
+	}
+	err = api.Download(TNetsRpcDownloadReq{Path: "../test/data/pack/file_1.txt"}, &TNetsRpcDownloadResp{})
+	if err != ErrRootRequired {
+		t.Errorf("Download without roots error is %v", err)
+	}
+	if _, err = os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("Upload without roots wrote file: %v", err)
+	}
+	err = SetHttpRoots([]TNetsRoot{{Name: "box", Path: dir}})
+	if err != nil {
+		t.Fatal("Error set roots:", err)
+	}
+	defer SetHttpRoots(nil)
+	err = api.Upload(TNetsRpcUploadReq{Path: "box:file.txt", Data: []byte("Satellite")}, &TNetsRpcUploadResp{})
+	if err != nil {
+		t.Fatal("Error rpc upload:", err)
+	}
+	for _, v := range []string{"box:../file.txt", "../test/data/pack/file_1.txt", "none:file.txt"} {
+		err = api.Download(TNetsRpcDownloadReq{Path: v}, &TNetsRpcDownloadResp{})
+		if err == nil {
+			t.Errorf("Download %v outside roots without error", v)
+		}
+	}
+	err = api.Pack(TNetsRpcPackReq{Src: []string{"box:file.txt"}, Dest: "box:file.pak", Type: "AES"}, &TNetsRpcPackResp{})
+	if err != nil {
+		t.Errorf("Error rpc pack inside roots: %v", err)
+	}
+}
+
+func BenchmarkGoApiRoots(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	err = SetHttpRoots([]TNetsRoot{{Name: "box", Path: dir}})
+	if err != nil {
+		b.Fatal("Error set roots:", err)
+	}
+	defer SetHttpRoots(nil)
+	api := new(GoApi)
+	for i := 0; i < b.N; i++ {
+		_ = api.Download(TNetsRpcDownloadReq{Path: "box:../file.txt"}, &TNetsRpcDownloadResp{})
+	}
+}
+
+func TestGoApiDownloadUpload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	setTestRpcRoots(t, dir)
+	defer SetHttpRoots(nil)
+	s, err := NewRpcServer("127.0.0.1", "12002", "tcp")
+	if err != nil {
+		t.Fatal("Error create rpc server:", err)
+	}
+	go s.ListenAndServe()
+	defer s.Shutdown(context.Background())
+	var client *rpc.Client
+	for i := 0; i < 50; i++ {
+		client, err = jsonrpc.Dial("tcp", "127.0.0.1:12002")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal("Error dial rpc server:", err)
+	}
+	defer client.Close()
+	origin, _ := ioutil.ReadFile("../test/data/pack/file_1.txt")
+	dest := "box:file.txt"
+	// upload in chunks of 4 bytes
+	var offset int64
+	for offset < int64(len(origin)) {
+		end := offset + 4
+		if end > int64(len(origin)) {
+			end = int64(len(origin))
+		}
+		var r TNetsRpcUploadResp
+		err = client.Call("GoApi.Upload", TNetsRpcUploadReq{Path: dest, Offset: offset, Data: origin[offset:end]}, &r)
+		if err != nil {
+			t.Fatal("Error rpc upload:", err)
+		}
+		offset = r.Size
+	}
+	// resume from wrong offset is refused
+	err = client.Call("GoApi.Upload", TNetsRpcUploadReq{Path: dest, Offset: 1, Data: []byte("x")}, &TNetsRpcUploadResp{})
+	if err == nil {
+		t.Error("Upload with wrong offset without error")
+	}
+	// download in chunks of 5 bytes
+	var data []byte
+	for {
+		var r TNetsRpcDownloadResp
+		err = client.Call("GoApi.Download", TNetsRpcDownloadReq{Path: dest, Offset: int64(len(data)), Size: 5}, &r)
+		if err != nil {
+			t.Fatal("Error rpc download:", err)
+		}
+		if len(r.Data) > 5 {
+			t.Fatalf("Chunk size %v over request size", len(r.Data))
+		}
+		data = append(data, r.Data...)
+		if r.EOF {
+			break
+		}
+	}
+	if !bytes.Equal(origin, data) {
+		t.Errorf("Download %q not equal %q", data, origin)
+	}
+}
+
+func BenchmarkGoApiDownloadUpload(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	setTestRpcRoots(b, dir)
+	defer SetHttpRoots(nil)
+	api := new(GoApi)
+	dest := "box:file.txt"
+	for i := 0; i < b.N; i++ {
+		err = api.Upload(TNetsRpcUploadReq{Path: dest, Data: []byte("Satellite")}, &TNetsRpcUploadResp{})
+		if err != nil {
+			b.Fatal("Error rpc upload:", err)
+		}
+		err = api.Download(TNetsRpcDownloadReq{Path: dest}, &TNetsRpcDownloadResp{})
+		if err != nil {
+			b.Fatal("Error rpc download:", err)
+		}
+	}
+}
@@ -1,68 +1,415 @@
 package nets
 
 import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/gob"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"net/rpc"
 	"net/rpc/jsonrpc"
-	"os"
+	. "satellite/global"
+	"strings"
+	"sync"
+	"time"
 )
 
-func StartRpcHttpServer(ip string, port string) {
+// TNetsRpcConfig describe security and limits of rpc server
+// TLS serve connections over TLS, client certificates are verified by its ClientCA
+// Token is required from clients, "AUTH <token>\n" line answered by "OK\n" over tcp,
+// "Authorization: Bearer <token>" header of CONNECT request over http
+// MaxConns limit concurrent connections, 0 means unlimited
+// IdleTimeout(Millisecond) close connections without call in progress, 0 means never
+type TNetsRpcConfig struct {
+	TLS         *TNetsHttpsConfig `json:"tls,omitempty"`
+	Token       string            `json:"token,omitempty"`
+	MaxConns    int               `json:"max_conns"`
+	IdleTimeout int               `json:"idle_timeout"`
+}
+
+// TNetsRpcServer is GoApi rpc server over "tcp" (json codec) or "http" (gob codec),
+// Shutdown stop reading new calls and wait in-flight calls within ctx
+type TNetsRpcServer struct {
+	Addr     string
+	Protocol string
+	config   TNetsRpcConfig
+	rpc      *rpc.Server
+	tls      *tls.Config
+	reloader *TNetsCertReloader
+	stop     chan struct{}
+	conns    tNetsConns
+}
+
+var (
+	ErrRpcProtocol     = errors.New("invalid rpc protocol, you can choose one from ['tcp','http']")
+	ErrRpcUnauthorized = errors.New("rpc unauthorized")
+)
+
+// NewRpcServer function
+// create rpc server with GoApi registered, default connection and idle limits
+func NewRpcServer(ip string, port string, protocol string) (*TNetsRpcServer, error) {
+	return NewRpcServerWithConfig(ip, port, protocol, TNetsRpcConfig{MaxConns: RpcMaxConns, IdleTimeout: RpcIdleTimeout})
+}
+
+// NewRpcServerWithConfig function
+// create rpc server with GoApi registered, TLS, token and limits in config
+func NewRpcServerWithConfig(ip string, port string, protocol string, c TNetsRpcConfig) (*TNetsRpcServer, error) {
+	if protocol != "tcp" && protocol != "http" {
+		return nil, ErrRpcProtocol
+	}
+	s := &TNetsRpcServer{Addr: ip + ":" + port, Protocol: protocol, config: c, rpc: rpc.NewServer(), stop: make(chan struct{})}
+	if c.TLS != nil {
+		if c.TLS.SelfSigned && len(c.TLS.Hosts) == 0 {
+			c.TLS.Hosts = []string{ip}
+		}
+		t, r, err := NewHttpsTLSConfig(*c.TLS)
+		if err != nil {
+			SetSubsystemState(CmdRpc, false, err.Error())
+			log.Println("Error create TLS config:", err)
+			return nil, err
+		}
+		s.tls, s.reloader = t, r
+	} else if c.Token != "" {
+		log.Println("Warning: rpc token is sent in clear text without TLS")
+	}
 	// rpc register interface...
-	err := rpc.Register(new(GoApi))
+	err := s.rpc.Register(new(GoApi))
 	if err != nil {
-		fmt.Println("Error RPC register interface:", err)
 		log.Println("Error RPC register interface:", err)
-		os.Exit(1)
+		return nil, err
 	}
-	// rpc handle http
-	rpc.HandleHTTP()
-	// rpc start http service...
-	l, err := net.Listen("tcp", ip+":"+port)
+	return s, nil
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (s *TNetsRpcServer) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr)
 	if err != nil {
+		SetSubsystemState(CmdRpc, false, err.Error())
 		fmt.Println("Error listen tcp:", err)
 		log.Println("Error listen tcp:", err)
-		os.Exit(1)
+		return err
 	}
-	fmt.Println("Start Listen And Server on ", ip+":"+port)
-	// start http service...
-	err = http.Serve(l, nil)
-	if err != nil {
-		fmt.Println("Error serve http:", err)
-		log.Println("Error serve http:", err)
-		os.Exit(1)
+	if s.tls != nil {
+		go s.reloader.Watch(HttpsReloadInterval*time.Second, s.stop)
+		l = tls.NewListener(l, s.tls)
+	}
+	if s.config.MaxConns > 0 {
+		l = &tNetsLimitListener{Listener: l, max: s.config.MaxConns}
+	}
+	if !s.conns.setListener(l) {
+		return l.Close()
 	}
+	fmt.Println("Start Listen And Server on ", s.Addr)
+	SetSubsystemState(CmdRpc, true, "listening on "+l.Addr().String())
+	defer SetSubsystemState(CmdRpc, false, "stopped")
+	if s.Protocol == "http" {
+		// rpc handle http, own mux keep DefaultServeMux handlers away
+		mux := http.NewServeMux()
+		mux.HandleFunc(rpc.DefaultRPCPath, s.serveHTTP)
+		err = http.Serve(l, mux)
+	} else {
+		err = s.serveTCP(l)
+	}
+	if s.conns.isClosing() {
+		return nil
+	}
+	fmt.Println("Error serve rpc:", err)
+	log.Println("Error serve rpc:", err)
+	return err
 }
 
-func StartRpcTcpServer(ip string, port string) {
-	// rpc register interface...
-	err := rpc.Register(new(GoApi))
-	if err != nil {
-		fmt.Println("Error RPC register interface:", err)
-		log.Println("Error RPC register interface:", err)
-		os.Exit(1)
+func (s *TNetsRpcServer) serveTCP(l net.Listener) error {
+	for {
+		// client connect to server...
+		conn, err := l.Accept()
+		if err != nil {
+			var e net.Error
+			if errors.As(err, &e) && e.Timeout() {
+				continue
+			}
+			return err
+		}
+		// handle json transfer after handshake
+		go func() {
+			err := s.handshake(conn)
+			if err != nil {
+				log.Printf("Error rpc handshake %v: %v\n", conn.RemoteAddr(), err)
+				_ = conn.Close()
+				return
+			}
+			s.serveConn(conn, jsonrpc.NewServerCodec(conn))
+		}()
+	}
+}
+
+// handshake function
+// finish TLS handshake and check "AUTH <token>" line when token required,
+// line is read byte by byte so no call data is buffered away from codec
+func (s *TNetsRpcServer) handshake(conn net.Conn) error {
+	_ = conn.SetDeadline(time.Now().Add(RpcHandshakeTimeout * time.Millisecond))
+	defer conn.SetDeadline(time.Time{})
+	tc := conn
+	if c, ok := tc.(*tNetsLimitConn); ok {
+		tc = c.Conn
+	}
+	if c, ok := tc.(*tls.Conn); ok {
+		if err := c.Handshake(); err != nil {
+			return err
+		}
+	}
+	if s.config.Token == "" {
+		return nil
+	}
+	line := make([]byte, 0, 64)
+	b := make([]byte, 1)
+	for len(line) < 512 {
+		_, err := conn.Read(b)
+		if err != nil {
+			return err
+		}
+		if b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
+	}
+	token := strings.TrimPrefix(strings.TrimRight(string(line), "\r"), "AUTH ")
+	if !s.allowToken(token) {
+		_, _ = io.WriteString(conn, "ERR unauthorized\n")
+		return ErrRpcUnauthorized
+	}
+	_, err := io.WriteString(conn, "OK\n")
+	return err
+}
+
+func (s *TNetsRpcServer) allowToken(token string) bool {
+	return s.config.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) == 1
+}
+
+// serveHTTP function
+// same as rpc.Server.ServeHTTP but check token and track hijacked connection
+func (s *TNetsRpcServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "CONNECT" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "405 must CONNECT\n")
+		return
 	}
-	// rpc start tcp service...
-	l, err := net.Listen("tcp", ip+":"+port)
+	if !s.allowToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+		log.Printf("Error rpc handshake %v: %v\n", r.RemoteAddr, ErrRpcUnauthorized)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
 	if err != nil {
-		fmt.Println("Error listen tcp:", err)
-		log.Println("Error listen tcp:", err)
-		os.Exit(1)
+		log.Println("Error rpc hijacking:", err)
+		return
 	}
-	fmt.Println("Start Listen And Server on ", ip+":"+port)
+	_, _ = io.WriteString(conn, "HTTP/1.0 200 Connected to Go RPC\n\n")
+	s.serveConn(conn, newNetsGobCodec(conn))
+}
+
+// serveConn function
+// serve rpc calls of connection, close it when idle too long
+func (s *TNetsRpcServer) serveConn(conn net.Conn, codec rpc.ServerCodec) {
+	if !s.conns.add(conn) {
+		_ = conn.Close()
+		return
+	}
+	defer s.conns.remove(conn)
+	fmt.Println("RPC client connect to server:", conn.RemoteAddr())
+	c := &tNetsRpcCodec{ServerCodec: codec, last: time.Now()}
+	if s.config.IdleTimeout > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go c.watch(time.Duration(s.config.IdleTimeout)*time.Millisecond, done)
+	}
+	s.rpc.ServeCodec(c)
+}
+
+// Shutdown function
+// close listener, stop reading new calls and wait in-flight calls,
+// connections still open when ctx done are closed
+func (s *TNetsRpcServer) Shutdown(ctx context.Context) error {
+	SetSubsystemState(CmdRpc, false, "shutting down")
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	return s.conns.shutdown(ctx, true)
+}
+
+// tNetsRpcCodec count calls in progress and last activity of connection
+type tNetsRpcCodec struct {
+	rpc.ServerCodec
+	mutex   sync.Mutex
+	pending int
+	last    time.Time
+}
+
+func (c *tNetsRpcCodec) ReadRequestHeader(r *rpc.Request) error {
+	err := c.ServerCodec.ReadRequestHeader(r)
+	if err == nil {
+		c.mutex.Lock()
+		c.pending++
+		c.last = time.Now()
+		c.mutex.Unlock()
+	}
+	return err
+}
+
+func (c *tNetsRpcCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	err := c.ServerCodec.WriteResponse(r, body)
+	c.mutex.Lock()
+	c.pending--
+	c.last = time.Now()
+	c.mutex.Unlock()
+	return err
+}
+
+// watch function
+// close codec when no call in progress for timeout
+func (c *tNetsRpcCodec) watch(timeout time.Duration, done <-chan struct{}) {
+	tick := time.NewTicker(timeout / 4)
+	defer tick.Stop()
 	for {
-		// client connect to server...
-		conn, err := l.Accept()
+		select {
+		case <-done:
+			return
+		case <-tick.C:
+		}
+		c.mutex.Lock()
+		idle := c.pending == 0 && time.Since(c.last) >= timeout
+		c.mutex.Unlock()
+		if idle {
+			log.Println("Close idle rpc connection")
+			_ = c.Close()
+			return
+		}
+	}
+}
+
+// tNetsGobCodec is gob server codec same as net/rpc default codec
+type tNetsGobCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	mutex  sync.Mutex
+	closed bool
+}
+
+func newNetsGobCodec(conn io.ReadWriteCloser) *tNetsGobCodec {
+	buf := bufio.NewWriter(conn)
+	return &tNetsGobCodec{rwc: conn, dec: gob.NewDecoder(conn), enc: gob.NewEncoder(buf), encBuf: buf}
+}
+
+func (c *tNetsGobCodec) ReadRequestHeader(r *rpc.Request) error {
+	return c.dec.Decode(r)
+}
+
+func (c *tNetsGobCodec) ReadRequestBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *tNetsGobCodec) WriteResponse(r *rpc.Response, body interface{}) (err error) {
+	if err = c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			log.Println("Error rpc encoding response:", err)
+			_ = c.Close()
+		}
+		return err
+	}
+	if err = c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			log.Println("Error rpc encoding body:", err)
+			_ = c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *tNetsGobCodec) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
+
+// tNetsLimitListener refuse connections over max
+type tNetsLimitListener struct {
+	net.Listener
+	mutex  sync.Mutex
+	active int
+	max    int
+}
+
+func (l *tNetsLimitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
 		if err != nil {
+			return nil, err
+		}
+		l.mutex.Lock()
+		if l.active >= l.max {
+			l.mutex.Unlock()
+			log.Printf("Refuse connection %v: %v\n", conn.RemoteAddr(), ErrConnLimit)
+			_ = conn.Close()
 			continue
 		}
-		// handle json transfer
-		go func(conn net.Conn) {
-			jsonrpc.ServeConn(conn)
-			fmt.Println("RPC client connect to server:", conn.RemoteAddr())
-		}(conn)
+		l.active++
+		l.mutex.Unlock()
+		return &tNetsLimitConn{Conn: conn, l: l}, nil
+	}
+}
+
+// tNetsLimitConn release listener slot once on close
+type tNetsLimitConn struct {
+	net.Conn
+	l    *tNetsLimitListener
+	once sync.Once
+}
+
+func (c *tNetsLimitConn) Close() error {
+	c.once.Do(func() {
+		c.l.mutex.Lock()
+		c.l.active--
+		c.l.mutex.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+func StartRpcHttpServer(ip string, port string) error {
+	return startRpcServer(ip, port, "http")
+}
+
+func StartRpcTcpServer(ip string, port string) error {
+	return startRpcServer(ip, port, "tcp")
+}
+
+func startRpcServer(ip string, port string, protocol string) error {
+	return StartRpcServerWithConfig(ip, port, protocol, TNetsRpcConfig{MaxConns: RpcMaxConns, IdleTimeout: RpcIdleTimeout})
+}
+
+// StartRpcServerWithConfig function
+// start rpc server with TLS, token and limits in config
+func StartRpcServerWithConfig(ip string, port string, protocol string, c TNetsRpcConfig) error {
+	s, err := NewRpcServerWithConfig(ip, port, protocol, c)
+	if err != nil {
+		fmt.Println("Error create rpc server:", err)
+		return err
 	}
+	return RunServer(s, NetShutdownTimeout*time.Millisecond)
 }
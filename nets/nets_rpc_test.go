@@ -1,8 +1,18 @@
 package nets
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestHttpCallRpcApiMD5Encode(t *testing.T) {
@@ -76,3 +86,183 @@ func TestTcpCallRpcApiMD5Equal(t *testing.T) {
 	fmt.Println("Rpc request:", request)
 	fmt.Println("Rpc response:", response)
 }
+
+func TestRpcServerShutdown(t *testing.T) {
+	s, err := NewRpcServer("127.0.0.1", "12001", "tcp")
+	if err != nil {
+		t.Fatal("Error create rpc server:", err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.ListenAndServe()
+	}()
+	var client *rpc.Client
+	for i := 0; i < 50; i++ {
+		client, err = jsonrpc.Dial("tcp", "127.0.0.1:12001")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal("Error dial rpc server:", err)
+	}
+	defer client.Close()
+	var response TNetsRpcPackMD5EncodeResp
+	err = client.Call("GoApi.MD5Encode", TNetsRpcPackMD5EncodeReq{Src: "Satellite"}, &response)
+	if err != nil {
+		t.Fatal("Error rpc call function:", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Errorf("Error shutdown rpc server: %v", err)
+	}
+	err = <-errc
+	if err != nil {
+		t.Errorf("Rpc server stopped by error: %v", err)
+	}
+}
+
+func BenchmarkRpcServerShutdown(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s, err := NewRpcServer("127.0.0.1", "12001", "tcp")
+		if err != nil {
+			b.Fatal("Error create rpc server:", err)
+		}
+		_ = s.Shutdown(context.Background())
+		_ = s.ListenAndServe()
+	}
+}
+
+// dialRpcTLS function
+// dial rpc server over tls and send token line, return server answer
+func dialRpcTLS(addr string, token string) (conn net.Conn, answer string, err error) {
+	conn, err = tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = io.WriteString(conn, "AUTH "+token+"\n")
+	if err != nil {
+		return conn, "", err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 3)
+	_, err = io.ReadFull(conn, b)
+	_ = conn.SetReadDeadline(time.Time{})
+	return conn, string(b), err
+}
+
+func TestRpcServerTLSToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	c := TNetsRpcConfig{
+		TLS:   &TNetsHttpsConfig{SelfSigned: true, Cert: filepath.Join(dir, "cert.pem"), Key: filepath.Join(dir, "key.pem")},
+		Token: "secret",
+	}
+	s, err := NewRpcServerWithConfig("127.0.0.1", "12021", "tcp", c)
+	if err != nil {
+		t.Fatal("Error create rpc server:", err)
+	}
+	go s.ListenAndServe()
+	defer s.Shutdown(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	// wrong token refused
+	conn, answer, err := dialRpcTLS("127.0.0.1:12021", "wrong")
+	if err != nil {
+		t.Fatal("Error dial rpc server:", err)
+	}
+	conn.Close()
+	if answer != "ERR" {
+		t.Errorf("Wrong token answer is %q", answer)
+	}
+	// plain tcp refused by tls
+	client, err := jsonrpc.Dial("tcp", "127.0.0.1:12021")
+	if err == nil {
+		err = client.Call("GoApi.MD5Encode", TNetsRpcPackMD5EncodeReq{Src: "Satellite"}, &TNetsRpcPackMD5EncodeResp{})
+		client.Close()
+	}
+	if err == nil {
+		t.Error("Plain tcp call without error")
+	}
+	// right token
+	conn, answer, err = dialRpcTLS("127.0.0.1:12021", "secret")
+	if err != nil || answer != "OK\n" {
+		t.Fatalf("Right token answer is %q: %v", answer, err)
+	}
+	client = jsonrpc.NewClient(conn)
+	defer client.Close()
+	var response TNetsRpcPackMD5EncodeResp
+	err = client.Call("GoApi.MD5Encode", TNetsRpcPackMD5EncodeReq{Src: "Satellite"}, &response)
+	if err != nil {
+		t.Fatal("Error rpc call function:", err)
+	}
+}
+
+func BenchmarkRpcServerTLSToken(b *testing.B) {
+	s, err := NewRpcServerWithConfig("127.0.0.1", "12022", "tcp", TNetsRpcConfig{Token: "secret"})
+	if err != nil {
+		b.Fatal("Error create rpc server:", err)
+	}
+	for i := 0; i < b.N; i++ {
+		s.allowToken("secret")
+	}
+}
+
+func TestRpcServerLimits(t *testing.T) {
+	s, err := NewRpcServerWithConfig("127.0.0.1", "12023", "tcp", TNetsRpcConfig{MaxConns: 1, IdleTimeout: 200})
+	if err != nil {
+		t.Fatal("Error create rpc server:", err)
+	}
+	go s.ListenAndServe()
+	defer s.Shutdown(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	first, err := jsonrpc.Dial("tcp", "127.0.0.1:12023")
+	if err != nil {
+		t.Fatal("Error dial rpc server:", err)
+	}
+	defer first.Close()
+	err = first.Call("GoApi.MD5Encode", TNetsRpcPackMD5EncodeReq{Src: "Satellite"}, &TNetsRpcPackMD5EncodeResp{})
+	if err != nil {
+		t.Fatal("Error rpc call function:", err)
+	}
+	// second connection over limit is closed
+	conn, err := net.Dial("tcp", "127.0.0.1:12023")
+	if err != nil {
+		t.Fatal("Error dial rpc server:", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	conn.Close()
+	if err != io.EOF {
+		t.Errorf("Connection over limit read error is %v", err)
+	}
+	// idle connection is closed and frees the slot
+	time.Sleep(400 * time.Millisecond)
+	err = first.Call("GoApi.MD5Encode", TNetsRpcPackMD5EncodeReq{Src: "Satellite"}, &TNetsRpcPackMD5EncodeResp{})
+	if err == nil {
+		t.Error("Idle connection not closed")
+	}
+	second, err := jsonrpc.Dial("tcp", "127.0.0.1:12023")
+	if err != nil {
+		t.Fatal("Error dial rpc server:", err)
+	}
+	defer second.Close()
+	err = second.Call("GoApi.MD5Encode", TNetsRpcPackMD5EncodeReq{Src: "Satellite"}, &TNetsRpcPackMD5EncodeResp{})
+	if err != nil {
+		t.Errorf("Error rpc call after idle connection closed: %v", err)
+	}
+}
+
+func BenchmarkRpcServerLimits(b *testing.B) {
+	l := &tNetsLimitListener{max: 1}
+	for i := 0; i < b.N; i++ {
+		c := &tNetsLimitConn{Conn: &net.TCPConn{}, l: l}
+		l.active++
+		c.once.Do(func() { l.active-- })
+	}
+}
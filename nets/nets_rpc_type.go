@@ -16,3 +16,137 @@ type TNetsRpcPackMD5EqualReq struct {
 type TNetsRpcPackMD5EqualResp struct {
 	Equal bool `json:"equal"`
 }
+
+type TNetsRpcPackReq struct {
+	Src          []string `json:"src"`
+	Dest         string   `json:"dest"`
+	Type         string   `json:"type"`
+	Reproducible bool     `json:"reproducible,omitempty"`
+}
+
+type TNetsRpcPackResp struct {
+	Files []string `json:"files"`
+	Size  int64    `json:"size"`
+}
+
+// TNetsRpcUnpackReq unpack whole package, or only Target file when not empty
+type TNetsRpcUnpackReq struct {
+	Src    string `json:"src"`
+	Dest   string `json:"dest"`
+	Target string `json:"target,omitempty"`
+}
+
+type TNetsRpcUnpackResp struct {
+	Files []string `json:"files"`
+}
+
+type TNetsRpcExtractInfoReq struct {
+	Src string `json:"src"`
+}
+
+type TNetsRpcFileInfo struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type TNetsRpcExtractInfoResp struct {
+	Type  string             `json:"type"`
+	Files []TNetsRpcFileInfo `json:"files"`
+}
+
+type TNetsRpcCompReq struct {
+	Src          []string `json:"src"`
+	Dest         string   `json:"dest"`
+	Type         string   `json:"type"`
+	Password     string   `json:"password,omitempty"`
+	Reproducible bool     `json:"reproducible,omitempty"`
+}
+
+type TNetsRpcCompResp struct {
+	Files []string `json:"files"`
+	Size  int64    `json:"size"`
+}
+
+type TNetsRpcDecompReq struct {
+	Src      string `json:"src"`
+	Dest     string `json:"dest"`
+	Type     string `json:"type"`
+	Password string `json:"password,omitempty"`
+}
+
+type TNetsRpcDecompResp struct {
+	Dest string `json:"dest"`
+}
+
+// TNetsRpcIniReq Type is one of "string", "int", "float64" and "bool",
+// Value is used by IniSet only
+type TNetsRpcIniReq struct {
+	Src     string `json:"src"`
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Value   string `json:"value,omitempty"`
+}
+
+type TNetsRpcIniResp struct {
+	Value string `json:"value"`
+}
+
+// TNetsRpcQRCodeReq write PNG to Dest when not empty, or return it in response
+type TNetsRpcQRCodeReq struct {
+	Content string `json:"content"`
+	Size    int    `json:"size"`
+	Dest    string `json:"dest,omitempty"`
+}
+
+type TNetsRpcQRCodeResp struct {
+	Data []byte `json:"data,omitempty"`
+}
+
+// TNetsRpcHashReq Type is one of "sha1", "sha256" and "sha512",
+// Key is the secret of HMAC
+type TNetsRpcHashReq struct {
+	Src  string `json:"src"`
+	Type string `json:"type"`
+	Key  string `json:"key,omitempty"`
+}
+
+type TNetsRpcHashResp struct {
+	Dest string `json:"dest"`
+}
+
+type TNetsRpcHashEqualReq struct {
+	Src  string `json:"src"`
+	Dest string `json:"dest"`
+	Type string `json:"type"`
+}
+
+type TNetsRpcHashEqualResp struct {
+	Equal bool `json:"equal"`
+}
+
+// TNetsRpcDownloadReq read at most Size bytes from Offset,
+// Size not greater than RpcChunkSize, 0 for RpcChunkSize
+type TNetsRpcDownloadReq struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+	Size   int    `json:"size,omitempty"`
+}
+
+type TNetsRpcDownloadResp struct {
+	Data  []byte `json:"data"`
+	Total int64  `json:"total"`
+	EOF   bool   `json:"eof"`
+}
+
+// TNetsRpcUploadReq write Data at Offset, Offset must equal current file size
+// so an interrupted upload resumes from it, Offset 0 truncate the file
+type TNetsRpcUploadReq struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+	Data   []byte `json:"data"`
+}
+
+type TNetsRpcUploadResp struct {
+	Size int64 `json:"size"`
+}
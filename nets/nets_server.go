@@ -0,0 +1,158 @@
+package nets
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Server is a long-running server which can be embedded,
+// ListenAndServe return nil after Shutdown
+type Server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+var ErrConnLimit = errors.New("connection limit reached")
+
+// RunServer function
+// serve until SIGINT or SIGTERM, then shutdown within timeout
+func RunServer(s Server, timeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.ListenAndServe()
+	}()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	select {
+	case err := <-errc:
+		return err
+	case v := <-sig:
+		fmt.Println("Receive signal", v, "shutting down...")
+		log.Println("Receive signal", v, "shutting down...")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	if e := <-errc; err == nil {
+		err = e
+	}
+	return err
+}
+
+// tNetsConns track listener and connections of a server for graceful shutdown
+type tNetsConns struct {
+	mutex    sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closing  bool
+	wg       sync.WaitGroup
+}
+
+// setListener function
+// keep listener to close on shutdown, false when already shutting down
+func (c *tNetsConns) setListener(l net.Listener) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.listener = l
+	return !c.closing
+}
+
+// add function
+// track connection, false when server is shutting down
+func (c *tNetsConns) add(conn net.Conn) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closing {
+		return false
+	}
+	if c.conns == nil {
+		c.conns = make(map[net.Conn]struct{})
+	}
+	c.conns[conn] = struct{}{}
+	c.wg.Add(1)
+	return true
+}
+
+func (c *tNetsConns) remove(conn net.Conn) {
+	c.mutex.Lock()
+	delete(c.conns, conn)
+	c.mutex.Unlock()
+	c.wg.Done()
+}
+
+func (c *tNetsConns) isClosing() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.closing
+}
+
+// shutdown function
+// close listener, when drain stop reading new requests of tcp connections
+// so handlers can finish in-flight work, close all when ctx done
+func (c *tNetsConns) shutdown(ctx context.Context, drain bool) error {
+	c.mutex.Lock()
+	c.closing = true
+	if c.listener != nil {
+		_ = c.listener.Close()
+	}
+	for k := range c.conns {
+		if !drain || !closeRead(k) {
+			_ = k.Close()
+		}
+	}
+	c.mutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	c.mutex.Lock()
+	for k := range c.conns {
+		_ = k.Close()
+	}
+	c.mutex.Unlock()
+	return ctx.Err()
+}
+
+// closeRead function
+// half close tcp connection under tls or limit wrappers, false when not tcp
+func closeRead(conn net.Conn) bool {
+	switch c := conn.(type) {
+	case *net.TCPConn:
+		return c.CloseRead() == nil
+	case *tls.Conn:
+		return closeRead(c.NetConn())
+	case *tNetsLimitConn:
+		return closeRead(c.Conn)
+	}
+	return false
+}
+
+// closeWrite function
+// half close tcp connection under tls or limit wrappers, false when not tcp
+func closeWrite(conn net.Conn) bool {
+	switch c := conn.(type) {
+	case *net.TCPConn:
+		return c.CloseWrite() == nil
+	case *tls.Conn:
+		return c.CloseWrite() == nil
+	case *tNetsLimitConn:
+		return closeWrite(c.Conn)
+	}
+	return false
+}
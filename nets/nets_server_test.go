@@ -0,0 +1,112 @@
+package nets
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestHttpServer function
+// serve http on port and wait until it answers,
+// ListenAndServe result is sent to returned channel
+func startTestHttpServer(t testing.TB, port string) (*TNetsHttpServer, chan error) {
+	s := NewHttpServer("127.0.0.1", port)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.ListenAndServe()
+	}()
+	var err error
+	for i := 0; i < 50; i++ {
+		var resp *http.Response
+		resp, err = http.Get("http://127.0.0.1:" + port + "/satellite")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal("Error get http server:", err)
+	}
+	return s, errc
+}
+
+func TestHttpServerShutdown(t *testing.T) {
+	s, errc := startTestHttpServer(t, "12514")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	if err != nil {
+		t.Errorf("Error shutdown http server: %v", err)
+	}
+	err = <-errc
+	if err != nil {
+		t.Errorf("Http server stopped by error: %v", err)
+	}
+	_, err = s.jobs.Start("1", "pack", nil)
+	if err != ErrJobsClosed {
+		t.Errorf("Start job after shutdown error is %v", err)
+	}
+}
+
+func BenchmarkHttpServerShutdown(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := NewHttpServer("127.0.0.1", "12514")
+		_ = s.Shutdown(context.Background())
+		_ = s.ListenAndServe()
+	}
+}
+
+func TestHttpServerShutdownOther(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	s1, errc := startTestHttpServer(t, "12516")
+	s2, _ := startTestHttpServer(t, "12517")
+	defer s2.Shutdown(context.Background())
+	err = s1.Shutdown(context.Background())
+	if err != nil {
+		t.Errorf("Error shutdown http server: %v", err)
+	}
+	<-errc
+	// the other server still accept jobs
+	dest, _ := json.Marshal(filepath.Join(dir, "file.pak"))
+	body := `{"src": ["../test/data/pack/file_1.txt"], "dest": ` + string(dest) + `, "type": "aes"}`
+	resp, err := http.Post("http://127.0.0.1:12517"+HttpURLPack, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal("Error post pack:", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Pack on other server response code is %v", resp.StatusCode)
+	}
+	// a new server in same process accept jobs too
+	s3 := NewHttpServer("127.0.0.1", "12516")
+	job, err := s3.jobs.Start("1", "pack", nil)
+	if err != nil {
+		t.Fatalf("Start job on new server error is %v", err)
+	}
+	job.Done(nil)
+}
+
+func BenchmarkHttpServerShutdownOther(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s1 := NewHttpServer("127.0.0.1", "12516")
+		s2 := NewHttpServer("127.0.0.1", "12517")
+		_ = s1.Shutdown(context.Background())
+		job, err := s2.jobs.Start("1", "pack", nil)
+		if err != nil {
+			b.Fatal("Error start job:", err)
+		}
+		job.Done(nil)
+	}
+}
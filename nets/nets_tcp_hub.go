@@ -0,0 +1,297 @@
+package nets
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	. "satellite/global"
+	"satellite/logs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TNetsTcpHub is multi-client tcp chat server of line protocol,
+// "/nick <name>" register or change nickname, "/msg <name> <text>" send direct message,
+// "/who" list nicknames, "/quit" leave, other lines are broadcast to registered clients
+type TNetsTcpHub struct {
+	Addr       string
+	transcript *logs.Logger
+	mutex      sync.Mutex
+	clients    map[string]*tNetsHubClient
+	conns      tNetsConns
+}
+
+// tNetsHubClient is one connection of hub, Nick is empty before registered
+type tNetsHubClient struct {
+	Nick  string
+	conn  net.Conn
+	queue chan string
+	once  sync.Once
+}
+
+var hubNickPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+var (
+	ErrHubNickInvalid = errors.New("nickname should be letters, digits, '_' or '-'")
+	ErrHubNickInUse   = errors.New("nickname in use")
+)
+
+// NewTcpHub function
+// create hub listen on ip:port, append transcript to file when not empty
+func NewTcpHub(ip string, port string, transcript string) (*TNetsTcpHub, error) {
+	h := &TNetsTcpHub{Addr: ip + ":" + port, clients: make(map[string]*tNetsHubClient)}
+	if transcript != "" {
+		w, err := logs.NewFileWriter(transcript, os.O_CREATE|os.O_APPEND|os.O_WRONLY)
+		if err != nil {
+			log.Println("Error open transcript:", err)
+			return nil, err
+		}
+		h.transcript = logs.NewLogger(w, logs.Ltime)
+	}
+	return h, nil
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (h *TNetsTcpHub) ListenAndServe() error {
+	l, err := net.Listen("tcp", h.Addr)
+	if err != nil {
+		fmt.Println("Error listen tcp:", err)
+		log.Println("Error listen tcp:", err)
+		return err
+	}
+	if !h.conns.setListener(l) {
+		return l.Close()
+	}
+	fmt.Println("Start Tcp Hub on", h.Addr)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if h.conns.isClosing() {
+				return nil
+			}
+			log.Println("Error accept connect:", err)
+			continue
+		}
+		if !h.conns.add(conn) {
+			_ = conn.Close()
+			continue
+		}
+		go func() {
+			defer h.conns.remove(conn)
+			h.serve(conn)
+		}()
+	}
+}
+
+// Shutdown function
+// close listener and connections, then transcript
+func (h *TNetsTcpHub) Shutdown(ctx context.Context) error {
+	err := h.conns.shutdown(ctx, false)
+	if h.transcript != nil {
+		h.transcript.Close()
+	}
+	return err
+}
+
+// serve function
+// read lines of client until quit or disconnect
+func (h *TNetsTcpHub) serve(conn net.Conn) {
+	c := &tNetsHubClient{conn: conn, queue: make(chan string, TcpHubQueue)}
+	go c.write()
+	defer h.leave(c)
+	c.send("* Welcome to satellite hub, register with /nick <name>")
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, TCPBufferSize), TCPBufferSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !h.handle(c, line) {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("Error read hub client:", err)
+	}
+}
+
+// handle function
+// handle one line of client, false when client quit
+func (h *TNetsTcpHub) handle(c *tNetsHubClient, line string) bool {
+	if !strings.HasPrefix(line, "/") {
+		if c.Nick == "" {
+			c.send("! register with /nick <name> first")
+			return true
+		}
+		h.broadcast(c, "<"+c.Nick+"> "+line)
+		return true
+	}
+	fields := strings.SplitN(line, " ", 3)
+	switch fields[0] {
+	case "/nick":
+		if len(fields) != 2 {
+			c.send("! usage: /nick <name>")
+			return true
+		}
+		err := h.nick(c, fields[1])
+		if err != nil {
+			c.send("! " + err.Error())
+		}
+	case "/msg":
+		if c.Nick == "" {
+			c.send("! register with /nick <name> first")
+			return true
+		}
+		if len(fields) != 3 || strings.TrimSpace(fields[2]) == "" {
+			c.send("! usage: /msg <name> <text>")
+			return true
+		}
+		h.direct(c, fields[1], strings.TrimSpace(fields[2]))
+	case "/who":
+		c.send("* online: " + strings.Join(h.who(), ", "))
+	case "/quit":
+		c.send("* bye")
+		return false
+	default:
+		c.send("! unknown command " + fields[0] + ", use /nick, /msg, /who or /quit")
+	}
+	return true
+}
+
+// nick function
+// register nickname and notify join, or rename
+func (h *TNetsTcpHub) nick(c *tNetsHubClient, name string) error {
+	if len(name) > TcpHubMaxNick || !hubNickPattern.MatchString(name) {
+		return ErrHubNickInvalid
+	}
+	h.mutex.Lock()
+	if v, ok := h.clients[strings.ToLower(name)]; ok && v != c {
+		h.mutex.Unlock()
+		return ErrHubNickInUse
+	}
+	old := c.Nick
+	if old != "" {
+		delete(h.clients, strings.ToLower(old))
+	}
+	c.Nick = name
+	h.clients[strings.ToLower(name)] = c
+	h.mutex.Unlock()
+	if old == "" {
+		h.broadcast(nil, "* "+name+" joined")
+	} else {
+		h.broadcast(nil, "* "+old+" is now known as "+name)
+	}
+	return nil
+}
+
+// direct function
+// send text to one nickname, echo to sender
+func (h *TNetsTcpHub) direct(c *tNetsHubClient, name string, text string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	to, ok := h.clients[strings.ToLower(name)]
+	if !ok {
+		c.send("! no such nickname " + name)
+		return
+	}
+	to.send("*" + c.Nick + "* " + text)
+	if to != c {
+		c.send("-> " + to.Nick + ": " + text)
+	}
+}
+
+// who function
+// sorted nicknames of registered clients
+func (h *TNetsTcpHub) who() (names []string) {
+	h.mutex.Lock()
+	for _, v := range h.clients {
+		names = append(names, v.Nick)
+	}
+	h.mutex.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// broadcast function
+// send line to registered clients except from, record it in transcript
+func (h *TNetsTcpHub) broadcast(from *tNetsHubClient, line string) {
+	h.mutex.Lock()
+	for _, v := range h.clients {
+		if v != from {
+			v.send(line)
+		}
+	}
+	h.mutex.Unlock()
+	if h.transcript != nil {
+		h.transcript.Info(line)
+	}
+}
+
+// leave function
+// unregister client and notify others
+func (h *TNetsTcpHub) leave(c *tNetsHubClient) {
+	h.mutex.Lock()
+	registered := c.Nick != "" && h.clients[strings.ToLower(c.Nick)] == c
+	if registered {
+		delete(h.clients, strings.ToLower(c.Nick))
+	}
+	h.mutex.Unlock()
+	c.close()
+	if registered {
+		h.broadcast(nil, "* "+c.Nick+" left")
+	}
+}
+
+// send function
+// queue line for client, drop client when queue full
+func (c *tNetsHubClient) send(line string) {
+	select {
+	case c.queue <- line:
+	default:
+		log.Println("Drop slow hub client:", c.conn.RemoteAddr().String())
+		_ = c.conn.Close()
+	}
+}
+
+// write function
+// write queued lines until closed
+func (c *tNetsHubClient) write() {
+	for line := range c.queue {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(TcpTransferTimeout * time.Millisecond))
+		_, err := c.conn.Write([]byte(line + "\n"))
+		if err != nil {
+			log.Println("Error write hub client:", err)
+			_ = c.conn.Close()
+			break
+		}
+	}
+	for range c.queue {
+	}
+	_ = c.conn.Close()
+}
+
+// close function
+// stop writer after queued lines are written, then close connection
+func (c *tNetsHubClient) close() {
+	c.once.Do(func() {
+		close(c.queue)
+	})
+}
+
+func StartTcpHub(ip string, port string, transcript string) error {
+	h, err := NewTcpHub(ip, port, transcript)
+	if err != nil {
+		fmt.Println("Error create tcp hub:", err)
+		return err
+	}
+	return RunServer(h, NetShutdownTimeout*time.Millisecond)
+}
@@ -0,0 +1,135 @@
+package nets
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tTestHubClient is line client of hub for tests
+type tTestHubClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialTestHub(t testing.TB, addr string, nick string) *tTestHubClient {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		conn, err = net.Dial("tcp", addr)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal("Error dial tcp hub:", err)
+	}
+	c := &tTestHubClient{conn: conn, r: bufio.NewReader(conn)}
+	c.expect(t, "* Welcome")
+	if nick != "" {
+		c.say(nick, "/nick "+nick)
+		c.expect(t, "* "+nick+" joined")
+	}
+	return c
+}
+
+func (c *tTestHubClient) say(nick string, line string) {
+	_, _ = c.conn.Write([]byte(line + "\n"))
+}
+
+// expect function
+// read next line, it should start with prefix
+func (c *tTestHubClient) expect(t testing.TB, prefix string) string {
+	_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, prefix) {
+		t.Errorf("Hub line is %q, should start with %q: %v", line, prefix, err)
+	}
+	return strings.TrimSpace(line)
+}
+
+func TestTcpHub(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	transcript := filepath.Join(dir, "log", "hub.log")
+	h, err := NewTcpHub("127.0.0.1", "11522", transcript)
+	if err != nil {
+		t.Fatal("Error create tcp hub:", err)
+	}
+	go h.ListenAndServe()
+	alice := dialTestHub(t, "127.0.0.1:11522", "alice")
+	defer alice.conn.Close()
+	bob := dialTestHub(t, "127.0.0.1:11522", "bob")
+	defer bob.conn.Close()
+	alice.expect(t, "* bob joined")
+	// register required and nickname unique
+	guest := dialTestHub(t, "127.0.0.1:11522", "")
+	guest.say("", "hello")
+	guest.expect(t, "! register")
+	guest.say("", "/nick Alice")
+	guest.expect(t, "! nickname in use")
+	guest.say("", "/nick ../x")
+	guest.expect(t, "! nickname should be")
+	guest.say("", "/quit")
+	guest.expect(t, "* bye")
+	guest.conn.Close()
+	// broadcast
+	alice.say("alice", "hello all")
+	bob.expect(t, "<alice> hello all")
+	// direct message
+	bob.say("bob", "/msg alice hi alice")
+	alice.expect(t, "*bob* hi alice")
+	bob.expect(t, "-> alice: hi alice")
+	bob.say("bob", "/msg carol hi")
+	bob.expect(t, "! no such nickname carol")
+	// who and rename
+	alice.say("alice", "/who")
+	alice.expect(t, "* online: alice, bob")
+	bob.say("bob", "/nick robert")
+	alice.expect(t, "* bob is now known as robert")
+	bob.expect(t, "* bob is now known as robert")
+	// leave
+	bob.conn.Close()
+	alice.expect(t, "* robert left")
+	err = h.Shutdown(context.Background())
+	if err != nil {
+		t.Errorf("Error shutdown tcp hub: %v", err)
+	}
+	data, err := ioutil.ReadFile(transcript)
+	if err != nil {
+		t.Fatal("Error read transcript:", err)
+	}
+	for _, v := range []string{"* alice joined", "<alice> hello all", "* robert left"} {
+		if !strings.Contains(string(data), v) {
+			t.Errorf("Transcript without %q", v)
+		}
+	}
+	if strings.Contains(string(data), "hi alice") {
+		t.Error("Transcript should not record direct messages")
+	}
+}
+
+func BenchmarkTcpHub(b *testing.B) {
+	h, err := NewTcpHub("127.0.0.1", "11523", "")
+	if err != nil {
+		b.Fatal("Error create tcp hub:", err)
+	}
+	go h.ListenAndServe()
+	defer h.Shutdown(context.Background())
+	alice := dialTestHub(b, "127.0.0.1:11523", "alice")
+	defer alice.conn.Close()
+	for i := 0; i < b.N; i++ {
+		alice.say("alice", "/who")
+		alice.expect(b, "* online: alice")
+	}
+}
@@ -1,39 +1,79 @@
 package nets
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
-	"os"
+	. "satellite/global"
+	"time"
 )
 
-func StartTcpServer(ip string, port string) {
+// TNetsTcpServer is tcp message server,
+// Shutdown close listener and all connections
+type TNetsTcpServer struct {
+	Addr  string
+	conns tNetsConns
+}
+
+// NewTcpServer function
+// create tcp server listen on ip:port
+func NewTcpServer(ip string, port string) *TNetsTcpServer {
+	return &TNetsTcpServer{Addr: ip + ":" + port}
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (s *TNetsTcpServer) ListenAndServe() error {
 	// resolve ip address
 	fmt.Println("Start Tcp Server")
-	addr, err := net.ResolveTCPAddr("tcp", ip+":"+port)
+	addr, err := net.ResolveTCPAddr("tcp", s.Addr)
 	if err != nil {
 		fmt.Println("Error resolve ip address:", err)
 		log.Println("Error resolve ip address:", err)
-		os.Exit(1)
+		return err
 	}
 	// start listen tcp
-	fmt.Println("Listen Tcp:", ip+":"+port)
-	socket, err := net.ListenTCP("tcp", addr)
+	fmt.Println("Listen Tcp:", s.Addr)
+	l, err := net.ListenTCP("tcp", addr)
 	if err != nil {
 		fmt.Println("Error listen tcp:", err)
 		log.Println("Error listen tcp:", err)
-		os.Exit(1)
+		return err
+	}
+	if !s.conns.setListener(l) {
+		return l.Close()
 	}
 	// loop waiting for connect...
 	fmt.Println("Loop waiting for connect...")
 	for {
-		conn, err := socket.Accept()
+		conn, err := l.Accept()
 		if err != nil {
+			if s.conns.isClosing() {
+				return nil
+			}
 			fmt.Println("Error accept connect:", err)
 			log.Println("Error accept connect:", err)
 			continue
 		}
+		if !s.conns.add(conn) {
+			_ = conn.Close()
+			continue
+		}
 		fmt.Println("Success accept client:", conn.RemoteAddr().String())
-		go connTcpRecvHandler(conn)
+		go func() {
+			defer s.conns.remove(conn)
+			connTcpRecvHandler(conn)
+		}()
 	}
 }
+
+// Shutdown function
+// close listener and connections, wait receive handlers within ctx
+func (s *TNetsTcpServer) Shutdown(ctx context.Context) error {
+	return s.conns.shutdown(ctx, false)
+}
+
+func StartTcpServer(ip string, port string) error {
+	return RunServer(NewTcpServer(ip, port), NetShutdownTimeout*time.Millisecond)
+}
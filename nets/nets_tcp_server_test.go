@@ -1,6 +1,11 @@
 package nets
 
-import "testing"
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
 
 func TestStartTcpServer(t *testing.T) {
 	ip := "127.0.0.1"
@@ -12,3 +17,42 @@ func TestStartTcpServer(t *testing.T) {
 	// start tcp client...
 	StartTcpClient(ip, port)
 }
+
+func TestTcpServerShutdown(t *testing.T) {
+	s := NewTcpServer("127.0.0.1", "11515")
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.ListenAndServe()
+	}()
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		conn, err = net.Dial("tcp", "127.0.0.1:11515")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal("Error dial tcp server:", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Errorf("Error shutdown tcp server: %v", err)
+	}
+	err = <-errc
+	if err != nil {
+		t.Errorf("Tcp server stopped by error: %v", err)
+	}
+}
+
+func BenchmarkTcpServerShutdown(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := NewTcpServer("127.0.0.1", "11515")
+		_ = s.Shutdown(context.Background())
+		_ = s.ListenAndServe()
+	}
+}
@@ -0,0 +1,580 @@
+package nets
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	. "satellite/global"
+	. "satellite/utils"
+	"strings"
+	"time"
+)
+
+// transfer frame is type(1 byte), payload length(4 bytes big endian) and payload,
+// sender: H hello, then F file header, D data ... for each file, Q quit
+// receiver: H hello ack, O offset and K done for each file, E error
+const (
+	tcpFrameHello  byte = 'H'
+	tcpFrameFile   byte = 'F'
+	tcpFrameOffset byte = 'O'
+	tcpFrameData   byte = 'D'
+	tcpFrameDone   byte = 'K'
+	tcpFrameQuit   byte = 'Q'
+	tcpFrameError  byte = 'E'
+)
+
+// TNetsTransferHello is first frame of sender, Salt is set when encrypted
+type TNetsTransferHello struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt,omitempty"`
+}
+
+// TNetsTransferFile is file header, Name is slash separated relative path
+type TNetsTransferFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// TNetsTransferOffset is receiver answer of file header,
+// sender continue from Offset, Offset equal Size means file already received
+type TNetsTransferOffset struct {
+	Offset int64 `json:"offset"`
+}
+
+var (
+	ErrTransferVersion  = errors.New("unsupported transfer protocol version")
+	ErrTransferKey      = errors.New("transfer encryption key mismatch")
+	ErrTransferFrame    = errors.New("invalid transfer frame")
+	ErrTransferName     = errors.New("invalid transfer file name")
+	ErrTransferChecksum = errors.New("transfer file sha256 mismatch")
+	ErrTransferRemote   = errors.New("transfer refused by remote")
+)
+
+// tNetsFrameConn read and write length-prefixed frames,
+// payloads except error frames are sealed by AES-GCM when aead set
+type tNetsFrameConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	sender bool
+	send   uint64
+	recv   uint64
+}
+
+func newNetsFrameConn(conn net.Conn, sender bool) *tNetsFrameConn {
+	return &tNetsFrameConn{conn: conn, r: bufio.NewReader(conn), sender: sender}
+}
+
+// writeFrame function
+// write one frame, seal payload when encrypted
+func (c *tNetsFrameConn) writeFrame(t byte, payload []byte) error {
+	if c.aead != nil && t != tcpFrameError {
+		payload = c.aead.Seal(nil, c.nonce(c.sender, c.send), payload, []byte{t})
+		c.send++
+	}
+	b := make([]byte, 5, 5+len(payload))
+	b[0] = t
+	binary.BigEndian.PutUint32(b[1:], uint32(len(payload)))
+	_, err := c.conn.Write(append(b, payload...))
+	return err
+}
+
+// readFrame function
+// read one frame within TcpTransferTimeout, error frame is returned as error
+func (c *tNetsFrameConn) readFrame() (t byte, payload []byte, err error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(TcpTransferTimeout * time.Millisecond))
+	b := make([]byte, 5)
+	_, err = io.ReadFull(c.r, b)
+	if err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(b[1:])
+	if n > TcpTransferFrameSize+1024 {
+		return 0, nil, ErrTransferFrame
+	}
+	payload = make([]byte, n)
+	_, err = io.ReadFull(c.r, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	t = b[0]
+	if t == tcpFrameError {
+		return t, nil, fmt.Errorf("%w: %s", ErrTransferRemote, payload)
+	}
+	if c.aead != nil {
+		payload, err = c.aead.Open(payload[:0], c.nonce(!c.sender, c.recv), payload, []byte{t})
+		if err != nil {
+			return 0, nil, ErrTransferKey
+		}
+		c.recv++
+	}
+	return t, payload, nil
+}
+
+// writeJSON function
+// write frame with json payload
+func (c *tNetsFrameConn) writeJSON(t byte, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(t, payload)
+}
+
+// readJSON function
+// read frame of type t, unmarshal json payload into v when not nil
+func (c *tNetsFrameConn) readJSON(t byte, v interface{}) error {
+	ft, payload, err := c.readFrame()
+	if err != nil {
+		return err
+	}
+	if ft != t {
+		return ErrTransferFrame
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// nonce function
+// direction byte and frame counter, unique for key of one connection
+func (c *tNetsFrameConn) nonce(sender bool, n uint64) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	if sender {
+		nonce[0] = 1
+	}
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], n)
+	return nonce
+}
+
+func transferCipher(key string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(PBKDF2([]byte(key), salt, TcpTransferIterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// TNetsTcpRecvServer receive files of tcp senders into Dest,
+// Shutdown close listener and all connections
+type TNetsTcpRecvServer struct {
+	Addr  string
+	Dest  string
+	key   string
+	conns tNetsConns
+}
+
+// NewTcpRecvServer function
+// create receiver listen on ip:port, key empty means no encryption
+func NewTcpRecvServer(ip string, port string, dest string, key string) *TNetsTcpRecvServer {
+	return &TNetsTcpRecvServer{Addr: ip + ":" + port, Dest: dest, key: key}
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (s *TNetsTcpRecvServer) ListenAndServe() error {
+	err := os.MkdirAll(s.Dest, os.ModePerm)
+	if err != nil {
+		fmt.Println("Error create target directory:", err)
+		log.Println("Error create target directory:", err)
+		return err
+	}
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		fmt.Println("Error listen tcp:", err)
+		log.Println("Error listen tcp:", err)
+		return err
+	}
+	if !s.conns.setListener(l) {
+		return l.Close()
+	}
+	fmt.Println("Receive files into", s.Dest, "on", s.Addr)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.conns.isClosing() {
+				return nil
+			}
+			log.Println("Error accept connect:", err)
+			continue
+		}
+		if !s.conns.add(conn) {
+			_ = conn.Close()
+			continue
+		}
+		go func() {
+			defer s.conns.remove(conn)
+			s.serve(conn)
+		}()
+	}
+}
+
+// Shutdown function
+// close listener and connections, partial files are kept for resume
+func (s *TNetsTcpRecvServer) Shutdown(ctx context.Context) error {
+	return s.conns.shutdown(ctx, false)
+}
+
+// serve function
+// handshake with sender, then receive files until quit
+func (s *TNetsTcpRecvServer) serve(conn net.Conn) {
+	defer conn.Close()
+	c := newNetsFrameConn(conn, false)
+	// check hello of sender
+	var hello TNetsTransferHello
+	err := c.readJSON(tcpFrameHello, &hello)
+	if err != nil {
+		log.Println("Error read transfer hello:", err)
+		return
+	}
+	if hello.Version != TcpTransferVersion {
+		_ = c.writeFrame(tcpFrameError, []byte(ErrTransferVersion.Error()))
+		return
+	}
+	if (s.key != "") != (len(hello.Salt) > 0) {
+		_ = c.writeFrame(tcpFrameError, []byte(ErrTransferKey.Error()))
+		return
+	}
+	if s.key != "" {
+		c.aead, err = transferCipher(s.key, hello.Salt)
+		if err != nil {
+			log.Println("Error create transfer cipher:", err)
+			return
+		}
+	}
+	err = c.writeFrame(tcpFrameHello, nil)
+	if err != nil {
+		log.Println("Error write transfer hello:", err)
+		return
+	}
+	// receive files
+	for {
+		t, payload, err := c.readFrame()
+		if err == nil {
+			switch t {
+			case tcpFrameQuit:
+				return
+			case tcpFrameFile:
+				err = s.receive(c, payload)
+			default:
+				err = ErrTransferFrame
+			}
+		}
+		if err != nil {
+			log.Println("Error receive file:", err)
+			if errors.Is(err, ErrTransferKey) || errors.Is(err, ErrTransferFrame) || errors.Is(err, ErrTransferName) || errors.Is(err, ErrTransferChecksum) {
+				_ = c.writeFrame(tcpFrameError, []byte(err.Error()))
+			}
+			return
+		}
+	}
+}
+
+// receive function
+// answer offset of partial file, write data frames to it,
+// rename it to target after sha256 verified
+func (s *TNetsTcpRecvServer) receive(c *tNetsFrameConn, payload []byte) error {
+	var f TNetsTransferFile
+	err := json.Unmarshal(payload, &f)
+	if err != nil || f.Size < 0 || len(f.SHA256) != sha256.Size*2 {
+		return ErrTransferFrame
+	}
+	dest, err := transferPath(s.Dest, f.Name)
+	if err != nil {
+		return err
+	}
+	// file already received
+	if sum, err := transferSHA256(dest); err == nil && sum == f.SHA256 {
+		err = c.writeJSON(tcpFrameOffset, TNetsTransferOffset{Offset: f.Size})
+		if err != nil {
+			return err
+		}
+		return c.writeFrame(tcpFrameDone, nil)
+	}
+	// resume from partial file of same content
+	err = os.MkdirAll(filepath.Dir(dest), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	part := dest + "." + f.SHA256[:16] + ".part"
+	file, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if offset > f.Size {
+		offset = 0
+		err = file.Truncate(0)
+		if err == nil {
+			_, err = file.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = c.writeJSON(tcpFrameOffset, TNetsTransferOffset{Offset: offset})
+	if err != nil {
+		return err
+	}
+	// write data frames
+	for offset < f.Size {
+		t, data, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		if t != tcpFrameData || offset+int64(len(data)) > f.Size {
+			return ErrTransferFrame
+		}
+		_, err = file.Write(data)
+		if err != nil {
+			return err
+		}
+		offset += int64(len(data))
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	// verify and rename
+	sum, err := transferSHA256(part)
+	if err != nil {
+		return err
+	}
+	if sum != f.SHA256 {
+		_ = os.Remove(part)
+		return ErrTransferChecksum
+	}
+	err = os.Rename(part, dest)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Received file:", f.Name)
+	return c.writeFrame(tcpFrameDone, nil)
+}
+
+// transferPath function
+// target path of relative file name, should stay in dest
+func transferPath(dest string, name string) (string, error) {
+	clean := path.Clean(name)
+	if name == "" || strings.Contains(name, "\\") || path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", ErrTransferName
+	}
+	dir, err := RealPath(dest)
+	if err != nil {
+		return "", err
+	}
+	target, err := RealPath(filepath.Join(dest, filepath.FromSlash(clean)))
+	if err != nil {
+		return "", err
+	}
+	if !PathWithin(dir, target) || target == dir {
+		return "", ErrTransferName
+	}
+	return target, nil
+}
+
+// transferSHA256 function
+// hex sha256 of file content
+func transferSHA256(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// tNetsTransferItem is local file of sender
+type tNetsTransferItem struct {
+	Path string
+	File TNetsTransferFile
+}
+
+// transferItems function
+// list files of src, directories are walked and named relative to their parent
+func transferItems(src []string) (items []tNetsTransferItem, err error) {
+	for _, v := range src {
+		v = filepath.Clean(v)
+		base := filepath.Dir(v)
+		err = filepath.Walk(v, func(p string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(base, p)
+			if err != nil {
+				return err
+			}
+			sum, err := transferSHA256(p)
+			if err != nil {
+				return err
+			}
+			items = append(items, tNetsTransferItem{Path: p, File: TNetsTransferFile{Name: filepath.ToSlash(rel), Size: info.Size(), SHA256: sum}})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// SendTcpFiles function
+// send files and directories in src to receiver at ip:port,
+// reconnect after disconnect and resume from receiver offset,
+// key empty means no encryption, return names of sent files
+func SendTcpFiles(ip string, port string, src []string, key string) (files []string, err error) {
+	items, err := transferItems(src)
+	if err != nil {
+		log.Println("Error list source files:", err)
+		return nil, err
+	}
+	next := 0
+	for retry := 0; ; retry++ {
+		err = sendTcpFiles(ip+":"+port, key, items, &next)
+		if err == nil || !retryableTransfer(err) || retry >= TcpTransferRetries {
+			break
+		}
+		fmt.Println("Connection lost, reconnect:", err)
+		log.Println("Error send files:", err)
+		time.Sleep(TcpTransferRetryWait * time.Millisecond)
+	}
+	for _, v := range items[:next] {
+		files = append(files, v.File.Name)
+	}
+	return files, err
+}
+
+// sendTcpFiles function
+// send items from next on one connection, next is advanced when file done
+func sendTcpFiles(addr string, key string, items []tNetsTransferItem, next *int) error {
+	conn, err := net.DialTimeout("tcp", addr, TcpTransferTimeout*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	c := newNetsFrameConn(conn, true)
+	// hello with salt of encryption key
+	hello := TNetsTransferHello{Version: TcpTransferVersion}
+	if key != "" {
+		hello.Salt = make([]byte, TcpTransferSaltSize)
+		_, err = rand.Read(hello.Salt)
+		if err != nil {
+			return err
+		}
+	}
+	err = c.writeJSON(tcpFrameHello, hello)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		c.aead, err = transferCipher(key, hello.Salt)
+		if err != nil {
+			return err
+		}
+	}
+	err = c.readJSON(tcpFrameHello, nil)
+	if err != nil {
+		return err
+	}
+	// send files
+	for ; *next < len(items); *next++ {
+		err = sendTcpFile(c, items[*next])
+		if err != nil {
+			return err
+		}
+	}
+	return c.writeFrame(tcpFrameQuit, nil)
+}
+
+// sendTcpFile function
+// send file header, then data from receiver offset
+func sendTcpFile(c *tNetsFrameConn, item tNetsTransferItem) error {
+	err := c.writeJSON(tcpFrameFile, item.File)
+	if err != nil {
+		return err
+	}
+	var o TNetsTransferOffset
+	err = c.readJSON(tcpFrameOffset, &o)
+	if err != nil {
+		return err
+	}
+	if o.Offset < 0 || o.Offset > item.File.Size {
+		return ErrTransferFrame
+	}
+	file, err := os.Open(item.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Seek(o.Offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, TcpTransferFrameSize)
+	for sent := o.Offset; sent < item.File.Size; {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if sent+int64(n) > item.File.Size {
+				n = int(item.File.Size - sent)
+			}
+			err = c.writeFrame(tcpFrameData, buf[:n])
+			if err != nil {
+				return err
+			}
+			sent += int64(n)
+			continue
+		}
+		if err == io.EOF {
+			return ErrTransferChecksum
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = c.readJSON(tcpFrameDone, nil)
+	if err != nil {
+		return err
+	}
+	if o.Offset > 0 {
+		fmt.Printf("Sent file: %v (resumed at %v)\n", item.File.Name, o.Offset)
+	} else {
+		fmt.Println("Sent file:", item.File.Name)
+	}
+	return nil
+}
+
+// retryableTransfer function
+// connection errors are retried, errors of protocol and files are not
+func retryableTransfer(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func StartTcpRecvServer(ip string, port string, dest string, key string) error {
+	return RunServer(NewTcpRecvServer(ip, port, dest, key), NetShutdownTimeout*time.Millisecond)
+}
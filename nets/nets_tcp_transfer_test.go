@@ -0,0 +1,272 @@
+package nets
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	. "satellite/global"
+	"testing"
+	"time"
+)
+
+// startTestRecvServer function
+// start receiver into temp dir, return dir and server
+func startTestRecvServer(t testing.TB, port string, key string) (string, *TNetsTcpRecvServer) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	s := NewTcpRecvServer("127.0.0.1", port, filepath.Join(dir, "recv"), key)
+	go s.ListenAndServe()
+	for i := 0; i < 50; i++ {
+		conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return dir, s
+}
+
+// writeTestTransferFile function
+// random file of size bytes in dir
+func writeTestTransferFile(t testing.TB, dir string, size int) (string, []byte) {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	name := filepath.Join(dir, "random.bin")
+	err := ioutil.WriteFile(name, data, 0644)
+	if err != nil {
+		t.Fatal("Error write file:", err)
+	}
+	return name, data
+}
+
+// copyTestTransferPack function
+// copy pack fixture files into dir, other tests write into the shared fixture
+func copyTestTransferPack(t testing.TB, dir string) string {
+	pack := filepath.Join(dir, "src", "pack")
+	err := os.MkdirAll(pack, 0755)
+	if err != nil {
+		t.Fatal("Error create directory:", err)
+	}
+	for _, v := range []string{"file.txt", "file_1.txt", "file_2.txt", "file_3.txt", "file_4.txt", "file_5.txt", "file_gob.gob"} {
+		data, err := ioutil.ReadFile(filepath.Join("../test/data/pack", v))
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(pack, v), data, 0644)
+		}
+		if err != nil {
+			t.Fatal("Error copy fixture:", err)
+		}
+	}
+	return pack
+}
+
+func TestSendTcpFiles(t *testing.T) {
+	for k, v := range map[string]string{"": "11516", "secret": "11517"} {
+		dir, s := startTestRecvServer(t, v, k)
+		name, data := writeTestTransferFile(t, dir, 3*TcpTransferFrameSize+100)
+		pack := copyTestTransferPack(t, dir)
+		files, err := SendTcpFiles("127.0.0.1", v, []string{pack, name}, k)
+		if err != nil {
+			t.Errorf("Error send files with key %q: %v", k, err)
+		}
+		names := []string{"pack/file.txt", "pack/file_1.txt", "pack/file_2.txt", "pack/file_3.txt", "pack/file_4.txt", "pack/file_5.txt", "pack/file_gob.gob", "random.bin"}
+		if !reflect.DeepEqual(files, names) {
+			t.Errorf("Sent files are %v", files)
+		}
+		got, err := ioutil.ReadFile(filepath.Join(dir, "recv", "random.bin"))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Received file differ: %v", err)
+		}
+		got, err = ioutil.ReadFile(filepath.Join(dir, "recv", "pack", "file_gob.gob"))
+		want, _ := ioutil.ReadFile("../test/data/pack/file_gob.gob")
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("Received file of directory differ: %v", err)
+		}
+		// send again, files already received
+		_, err = SendTcpFiles("127.0.0.1", v, []string{name}, k)
+		if err != nil {
+			t.Errorf("Error send received files: %v", err)
+		}
+		_ = s.Shutdown(context.Background())
+		os.RemoveAll(dir)
+	}
+}
+
+func BenchmarkSendTcpFiles(b *testing.B) {
+	dir, s := startTestRecvServer(b, "11518", "")
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	for i := 0; i < b.N; i++ {
+		_, err := SendTcpFiles("127.0.0.1", "11518", []string{"../test/data/pack"}, "")
+		if err != nil {
+			b.Error("Error send files:", err)
+		}
+	}
+}
+
+func TestSendTcpFilesKey(t *testing.T) {
+	dir, s := startTestRecvServer(t, "11519", "secret")
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	start := time.Now()
+	_, err := SendTcpFiles("127.0.0.1", "11519", []string{"../test/data/pack/file.txt"}, "wrong")
+	if !errors.Is(err, ErrTransferKey) {
+		t.Errorf("Wrong key error is %v", err)
+	}
+	_, err = SendTcpFiles("127.0.0.1", "11519", []string{"../test/data/pack/file.txt"}, "")
+	if !errors.Is(err, ErrTransferRemote) {
+		t.Errorf("No key error is %v", err)
+	}
+	if time.Since(start) > TcpTransferRetryWait*time.Millisecond {
+		t.Error("Key errors should not be retried")
+	}
+}
+
+func BenchmarkSendTcpFilesKey(b *testing.B) {
+	dir, s := startTestRecvServer(b, "11545", "secret")
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	name, _ := writeTestTransferFile(b, dir, 3*TcpTransferFrameSize)
+	for i := 0; i < b.N; i++ {
+		// received file is skipped, remove it to send encrypted data again
+		_ = os.Remove(filepath.Join(dir, "recv", "random.bin"))
+		_, err := SendTcpFiles("127.0.0.1", "11545", []string{name}, "secret")
+		if err != nil {
+			b.Error("Error send files with key:", err)
+		}
+	}
+}
+
+func TestTcpTransferResume(t *testing.T) {
+	dir, s := startTestRecvServer(t, "11520", "")
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	name, data := writeTestTransferFile(t, dir, 2*TcpTransferFrameSize)
+	items, err := transferItems([]string{name})
+	if err != nil {
+		t.Fatal("Error list files:", err)
+	}
+	// partial file left by broken connection
+	part := filepath.Join(dir, "recv", "random.bin."+items[0].File.SHA256[:16]+".part")
+	err = ioutil.WriteFile(part, data[:1000], 0644)
+	if err != nil {
+		t.Fatal("Error write partial file:", err)
+	}
+	conn, err := net.Dial("tcp", "127.0.0.1:11520")
+	if err != nil {
+		t.Fatal("Error dial receiver:", err)
+	}
+	defer conn.Close()
+	c := newNetsFrameConn(conn, true)
+	_ = c.writeJSON(tcpFrameHello, TNetsTransferHello{Version: TcpTransferVersion})
+	err = c.readJSON(tcpFrameHello, nil)
+	if err != nil {
+		t.Fatal("Error read hello:", err)
+	}
+	_ = c.writeJSON(tcpFrameFile, items[0].File)
+	var o TNetsTransferOffset
+	err = c.readJSON(tcpFrameOffset, &o)
+	if err != nil || o.Offset != 1000 {
+		t.Fatalf("Resume offset is %v: %v", o.Offset, err)
+	}
+	for i := 1000; i < len(data); i += TcpTransferFrameSize {
+		end := i + TcpTransferFrameSize
+		if end > len(data) {
+			end = len(data)
+		}
+		_ = c.writeFrame(tcpFrameData, data[i:end])
+	}
+	err = c.readJSON(tcpFrameDone, nil)
+	if err != nil {
+		t.Fatal("Error read done:", err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "recv", "random.bin"))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Resumed file differ: %v", err)
+	}
+	if _, err = os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf("Partial file not removed: %v", err)
+	}
+}
+
+func BenchmarkTcpTransferResume(b *testing.B) {
+	dir, s := startTestRecvServer(b, "11546", "")
+	defer os.RemoveAll(dir)
+	defer s.Shutdown(context.Background())
+	name, data := writeTestTransferFile(b, dir, 3*TcpTransferFrameSize)
+	items, err := transferItems([]string{name})
+	if err != nil {
+		b.Fatal("Error list files:", err)
+	}
+	part := filepath.Join(dir, "recv", "random.bin."+items[0].File.SHA256[:16]+".part")
+	for i := 0; i < b.N; i++ {
+		// first frame left by broken connection, the rest is resumed
+		_ = os.Remove(filepath.Join(dir, "recv", "random.bin"))
+		err = ioutil.WriteFile(part, data[:TcpTransferFrameSize], 0644)
+		if err != nil {
+			b.Fatal("Error write partial file:", err)
+		}
+		_, err = SendTcpFiles("127.0.0.1", "11546", []string{name}, "")
+		if err != nil {
+			b.Error("Error resume files:", err)
+		}
+	}
+}
+
+func TestSendTcpFilesReconnect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// receiver start after first connection refused
+	s := NewTcpRecvServer("127.0.0.1", "11521", dir, "")
+	time.AfterFunc(200*time.Millisecond, func() { _ = s.ListenAndServe() })
+	defer s.Shutdown(context.Background())
+	files, err := SendTcpFiles("127.0.0.1", "11521", []string{"../test/data/pack/file.txt"}, "")
+	if err != nil || len(files) != 1 {
+		t.Errorf("Error send files after reconnect: %v", err)
+	}
+}
+
+func BenchmarkSendTcpFilesReconnect(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		// receiver start after first connection refused
+		_ = os.Remove(filepath.Join(dir, "file.txt"))
+		s := NewTcpRecvServer("127.0.0.1", "11547", dir, "")
+		time.AfterFunc(100*time.Millisecond, func() { _ = s.ListenAndServe() })
+		files, err := SendTcpFiles("127.0.0.1", "11547", []string{"../test/data/pack/file.txt"}, "")
+		if err != nil || len(files) != 1 {
+			b.Error("Error send files after reconnect:", err)
+		}
+		_ = s.Shutdown(context.Background())
+	}
+}
+
+func TestTransferPath(t *testing.T) {
+	for k, v := range map[string]bool{"a.pak": true, "dir/a.pak": true, "../a.pak": false, "/etc/passwd": false, "dir/../../a.pak": false, "": false, ".": false, "a\\..\\b": false} {
+		_, err := transferPath("../test/data", k)
+		if (err == nil) != v {
+			t.Errorf("Transfer path %q error is %v", k, err)
+		}
+	}
+}
+
+func BenchmarkTransferPath(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = transferPath("../test/data", "dir/a.pak")
+	}
+}
@@ -0,0 +1,717 @@
+package nets
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	. "satellite/global"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reliable udp packet is kind(1) session(4) seq(4) flags(1) payload,
+// data packets are fragments of message numbered by seq, last one flagged end,
+// ack packet seq is the acked packet and payload is next expected seq(4) and receive window(4),
+// the window is packets after next expected seq the receiver has room for,
+// ping keep connection alive and is answered with ack, fin close connection
+const (
+	rudpData       = 'D'
+	rudpAck        = 'A'
+	rudpPing       = 'P'
+	rudpFin        = 'F'
+	rudpHeaderSize = 10
+	rudpAckSize    = 8
+	rudpFlagEnd    = 1
+)
+
+var (
+	ErrRudpClosed      = errors.New("reliable udp connection closed")
+	ErrRudpTimeout     = errors.New("reliable udp peer not responding")
+	ErrRudpDeadline    = errors.New("reliable udp read deadline exceeded")
+	ErrRudpMessageSize = errors.New("reliable udp message too large")
+	ErrRudpPacket      = errors.New("invalid reliable udp packet")
+)
+
+// tNetsRudpPacket is one packet of reliable udp
+type tNetsRudpPacket struct {
+	kind    byte
+	session uint32
+	seq     uint32
+	flags   byte
+	payload []byte
+}
+
+func (p tNetsRudpPacket) marshal() []byte {
+	b := make([]byte, rudpHeaderSize+len(p.payload))
+	b[0] = p.kind
+	binary.BigEndian.PutUint32(b[1:5], p.session)
+	binary.BigEndian.PutUint32(b[5:9], p.seq)
+	b[9] = p.flags
+	copy(b[rudpHeaderSize:], p.payload)
+	return b
+}
+
+// parseNetsRudpPacket function
+// parse packet, payload is copied
+func parseNetsRudpPacket(b []byte) (p tNetsRudpPacket, err error) {
+	if len(b) < rudpHeaderSize || len(b) > rudpHeaderSize+UdpReliableMTU {
+		return p, ErrRudpPacket
+	}
+	p.kind = b[0]
+	p.session = binary.BigEndian.Uint32(b[1:5])
+	p.seq = binary.BigEndian.Uint32(b[5:9])
+	p.flags = b[9]
+	p.payload = append([]byte{}, b[rudpHeaderSize:]...)
+	switch p.kind {
+	case rudpData, rudpPing, rudpFin:
+	case rudpAck:
+		if len(p.payload) != rudpAckSize {
+			return p, ErrRudpPacket
+		}
+	default:
+		return p, ErrRudpPacket
+	}
+	return p, nil
+}
+
+// seqBefore check whether sequence a is before b, wrapping around
+func seqBefore(a uint32, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// tNetsRudpOutgoing is data packet waiting for ack
+type tNetsRudpOutgoing struct {
+	data    []byte
+	sent    time.Time
+	retries int
+}
+
+// TNetsRudpConn is reliable message connection to one peer over udp,
+// messages are fragmented, acked, retransmitted on loss and received in order,
+// sender stop at the receive window of peer so a slow reader is not timed out
+type TNetsRudpConn struct {
+	pc         net.PacketConn
+	peer       net.Addr
+	session    uint32
+	release    func()
+	sendMutex  sync.Mutex
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	next       uint32
+	limit      uint32
+	blocked    bool
+	unacked    map[uint32]*tNetsRudpOutgoing
+	expect     uint32
+	advertised uint32
+	queued     int
+	pending    map[uint32]tNetsRudpPacket
+	partial    []byte
+	queue      [][]byte
+	err        error
+	deadline   time.Time
+	lastRecv   time.Time
+	lastSend   time.Time
+	done       chan struct{}
+	once       sync.Once
+}
+
+func newNetsRudpConn(pc net.PacketConn, peer net.Addr, session uint32) *TNetsRudpConn {
+	now := time.Now()
+	c := &TNetsRudpConn{pc: pc, peer: peer, session: session, unacked: make(map[uint32]*tNetsRudpOutgoing),
+		limit: UdpReliableWindow, advertised: UdpReliableWindow,
+		pending: make(map[uint32]tNetsRudpPacket), lastRecv: now, lastSend: now, done: make(chan struct{})}
+	c.cond = sync.NewCond(&c.mutex)
+	go c.timer()
+	return c
+}
+
+// NewRudpConn function
+// create reliable connection to peer over packet connection, Close close pc
+func NewRudpConn(pc net.PacketConn, peer net.Addr) (*TNetsRudpConn, error) {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		log.Println("Error create session:", err)
+		return nil, err
+	}
+	c := newNetsRudpConn(pc, peer, binary.BigEndian.Uint32(b))
+	c.release = func() { _ = pc.Close() }
+	go c.read()
+	// announce connection to listener
+	c.mutex.Lock()
+	c.write(tNetsRudpPacket{kind: rudpPing, session: c.session}.marshal())
+	c.mutex.Unlock()
+	return c, nil
+}
+
+// DialRudp function
+// create reliable connection to ip:port
+func DialRudp(ip string, port string) (*TNetsRudpConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", ip+":"+port)
+	if err != nil {
+		log.Println("Error resolve ip address:", err)
+		return nil, err
+	}
+	pc, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		log.Println("Error listen udp:", err)
+		return nil, err
+	}
+	c, err := NewRudpConn(pc, addr)
+	if err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// RemoteAddr function
+// address of peer
+func (c *TNetsRudpConn) RemoteAddr() net.Addr {
+	return c.peer
+}
+
+// Send function
+// send message, return after all fragments acked
+func (c *TNetsRudpConn) Send(msg []byte) error {
+	if len(msg) > UdpReliableMaxMessage {
+		return ErrRudpMessageSize
+	}
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for off := 0; off == 0 || off < len(msg); off += UdpReliableMTU {
+		end := off + UdpReliableMTU
+		if end > len(msg) {
+			end = len(msg)
+		}
+		// wait for window, and for receive window of peer
+		for c.err == nil && (len(c.unacked) >= UdpReliableWindow || !seqBefore(c.next, c.limit)) {
+			c.blocked = true
+			c.cond.Wait()
+		}
+		c.blocked = false
+		if c.err != nil {
+			return c.err
+		}
+		p := tNetsRudpPacket{kind: rudpData, session: c.session, seq: c.next, payload: msg[off:end]}
+		if end == len(msg) {
+			p.flags = rudpFlagEnd
+		}
+		o := &tNetsRudpOutgoing{data: p.marshal(), sent: time.Now()}
+		c.unacked[p.seq] = o
+		c.next++
+		c.write(o.data)
+	}
+	// wait for acks
+	for len(c.unacked) > 0 {
+		if c.err != nil {
+			return c.err
+		}
+		c.cond.Wait()
+	}
+	return nil
+}
+
+// Recv function
+// receive next message in order, io.EOF when peer closed
+func (c *TNetsRudpConn) Recv() ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for len(c.queue) == 0 && c.err == nil {
+		if !c.deadline.IsZero() && !time.Now().Before(c.deadline) {
+			return nil, ErrRudpDeadline
+		}
+		c.cond.Wait()
+	}
+	if len(c.queue) == 0 {
+		return nil, c.err
+	}
+	msg := c.queue[0]
+	c.queue = c.queue[1:]
+	c.queued -= len(msg)
+	// reopen closed receive window
+	if !seqBefore(c.expect, c.advertised) {
+		c.ack(c.expect - 1)
+	}
+	return msg, nil
+}
+
+// SetReadDeadline function
+// Recv return ErrRudpDeadline after t, zero means no deadline
+func (c *TNetsRudpConn) SetReadDeadline(t time.Time) {
+	c.mutex.Lock()
+	c.deadline = t
+	c.mutex.Unlock()
+	if !t.IsZero() {
+		time.AfterFunc(time.Until(t), func() {
+			c.mutex.Lock()
+			c.cond.Broadcast()
+			c.mutex.Unlock()
+		})
+	}
+}
+
+// Close function
+// notify peer and close connection
+func (c *TNetsRudpConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err == nil {
+		c.write(tNetsRudpPacket{kind: rudpFin, session: c.session}.marshal())
+	}
+	c.fail(ErrRudpClosed)
+	return nil
+}
+
+// fail function
+// stop connection with err, mutex should be held
+func (c *TNetsRudpConn) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+	c.cond.Broadcast()
+	c.once.Do(func() {
+		close(c.done)
+		if c.release != nil {
+			c.release()
+		}
+	})
+}
+
+// write function
+// write packet to peer, mutex should be held
+func (c *TNetsRudpConn) write(b []byte) {
+	c.lastSend = time.Now()
+	_, err := c.pc.WriteTo(b, c.peer)
+	if err != nil {
+		log.Println("Error write udp:", err)
+	}
+}
+
+// read function
+// read packets of peer until packet connection closed
+func (c *TNetsRudpConn) read() {
+	buf := make([]byte, rudpHeaderSize+UdpReliableMTU+1)
+	for {
+		n, addr, err := c.pc.ReadFrom(buf)
+		if err != nil {
+			c.mutex.Lock()
+			if c.err == nil {
+				log.Println("Error read udp:", err)
+			}
+			c.fail(err)
+			c.mutex.Unlock()
+			return
+		}
+		if addr.String() != c.peer.String() {
+			continue
+		}
+		p, err := parseNetsRudpPacket(buf[:n])
+		if err != nil || p.session != c.session {
+			continue
+		}
+		c.input(p)
+	}
+}
+
+// input function
+// handle packet of peer
+func (c *TNetsRudpConn) input(p tNetsRudpPacket) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.lastRecv = time.Now()
+	switch p.kind {
+	case rudpAck:
+		next := binary.BigEndian.Uint32(p.payload)
+		if limit := next + binary.BigEndian.Uint32(p.payload[4:]); seqBefore(c.limit, limit) {
+			c.limit = limit
+		}
+		delete(c.unacked, p.seq)
+		for k := range c.unacked {
+			if seqBefore(k, next) {
+				delete(c.unacked, k)
+			}
+		}
+		c.cond.Broadcast()
+	case rudpData:
+		c.receive(p)
+	case rudpPing:
+		c.ack(c.expect - 1)
+	case rudpFin:
+		c.fail(io.EOF)
+	}
+}
+
+// receive function
+// buffer data packet, reassemble messages in order and ack,
+// packets after advertised window are dropped, mutex should be held
+func (c *TNetsRudpConn) receive(p tNetsRudpPacket) {
+	if seqBefore(p.seq, c.expect) {
+		// duplicate, ack lost
+		c.ack(p.seq)
+		return
+	}
+	if !seqBefore(p.seq, c.advertised) {
+		return
+	}
+	if _, ok := c.pending[p.seq]; !ok {
+		c.queued += len(p.payload)
+	}
+	c.pending[p.seq] = p
+	for {
+		f, ok := c.pending[c.expect]
+		if !ok {
+			break
+		}
+		delete(c.pending, c.expect)
+		c.expect++
+		if len(c.partial)+len(f.payload) > UdpReliableMaxMessage {
+			log.Println("Error receive message:", ErrRudpMessageSize)
+			c.fail(ErrRudpMessageSize)
+			return
+		}
+		c.partial = append(c.partial, f.payload...)
+		if f.flags&rudpFlagEnd != 0 {
+			if c.partial == nil {
+				c.partial = []byte{}
+			}
+			c.queue = append(c.queue, c.partial)
+			c.partial = nil
+			c.cond.Broadcast()
+		}
+	}
+	c.ack(p.seq)
+}
+
+// ack function
+// ack packet seq with next expected seq and receive window,
+// window is room left of queue size, advertised window is never taken back,
+// mutex should be held
+func (c *TNetsRudpConn) ack(seq uint32) {
+	window := 0
+	if free := UdpReliableQueueSize - c.queued; free > 0 {
+		window = free / UdpReliableMTU
+	}
+	if window > UdpReliableWindow {
+		window = UdpReliableWindow
+	}
+	if limit := c.expect + uint32(window); seqBefore(c.advertised, limit) {
+		c.advertised = limit
+	}
+	payload := make([]byte, rudpAckSize)
+	binary.BigEndian.PutUint32(payload, c.expect)
+	binary.BigEndian.PutUint32(payload[4:], c.advertised-c.expect)
+	c.write(tNetsRudpPacket{kind: rudpAck, session: c.session, seq: seq, payload: payload}.marshal())
+}
+
+// timer function
+// retransmit, keep alive and check peer until closed
+func (c *TNetsRudpConn) timer() {
+	t := time.NewTicker(UdpReliableRTO / 4 * time.Millisecond)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-t.C:
+			c.tick(now)
+		}
+	}
+}
+
+func (c *TNetsRudpConn) tick(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, v := range c.unacked {
+		if now.Sub(v.sent) < UdpReliableRTO*time.Millisecond {
+			continue
+		}
+		if v.retries >= UdpReliableRetries {
+			log.Println("Error send message:", ErrRudpTimeout)
+			c.fail(ErrRudpTimeout)
+			return
+		}
+		v.retries++
+		v.sent = now
+		c.write(v.data)
+	}
+	idle := UdpReliableIdleTimeout * time.Millisecond
+	if now.Sub(c.lastRecv) > idle {
+		c.fail(ErrRudpTimeout)
+		return
+	}
+	// probe closed receive window of peer, window update may be lost
+	probe := c.blocked && len(c.unacked) == 0 && now.Sub(c.lastSend) >= UdpReliableRTO*time.Millisecond
+	if probe || now.Sub(c.lastSend) > idle/4 {
+		c.write(tNetsRudpPacket{kind: rudpPing, session: c.session}.marshal())
+	}
+}
+
+// TNetsRudpListener accept reliable connections over one packet connection,
+// connections are created by first packet of peer session, at most UdpReliableMaxConns
+type TNetsRudpListener struct {
+	pc     net.PacketConn
+	mutex  sync.Mutex
+	conns  map[string]*TNetsRudpConn
+	accept chan *TNetsRudpConn
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewRudpListener function
+// accept reliable connections over packet connection, Close close pc
+func NewRudpListener(pc net.PacketConn) *TNetsRudpListener {
+	l := &TNetsRudpListener{pc: pc, conns: make(map[string]*TNetsRudpConn), accept: make(chan *TNetsRudpConn, UdpReliableBacklog), done: make(chan struct{})}
+	go l.serve()
+	return l
+}
+
+// ListenRudp function
+// accept reliable connections on ip:port
+func ListenRudp(ip string, port string) (*TNetsRudpListener, error) {
+	pc, err := net.ListenPacket("udp", ip+":"+port)
+	if err != nil {
+		log.Println("Error listen udp:", err)
+		return nil, err
+	}
+	return NewRudpListener(pc), nil
+}
+
+// Accept function
+// wait for next connection
+func (l *TNetsRudpListener) Accept() (*TNetsRudpConn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.done:
+		return nil, ErrRudpClosed
+	}
+}
+
+// Addr function
+// local address of listener
+func (l *TNetsRudpListener) Addr() net.Addr {
+	return l.pc.LocalAddr()
+}
+
+// Close function
+// close packet connection and all connections
+func (l *TNetsRudpListener) Close() error {
+	var err error
+	l.once.Do(func() {
+		close(l.done)
+		err = l.pc.Close()
+		l.mutex.Lock()
+		conns := make([]*TNetsRudpConn, 0, len(l.conns))
+		for _, v := range l.conns {
+			conns = append(conns, v)
+		}
+		l.mutex.Unlock()
+		for _, v := range conns {
+			_ = v.Close()
+		}
+	})
+	return err
+}
+
+// serve function
+// dispatch packets to connections until closed
+func (l *TNetsRudpListener) serve() {
+	buf := make([]byte, rudpHeaderSize+UdpReliableMTU+1)
+	for {
+		n, addr, err := l.pc.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-l.done:
+			default:
+				log.Println("Error read udp:", err)
+				_ = l.Close()
+			}
+			return
+		}
+		p, err := parseNetsRudpPacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		if c := l.conn(addr, p); c != nil {
+			c.input(p)
+		}
+	}
+}
+
+// conn function
+// connection of peer session, created by data or ping packet of new session
+func (l *TNetsRudpListener) conn(addr net.Addr, p tNetsRudpPacket) *TNetsRudpConn {
+	key := addr.String()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	old, ok := l.conns[key]
+	if ok && old.session == p.session {
+		return old
+	}
+	if p.kind != rudpData && p.kind != rudpPing {
+		return nil
+	}
+	if !ok && len(l.conns) >= UdpReliableMaxConns {
+		log.Printf("Drop reliable udp connection of %v: too many connections\n", addr)
+		return nil
+	}
+	c := newNetsRudpConn(l.pc, addr, p.session)
+	c.release = func() { l.remove(key, c) }
+	select {
+	case l.accept <- c:
+	default:
+		log.Printf("Drop reliable udp connection of %v: backlog full\n", addr)
+		c.mutex.Lock()
+		c.release = nil
+		c.fail(ErrRudpClosed)
+		c.mutex.Unlock()
+		return nil
+	}
+	l.conns[key] = c
+	if ok {
+		// peer restarted with new session
+		go func() {
+			old.mutex.Lock()
+			old.fail(ErrRudpClosed)
+			old.mutex.Unlock()
+		}()
+	}
+	return c
+}
+
+func (l *TNetsRudpListener) remove(key string, c *TNetsRudpConn) {
+	l.mutex.Lock()
+	if l.conns[key] == c {
+		delete(l.conns, key)
+	}
+	l.mutex.Unlock()
+}
+
+// TNetsRudpServer is reliable udp message server print messages of peers,
+// Shutdown close listener and connections
+type TNetsRudpServer struct {
+	Addr    string
+	mutex   sync.Mutex
+	l       *TNetsRudpListener
+	closing bool
+}
+
+// NewRudpServer function
+// create reliable udp server listen on ip:port
+func NewRudpServer(ip string, port string) *TNetsRudpServer {
+	return &TNetsRudpServer{Addr: ip + ":" + port}
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (s *TNetsRudpServer) ListenAndServe() error {
+	host, port, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+	l, err := ListenRudp(host, port)
+	if err != nil {
+		fmt.Println("Error listen udp:", err)
+		return err
+	}
+	s.mutex.Lock()
+	s.l = l
+	closing := s.closing
+	s.mutex.Unlock()
+	if closing {
+		return l.Close()
+	}
+	fmt.Println("Start Reliable Udp Server on", l.Addr().String())
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return nil
+		}
+		go func() {
+			defer c.Close()
+			for {
+				msg, err := c.Recv()
+				if err != nil {
+					if err != io.EOF && err != ErrRudpClosed {
+						log.Println("Error receive message:", err)
+					}
+					return
+				}
+				fmt.Println("["+c.RemoteAddr().String()+"] ", time.Now().Format("15:04:05"))
+				fmt.Println("Remote->Local:", strings.TrimSpace(string(msg)))
+			}
+		}()
+	}
+}
+
+// Shutdown function
+// close listener and connections
+func (s *TNetsRudpServer) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closing = true
+	if s.l != nil {
+		return s.l.Close()
+	}
+	return nil
+}
+
+func StartRudpServer(ip string, port string) error {
+	return RunServer(NewRudpServer(ip, port), NetShutdownTimeout*time.Millisecond)
+}
+
+// StartRudpClient function
+// send lines of stdin as reliable messages, print messages of server
+func StartRudpClient(ip string, port string) error {
+	fmt.Println("Start Reliable Udp Client")
+	c, err := DialRudp(ip, port)
+	if err != nil {
+		fmt.Println("Error connect udp:", err)
+		return err
+	}
+	defer c.Close()
+	go func() {
+		for {
+			msg, err := c.Recv()
+			if err != nil {
+				return
+			}
+			fmt.Println("["+c.RemoteAddr().String()+"] ", time.Now().Format("15:04:05"))
+			fmt.Println("Remote->Local:", strings.TrimSpace(string(msg)))
+		}
+	}()
+	rd := bufio.NewReader(os.Stdin)
+	for {
+		in, err := rd.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			log.Println("Error read stdin data stream:", err)
+			return err
+		}
+		in = strings.TrimSpace(in)
+		err = c.Send([]byte(in))
+		if err != nil {
+			fmt.Println("Error send message:", err)
+			log.Println("Error send message:", err)
+			return err
+		}
+		fmt.Println("["+c.pc.LocalAddr().String()+"] ", time.Now().Format("15:04:05"))
+		fmt.Println("Remote<-Local:", in)
+	}
+}
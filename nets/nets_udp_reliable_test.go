@@ -0,0 +1,363 @@
+package nets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	mrand "math/rand"
+	"net"
+	. "satellite/global"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tTestLossyConn is lossy link simulator,
+// written packets are dropped, duplicated or delayed out of order
+type tTestLossyConn struct {
+	net.PacketConn
+	mutex sync.Mutex
+	rand  *mrand.Rand
+	loss  float64
+	dup   float64
+	delay time.Duration
+}
+
+func newTestLossyConn(t testing.TB, loss float64, dup float64, delay time.Duration) *tTestLossyConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error listen udp:", err)
+	}
+	return &tTestLossyConn{PacketConn: pc, rand: mrand.New(mrand.NewSource(1)), loss: loss, dup: dup, delay: delay}
+}
+
+func (c *tTestLossyConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mutex.Lock()
+	drop := c.rand.Float64() < c.loss
+	times := 1
+	if c.rand.Float64() < c.dup {
+		times = 2
+	}
+	var delay time.Duration
+	if c.delay > 0 {
+		delay = time.Duration(c.rand.Int63n(int64(c.delay)))
+	}
+	c.mutex.Unlock()
+	if drop {
+		return len(b), nil
+	}
+	data := append([]byte{}, b...)
+	for i := 0; i < times; i++ {
+		time.AfterFunc(delay, func() { _, _ = c.PacketConn.WriteTo(data, addr) })
+	}
+	return len(b), nil
+}
+
+// startTestRudpEcho function
+// listener echo messages back over lossy link
+func startTestRudpEcho(t testing.TB, loss float64) *TNetsRudpListener {
+	l := NewRudpListener(newTestLossyConn(t, loss, loss/2, 20*time.Millisecond))
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				for {
+					msg, err := c.Recv()
+					if err != nil {
+						return
+					}
+					if c.Send(msg) != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestRudpLossyLink(t *testing.T) {
+	l := startTestRudpEcho(t, 0.2)
+	defer l.Close()
+	c, err := NewRudpConn(newTestLossyConn(t, 0.2, 0.1, 20*time.Millisecond), l.Addr())
+	if err != nil {
+		t.Fatal("Error create reliable udp connection:", err)
+	}
+	defer c.Close()
+	big := make([]byte, 100*UdpReliableMTU+7)
+	_, _ = rand.Read(big)
+	msgs := [][]byte{[]byte("hello satellite"), {}, big, []byte("bye")}
+	for i := 0; i < 10; i++ {
+		msgs = append(msgs, bytes.Repeat([]byte{byte(i)}, i*UdpReliableMTU/3))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.SetReadDeadline(time.Now().Add(20 * time.Second))
+		for i, v := range msgs {
+			got, err := c.Recv()
+			if err != nil || !bytes.Equal(got, v) {
+				t.Errorf("Echo message %v differ, length %v should be %v: %v", i, len(got), len(v), err)
+				return
+			}
+		}
+	}()
+	for _, v := range msgs {
+		err = c.Send(v)
+		if err != nil {
+			t.Fatal("Error send message:", err)
+		}
+	}
+	<-done
+}
+
+func BenchmarkRudpLossyLink(b *testing.B) {
+	l := startTestRudpEcho(b, 0)
+	defer l.Close()
+	pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	c, _ := NewRudpConn(pc, l.Addr())
+	defer c.Close()
+	msg := make([]byte, 10*UdpReliableMTU)
+	for i := 0; i < b.N; i++ {
+		_ = c.Send(msg)
+		_, _ = c.Recv()
+	}
+}
+
+func TestRudpClose(t *testing.T) {
+	pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	l := NewRudpListener(pc)
+	defer l.Close()
+	c, err := DialRudp("127.0.0.1", portOfAddr(l.Addr()))
+	if err != nil {
+		t.Fatal("Error dial reliable udp:", err)
+	}
+	s, err := l.Accept()
+	if err != nil {
+		t.Fatal("Error accept reliable udp:", err)
+	}
+	// deadline
+	s.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err = s.Recv(); err != ErrRudpDeadline {
+		t.Errorf("Recv error after deadline is %v", err)
+	}
+	s.SetReadDeadline(time.Time{})
+	// message queued before close still received
+	_ = c.Send([]byte("last"))
+	_ = c.Close()
+	msg, err := s.Recv()
+	if err != nil || string(msg) != "last" {
+		t.Errorf("Message before close is %q: %v", msg, err)
+	}
+	if _, err = s.Recv(); err != io.EOF {
+		t.Errorf("Recv error after peer closed is %v", err)
+	}
+	if err = c.Send([]byte("closed")); err != ErrRudpClosed {
+		t.Errorf("Send error after close is %v", err)
+	}
+}
+
+func BenchmarkRudpClose(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		c, _ := DialRudp("127.0.0.1", "1")
+		_ = c.Close()
+	}
+}
+
+func TestRudpSlowReader(t *testing.T) {
+	pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	l := NewRudpListener(pc)
+	defer l.Close()
+	c, err := DialRudp("127.0.0.1", portOfAddr(l.Addr()))
+	if err != nil {
+		t.Fatal("Error dial reliable udp:", err)
+	}
+	defer c.Close()
+	s, err := l.Accept()
+	if err != nil {
+		t.Fatal("Error accept reliable udp:", err)
+	}
+	// messages over queue size wait for reader instead of timeout
+	msg := make([]byte, UdpReliableMaxMessage/2)
+	n := UdpReliableQueueSize/len(msg) + 2
+	sent := make(chan error, 1)
+	go func() {
+		for i := 0; i < n; i++ {
+			if err := c.Send(msg); err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- nil
+	}()
+	// receiver fill its queue and close the window
+	waitTestRudp(t, s, func() bool { return !seqBefore(s.expect, s.advertised) }, "receive window not closed")
+	s.mutex.Lock()
+	queued := s.queued
+	s.mutex.Unlock()
+	if queued > UdpReliableQueueSize+UdpReliableWindow*UdpReliableMTU {
+		t.Errorf("Queued %v bytes over queue size", queued)
+	}
+	// sender got every packet acked and wait for window
+	waitTestRudp(t, c, func() bool { return c.blocked && len(c.unacked) == 0 }, "sender not waiting for window")
+	c.mutex.Lock()
+	cerr := c.err
+	c.mutex.Unlock()
+	if cerr != nil {
+		t.Errorf("Sender failed with closed window: %v", cerr)
+	}
+	for i := 0; i < n; i++ {
+		got, err := s.Recv()
+		if err != nil || len(got) != len(msg) {
+			t.Fatalf("Receive message %v length %v: %v", i, len(got), err)
+		}
+	}
+	if err = <-sent; err != nil {
+		t.Errorf("Error send message: %v", err)
+	}
+}
+
+// waitTestRudp function
+// poll cond with connection mutex held, fatal when not met in time
+func waitTestRudp(t *testing.T, c *TNetsRudpConn, cond func() bool, msg string) {
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		c.mutex.Lock()
+		ok := cond()
+		err := c.err
+		c.mutex.Unlock()
+		if ok {
+			return
+		}
+		if err != nil || time.Now().After(deadline) {
+			t.Fatalf("Error %v: %v", msg, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func BenchmarkRudpSlowReader(b *testing.B) {
+	c := newNetsRudpConn(newTestLossyConn(b, 1, 0, 0), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}, 1)
+	defer c.Close()
+	p := tNetsRudpPacket{kind: rudpData, session: 1, flags: rudpFlagEnd, payload: make([]byte, UdpReliableMTU)}
+	for i := 0; i < b.N; i++ {
+		c.input(p)
+		p.seq++
+		_, _ = c.Recv()
+	}
+}
+
+func TestRudpMaxConns(t *testing.T) {
+	pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	l := NewRudpListener(pc)
+	defer l.Close()
+	// sessions over max are dropped before a connection is created
+	for i := 0; i < UdpReliableMaxConns+10; i++ {
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 10000 + i}
+		l.conn(addr, tNetsRudpPacket{kind: rudpPing, session: uint32(i)})
+		if i%UdpReliableBacklog == 0 {
+			for len(l.accept) > 0 {
+				<-l.accept
+			}
+		}
+	}
+	l.mutex.Lock()
+	n := len(l.conns)
+	l.mutex.Unlock()
+	if n != UdpReliableMaxConns {
+		t.Errorf("Listener has %v connections", n)
+	}
+}
+
+func BenchmarkRudpMaxConns(b *testing.B) {
+	pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	l := NewRudpListener(pc)
+	defer l.Close()
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 10000}
+	for i := 0; i < b.N; i++ {
+		l.conn(addr, tNetsRudpPacket{kind: rudpPing, session: uint32(i)})
+		for len(l.accept) > 0 {
+			<-l.accept
+		}
+	}
+}
+
+func TestRudpTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("IGNORE: TestRudpTimeout in short mode")
+	}
+	// peer drop every packet
+	l := startTestRudpEcho(t, 1)
+	defer l.Close()
+	c, err := NewRudpConn(newTestLossyConn(t, 0, 0, 0), l.Addr())
+	if err != nil {
+		t.Fatal("Error create reliable udp connection:", err)
+	}
+	defer c.Close()
+	start := time.Now()
+	if err = c.Send([]byte("hello")); err != ErrRudpTimeout {
+		t.Errorf("Send error without acks is %v", err)
+	}
+	if time.Since(start) < UdpReliableRetries*UdpReliableRTO*time.Millisecond {
+		t.Error("Send should retransmit before timeout")
+	}
+	if _, err = c.Recv(); err != ErrRudpTimeout {
+		t.Errorf("Recv error after timeout is %v", err)
+	}
+}
+
+func BenchmarkRudpTimeout(b *testing.B) {
+	if testing.Short() {
+		b.Skip("IGNORE: BenchmarkRudpTimeout in short mode")
+	}
+	// peer drop every packet
+	l := startTestRudpEcho(b, 1)
+	defer l.Close()
+	for i := 0; i < b.N; i++ {
+		c, err := NewRudpConn(newTestLossyConn(b, 0, 0, 0), l.Addr())
+		if err != nil {
+			b.Fatal("Error create reliable udp connection:", err)
+		}
+		if err = c.Send([]byte("hello")); err != ErrRudpTimeout {
+			b.Errorf("Send error without acks is %v", err)
+		}
+		_ = c.Close()
+	}
+}
+
+func TestParseNetsRudpPacket(t *testing.T) {
+	p := tNetsRudpPacket{kind: rudpAck, session: 7, seq: 9, payload: []byte{0, 0, 0, 10, 0, 0, 0, 64}}
+	got, err := parseNetsRudpPacket(p.marshal())
+	if err != nil || got.kind != p.kind || got.session != 7 || got.seq != 9 || !bytes.Equal(got.payload, p.payload) {
+		t.Errorf("Parsed packet is %+v: %v", got, err)
+	}
+	for _, v := range [][]byte{nil, []byte("D123"), tNetsRudpPacket{kind: 'X'}.marshal(), tNetsRudpPacket{kind: rudpAck, payload: []byte{0, 0, 0, 10}}.marshal(), tNetsRudpPacket{kind: rudpData, payload: make([]byte, UdpReliableMTU+1)}.marshal()} {
+		if _, err = parseNetsRudpPacket(v); err != ErrRudpPacket {
+			t.Errorf("Parse packet %q error is %v", v, err)
+		}
+	}
+	if !seqBefore(1, 2) || seqBefore(2, 1) || !seqBefore(0xfffffffe, 1) || seqBefore(1, 0xfffffffe) {
+		t.Error("Sequence compare should wrap around")
+	}
+}
+
+func BenchmarkParseNetsRudpPacket(b *testing.B) {
+	data := tNetsRudpPacket{kind: rudpData, session: 1, seq: 2, flags: rudpFlagEnd, payload: make([]byte, UdpReliableMTU)}.marshal()
+	for i := 0; i < b.N; i++ {
+		_, err := parseNetsRudpPacket(data)
+		if err != nil {
+			b.Fatal("Error parse packet:", err)
+		}
+	}
+}
+
+// portOfAddr function
+// port of network address
+func portOfAddr(addr net.Addr) string {
+	_, port, _ := net.SplitHostPort(addr.String())
+	return port
+}
@@ -1,31 +1,74 @@
 package nets
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
-	"os"
+	. "satellite/global"
+	"sync"
+	"time"
 )
 
-func StartUdpServer(ip string, port string) {
+// TNetsUdpServer is udp message server,
+// Shutdown close the socket
+type TNetsUdpServer struct {
+	Addr    string
+	mutex   sync.Mutex
+	conn    *net.UDPConn
+	closing bool
+}
+
+// NewUdpServer function
+// create udp server listen on ip:port
+func NewUdpServer(ip string, port string) *TNetsUdpServer {
+	return &TNetsUdpServer{Addr: ip + ":" + port}
+}
+
+// ListenAndServe function
+// serve until Shutdown
+func (s *TNetsUdpServer) ListenAndServe() error {
 	// resolve ip address
 	fmt.Println("Start Udp Server")
-	addr, err := net.ResolveUDPAddr("udp", ip+":"+port)
+	addr, err := net.ResolveUDPAddr("udp", s.Addr)
 	if err != nil {
 		fmt.Println("Error resolve ip address:", err)
 		log.Println("Error resolve ip address:", err)
-		os.Exit(1)
+		return err
 	}
-	// start listen tcp
-	fmt.Println("Listen Udp:", ip+":"+port)
+	// start listen udp
+	fmt.Println("Listen Udp:", s.Addr)
 	conn, err := net.ListenUDP("udp", addr)
 	if err != nil {
 		fmt.Println("Error listen udp:", err)
 		log.Println("Error listen udp:", err)
-		os.Exit(1)
+		return err
+	}
+	s.mutex.Lock()
+	s.conn = conn
+	closing := s.closing
+	s.mutex.Unlock()
+	if closing {
+		return conn.Close()
 	}
-	defer conn.Close()
 	// loop waiting for send message...
 	fmt.Println("Loop waiting for send message...")
 	connUdpRecvHandler(conn)
+	return nil
+}
+
+// Shutdown function
+// close the socket, receive handler return at once
+func (s *TNetsUdpServer) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closing = true
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+func StartUdpServer(ip string, port string) error {
+	return RunServer(NewUdpServer(ip, port), NetShutdownTimeout*time.Millisecond)
 }
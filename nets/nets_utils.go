@@ -11,6 +11,7 @@ import (
 	"log"
 	"math/big"
 	"net"
+	"net/http"
 	"os"
 	. "satellite/global"
 	"strings"
@@ -33,8 +34,8 @@ func connTcpSendHandler(c net.Conn) {
 		}
 		// handle data stream
 		in = strings.TrimSpace(in)
-		// send data stream
-		_, err = c.Write([]byte(in))
+		// send data stream as one line
+		_, err = c.Write([]byte(in + "\n"))
 		if err != nil {
 			fmt.Println("Error write data stream:", err)
 			log.Println("Error write data stream:", err)
@@ -141,8 +142,18 @@ func connUdpRecvHandler(c *net.UDPConn) {
 
 func createHttpRouter() (r *mux.Router) {
 	r = mux.NewRouter()
+	r.Use(requestIDMiddleware)
+	r.Use(metricsMiddleware)
+	if httpLimit != nil {
+		r.Use(httpLimit.Middleware)
+	}
+	r.NotFoundHandler = requestIDMiddleware(http.HandlerFunc(handleNetsNotFound))
+	r.MethodNotAllowedHandler = requestIDMiddleware(http.HandlerFunc(handleNetsMethodNotAllowed))
 	r.HandleFunc(HttpURLRoot, handleRoot).Methods("GET")
 	r.HandleFunc(HttpURLSatellite, handleIndex).Methods("GET")
+	r.HandleFunc(HttpURLOpenAPI, handleNetsOpenAPI).Methods("GET")
+	r.HandleFunc(HttpURLExplorer, handleNetsExplorer).Methods("GET")
+	r.HandleFunc(HttpURLMetrics, handleNetsMetrics).Methods("GET")
 	r.HandleFunc(HttpURLPack, handleNetsPack).Methods("POST")
 	r.HandleFunc(HttpURLUnpack, handleNetsUnpack).Methods("POST")
 	r.HandleFunc(HttpURLPackProcess, handleNetsPackProcess).Methods("GET", "POST")
@@ -157,10 +168,23 @@ func createHttpRouter() (r *mux.Router) {
 	r.HandleFunc(HttpURLImagesQRCodeToFile, handleNetsImagesQRCodeToFile).Methods("POST")
 	r.HandleFunc(HttpURLImagesQRCodeToMemory, handleNetsImagesQRCodeToMemory).Methods("POST")
 	r.HandleFunc(HttpURLParsesIni, handleNetsParsesIniValue).Methods("GET", "PUT")
+	r.HandleFunc(HttpURLJobEvents, handleNetsJobEvents).Methods("GET")
+	r.HandleFunc(HttpURLRpc, handleNetsJsonRpc).Methods("POST")
+	r.HandleFunc(HttpURLPeers, handleNetsPeers).Methods("GET")
+	if httpAuth != nil {
+		r.Use(httpAuth.Middleware)
+	}
 	return r
 }
 
 func GenerateCA(ip string) (err error) {
+	return GenerateCertificate([]string{ip}, HttpsCertFile, HttpsKeyFile)
+}
+
+// GenerateCertificate function
+// generate self-signed certificate with SANs for hosts,
+// ip addresses and DNS names are both accepted
+func GenerateCertificate(hosts []string, certFile string, keyFile string) (err error) {
 	max := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, max)
 	if err != nil {
@@ -177,7 +201,13 @@ func GenerateCA(ip string) (err error) {
 		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
 		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		IPAddresses:  []net.IP{net.ParseIP(ip)},
+	}
+	for _, v := range hosts {
+		if ip := net.ParseIP(v); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if v != "" {
+			template.DNSNames = append(template.DNSNames, v)
+		}
 	}
 	pk, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -187,7 +217,7 @@ func GenerateCA(ip string) (err error) {
 	if err != nil {
 		return err
 	}
-	certOut, err := os.Create("cert.pem")
+	certOut, err := os.Create(certFile)
 	if err != nil {
 		return err
 	}
@@ -196,7 +226,7 @@ func GenerateCA(ip string) (err error) {
 		return err
 	}
 	certOut.Close()
-	keyOut, err := os.Create("key.pem")
+	keyOut, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,8 @@
 package pack
 
 import (
-	"errors"
 	"fmt"
+	. "satellite/utils"
 )
 
 // Pack function
@@ -25,8 +25,7 @@ func Pack(src []string, dest string, algorithm string) (err error) {
 	case "BASE64", "base64":
 		err = PackBase64(src, dest)
 	default:
-		s := fmt.Sprint("Undefined pack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined pack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -50,8 +49,7 @@ func WorkCalculate(src []string, algorithm string, work *int64) (err error) {
 	case "BASE64", "base64":
 		*work, err = PackBase64WorkCalculate(src)
 	default:
-		s := fmt.Sprint("Undefined pack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined pack algorithm", ErrAlgorithm)
 	}
 	return err
 }
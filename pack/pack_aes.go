@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -16,7 +15,6 @@ import (
 	. "satellite/utils"
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 // PackAES function
@@ -199,7 +197,6 @@ func PackAESOneConfineGo(src string, r *[]byte, wg *sync.WaitGroup, ch chan inte
 // PackAESOne function
 // it the base function of PackAESOneGo
 func PackAESOne(src string) (r []byte, err error) {
-	rand.Seed(time.Now().UnixNano())
 	// first, open the file
 	file, err := os.Open(src)
 	if err != nil {
@@ -215,7 +212,7 @@ func PackAESOne(src string) (r []byte, err error) {
 	}
 	// third, generate random key
 	key := make([]byte, 16)
-	_, err = rand.Read(key)
+	err = generateKey(key, src)
 	if err != nil {
 		log.Println("Error generate random key:", err)
 		return r, err
@@ -274,7 +271,6 @@ func PackAESOne(src string) (r []byte, err error) {
 // PackAESOneConfineGo function
 // it the base function of PackAESOneConfineGo
 func PackAESOneConfine(src string) (r []byte, err error) {
-	rand.Seed(time.Now().UnixNano())
 	// first, open the file
 	file, err := os.Open(src)
 	if err != nil {
@@ -290,7 +286,7 @@ func PackAESOneConfine(src string) (r []byte, err error) {
 	}
 	// third, generate random key
 	key := make([]byte, 16)
-	_, err = rand.Read(key)
+	err = generateKey(key, src)
 	if err != nil {
 		log.Println("Error generate random key:", err)
 		return r, err
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -16,7 +15,6 @@ import (
 	. "satellite/utils"
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 // Pack3DES function
@@ -183,7 +181,6 @@ func Pack3DESOneGo(src string, r *[]byte, wg *sync.WaitGroup) (err error) {
 // this function pack one file by 3des
 // inner function called by Pack3DESOneGo
 func Pack3DESOne(src string) (r []byte, err error) {
-	rand.Seed(time.Now().UnixNano())
 	// first, open the file
 	file, err := os.Open(src)
 	if err != nil {
@@ -199,7 +196,7 @@ func Pack3DESOne(src string) (r []byte, err error) {
 	}
 	// third, generate random key
 	key := make([]byte, 24)
-	_, err = rand.Read(key)
+	err = generateKey(key, src)
 	if err != nil {
 		log.Println("Error generate random key:", err)
 		return r, err
@@ -266,7 +263,6 @@ func PackDESOneGo(src string, r *[]byte, wg *sync.WaitGroup) (err error) {
 // this function pack one file by des
 // inner function called by PackDESOneGo
 func PackDESOne(src string) (r []byte, err error) {
-	rand.Seed(time.Now().UnixNano())
 	// first, open the file
 	file, err := os.Open(src)
 	if err != nil {
@@ -282,7 +278,7 @@ func PackDESOne(src string) (r []byte, err error) {
 	}
 	// third, generate random key
 	key := make([]byte, 8)
-	_, err = rand.Read(key)
+	err = generateKey(key, src)
 	if err != nil {
 		log.Println("Error generate random key:", err)
 		return r, err
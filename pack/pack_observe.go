@@ -0,0 +1,103 @@
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	. "satellite/utils"
+	"sync"
+)
+
+// PackObserve function
+// input src file list, output dest file path, algorithm and observer, return error info
+// it is same as Pack, but every src file is reported to observer when it starts
+// and finishes, so callers get progress without reading the global Done
+// return err indicate the success or failure function execute
+func PackObserve(src []string, dest string, algorithm string, o Observer) (err error) {
+	var one func(string) ([]byte, error)
+	var tp string
+	switch algorithm {
+	case "AES", "aes":
+		one, tp = PackAESOne, "AES"
+	case "DES", "des":
+		one, tp = PackDESOne, "DES"
+	case "3DES", "3des":
+		one, tp = Pack3DESOne, "3DES"
+	case "RSA", "rsa":
+		one, tp = PackRSAOne, "RSA"
+	case "BASE64", "base64":
+		one = func(src string) ([]byte, error) {
+			r, err := PackBase64One(src)
+			return []byte(r), err
+		}
+		tp = "BASE64"
+	default:
+		err = fmt.Errorf("%w: undefined pack algorithm", ErrAlgorithm)
+		return err
+	}
+	_, name := filepath.Split(dest)
+	if len([]byte(name)) > 32 {
+		err = fmt.Errorf("dest file name longer than 32 bytes: %v", name)
+		return err
+	}
+	// first, pack every src file and report it
+	wg := &sync.WaitGroup{}
+	r := make([][]byte, len(src)+4)
+	for k, v := range src {
+		wg.Add(1)
+		go func(k int, v string) {
+			defer wg.Done()
+			var size int64
+			if info, err := os.Stat(v); err == nil {
+				size = info.Size()
+			}
+			_, entry := filepath.Split(v)
+			o.EntryStart(entry, size)
+			var data []byte
+			err := ObserverErr(o)
+			if err == nil {
+				data, err = one(v)
+			}
+			if err == nil && len(data) == 0 {
+				err = fmt.Errorf("Error %s pack one file: %v", tp, v)
+			}
+			r[k+4] = data
+			o.EntryFinish(entry, size, err)
+		}(k, v)
+	}
+	wg.Wait()
+	err = ObserverErr(o)
+	if err != nil {
+		return err
+	}
+	// second, check goroutine whether success or not
+	for i := 0; i < len(src); i++ {
+		if len(r[i+4]) == 0 {
+			err = fmt.Errorf("Error %s pack one file: %v", tp, src[i])
+			return err
+		}
+	}
+	// third, fill the header
+	head := TPackAES{}
+	head.Name = make([]byte, 32)
+	head.Author = make([]byte, 16)
+	head.Type = make([]byte, 8)
+	head.Number = make([]byte, 4)
+	BytesCopy(&(head.Name), []byte(name))
+	BytesCopy(&(head.Author), []byte("Alopex6414"))
+	BytesCopy(&(head.Type), []byte(tp))
+	BytesCopy(&(head.Number), IntToBytes(len(src)))
+	r[0] = head.Name
+	r[1] = head.Author
+	r[2] = head.Type
+	r[3] = head.Number
+	// finally, write to dest file
+	err = ioutil.WriteFile(dest, bytes.Join(r, []byte("")), 0644)
+	if err != nil {
+		log.Println("Error write pack file:", err)
+	}
+	return err
+}
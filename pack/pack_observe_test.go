@@ -0,0 +1,109 @@
+package pack
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type testObserver struct {
+	sync.Mutex
+	start  map[string]int64
+	finish map[string]error
+}
+
+func (o *testObserver) EntryStart(name string, size int64) {
+	o.Lock()
+	defer o.Unlock()
+	o.start[name] = size
+}
+
+func (o *testObserver) EntryFinish(name string, size int64, err error) {
+	o.Lock()
+	defer o.Unlock()
+	o.finish[name] = err
+}
+
+// TestPackObserve function
+func TestPackObserve(t *testing.T) {
+	src := []string{"../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt", "../test/data/pack/file_3.txt", "../test/data/pack/file_4.txt", "../test/data/pack/file_5.txt"}
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, algorithm := range []string{"AES", "DES", "3DES", "RSA", "BASE64"} {
+		o := &testObserver{start: make(map[string]int64), finish: make(map[string]error)}
+		dest := filepath.Join(dir, "file_observe.pak")
+		err = PackObserve(src, dest, algorithm, o)
+		if err != nil {
+			t.Fatal("Error PackObserve:", err)
+		}
+		if len(o.start) != len(src) || len(o.finish) != len(src) {
+			t.Errorf("%v observed %v start and %v finish", algorithm, len(o.start), len(o.finish))
+		}
+		for k, v := range o.finish {
+			if v != nil {
+				t.Errorf("%v entry %v failure: %v", algorithm, k, v)
+			}
+		}
+		// same layout as Pack
+		expect := filepath.Join(dir, "file_observe.pak")
+		info, _ := os.Stat(dest)
+		err = Pack(src, expect, algorithm)
+		if err != nil {
+			t.Fatal("Error Pack:", err)
+		}
+		other, _ := os.Stat(expect)
+		if info.Size() != other.Size() {
+			t.Errorf("%v package size %v not equal %v", algorithm, info.Size(), other.Size())
+		}
+	}
+}
+
+type testCancelObserver struct {
+	testObserver
+}
+
+func (o *testCancelObserver) Err() error {
+	return errors.New("canceled")
+}
+
+// TestPackObserveCancel function
+func TestPackObserveCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	o := &testCancelObserver{testObserver{start: make(map[string]int64), finish: make(map[string]error)}}
+	dest := filepath.Join(dir, "file_cancel.pak")
+	err = PackObserve([]string{"../test/data/pack/file_1.txt"}, dest, "AES", o)
+	if err == nil || err.Error() != "canceled" {
+		t.Fatal("Error PackObserve: canceled observer should stop pack:", err)
+	}
+	_, err = os.Stat(dest)
+	if err == nil {
+		t.Fatal("Error PackObserve: canceled pack written")
+	}
+}
+
+// BenchmarkPackObserve function
+func BenchmarkPackObserve(b *testing.B) {
+	src := []string{"../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt", "../test/data/pack/file_3.txt", "../test/data/pack/file_4.txt", "../test/data/pack/file_5.txt"}
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		o := &testObserver{start: make(map[string]int64), finish: make(map[string]error)}
+		err = PackObserve(src, filepath.Join(dir, "file_observe.pak"), "AES", o)
+		if err != nil {
+			b.Fatal("Error PackObserve:", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package pack
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	. "satellite/utils"
+	"sort"
+	"sync"
+)
+
+var seedLock sync.RWMutex
+var testingSeed []byte
+
+// SetTestingSeed function
+// input one seed, the symmetric keys of AES, DES and 3DES will be derived
+// from seed and file name instead of random bytes
+// it is only used by testing to produce byte-identical packages,
+// deterministic keys are NOT secure, input nil to restore random keys
+func SetTestingSeed(seed []byte) {
+	seedLock.Lock()
+	defer seedLock.Unlock()
+	testingSeed = seed
+}
+
+// PackReproducible function
+// input src file list, output dest file path and algorithm which used in pack, return error info
+// it is same as Pack, but the files are sorted by the name in package,
+// so the same file set always get the same package layout
+// keys are still random unless SetTestingSeed is called, RSA does not support testing seed
+// return err indicate the success or failure function execute
+func PackReproducible(src []string, dest string, algorithm string) (err error) {
+	err = checkReproducible(algorithm)
+	if err != nil {
+		return err
+	}
+	return Pack(SortSource(src), dest, algorithm)
+}
+
+// PackReproducibleObserve function
+// it is same as PackReproducible, but every src file is reported to observer
+// when it starts and finishes like PackObserve
+func PackReproducibleObserve(src []string, dest string, algorithm string, o Observer) (err error) {
+	err = checkReproducible(algorithm)
+	if err != nil {
+		return err
+	}
+	return PackObserve(SortSource(src), dest, algorithm, o)
+}
+
+// checkReproducible function
+// RSA keys can not be derived from testing seed
+func checkReproducible(algorithm string) (err error) {
+	seedLock.RLock()
+	seeded := testingSeed != nil
+	seedLock.RUnlock()
+	if seeded && (algorithm == "RSA" || algorithm == "rsa") {
+		err = fmt.Errorf("%w: rsa pack does not support testing seed", ErrAlgorithm)
+		return err
+	}
+	return err
+}
+
+// SortSource function
+// input src file list, return a new list sorted by the file name in package
+func SortSource(src []string) []string {
+	dest := make([]string, len(src))
+	copy(dest, src)
+	sort.SliceStable(dest, func(i, j int) bool {
+		a, b := filepath.Base(dest[i]), filepath.Base(dest[j])
+		if a != b {
+			return a < b
+		}
+		return dest[i] < dest[j]
+	})
+	return dest
+}
+
+// generateKey function
+// fill key with random bytes, or derive it from testing seed and file name
+func generateKey(key []byte, src string) (err error) {
+	seedLock.RLock()
+	seed := testingSeed
+	seedLock.RUnlock()
+	if seed == nil {
+		_, err = rand.Read(key)
+		return err
+	}
+	h := hmac.New(sha256.New, seed)
+	h.Write([]byte(filepath.Base(src)))
+	copy(key, h.Sum(nil))
+	return err
+}
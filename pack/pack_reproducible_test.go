@@ -0,0 +1,118 @@
+package pack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackReproducible(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	SetTestingSeed([]byte("satellite"))
+	defer SetTestingSeed(nil)
+	src := []string{"../test/data/pack/file_3.txt", "../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt"}
+	for _, algorithm := range []string{"AES", "DES", "3DES", "BASE64"} {
+		a := filepath.Join(dir, "a", "file.pak")
+		b := filepath.Join(dir, "b", "file.pak")
+		os.MkdirAll(filepath.Dir(a), 0755)
+		os.MkdirAll(filepath.Dir(b), 0755)
+		err = PackReproducible(src, a, algorithm)
+		if err != nil {
+			t.Fatal("Error Pack Reproducible:", err)
+		}
+		err = PackReproducible([]string{src[2], src[0], src[1]}, b, algorithm)
+		if err != nil {
+			t.Fatal("Error Pack Reproducible:", err)
+		}
+		x, _ := ioutil.ReadFile(a)
+		y, _ := ioutil.ReadFile(b)
+		if !bytes.Equal(x, y) {
+			t.Fatalf("Error Pack Reproducible: %v packages are different", algorithm)
+		}
+	}
+	err = PackReproducible(src, filepath.Join(dir, "file.pak"), "RSA")
+	if err == nil {
+		t.Fatal("Error Pack Reproducible: rsa with testing seed should fail")
+	}
+}
+
+func TestPackReproducibleObserve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	SetTestingSeed([]byte("satellite"))
+	defer SetTestingSeed(nil)
+	src := []string{"../test/data/pack/file_3.txt", "../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt"}
+	a := filepath.Join(dir, "a", "file.pak")
+	b := filepath.Join(dir, "b", "file.pak")
+	os.MkdirAll(filepath.Dir(a), 0755)
+	os.MkdirAll(filepath.Dir(b), 0755)
+	o := &testObserver{start: make(map[string]int64), finish: make(map[string]error)}
+	err = PackReproducibleObserve(src, a, "AES", o)
+	if err != nil {
+		t.Fatal("Error Pack Reproducible Observe:", err)
+	}
+	if len(o.start) != len(src) || len(o.finish) != len(src) {
+		t.Errorf("Observed %v start and %v finish", len(o.start), len(o.finish))
+	}
+	// same package as without observer
+	err = PackReproducible(src, b, "AES")
+	if err != nil {
+		t.Fatal("Error Pack Reproducible:", err)
+	}
+	x, _ := ioutil.ReadFile(a)
+	y, _ := ioutil.ReadFile(b)
+	if !bytes.Equal(x, y) {
+		t.Error("Error Pack Reproducible Observe: packages are different")
+	}
+	err = PackReproducibleObserve(src, a, "RSA", o)
+	if err == nil {
+		t.Error("Error Pack Reproducible Observe: rsa with testing seed should fail")
+	}
+}
+
+func BenchmarkPackReproducibleObserve(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	src := []string{"../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt", "../test/data/pack/file_3.txt"}
+	for i := 0; i < b.N; i++ {
+		o := &testObserver{start: make(map[string]int64), finish: make(map[string]error)}
+		err = PackReproducibleObserve(src, filepath.Join(dir, "file.pak"), "AES", o)
+		if err != nil {
+			b.Fatal("Error Pack Reproducible Observe:", err)
+		}
+	}
+}
+
+func TestSortSource(t *testing.T) {
+	src := []string{"b/file_2.txt", "a/file_3.txt", "c/file_1.txt"}
+	dest := SortSource(src)
+	if dest[0] != "c/file_1.txt" || dest[1] != "b/file_2.txt" || dest[2] != "a/file_3.txt" {
+		t.Fatal("Error Sort Source:", dest)
+	}
+	if src[0] != "b/file_2.txt" {
+		t.Fatal("Error Sort Source: input should not be changed")
+	}
+}
+
+func BenchmarkPackReproducible(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt", "../test/data/pack/file_3.txt", "../test/data/pack/file_4.txt", "../test/data/pack/file_5.txt"}
+		dest := "../test/data/pack/file.txt"
+		err := PackReproducible(src, dest, "AES")
+		if err != nil {
+			b.Fatal("Error Pack Reproducible:", err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package pack
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	. "satellite/utils"
+)
+
+// tPackStreamCodec is how one algorithm write entries into package,
+// src file is read in chunk bytes and crypt one chunk at a time,
+// pad fill the last chunk with zero like SplitByte, size is crypt size of n bytes
+type tPackStreamCodec struct {
+	tp    string
+	chunk int
+	pad   bool
+	key   func(src string) (head []byte, key []byte, err error)
+	size  func(n int64) int64
+	crypt func(data []byte, key []byte) ([]byte, error)
+}
+
+// PackStream function
+// input src file list, output writer and algorithm which used in pack, return error info
+// package is same as Pack, but it is written into w entry by entry, every src
+// file is read and encrypted in buffer size chunks, crypt size in entry header is
+// known from the file size, so neither package nor file is kept in memory or on disk,
+// the package name in header will be StreamPackName
+// w can be any writer such as stdout, file or network connection
+// return err indicate the success or failure function execute
+func PackStream(src []string, w io.Writer, algorithm string) (err error) {
+	c, err := packStreamCodec(algorithm)
+	if err != nil {
+		return err
+	}
+	// first, write the package header
+	head := TPackAES{}
+	head.Name = make([]byte, 32)
+	head.Author = make([]byte, 16)
+	head.Type = make([]byte, 8)
+	head.Number = make([]byte, 4)
+	BytesCopy(&(head.Name), []byte(StreamPackName))
+	BytesCopy(&(head.Author), []byte("Alopex6414"))
+	BytesCopy(&(head.Type), []byte(c.tp))
+	BytesCopy(&(head.Number), IntToBytes(len(src)))
+	for _, v := range [][]byte{head.Name, head.Author, head.Type, head.Number} {
+		_, err = w.Write(v)
+		if err != nil {
+			log.Println("Error write package header:", err)
+			return err
+		}
+	}
+	// second, write every src file
+	for _, v := range src {
+		err = packStreamOne(v, w, c)
+		if err != nil {
+			log.Println("Error pack stream one file:", err)
+			return err
+		}
+	}
+	return err
+}
+
+// packStreamOne function
+// write entry header and crypt data of src file into w
+func packStreamOne(src string, w io.Writer, c tPackStreamCodec) (err error) {
+	file, err := os.Open(src)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Println("Error stat file:", err)
+		return err
+	}
+	size := info.Size()
+	if c.size(size) > math.MaxUint32 {
+		err = fmt.Errorf("source file larger than 4GB after pack: %v", src)
+		return err
+	}
+	_, name := filepath.Split(src)
+	if len([]byte(name)) > 32 {
+		err = fmt.Errorf("source file name longer than 32 bytes: %v", name)
+		return err
+	}
+	key, enc, err := c.key(src)
+	if err != nil {
+		log.Println("Error generate key:", err)
+		return err
+	}
+	// first, write the entry header, BASE64 has no key and origin size
+	head := make([]byte, 32)
+	BytesCopy(&head, []byte(name))
+	if c.tp != "BASE64" {
+		head = append(head, key...)
+		head = append(head, IntToBytes(int(size))...)
+	}
+	head = append(head, IntToBytes(int(c.size(size)))...)
+	_, err = w.Write(head)
+	if err != nil {
+		log.Println("Error write entry header:", err)
+		return err
+	}
+	// second, crypt the file chunk by chunk
+	buf := make([]byte, c.chunk)
+	for remain := size; remain > 0; {
+		n := c.chunk
+		if remain < int64(n) {
+			n = int(remain)
+		}
+		_, err = io.ReadFull(file, buf[:n])
+		if err != nil {
+			err = fmt.Errorf("source file changed while packing: %v: %w", src, err)
+			return err
+		}
+		data := buf[:n]
+		if c.pad {
+			for i := n; i < len(buf); i++ {
+				buf[i] = 0
+			}
+			data = buf
+		}
+		r, err := c.crypt(data, enc)
+		if err != nil {
+			log.Println("Error crypt data:", err)
+			return err
+		}
+		_, err = w.Write(r)
+		if err != nil {
+			log.Println("Error write package into stream:", err)
+			return err
+		}
+		remain -= int64(n)
+	}
+	return err
+}
+
+// packStreamCodec function
+// codec of algorithm, entry layout is same as the one file pack of algorithm
+func packStreamCodec(algorithm string) (c tPackStreamCodec, err error) {
+	// key in header is used to crypt
+	random := func(n int) func(string) ([]byte, []byte, error) {
+		return func(src string) ([]byte, []byte, error) {
+			key := make([]byte, n)
+			err := generateKey(key, src)
+			return key, key, err
+		}
+	}
+	// every chunk is padded into chunk size
+	chunks := func(size int64) func(int64) int64 {
+		return func(n int64) int64 {
+			return (n + size - 1) / size * size
+		}
+	}
+	switch algorithm {
+	case "AES", "aes":
+		c = tPackStreamCodec{tp: "AES", chunk: AESBufferSize, pad: true, key: random(16), size: chunks(AESBufferSize), crypt: AESEncrypt}
+	case "DES", "des":
+		c = tPackStreamCodec{tp: "DES", chunk: DESBufferSize, pad: true, key: random(8), size: chunks(DESBufferSize), crypt: DESEncrypt}
+	case "3DES", "3des":
+		c = tPackStreamCodec{tp: "3DES", chunk: DESBufferSize, pad: true, key: random(24), size: chunks(DESBufferSize), crypt: TripleDESEncrypt}
+	case "RSA", "rsa":
+		// private key in header, chunk encrypted by public key into RSAUnpackSize
+		key := func(src string) ([]byte, []byte, error) {
+			var pri []byte
+			var pub []byte
+			err := GenRSAKey2Memory(&pri, &pub, 1024)
+			head := make([]byte, 1024)
+			BytesCopy(&head, pri)
+			return head, pub, err
+		}
+		size := func(n int64) int64 {
+			return (n + RSAPacketSize - 1) / RSAPacketSize * RSAUnpackSize
+		}
+		c = tPackStreamCodec{tp: "RSA", chunk: RSAPacketSize, pad: true, key: key, size: size, crypt: RSAEncrypt}
+	case "BASE64", "base64":
+		key := func(src string) ([]byte, []byte, error) {
+			return nil, nil, nil
+		}
+		// last chunk is not padded
+		size := func(n int64) int64 {
+			full := int64(base64.StdEncoding.EncodedLen(Base64BufferSize))
+			return n/Base64BufferSize*full + int64(base64.StdEncoding.EncodedLen(int(n%Base64BufferSize)))
+		}
+		crypt := func(data []byte, key []byte) ([]byte, error) {
+			return []byte(Base64Encrypt(string(data))), nil
+		}
+		c = tPackStreamCodec{tp: "BASE64", chunk: Base64BufferSize, key: key, size: size, crypt: crypt}
+	default:
+		err = fmt.Errorf("%w: undefined pack algorithm", ErrAlgorithm)
+	}
+	return c, err
+}
@@ -0,0 +1,72 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	. "satellite/utils"
+	"testing"
+)
+
+func TestPackStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	SetTestingSeed([]byte("satellite"))
+	defer SetTestingSeed(nil)
+	// random file of more than one chunk
+	data := make([]byte, 1000)
+	_, _ = rand.Read(data)
+	random := filepath.Join(dir, "random.bin")
+	err = ioutil.WriteFile(random, data, 0644)
+	if err != nil {
+		t.Fatal("Error write file:", err)
+	}
+	src := []string{"../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt", "../test/data/pack/file_3.txt", "../test/data/pack/file_4.txt", "../test/data/pack/file_5.txt", random}
+	// same package as Pack named StreamPackName
+	for _, algorithm := range []string{"AES", "DES", "3DES", "BASE64"} {
+		var buf bytes.Buffer
+		err = PackStream(src, &buf, algorithm)
+		if err != nil {
+			t.Fatal("Error Pack Stream:", err)
+		}
+		dest := filepath.Join(dir, StreamPackName)
+		err = Pack(src, dest, algorithm)
+		if err != nil {
+			t.Fatal("Error Pack:", err)
+		}
+		data, _ = ioutil.ReadFile(dest)
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%v stream package differ from Pack", algorithm)
+		}
+	}
+	// package name in header should be the stream package name
+	var buf bytes.Buffer
+	err = PackStream(src[:1], &buf, "RSA")
+	if err != nil {
+		t.Fatal("Error Pack Stream:", err)
+	}
+	if string(bytes.TrimRight(buf.Bytes()[:32], "\x00")) != StreamPackName {
+		t.Fatal("Error Pack Stream header name:", string(buf.Bytes()[:32]))
+	}
+	err = PackStream(src, &buf, "rar")
+	if !errors.Is(err, ErrAlgorithm) {
+		t.Error("Error Pack Stream undefined algorithm:", err)
+	}
+}
+
+func BenchmarkPackStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := []string{"../test/data/pack/file_1.txt", "../test/data/pack/file_2.txt", "../test/data/pack/file_3.txt", "../test/data/pack/file_4.txt", "../test/data/pack/file_5.txt"}
+		err := PackStream(src, ioutil.Discard, "AES")
+		if err != nil {
+			b.Fatal("Error Pack Stream:", err)
+		}
+	}
+}
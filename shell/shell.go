@@ -9,6 +9,8 @@ func Shell(src string, dest string, algorithm string) (r string, err error) {
 	switch algorithm {
 	case "upx":
 		r, err = shellUpx(src, dest)
+	case "sfx":
+		r, err = ShellSfx("", src, dest, "")
 	default:
 		s := fmt.Sprint("Undefined shell algorithm.")
 		err = errors.New(s)
@@ -0,0 +1,451 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"satellite/decomp"
+	. "satellite/global"
+	"satellite/unpack"
+	. "satellite/utils"
+	"strings"
+)
+
+// ErrNotSfx means the executable has no self-extracting payload
+var ErrNotSfx = errors.New("not a self-extracting executable")
+
+// TSfxTrailer is written after the payload at the end of executable
+// magic is the last 16 bytes so that it can be found by seeking from the end
+type TSfxTrailer struct {
+	Name      [32]byte // payload file name, package header check this name
+	Type      [8]byte  // payload type: pak, tar, tar.gz or zip
+	Size      uint64   // payload size in bytes
+	Encrypted uint64   // 1 when payload is encrypted with passphrase
+	Magic     [16]byte // SfxMagic
+}
+
+// ShellSfx function
+// input extraction stub, payload file, dest executable and passphrase, return result info
+// stub is the minimal extraction executable built from app/sfx, satellite-sfx next to
+// current executable is used when it is empty
+// src can be one package(.pak) or archive(.tar, .tar.gz, .zip)
+// when passphrase is not empty, payload is encrypted with AES-256-GCM in chunks
+// stub and payload are streamed into dest, neither of them is read into memory
+// return err indicate the success or failure function execute
+func ShellSfx(stub string, src string, dest string, passphrase string) (r string, err error) {
+	if stub == "" {
+		stub, err = SfxStub()
+		if err != nil {
+			return r, err
+		}
+	}
+	in, err := os.Open(stub)
+	if err != nil {
+		log.Println("Error open stub file:", err)
+		return r, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		log.Println("Error stat stub file:", err)
+		return r, err
+	}
+	// stub itself may be a self-extracting executable, strip the old payload
+	size := info.Size()
+	t, err := ReadSfxTrailer(stub)
+	if err == nil {
+		size -= int64(binary.Size(t)) + int64(t.Size)
+	}
+	payload, err := os.Open(src)
+	if err != nil {
+		log.Println("Error open payload file:", err)
+		return r, err
+	}
+	defer payload.Close()
+	header := make([]byte, 60)
+	n, err := io.ReadFull(payload, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		log.Println("Error read payload file:", err)
+		return r, err
+	}
+	tp, name, err := sfxPayloadType(src, header[:n])
+	if err != nil {
+		return r, err
+	}
+	_, err = payload.Seek(0, io.SeekStart)
+	if err != nil {
+		log.Println("Error seek payload file:", err)
+		return r, err
+	}
+	// write stub, payload and trailer
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		log.Println("Error create dest file:", err)
+		return r, err
+	}
+	defer func() {
+		out.Close()
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+	_, err = io.CopyN(out, in, size)
+	if err != nil {
+		log.Println("Error write stub:", err)
+		return r, err
+	}
+	t = TSfxTrailer{}
+	if passphrase != "" {
+		err = sfxEncryptStream(out, payload, []byte(passphrase))
+		if err != nil {
+			log.Println("Error encrypt payload:", err)
+			return r, err
+		}
+		t.Encrypted = 1
+	} else {
+		_, err = io.Copy(out, payload)
+		if err != nil {
+			log.Println("Error write payload:", err)
+			return r, err
+		}
+	}
+	end, err := out.Seek(0, io.SeekCurrent)
+	if err != nil {
+		log.Println("Error seek dest file:", err)
+		return r, err
+	}
+	copy(t.Name[:], name)
+	copy(t.Type[:], tp)
+	copy(t.Magic[:], SfxMagic)
+	t.Size = uint64(end - size)
+	err = binary.Write(out, binary.LittleEndian, t)
+	if err != nil {
+		log.Println("Error write trailer:", err)
+		return r, err
+	}
+	r = fmt.Sprintf("Self-extracting executable %v created with %v payload %v (%d bytes).", dest, tp, name, t.Size)
+	return r, err
+}
+
+// SfxStub function
+// return the default extraction stub, satellite-sfx next to current executable
+func SfxStub() (stub string, err error) {
+	exe, err := os.Executable()
+	if err != nil {
+		log.Println("Error get executable path:", err)
+		return stub, err
+	}
+	stub = filepath.Join(filepath.Dir(exe), SfxStubName)
+	if runtime.GOOS == "windows" {
+		stub += ".exe"
+	}
+	_, err = os.Stat(stub)
+	if err != nil {
+		err = fmt.Errorf("sfx stub %v not found, build it from app/sfx or choose one with -stub", stub)
+		return stub, err
+	}
+	return stub, err
+}
+
+// ReadSfxTrailer function
+// input executable path, return the trailer or ErrNotSfx
+func ReadSfxTrailer(path string) (t TSfxTrailer, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return t, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return t, err
+	}
+	size := int64(binary.Size(t))
+	if info.Size() < size {
+		return t, ErrNotSfx
+	}
+	buf := make([]byte, size)
+	_, err = file.ReadAt(buf, info.Size()-size)
+	if err != nil {
+		return t, err
+	}
+	t, err = parseSfxTrailer(buf)
+	if err != nil {
+		return t, err
+	}
+	if int64(t.Size) > info.Size()-size {
+		return t, ErrNotSfx
+	}
+	return t, err
+}
+
+// ExtractSfx function
+// input self-extracting executable, dest path and passphrase, extract payload into dest
+// passphrase is required when payload is encrypted
+// return err indicate the success or failure function execute
+func ExtractSfx(path string, dest string, passphrase string) (err error) {
+	t, err := ReadSfxTrailer(path)
+	if err != nil {
+		return err
+	}
+	if t.Encrypted != 0 && passphrase == "" {
+		err = errors.New("passphrase required")
+		return err
+	}
+	name := string(bytes.TrimRight(t.Name[:], "\x00"))
+	if name == "" || name != filepath.Base(name) {
+		err = fmt.Errorf("illegal payload name: %q", name)
+		return err
+	}
+	// save payload into temporary file with the origin name
+	dir, err := ioutil.TempDir("", AppName)
+	if err != nil {
+		log.Println("Error create temp dir:", err)
+		return err
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, name)
+	err = extractSfxPayload(path, src, t, passphrase)
+	if err != nil {
+		return err
+	}
+	// extract into dest
+	err = os.MkdirAll(dest, 0755)
+	if err != nil {
+		log.Println("Error make dir all:", err)
+		return err
+	}
+	if !strings.HasSuffix(dest, "/") && !strings.HasSuffix(dest, string(filepath.Separator)) {
+		dest += string(filepath.Separator)
+	}
+	tp := string(bytes.TrimRight(t.Type[:], "\x00"))
+	switch tp {
+	case "pak":
+		err = unpack.Unpack(src, dest)
+	case "tar", "tar.gz", "zip":
+		err = decomp.DeCompress(src, dest, tp)
+	default:
+		err = fmt.Errorf("unsupported payload type: %q", tp)
+	}
+	return err
+}
+
+// ExtractSfxExecutable function
+// extract the payload of current executable into dest, passphrase is asked when
+// payload is encrypted and passphrase is empty
+func ExtractSfxExecutable(dest string, passphrase string) (err error) {
+	exe, err := os.Executable()
+	if err != nil {
+		log.Println("Error get executable path:", err)
+		return err
+	}
+	t, err := ReadSfxTrailer(exe)
+	if err != nil {
+		log.Println("Error read sfx trailer:", err)
+		return err
+	}
+	// ask passphrase when payload is encrypted
+	if t.Encrypted != 0 && passphrase == "" {
+		passphrase, err = ReadPassphrase("Passphrase: ")
+		if err != nil {
+			log.Println("Error read passphrase:", err)
+			return err
+		}
+	}
+	return ExtractSfx(exe, dest, passphrase)
+}
+
+// extractSfxPayload copy or decrypt the payload of executable path into file src
+func extractSfxPayload(path string, src string, t TSfxTrailer, passphrase string) (err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Println("Error stat file:", err)
+		return err
+	}
+	payload := io.NewSectionReader(file, info.Size()-int64(binary.Size(t))-int64(t.Size), int64(t.Size))
+	out, err := os.OpenFile(src, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Println("Error create payload file:", err)
+		return err
+	}
+	defer out.Close()
+	if t.Encrypted != 0 {
+		return sfxDecryptStream(out, payload, int64(t.Size), []byte(passphrase))
+	}
+	_, err = io.Copy(out, payload)
+	if err != nil {
+		log.Println("Error write payload:", err)
+		return err
+	}
+	return err
+}
+
+func parseSfxTrailer(data []byte) (t TSfxTrailer, err error) {
+	size := binary.Size(t)
+	if len(data) < size {
+		return t, ErrNotSfx
+	}
+	err = binary.Read(bytes.NewReader(data[len(data)-size:]), binary.LittleEndian, &t)
+	if err != nil {
+		return t, err
+	}
+	if string(t.Magic[:]) != SfxMagic {
+		return t, ErrNotSfx
+	}
+	return t, err
+}
+
+// sfxPayloadType return the payload type and name stored in trailer
+func sfxPayloadType(src string, data []byte) (tp string, name string, err error) {
+	name = filepath.Base(src)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		tp = "tar.gz"
+	case strings.HasSuffix(name, ".tar"):
+		tp = "tar"
+	case strings.HasSuffix(name, ".zip"):
+		tp = "zip"
+	default:
+		// package name in header should be the file name
+		if len(data) < 60 || !bytes.Equal(bytes.TrimRight(data[:32], "\x00"), []byte(name)) {
+			err = fmt.Errorf("payload %v is neither package nor archive", src)
+			return tp, name, err
+		}
+		tp = "pak"
+	}
+	if len(name) > 32 {
+		err = fmt.Errorf("payload name %v is longer than 32 bytes", name)
+		return tp, name, err
+	}
+	return tp, name, err
+}
+
+// sfxEncryptStream encrypt payload with AES-256-GCM, key derived from passphrase by PBKDF2-SHA256
+// output is salt, nonce prefix and sealed chunks of SfxChunkSize, chunk nonce is the prefix
+// and chunk number, the last chunk is sealed with a different additional data so that
+// truncating or reordering chunks is detected
+func sfxEncryptStream(w io.Writer, r io.Reader, passphrase []byte) (err error) {
+	salt := make([]byte, SfxSaltSize)
+	_, err = io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := sfxCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce[:sfxNoncePrefix])
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(salt, nonce[:sfxNoncePrefix]...))
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReaderSize(r, SfxChunkSize)
+	buf := make([]byte, SfxChunkSize)
+	sealed := make([]byte, 0, SfxChunkSize+gcm.Overhead())
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		// the chunk is the last one when nothing follows it
+		last := err != nil
+		if !last {
+			_, err = br.Peek(1)
+			last = err == io.EOF
+		}
+		binary.BigEndian.PutUint32(nonce[sfxNoncePrefix:], i)
+		sealed = gcm.Seal(sealed[:0], nonce, buf[:n], sfxChunkData(last))
+		_, err = w.Write(sealed)
+		if err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// sfxDecryptStream decrypt size bytes of payload read from r and write it into w
+func sfxDecryptStream(w io.Writer, r io.Reader, size int64, passphrase []byte) (err error) {
+	head := make([]byte, SfxSaltSize+sfxNoncePrefix)
+	if size < int64(len(head)) {
+		err = errors.New("payload is too short")
+		return err
+	}
+	_, err = io.ReadFull(r, head)
+	if err != nil {
+		return err
+	}
+	gcm, err := sfxCipher(passphrase, head[:SfxSaltSize])
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	copy(nonce, head[SfxSaltSize:])
+	remain := size - int64(len(head))
+	buf := make([]byte, SfxChunkSize+gcm.Overhead())
+	var data []byte
+	for i := uint32(0); remain > 0; i++ {
+		n := int64(len(buf))
+		if remain < n {
+			n = remain
+		}
+		_, err = io.ReadFull(r, buf[:n])
+		if err != nil {
+			return err
+		}
+		remain -= n
+		binary.BigEndian.PutUint32(nonce[sfxNoncePrefix:], i)
+		data, err = gcm.Open(buf[:0], nonce, buf[:n], sfxChunkData(remain == 0))
+		if err != nil {
+			err = errors.New("wrong passphrase or damaged payload")
+			return err
+		}
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// sfxNoncePrefix is the random part of chunk nonce, the rest is chunk number
+const sfxNoncePrefix = 8
+
+// sfxChunkData return the additional data of one chunk
+func sfxChunkData(last bool) []byte {
+	if last {
+		return []byte(SfxMagic + "\x01")
+	}
+	return []byte(SfxMagic + "\x00")
+}
+
+func sfxCipher(passphrase []byte, salt []byte) (cipher.AEAD, error) {
+	key := PBKDF2(passphrase, salt, SfxIterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,34 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// ReadPassphrase function
+// print prompt into stderr and read one line from stdin without echo
+func ReadPassphrase(prompt string) (s string, err error) {
+	fmt.Fprint(os.Stderr, prompt)
+	fd := os.Stdin.Fd()
+	var old syscall.Termios
+	_, _, e := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&old)))
+	if e == 0 {
+		// stdin is one terminal, turn off the echo
+		t := old
+		t.Lflag &^= syscall.ECHO
+		syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t)))
+		defer func() {
+			syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&old)))
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	s, err = bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && s == "" {
+		return s, err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadPassphrase function
+// print prompt into stderr and read one line from stdin
+func ReadPassphrase(prompt string) (s string, err error) {
+	fmt.Fprint(os.Stderr, prompt)
+	s, err = bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && s == "" {
+		return s, err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	"testing"
+)
+
+func TestShellSfx(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// any file can be used as stub in test
+	stub := filepath.Join(dir, "stub")
+	err = ioutil.WriteFile(stub, []byte("#!/bin/sh\nexit 0\n"), 0755)
+	if err != nil {
+		t.Fatal("Error write stub:", err)
+	}
+	src := "../test/data/unpack/file_aes.txt"
+	dest := filepath.Join(dir, "sfx")
+	_, err = ShellSfx(stub, src, dest, "satellite")
+	if err != nil {
+		t.Fatal("Error Shell Sfx:", err)
+	}
+	tr, err := ReadSfxTrailer(dest)
+	if err != nil {
+		t.Fatal("Error Read Sfx Trailer:", err)
+	}
+	if tr.Encrypted != 1 || string(bytes.TrimRight(tr.Type[:], "\x00")) != "pak" {
+		t.Fatal("Error Read Sfx Trailer:", tr)
+	}
+	// wrong passphrase should fail
+	err = ExtractSfx(dest, filepath.Join(dir, "out"), "wrong")
+	if err == nil {
+		t.Fatal("Error Extract Sfx: wrong passphrase should fail")
+	}
+	err = ExtractSfx(dest, filepath.Join(dir, "out"), "satellite")
+	if err != nil {
+		t.Fatal("Error Extract Sfx:", err)
+	}
+	_, err = os.Stat(filepath.Join(dir, "out", "file_1.txt"))
+	if err != nil {
+		t.Fatal("Error Extract Sfx:", err)
+	}
+	// shell the sfx again should replace the old payload
+	again := filepath.Join(dir, "again")
+	_, err = ShellSfx(dest, "../test/data/decomp/file.tar.gz", again, "")
+	if err != nil {
+		t.Fatal("Error Shell Sfx:", err)
+	}
+	a, _ := ioutil.ReadFile(again)
+	if !bytes.HasPrefix(a, []byte("#!/bin/sh\nexit 0\n")) {
+		t.Fatal("Error Shell Sfx: stub changed")
+	}
+	err = ExtractSfx(again, filepath.Join(dir, "out2"), "")
+	if err != nil {
+		t.Fatal("Error Extract Sfx:", err)
+	}
+}
+
+func TestSfxEncryptStream(t *testing.T) {
+	data := make([]byte, 3*SfxChunkSize+100)
+	rand.Read(data)
+	for _, size := range []int{0, 100, 2 * SfxChunkSize, len(data)} {
+		var sealed bytes.Buffer
+		err := sfxEncryptStream(&sealed, bytes.NewReader(data[:size]), []byte("satellite"))
+		if err != nil {
+			t.Fatal("Error Sfx Encrypt Stream:", err)
+		}
+		var out bytes.Buffer
+		err = sfxDecryptStream(&out, bytes.NewReader(sealed.Bytes()), int64(sealed.Len()), []byte("satellite"))
+		if err != nil || !bytes.Equal(out.Bytes(), data[:size]) {
+			t.Fatal("Error Sfx Decrypt Stream:", size, err)
+		}
+	}
+	var sealed bytes.Buffer
+	err := sfxEncryptStream(&sealed, bytes.NewReader(data), []byte("satellite"))
+	if err != nil {
+		t.Fatal("Error Sfx Encrypt Stream:", err)
+	}
+	// payload truncated at one chunk boundary should fail
+	b := sealed.Bytes()
+	n := int64(SfxSaltSize + sfxNoncePrefix + 2*(SfxChunkSize+16))
+	err = sfxDecryptStream(ioutil.Discard, bytes.NewReader(b), n, []byte("satellite"))
+	if err == nil {
+		t.Fatal("Error Sfx Decrypt Stream: truncated payload should fail")
+	}
+	// tampered payload should fail
+	b[len(b)-20] ^= 1
+	err = sfxDecryptStream(ioutil.Discard, bytes.NewReader(b), int64(len(b)), []byte("satellite"))
+	if err == nil {
+		t.Fatal("Error Sfx Decrypt Stream: tampered payload should fail")
+	}
+}
+
+func TestReadSfxTrailer(t *testing.T) {
+	_, err := ReadSfxTrailer("../test/data/unpack/file_aes.txt")
+	if err != ErrNotSfx {
+		t.Fatal("Error Read Sfx Trailer: plain file should not be sfx:", err)
+	}
+}
+
+func BenchmarkReadSfxTrailer(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, err := ReadSfxTrailer("../test/data/unpack/file_aes.txt")
+		if err != ErrNotSfx {
+			b.Fatal("Error Read Sfx Trailer:", err)
+		}
+	}
+}
@@ -3,10 +3,10 @@ package unpack
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"log"
 	"os"
+	. "satellite/utils"
 )
 
 func Unpack(src string, dest string) (err error) {
@@ -46,8 +46,7 @@ func Unpack(src string, dest string) (err error) {
 	case "BASE64", "base64":
 		err = UnpackBase64(src, dest)
 	default:
-		s := fmt.Sprint("Undefined unpack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -89,8 +88,7 @@ func UnpackConfine(src string, dest string) (err error) {
 	case "BASE64", "base64":
 		err = UnpackBase64Confine(src, dest)
 	default:
-		s := fmt.Sprint("Undefined unpack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -132,8 +130,7 @@ func UnpackToFile(src string, target string, dest string) (err error) {
 	case "BASE64", "base64":
 		err = UnpackBase64ToFile(src, target, dest)
 	default:
-		s := fmt.Sprint("Undefined unpack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -175,8 +172,7 @@ func UnpackToFileConfine(src string, target string, dest string) (err error) {
 	case "BASE64", "base64":
 		err = UnpackBase64ToFileConfine(src, target, dest)
 	default:
-		s := fmt.Sprint("Undefined unpack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -218,8 +214,7 @@ func UnpackToMemory(src string, target string, dest *[]byte) (err error) {
 	case "BASE64", "base64":
 		err = UnpackBase64ToMemory(src, target, dest)
 	default:
-		s := fmt.Sprint("Undefined unpack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -266,8 +261,7 @@ func ExtractInfo(src string, dest *[]string, sz *[]int, algorithm *string) (err
 		err = UnpackBase64ExtractInfo(src, dest, sz)
 		*algorithm = "base64"
 	default:
-		s := fmt.Sprint("Undefined unpack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -314,8 +308,7 @@ func WorkCalculate(src string, algorithm *string, work *int64) (err error) {
 		*work, err = UnpackBase64WorkCalculate(src)
 		*algorithm = "BASE64"
 	default:
-		s := fmt.Sprint("Undefined unpack algorithm.")
-		err = errors.New(s)
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
 	}
 	return err
 }
@@ -841,7 +841,11 @@ func UnpackAESOne(data []byte, head TUnpackAESOne, path string) (err error) {
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, AESBufferSize)
@@ -879,7 +883,11 @@ func UnpackAESOneConfine(data []byte, head TUnpackAESOne, path string) (err erro
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, AESBufferSize)
@@ -760,7 +760,11 @@ func UnpackBase64One(data []byte, head TUnpackBase64One, path string) (err error
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	// first, split the data slice
 	ss, err := SplitByte(data, Base64BufferSize)
 	if err != nil {
@@ -799,7 +803,11 @@ func UnpackBase64OneConfine(data []byte, head TUnpackBase64One, path string) (er
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	// first, split the data slice
 	ss, err := SplitByte(data, Base64BufferSize)
 	if err != nil {
@@ -1614,7 +1614,11 @@ func Unpack3DESOne(data []byte, head TUnpack3DESOne, path string) (err error) {
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, DESBufferSize)
@@ -1652,7 +1656,11 @@ func Unpack3DESOneConfine(data []byte, head TUnpack3DESOne, path string) (err er
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, DESBufferSize)
@@ -1738,7 +1746,11 @@ func UnpackDESOne(data []byte, head TUnpackDESOne, path string) (err error) {
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, DESBufferSize)
@@ -1776,7 +1788,11 @@ func UnpackDESOneConfine(data []byte, head TUnpackDESOne, path string) (err erro
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, DESBufferSize)
@@ -0,0 +1,120 @@
+package unpack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	. "satellite/utils"
+	"sync"
+)
+
+// UnpackObserve function
+// input package path, dest path and observer, return error info
+// it is same as Unpack, but every file in package is reported to observer
+// when it starts and finishes, so callers get progress without reading the global Done
+// return err indicate the success or failure function execute
+func UnpackObserve(src string, dest string, o Observer) (err error) {
+	// first, read file data
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		log.Println("Error read file:", err)
+		return err
+	}
+	rd := bytes.NewReader(data)
+	// second, read and check the header
+	h := TUnpackAES{}
+	h.Name = make([]byte, 32)
+	h.Author = make([]byte, 16)
+	h.Type = make([]byte, 8)
+	h.Number = make([]byte, 4)
+	for _, v := range [][]byte{h.Name, h.Author, h.Type, h.Number} {
+		_, err = io.ReadFull(rd, v)
+		if err != nil {
+			log.Println("Error read header:", err)
+			return err
+		}
+	}
+	_, name := filepath.Split(src)
+	s := make([]byte, 32)
+	BytesCopy(&s, []byte(name))
+	if !bytes.Equal(h.Name, s) {
+		err = fmt.Errorf("package name in header not match: %v", name)
+		return err
+	}
+	tp := string(bytes.TrimRight(h.Type, "\x00"))
+	keys := map[string]int{"AES": 16, "DES": 8, "3DES": 24, "RSA": 1024, "BASE64": 0}
+	size, ok := keys[tp]
+	if !ok {
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
+		return err
+	}
+	// third, read every one file in packet and unpack it
+	wg := &sync.WaitGroup{}
+	number := BytesToInt(h.Number)
+	errs := make([]error, number)
+	for i := 0; i < number; i++ {
+		var entry, key, origin, body []byte
+		entry = make([]byte, 32)
+		key = make([]byte, size)
+		origin = make([]byte, 4)
+		crypt := make([]byte, 4)
+		fields := [][]byte{entry, key, origin, crypt}
+		if tp == "BASE64" {
+			fields = [][]byte{entry, crypt}
+		}
+		for _, v := range fields {
+			_, err = io.ReadFull(rd, v)
+			if err != nil {
+				log.Println("Error read header:", err)
+				wg.Wait()
+				return err
+			}
+		}
+		body = make([]byte, BytesToInt(crypt))
+		_, err = io.ReadFull(rd, body)
+		if err != nil {
+			log.Println("Error read body:", err)
+			wg.Wait()
+			return err
+		}
+		if tp == "BASE64" {
+			origin = crypt
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := string(bytes.TrimRight(entry, "\x00"))
+			sz := int64(BytesToInt(origin))
+			o.EntryStart(n, sz)
+			errs[i] = ObserverErr(o)
+			switch {
+			case errs[i] != nil:
+			case tp == "AES":
+				errs[i] = UnpackAESOne(body, TUnpackAESOne{Name: entry, Key: key, OriginSize: origin, CryptSize: crypt}, dest)
+			case tp == "DES":
+				errs[i] = UnpackDESOne(body, TUnpackDESOne{Name: entry, Key: key, OriginSize: origin, CryptSize: crypt}, dest)
+			case tp == "3DES":
+				errs[i] = Unpack3DESOne(body, TUnpack3DESOne{Name: entry, Key: key, OriginSize: origin, CryptSize: crypt}, dest)
+			case tp == "RSA":
+				errs[i] = UnpackRSAOne(body, TUnpackRSAOne{Name: entry, Key: key, OriginSize: origin, CryptSize: crypt}, dest)
+			case tp == "BASE64":
+				errs[i] = UnpackBase64One(body, TUnpackBase64One{Name: entry, Size: crypt}, dest)
+			}
+			o.EntryFinish(n, sz, errs[i])
+		}(i)
+	}
+	wg.Wait()
+	err = ObserverErr(o)
+	if err != nil {
+		return err
+	}
+	for _, v := range errs {
+		if v != nil {
+			return v
+		}
+	}
+	return err
+}
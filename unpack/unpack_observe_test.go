@@ -0,0 +1,80 @@
+package unpack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type testObserver struct {
+	sync.Mutex
+	start  map[string]int64
+	finish map[string]error
+}
+
+func (o *testObserver) EntryStart(name string, size int64) {
+	o.Lock()
+	defer o.Unlock()
+	o.start[name] = size
+}
+
+func (o *testObserver) EntryFinish(name string, size int64, err error) {
+	o.Lock()
+	defer o.Unlock()
+	o.finish[name] = err
+}
+
+func TestUnpackObserve(t *testing.T) {
+	for _, v := range []string{"file_aes.txt", "file_des.txt", "file_3des.txt", "file_rsa.txt", "file_base64.txt"} {
+		src := "../test/data/unpack/" + v
+		dir, err := ioutil.TempDir("", "satellite")
+		if err != nil {
+			t.Fatal("Error create temp dir:", err)
+		}
+		defer os.RemoveAll(dir)
+		o := &testObserver{start: make(map[string]int64), finish: make(map[string]error)}
+		err = UnpackObserve(src, dir+string(filepath.Separator), o)
+		if err != nil {
+			t.Fatal("Error UnpackObserve:", err)
+		}
+		var files []string
+		var sizes []int
+		var algorithm string
+		err = ExtractInfo(src, &files, &sizes, &algorithm)
+		if err != nil {
+			t.Fatal("Error extract info:", err)
+		}
+		if len(o.start) != len(files) || len(o.finish) != len(files) {
+			t.Errorf("%v observed %v start and %v finish of %v files", v, len(o.start), len(o.finish), len(files))
+		}
+		for _, f := range files {
+			var data []byte
+			err = UnpackToMemory(src, f, &data)
+			if err != nil {
+				t.Fatal("Error unpack to memory:", err)
+			}
+			file, err := ioutil.ReadFile(filepath.Join(dir, f))
+			if err != nil || !bytes.Equal(file, data) {
+				t.Errorf("%v entry %v not equal: %v", v, f, err)
+			}
+		}
+	}
+}
+
+func BenchmarkUnpackObserve(b *testing.B) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < b.N; i++ {
+		o := &testObserver{start: make(map[string]int64), finish: make(map[string]error)}
+		err = UnpackObserve("../test/data/unpack/file_aes.txt", dir+string(filepath.Separator), o)
+		if err != nil {
+			b.Fatal("Error UnpackObserve:", err)
+		}
+	}
+}
@@ -845,7 +845,11 @@ func UnpackRSAOne(data []byte, head TUnpackRSAOne, path string) (err error) {
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, RSAUnpackSize)
@@ -883,7 +887,11 @@ func UnpackRSAOneConfine(data []byte, head TUnpackRSAOne, path string) (err erro
 		}
 		s = append(s, v)
 	}
-	file := path + string(s)
+	file, err := ConfinePath(path, string(s))
+	if err != nil {
+		log.Println("Error confine file path:", err)
+		return err
+	}
 	key := head.Key
 	// first, split the data slice
 	ss, err := SplitByte(data, RSAUnpackSize)
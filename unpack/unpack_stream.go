@@ -0,0 +1,255 @@
+package unpack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	. "satellite/global"
+	. "satellite/utils"
+)
+
+// tUnpackStreamCodec is how one algorithm read entries of package,
+// key is key length in entry header, crypt data is decrypted in chunk bytes
+type tUnpackStreamCodec struct {
+	tp    string
+	key   int
+	chunk int
+	crypt func(data []byte, key []byte) ([]byte, error)
+}
+
+// tUnpackStreamEntry is entry header read from package,
+// origin is -1 when the algorithm does not record it
+type tUnpackStreamEntry struct {
+	name   string
+	key    []byte
+	origin int64
+	crypt  int64
+}
+
+// SpoolPackage function
+// input package reader and temporary directory, output the package path
+// package header check the file name, so the stream is saved as the name
+// in the first 32 bytes header
+// only use it when package path is required, such as ExtractInfo or
+// WorkCalculate, UnpackStream and UnpackStreamToWriter read the reader directly
+// return err indicate the success or failure function execute
+func SpoolPackage(r io.Reader, dir string) (path string, err error) {
+	// first, read the header name
+	head := make([]byte, 32)
+	_, err = io.ReadFull(r, head)
+	if err != nil {
+		log.Println("Error read header name:", err)
+		return path, err
+	}
+	name := string(bytes.TrimRight(head, "\x00"))
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		err = fmt.Errorf("illegal package name in header: %q", name)
+		return path, err
+	}
+	// second, write the package into file
+	path = filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		log.Println("Error create file:", err)
+		return path, err
+	}
+	defer file.Close()
+	_, err = file.Write(head)
+	if err != nil {
+		log.Println("Error write file:", err)
+		return path, err
+	}
+	_, err = io.Copy(file, r)
+	if err != nil {
+		log.Println("Error write file:", err)
+		return path, err
+	}
+	return path, err
+}
+
+// UnpackStream function
+// input package reader and dest path, unpack all files into dest
+// entries are read in order and decrypted in buffer size chunks into dest
+// files, so neither package nor file is kept in memory or on disk,
+// the package name in header is not checked because there is no package path
+// return err indicate the success or failure function execute
+func UnpackStream(r io.Reader, dest string) (err error) {
+	c, number, err := readUnpackStreamHeader(r)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < number; i++ {
+		e, err := readUnpackStreamEntry(r, c)
+		if err != nil {
+			return err
+		}
+		path, err := ConfinePath(dest, e.name)
+		if err != nil {
+			log.Println("Error confine file path:", err)
+			return err
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			log.Println("Error create file:", err)
+			return err
+		}
+		err = unpackStreamData(r, file, c, e)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		err = file.Close()
+		if err != nil {
+			log.Println("Error close file:", err)
+			return err
+		}
+	}
+	return err
+}
+
+// UnpackStreamToWriter function
+// input package reader, target file name and writer, unpack target file into w
+// target can be empty when there is only one file in package, entries before
+// target are skipped without decrypt, target is decrypted in buffer size chunks
+// return err indicate the success or failure function execute
+func UnpackStreamToWriter(r io.Reader, target string, w io.Writer) (err error) {
+	c, number, err := readUnpackStreamHeader(r)
+	if err != nil {
+		return err
+	}
+	if target == "" && number != 1 {
+		err = errors.New("target file name required when package has more than one file")
+		return err
+	}
+	for i := 0; i < number; i++ {
+		e, err := readUnpackStreamEntry(r, c)
+		if err != nil {
+			return err
+		}
+		if target == "" || e.name == target {
+			return unpackStreamData(r, w, c, e)
+		}
+		_, err = io.CopyN(ioutil.Discard, r, e.crypt)
+		if err != nil {
+			log.Println("Error skip entry data:", err)
+			return err
+		}
+	}
+	err = fmt.Errorf("target file %v in package: %w", target, os.ErrNotExist)
+	return err
+}
+
+// UnpackToWriter function
+// input package path, target file name and writer, unpack target file into w
+// it is same as UnpackStreamToWriter, but read the package file
+func UnpackToWriter(src string, target string, w io.Writer) (err error) {
+	file, err := os.Open(src)
+	if err != nil {
+		log.Println("Error open file:", err)
+		return err
+	}
+	defer file.Close()
+	return UnpackStreamToWriter(file, target, w)
+}
+
+// readUnpackStreamHeader function
+// read package header, return codec of algorithm and files number
+func readUnpackStreamHeader(r io.Reader) (c tUnpackStreamCodec, number int, err error) {
+	head := make([]byte, 60)
+	_, err = io.ReadFull(r, head)
+	if err != nil {
+		log.Println("Error read package header:", err)
+		return c, number, err
+	}
+	author := make([]byte, 16)
+	BytesCopy(&author, []byte("Alopex6414"))
+	if !bytes.Equal(head[32:48], author) {
+		err = errors.New("illegal package header author")
+		return c, number, err
+	}
+	tp := string(bytes.TrimRight(head[48:56], "\x00"))
+	switch tp {
+	case "AES", "aes":
+		c = tUnpackStreamCodec{tp: "AES", key: 16, chunk: AESBufferSize, crypt: AESDecrypt}
+	case "DES", "des":
+		c = tUnpackStreamCodec{tp: "DES", key: 8, chunk: DESBufferSize, crypt: DESDecrypt}
+	case "3DES", "3des":
+		c = tUnpackStreamCodec{tp: "3DES", key: 24, chunk: DESBufferSize, crypt: TripleDESDecrypt}
+	case "RSA", "rsa":
+		c = tUnpackStreamCodec{tp: "RSA", key: 1024, chunk: RSAUnpackSize, crypt: RSADecrypt}
+	case "BASE64", "base64":
+		crypt := func(data []byte, key []byte) ([]byte, error) {
+			return []byte(Base64Decrypt(string(data))), nil
+		}
+		c = tUnpackStreamCodec{tp: "BASE64", chunk: Base64BufferSize, crypt: crypt}
+	default:
+		err = fmt.Errorf("%w: undefined unpack algorithm", ErrAlgorithm)
+		return c, number, err
+	}
+	number = BytesToInt(head[56:60])
+	return c, number, err
+}
+
+// readUnpackStreamEntry function
+// read entry header, BASE64 has no key and origin size
+func readUnpackStreamEntry(r io.Reader, c tUnpackStreamCodec) (e tUnpackStreamEntry, err error) {
+	head := make([]byte, 32+c.key+8)
+	if c.tp == "BASE64" {
+		head = head[:32+4]
+	}
+	_, err = io.ReadFull(r, head)
+	if err != nil {
+		log.Println("Error read entry header:", err)
+		return e, err
+	}
+	e.name = string(bytes.TrimRight(head[:32], "\x00"))
+	e.origin = -1
+	if c.tp != "BASE64" {
+		e.key = head[32 : 32+c.key]
+		e.origin = int64(BytesToInt(head[32+c.key : 36+c.key]))
+	}
+	e.crypt = int64(BytesToInt(head[len(head)-4:]))
+	return e, err
+}
+
+// unpackStreamData function
+// decrypt crypt data of entry e from r chunk by chunk and write origin data into w
+func unpackStreamData(r io.Reader, w io.Writer, c tUnpackStreamCodec, e tUnpackStreamEntry) (err error) {
+	buf := make([]byte, c.chunk)
+	origin := e.origin
+	for remain := e.crypt; remain > 0; {
+		n := c.chunk
+		if remain < int64(n) {
+			n = int(remain)
+		}
+		_, err = io.ReadFull(r, buf[:n])
+		if err != nil {
+			log.Println("Error read entry data:", err)
+			return err
+		}
+		data, err := c.crypt(buf[:n], e.key)
+		if err != nil {
+			log.Println("Error decrypt data:", err)
+			return err
+		}
+		// delete the more data of last chunk
+		if origin >= 0 {
+			if int64(len(data)) > origin {
+				data = data[:origin]
+			}
+			origin -= int64(len(data))
+		}
+		_, err = w.Write(data)
+		if err != nil {
+			log.Println("Error write unpack data into stream:", err)
+			return err
+		}
+		remain -= int64(n)
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+package unpack
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"satellite/pack"
+	"testing"
+)
+
+func TestSpoolPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile("../test/data/unpack/file_aes.txt")
+	if err != nil {
+		t.Fatal("Error read file:", err)
+	}
+	path, err := SpoolPackage(bytes.NewReader(data), dir)
+	if err != nil {
+		t.Fatal("Error Spool Package:", err)
+	}
+	if filepath.Base(path) != "file_aes.txt" {
+		t.Fatal("Error Spool Package name:", path)
+	}
+	// illegal name in header should be rejected
+	head := make([]byte, 32)
+	copy(head, "../file_aes.txt")
+	_, err = SpoolPackage(bytes.NewReader(head), dir)
+	if err == nil {
+		t.Fatal("Error Spool Package: illegal name should fail")
+	}
+}
+
+func TestUnpackStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// same files as Unpack
+	for _, v := range []string{"file_aes.txt", "file_des.txt", "file_3des.txt", "file_rsa.txt", "file_base64.txt"} {
+		data, err := ioutil.ReadFile(filepath.Join("../test/data/unpack", v))
+		if err != nil {
+			t.Fatal("Error read file:", err)
+		}
+		a := filepath.Join(dir, v, "a")
+		b := filepath.Join(dir, v, "b")
+		_ = os.MkdirAll(a, 0755)
+		_ = os.MkdirAll(b, 0755)
+		err = UnpackStream(bytes.NewReader(data), a)
+		if err != nil {
+			t.Fatalf("Error Unpack Stream %v: %v", v, err)
+		}
+		err = Unpack(filepath.Join("../test/data/unpack", v), b)
+		if err != nil {
+			t.Fatalf("Error Unpack %v: %v", v, err)
+		}
+		files, _ := ioutil.ReadDir(b)
+		if len(files) == 0 {
+			t.Fatalf("Unpack %v without files", v)
+		}
+		for _, f := range files {
+			x, err := ioutil.ReadFile(filepath.Join(a, f.Name()))
+			y, _ := ioutil.ReadFile(filepath.Join(b, f.Name()))
+			if err != nil || !bytes.Equal(x, y) {
+				t.Errorf("%v file %v differ from Unpack: %v", v, f.Name(), err)
+			}
+		}
+	}
+}
+
+func TestUnpackStreamRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		t.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	// random file of more than one chunk
+	data := make([]byte, 1000)
+	_, _ = rand.Read(data)
+	random := filepath.Join(dir, "random.bin")
+	err = ioutil.WriteFile(random, data, 0644)
+	if err != nil {
+		t.Fatal("Error write file:", err)
+	}
+	src := []string{"../test/data/pack/file_1.txt", random, "../test/data/pack/file_2.txt"}
+	for _, algorithm := range []string{"AES", "DES", "3DES", "RSA"} {
+		var buf bytes.Buffer
+		err = pack.PackStream(src, &buf, algorithm)
+		if err != nil {
+			t.Fatal("Error Pack Stream:", err)
+		}
+		dest := filepath.Join(dir, algorithm)
+		_ = os.Mkdir(dest, 0755)
+		err = UnpackStream(bytes.NewReader(buf.Bytes()), dest)
+		if err != nil {
+			t.Fatalf("Error %v Unpack Stream: %v", algorithm, err)
+		}
+		for _, v := range src {
+			x, _ := ioutil.ReadFile(v)
+			y, err := ioutil.ReadFile(filepath.Join(dest, filepath.Base(v)))
+			if err != nil || !bytes.Equal(x, y) {
+				t.Errorf("%v unpacked %v differ: %v", algorithm, v, err)
+			}
+		}
+		// target in the middle, earlier entries are skipped
+		var out bytes.Buffer
+		err = UnpackStreamToWriter(bytes.NewReader(buf.Bytes()), "random.bin", &out)
+		if err != nil || !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("%v unpacked target differ: %v", algorithm, err)
+		}
+	}
+}
+
+func TestUnpackToWriter(t *testing.T) {
+	var buf bytes.Buffer
+	src := "../test/data/unpack/file_aes.txt"
+	target := "file_1.txt"
+	err := UnpackToWriter(src, target, &buf)
+	if err != nil {
+		t.Fatal("Error Unpack To Writer:", err)
+	}
+	var data []byte
+	err = UnpackToMemory(src, target, &data)
+	if err != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("Error Unpack To Writer: differ from Unpack To Memory:", err)
+	}
+	// more than one file in package, target is required
+	err = UnpackToWriter(src, "", &buf)
+	if err == nil {
+		t.Fatal("Error Unpack To Writer: empty target should fail")
+	}
+	err = UnpackToWriter(src, "not_exist.txt", &buf)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("Error Unpack To Writer: missing target error is", err)
+	}
+}
+
+func BenchmarkUnpackStream(b *testing.B) {
+	data, err := ioutil.ReadFile("../test/data/unpack/file_aes.txt")
+	if err != nil {
+		b.Fatal("Error read file:", err)
+	}
+	dest, err := ioutil.TempDir("", "satellite")
+	if err != nil {
+		b.Fatal("Error create temp dir:", err)
+	}
+	defer os.RemoveAll(dest)
+	for i := 0; i < b.N; i++ {
+		err := UnpackStream(bytes.NewReader(data), dest)
+		if err != nil {
+			b.Fatal("Error Unpack Stream:", err)
+		}
+	}
+}
+
+func BenchmarkUnpackToWriter(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		src := "../test/data/unpack/file_aes.txt"
+		target := "file_1.txt"
+		err := UnpackToWriter(src, target, ioutil.Discard)
+		if err != nil {
+			b.Fatal("Error Unpack To Writer:", err)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package utils
+
+import (
+	"errors"
+)
+
+// ErrAlgorithm is wrapped by pack, unpack, compress and decompress errors
+// when the algorithm is undefined or not support by the operation,
+// check it with errors.Is instead of matching the error text
+var ErrAlgorithm = errors.New("algorithm not support")
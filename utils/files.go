@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -65,3 +66,67 @@ func ListFiles(dir string, term string) (files []os.FileInfo, err error) {
 	}
 	return
 }
+
+// ConfinePath function
+// join the entry name of archive or package with dest, return error when the
+// result is not inside dest, either by ".." elements or by symlinks already
+// on the path, so that extracting one entry never write outside dest
+func ConfinePath(dest string, name string) (path string, err error) {
+	path = filepath.Join(dest, filepath.FromSlash(name))
+	if !PathWithin(filepath.Clean(dest), path) {
+		err = fmt.Errorf("illegal entry name in archive: %v", name)
+		return path, err
+	}
+	// existing symlinks on the path may point out of dest
+	root, err := RealPath(dest)
+	if err != nil {
+		return path, err
+	}
+	real, err := RealPath(path)
+	if err != nil {
+		return path, err
+	}
+	if !PathWithin(root, real) {
+		err = fmt.Errorf("illegal entry name in archive: %v links out of %v", name, dest)
+		return path, err
+	}
+	return path, nil
+}
+
+// RealPath function
+// resolve symlinks of the longest existing prefix of path, keep the rest as it is,
+// so that paths not created yet can be checked too
+func RealPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for {
+		_, err = os.Lstat(path)
+		if err == nil {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(path, rest), nil
+}
+
+// PathWithin function
+// check whether path is dir or inside it, dir and path should be absolute and clean
+func PathWithin(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConfinePath(t *testing.T) {
+	_, err := ConfinePath("../test/data/unpack/", "file_1.txt")
+	if err != nil {
+		t.Fatal("Error Confine Path:", err)
+	}
+	_, err = ConfinePath("../test/data/unpack/", "dir/../file_1.txt")
+	if err != nil {
+		t.Fatal("Error Confine Path:", err)
+	}
+	_, err = ConfinePath("../test/data/unpack/", "../file_1.txt")
+	if err == nil {
+		t.Fatal("Error Confine Path: name out of dest should fail")
+	}
+}
+
+func BenchmarkConfinePath(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, err := ConfinePath("../test/data/unpack/", "file_1.txt")
+		if err != nil {
+			b.Fatal("Error Confine Path:", err)
+		}
+	}
+}
+
+func TestPathWithin(t *testing.T) {
+	root, err := RealPath("../test/data")
+	if err != nil {
+		t.Fatal("Error Real Path:", err)
+	}
+	// path not exist yet keep the missing rest
+	path, err := RealPath("../test/data/not_exist/file.txt")
+	if err != nil || !PathWithin(root, path) {
+		t.Fatalf("Error Real Path: %v not within %v: %v", path, root, err)
+	}
+	if !PathWithin(root, root) {
+		t.Error("Error Path Within: dir should be within itself")
+	}
+	if PathWithin(root, root+"_other") || PathWithin(root, filepath.Dir(root)) {
+		t.Error("Error Path Within: sibling or parent should not be within dir")
+	}
+}
+
+func BenchmarkPathWithin(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		path, err := RealPath("../test/data/unpack/file_1.txt")
+		if err != nil || !PathWithin(filepath.Dir(path), path) {
+			b.Fatal("Error Path Within:", err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"io"
+)
+
+// Observer receive per-entry progress of pack, unpack and compress,
+// size is the origin size of entry in bytes, err is nil on success
+// methods may be called from different goroutines at the same time
+type Observer interface {
+	EntryStart(name string, size int64)
+	EntryFinish(name string, size int64, err error)
+}
+
+// Canceler is implemented by observers whose work can be stopped, Err return
+// non nil once the work should stop, the work then return this error
+type Canceler interface {
+	Err() error
+}
+
+// ObserverErr function
+// return the stop error of observer o, nil when o is nil or can not stop the work
+func ObserverErr(o Observer) error {
+	if c, ok := o.(Canceler); ok {
+		return c.Err()
+	}
+	return nil
+}
+
+// ObserverReader function
+// return reader which fail with the stop error of observer o,
+// so that a long copy stop in the middle of one entry
+func ObserverReader(o Observer, r io.Reader) io.Reader {
+	if c, ok := o.(Canceler); ok {
+		return &observerReader{r: r, c: c}
+	}
+	return r
+}
+
+type observerReader struct {
+	r io.Reader
+	c Canceler
+}
+
+func (r *observerReader) Read(p []byte) (n int, err error) {
+	err = r.c.Err()
+	if err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// PBKDF2 derive a key from password and salt as described in RFC 2898
+// iter is the iteration count, size is the length of derived key in bytes
+// h is the hash function used by the HMAC, such as sha1.New or sha256.New
+func PBKDF2(password, salt []byte, iter, size int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	n := prf.Size()
+	blocks := (size + n - 1) / n
+	dk := make([]byte, 0, blocks*n)
+	u := make([]byte, n)
+	for block := 1; block <= blocks; block++ {
+		// U1 = PRF(password, salt || INT(block))
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		t := prf.Sum(nil)
+		copy(u, t)
+		// Un = PRF(password, Un-1)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for k := range t {
+				t[k] ^= u[k]
+			}
+		}
+		dk = append(dk, t...)
+	}
+	return dk[:size]
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// test vectors from RFC 6070
+	dk := PBKDF2([]byte("password"), []byte("salt"), 1, 20, sha1.New)
+	if hex.EncodeToString(dk) != "0c60c80f961f0e71f3a9b524af6012062fe037a6" {
+		t.Fatal("Error PBKDF2 iteration 1:", hex.EncodeToString(dk))
+	}
+	dk = PBKDF2([]byte("password"), []byte("salt"), 4096, 20, sha1.New)
+	if hex.EncodeToString(dk) != "4b007901b765489abead49d926f721d065a429c1" {
+		t.Fatal("Error PBKDF2 iteration 4096:", hex.EncodeToString(dk))
+	}
+	dk = PBKDF2([]byte("passwordPASSWORDpassword"), []byte("saltSALTsaltSALTsaltSALTsaltSALTsalt"), 4096, 25, sha1.New)
+	if hex.EncodeToString(dk) != "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038" {
+		t.Fatal("Error PBKDF2 multiple blocks:", hex.EncodeToString(dk))
+	}
+}
+
+func BenchmarkPBKDF2(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = PBKDF2([]byte("password"), []byte("salt"), WinZipAESIterations, 66, sha1.New)
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"errors"
+)
+
+const (
+	WinZipAESIterations = 1000   // WinZip AES key derivation iteration count
+	WinZipAESExtraID    = 0x9901 // WinZip AES extra field header id
+	WinZipAESMethod     = 99     // WinZip AES compression method
+	WinZipAESMacSize    = 10     // WinZip AES authentication code length
+	WinZipAESVerifySize = 2      // WinZip AES password verification value length
+)
+
+// WinZipAESSaltSize function
+// return salt length of WinZip AES strength, 1(AES-128), 2(AES-192) or 3(AES-256)
+func WinZipAESSaltSize(strength int) int {
+	return 4 + strength*4
+}
+
+// WinZipAESKeys function
+// derive encryption key, authentication key and password verification value
+// strength should be 1(AES-128), 2(AES-192) or 3(AES-256)
+func WinZipAESKeys(password, salt []byte, strength int) (key, mac, verify []byte, err error) {
+	if strength < 1 || strength > 3 {
+		err = errors.New("unsupported winzip aes strength")
+		return key, mac, verify, err
+	}
+	size := 8 + strength*8
+	dk := PBKDF2(password, salt, WinZipAESIterations, size*2+WinZipAESVerifySize, sha1.New)
+	return dk[:size], dk[size : size*2], dk[size*2:], err
+}
+
+// NewWinZipAESStream function
+// WinZip AES use CTR mode with a little-endian counter starting at 1,
+// which is different from the big-endian counter of cipher.NewCTR
+func NewWinZipAESStream(key []byte) (cipher.Stream, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &winZipAESStream{block: block}, nil
+}
+
+type winZipAESStream struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+func (s *winZipAESStream) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("winzip aes: output smaller than input")
+	}
+	for i := range src {
+		if s.used == 0 {
+			// increase little-endian counter
+			for k := range s.counter {
+				s.counter[k]++
+				if s.counter[k] != 0 {
+					break
+				}
+			}
+			s.block.Encrypt(s.stream[:], s.counter[:])
+		}
+		dst[i] = src[i] ^ s.stream[s.used]
+		s.used = (s.used + 1) % aes.BlockSize
+	}
+}
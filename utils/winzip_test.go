@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWinZipAESKeys(t *testing.T) {
+	salt := make([]byte, WinZipAESSaltSize(3))
+	key, mac, verify, err := WinZipAESKeys([]byte("satellite"), salt, 3)
+	if err != nil {
+		t.Fatal("Error WinZip AES Keys:", err)
+	}
+	if len(key) != 32 || len(mac) != 32 || len(verify) != WinZipAESVerifySize {
+		t.Fatalf("Error WinZip AES Keys length: %d %d %d", len(key), len(mac), len(verify))
+	}
+	_, _, _, err = WinZipAESKeys([]byte("satellite"), salt, 4)
+	if err == nil {
+		t.Fatal("Error WinZip AES Keys: strength 4 should be unsupported")
+	}
+}
+
+func TestNewWinZipAESStream(t *testing.T) {
+	key := make([]byte, 32)
+	src := bytes.Repeat([]byte("satellite"), 100)
+	dest := make([]byte, len(src))
+	s, err := NewWinZipAESStream(key)
+	if err != nil {
+		t.Fatal("Error New WinZip AES Stream:", err)
+	}
+	s.XORKeyStream(dest, src)
+	if bytes.Equal(dest, src) {
+		t.Fatal("Error New WinZip AES Stream: data not encrypted")
+	}
+	// decrypt in pieces should give the origin data
+	s, _ = NewWinZipAESStream(key)
+	s.XORKeyStream(dest[:7], dest[:7])
+	s.XORKeyStream(dest[7:], dest[7:])
+	if !bytes.Equal(dest, src) {
+		t.Fatal("Error New WinZip AES Stream: data not decrypted")
+	}
+}
+
+func BenchmarkNewWinZipAESStream(b *testing.B) {
+	key := make([]byte, 32)
+	src := bytes.Repeat([]byte("satellite"), 100)
+	for i := 0; i < b.N; i++ {
+		s, err := NewWinZipAESStream(key)
+		if err != nil {
+			b.Fatal("Error New WinZip AES Stream:", err)
+		}
+		s.XORKeyStream(src, src)
+	}
+}